@@ -127,6 +127,7 @@ fn main() {
         risk_score: calculate_risk_score(&all_vulnerabilities),
         language: Language::JavaScript, // Default, doesn't matter for report
         recommendations: Vec::new(),
+        protocol_compliance: Vec::new(),
     };
 
     audit_result.generate_recommendations();
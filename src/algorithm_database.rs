@@ -130,6 +130,12 @@ pub fn get_cccs_status(crypto_type: &CryptoType) -> CCCSApprovalStatus {
         CryptoType::Des => "DES",
         CryptoType::TripleDes => "3DES",
         CryptoType::Rc4 => "RC4",
+        CryptoType::Md4 => "MD4",
+        CryptoType::Ripemd160 => "RIPEMD-160",
+        CryptoType::Blowfish => "Blowfish",
+        CryptoType::Cast5 => "CAST5",
+        CryptoType::DeprecatedApi => "Deprecated API",
+        CryptoType::InsecureTls => "Insecure TLS",
     };
 
     get_algorithm_validation(algorithm_name)
@@ -317,6 +323,12 @@ pub fn get_itsp_reference(crypto_type: &CryptoType) -> String {
         CryptoType::Des => "DES",
         CryptoType::TripleDes => "3DES",
         CryptoType::Rc4 => "RC4",
+        CryptoType::Md4 => "MD4",
+        CryptoType::Ripemd160 => "RIPEMD-160",
+        CryptoType::Blowfish => "Blowfish",
+        CryptoType::Cast5 => "CAST5",
+        CryptoType::DeprecatedApi => "Deprecated API",
+        CryptoType::InsecureTls => "Insecure TLS",
     };
 
     get_algorithm_validation(algorithm_name)
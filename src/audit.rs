@@ -1,7 +1,17 @@
+use crate::cgo::label_native_crypto;
+use crate::confidence::classify_match;
+use crate::explain::{MatchOutcome, MatchTrace};
+use crate::fingerprint::assign_fingerprints;
+use crate::go_deprecated::detect_deprecated_go_apis;
+use crate::go_ssh::detect_go_ssh_configs;
+use crate::hash_usage::apply_hash_usage;
+use crate::semgrep::CustomRule;
+use crate::tls_misuse::detect_tls_misuse;
 use crate::types::*;
 use lazy_static::lazy_static;
 use regex::Regex;
 use std::str::FromStr;
+use std::time::{Duration, Instant};
 use thiserror::Error;
 
 #[derive(Error, Debug)]
@@ -20,70 +30,191 @@ pub enum AuditError {
 
     #[error("Parse error: {0}")]
     ParseError(String),
+
+    #[error("Time budget exceeded after {0} of {1} lines (budget: {2:?})")]
+    TimeBudgetExceeded(usize, usize, Duration),
+
+    #[error("Memory cap exceeded: ~{0} bytes (cap: {1})")]
+    MemoryCapExceeded(usize, usize),
+}
+
+/// Per-file resource limits; `None` means unlimited
+#[derive(Debug, Clone, Copy, Default)]
+pub struct ResourceLimits {
+    /// Wall-clock time for analyzing one file
+    pub time_budget: Option<Duration>,
+    /// Estimated memory for one file: source, line index and findings
+    pub memory_cap: Option<usize>,
 }
 
 // Input validation constants
 const MAX_SOURCE_SIZE: usize = 10 * 1024 * 1024; // 10MB
 const MAX_LINES: usize = 500_000;
 
+/// Lines analyzed between time budget checks
+const BUDGET_CHECK_INTERVAL: usize = 64;
+
+/// Longest context snippet kept for a finding (minified bundles put everything on one line)
+const MAX_CONTEXT_LEN: usize = 160;
+
 // Lazy-compiled regex patterns for crypto detection
+//
+// Each pattern matches exactly the algorithm token, so the match span is the
+// finding's column range. Token boundaries are checked in code (see
+// `at_token_boundary`) because the regex crate has no look-around.
 lazy_static! {
     // RSA patterns with key size detection
     static ref RSA_PATTERN: Regex = Regex::new(
-        r"(?i)(RSA|Rsa|rsa)[^a-zA-Z]*([\d]{3,4})?|(generate.*rsa.*key|rsa.*keygen)"
+        r"(?i)rsa"
     ).expect("RSA_PATTERN: Invalid regex - this is a compile-time bug");
 
-    static ref RSA_KEYGEN: Regex = Regex::new(
-        r"(?i)(RSA\.generate|generateKeyPair.*RSA|KeyPairGenerator\.getInstance.*RSA|rsa\.GenerateKey)"
-    ).expect("RSA_KEYGEN: Invalid regex - this is a compile-time bug");
-
     static ref RSA_KEY_SIZE: Regex = Regex::new(
         r"(?i)(?:rsa|RSA)[^0-9]*(512|1024|2048|3072|4096|8192)"
     ).expect("RSA_KEY_SIZE: Invalid regex - this is a compile-time bug");
 
     // ECDSA/ECDH patterns
     static ref ECDSA_PATTERN: Regex = Regex::new(
-        r"(?i)(ECDSA|ECC|elliptic.*curve|secp256k1|secp384r1|prime256v1|P-256|P-384|P-521)"
+        r"(?i)(ECDSA|ECC|elliptic[\s_.-]*curve)"
     ).expect("ECDSA_PATTERN: Invalid regex - this is a compile-time bug");
 
+    // Named curves imply ECDSA unless the line is about ECDH
+    static ref CURVE_PATTERN: Regex = Regex::new(
+        r"(?i)(secp(?:256|384|521)[kr]1|prime256v1|P-?(?:256|384|521))"
+    ).expect("CURVE_PATTERN: Invalid regex - this is a compile-time bug");
+
     static ref ECDH_PATTERN: Regex = Regex::new(
-        r"(?i)(ECDH|ecdh|elliptic.*diffie|curve25519)"
+        r"(?i)(ECDH|elliptic[\s_.-]*diffie|curve25519)"
     ).expect("ECDH_PATTERN: Invalid regex - this is a compile-time bug");
 
     // DSA and Diffie-Hellman
     static ref DSA_PATTERN: Regex = Regex::new(
-        r"(?i)(DSA|dsa)[^a-zA-Z]|(Digital.*Signature.*Algorithm)"
+        r"(?i)(DSA|Digital[\s_]*Signature[\s_]*Algorithm)"
     ).expect("DSA_PATTERN: Invalid regex - this is a compile-time bug");
 
     static ref DH_PATTERN: Regex = Regex::new(
-        r"(?i)(diffie.*hellman|DH_|DHE|DHE_)"
+        r"(?i)(diffie[\s_-]*hellman|DHE?_|DHE)"
     ).expect("DH_PATTERN: Invalid regex - this is a compile-time bug");
 
     // Deprecated hash functions
     static ref SHA1_PATTERN: Regex = Regex::new(
-        r"(?i)(SHA1|sha1|SHA-1|sha-1)[^0-9]"
+        r"(?i)SHA-?1"
     ).expect("SHA1_PATTERN: Invalid regex - this is a compile-time bug");
 
     static ref MD5_PATTERN: Regex = Regex::new(
-        r"(?i)(MD5|md5)"
+        r"(?i)MD5"
     ).expect("MD5_PATTERN: Invalid regex - this is a compile-time bug");
 
-    // Deprecated ciphers - simpler pattern without lookahead
+    // Deprecated ciphers
     static ref DES_PATTERN: Regex = Regex::new(
-        r"(?i)(DES_|_DES|\.DES|DES\.|\bDES\b)"
+        r"(?i)DES"
     ).expect("DES_PATTERN: Invalid regex - this is a compile-time bug");
 
     static ref TRIPLE_DES_PATTERN: Regex = Regex::new(
-        r"(?i)(3DES|TripleDES|DESede)"
+        r"(?i)(3DES|Triple[_-]?DES|DESede|DES-EDE3?)"
     ).expect("TRIPLE_DES_PATTERN: Invalid regex - this is a compile-time bug");
 
     static ref RC4_PATTERN: Regex = Regex::new(
-        r"(?i)(RC4|rc4|ARCFOUR)"
+        r"(?i)(RC4|ARCFOUR)"
     ).expect("RC4_PATTERN: Invalid regex - this is a compile-time bug");
+
+    static ref RULES: Vec<Rule> = vec![
+        Rule::new(CryptoType::Rsa, &RSA_PATTERN, 1),
+        Rule::new(CryptoType::Ecdsa, &ECDSA_PATTERN, 2),
+        Rule { curve: true, ..Rule::new(CryptoType::Ecdsa, &CURVE_PATTERN, 2) },
+        Rule::new(CryptoType::Ecdh, &ECDH_PATTERN, 2),
+        Rule::new(CryptoType::Dsa, &DSA_PATTERN, 1),
+        Rule::new(CryptoType::DiffieHellman, &DH_PATTERN, 1),
+        Rule::new(CryptoType::Sha1, &SHA1_PATTERN, 1),
+        Rule::new(CryptoType::Md5, &MD5_PATTERN, 1),
+        Rule::new(CryptoType::Des, &DES_PATTERN, 1),
+        Rule::new(CryptoType::TripleDes, &TRIPLE_DES_PATTERN, 2),
+        Rule::new(CryptoType::Rc4, &RC4_PATTERN, 1),
+    ];
+}
+
+/// A detection rule: the algorithm a pattern identifies and how specific it is
+struct Rule {
+    crypto_type: CryptoType,
+    pattern: &'static Regex,
+    /// Wins over overlapping matches of lower specificity (ECDSA over DSA, ECDH over DH, 3DES over DES)
+    specificity: u8,
+    /// Curve names only imply ECDSA when no ECDH usage is on the same line
+    curve: bool,
+}
+
+impl Rule {
+    fn new(crypto_type: CryptoType, pattern: &'static Regex, specificity: u8) -> Self {
+        Self {
+            crypto_type,
+            pattern,
+            specificity,
+            curve: false,
+        }
+    }
+}
+
+/// A rule match with its exact byte span in the line
+#[derive(Debug, Clone)]
+struct Candidate {
+    crypto_type: CryptoType,
+    pattern: &'static str,
+    start: usize,
+    end: usize,
+    specificity: u8,
+    curve: bool,
+    match_kind: MatchKind,
+}
+
+impl Candidate {
+    fn overlaps(&self, other: &Candidate) -> bool {
+        self.start < other.end && other.start < self.end
+    }
+
+    fn trace(&self, line: &str, outcome: MatchOutcome) -> MatchTrace {
+        MatchTrace {
+            rule_id: self.crypto_type.rule_id().to_string(),
+            pattern: self.pattern.to_string(),
+            crypto_type: self.crypto_type.clone(),
+            start: self.start,
+            end: self.end,
+            text: line[self.start..self.end].to_string(),
+            match_kind: (outcome == MatchOutcome::Reported).then_some(self.match_kind),
+            outcome,
+        }
+    }
 }
 
 /// Main audit function - analyzes source code for quantum-vulnerable cryptography
 pub fn analyze(source: &str, language: &str) -> Result<AuditResult, AuditError> {
+    analyze_with_rules(source, language, &[])
+}
+
+/// Analyze with imported custom rules running alongside the built-in detectors
+///
+/// A custom rule finding replaces the built-in finding of the same algorithm
+/// it overlaps, so a rule written for `rsa.GenerateKey` reports once, with
+/// the rule's message and severity.
+pub fn analyze_with_rules(
+    source: &str,
+    language: &str,
+    rules: &[CustomRule],
+) -> Result<AuditResult, AuditError> {
+    analyze_with_limits(source, language, rules, &ResourceLimits::default())
+}
+
+/// Analyze within per-file resource limits
+///
+/// The time budget is checked between lines (regex matching is linear, so
+/// no single line can stall) and between the whole-file passes (deprecated
+/// Go APIs, TLS misuse, cgo, SSH configurations), the memory estimate
+/// before analysis and as findings accumulate. A file over either limit
+/// yields an error instead of a partial result.
+pub fn analyze_with_limits(
+    source: &str,
+    language: &str,
+    rules: &[CustomRule],
+    limits: &ResourceLimits,
+) -> Result<AuditResult, AuditError> {
     // Parse language
     let lang = parse_language(language)?;
 
@@ -107,58 +238,71 @@ pub fn analyze(source: &str, language: &str) -> Result<AuditResult, AuditError>
         return Err(AuditError::TooManyLines(line_count, MAX_LINES));
     }
 
+    let mut memory = source_size + line_count * std::mem::size_of::<&str>();
+    check_memory(memory, limits)?;
+    let started = limits.time_budget.map(|budget| (Instant::now(), budget));
+    let check_budget = |lines_done: usize| match started {
+        Some((started, budget)) if started.elapsed() > budget => Err(
+            AuditError::TimeBudgetExceeded(lines_done, line_count, budget),
+        ),
+        _ => Ok(()),
+    };
+
     let mut result = AuditResult::new(lang, line_count);
+    let rules: Vec<&CustomRule> = rules.iter().filter(|r| r.applies_to(lang)).collect();
+    let mut deprecated_apis = if lang == Language::Go {
+        detect_deprecated_go_apis(source)
+    } else {
+        Vec::new()
+    };
+    check_budget(0)?;
+    let mut tls_misuse = detect_tls_misuse(source, lang);
 
     // Scan each line for crypto patterns
     for (line_idx, line) in lines.iter().enumerate() {
-        let line_num = line_idx + 1;
-
-        // Detect RSA
-        if let Some(vuln) = detect_rsa(line, line_num) {
-            result.add_vulnerability(vuln);
-        }
-
-        // Detect ECDSA
-        if let Some(vuln) = detect_ecdsa(line, line_num) {
-            result.add_vulnerability(vuln);
-        }
-
-        // Detect ECDH
-        if let Some(vuln) = detect_ecdh(line, line_num) {
-            result.add_vulnerability(vuln);
-        }
-
-        // Detect DSA
-        if let Some(vuln) = detect_dsa(line, line_num) {
-            result.add_vulnerability(vuln);
-        }
-
-        // Detect Diffie-Hellman
-        if let Some(vuln) = detect_diffie_hellman(line, line_num) {
-            result.add_vulnerability(vuln);
-        }
-
-        // Detect deprecated hash functions
-        if let Some(vuln) = detect_sha1(line, line_num) {
-            result.add_vulnerability(vuln);
+        if line_idx % BUDGET_CHECK_INTERVAL == 0 {
+            check_budget(line_idx)?;
         }
 
-        if let Some(vuln) = detect_md5(line, line_num) {
-            result.add_vulnerability(vuln);
+        let mut found = detect_line(line, line_idx + 1);
+        for rule in &rules {
+            if let Some(custom) = rule.detect_line(line, line_idx + 1) {
+                found.retain(|v| {
+                    v.rule_id.is_some()
+                        || v.crypto_type != custom.crypto_type
+                        || v.end_column <= custom.column
+                        || custom.end_column <= v.column
+                });
+                found.push(custom);
+            }
         }
-
-        // Detect deprecated ciphers
-        if let Some(vuln) = detect_des(line, line_num) {
-            result.add_vulnerability(vuln);
+        // A deprecated package import supersedes the text match of its algorithm
+        for deprecated in take_line(&mut deprecated_apis, line_idx + 1) {
+            found.retain(|v| v.rule_id.is_some() || v.crypto_type != deprecated.crypto_type);
+            found.push(deprecated);
         }
-
-        if let Some(vuln) = detect_triple_des(line, line_num) {
+        found.extend(take_line(&mut tls_misuse, line_idx + 1));
+        found.sort_by_key(|v| v.column);
+        // Weak hashes are rescored for their usage before they count toward the stats
+        apply_hash_usage(&lines, line_idx, &mut found);
+
+        for vuln in found {
+            memory += std::mem::size_of::<Vulnerability>()
+                + vuln.context.len()
+                + vuln.message.len()
+                + vuln.recommendation.len();
             result.add_vulnerability(vuln);
         }
+        check_memory(memory, limits)?;
+    }
 
-        if let Some(vuln) = detect_rc4(line, line_num) {
-            result.add_vulnerability(vuln);
-        }
+    // C code in cgo preambles and calls into it run outside the Go standard library
+    if lang == Language::Go {
+        check_budget(line_count)?;
+        label_native_crypto(source, &mut result.vulnerabilities);
+        check_budget(line_count)?;
+        result.protocol_compliance = detect_go_ssh_configs(source);
+        check_budget(line_count)?;
     }
 
     // Calculate overall risk score
@@ -167,276 +311,415 @@ pub fn analyze(source: &str, language: &str) -> Result<AuditResult, AuditError>
     // Generate recommendations
     result.generate_recommendations();
 
+    assign_fingerprints(&mut result.vulnerabilities);
+
     Ok(result)
 }
 
+/// Remove and return the findings reported on `line`
+fn take_line(findings: &mut Vec<Vulnerability>, line: usize) -> Vec<Vulnerability> {
+    let (taken, rest) = std::mem::take(findings)
+        .into_iter()
+        .partition(|v| v.line == line);
+    *findings = rest;
+    taken
+}
+
+fn check_memory(estimate: usize, limits: &ResourceLimits) -> Result<(), AuditError> {
+    match limits.memory_cap {
+        Some(cap) if estimate > cap => Err(AuditError::MemoryCapExceeded(estimate, cap)),
+        _ => Ok(()),
+    }
+}
+
 /// Parse language string to enum
 fn parse_language(lang: &str) -> Result<Language, AuditError> {
     Language::from_string(lang).ok_or_else(|| AuditError::UnsupportedLanguage(lang.to_string()))
 }
 
-/// Detect RSA usage and determine risk
-fn detect_rsa(line: &str, line_num: usize) -> Option<Vulnerability> {
-    if !RSA_PATTERN.is_match(line) && !RSA_KEYGEN.is_match(line) {
-        return None;
+/// Run every detector against a single line of source
+///
+/// Matches of all rules are collected with their spans, overlapping matches
+/// are resolved to the most specific algorithm, and each algorithm yields at
+/// most one finding per line, at its most confident occurrence.
+pub(crate) fn detect_line(line: &str, line_num: usize) -> Vec<Vulnerability> {
+    let best = select_per_algorithm(line, resolve_line(line, None), None);
+
+    let mut found: Vec<Vulnerability> = best
+        .iter()
+        .map(|candidate| build_finding(candidate, line, line_num))
+        .collect();
+
+    if line.trim().len() > MAX_CONTEXT_LEN {
+        for vuln in &mut found {
+            vuln.context = truncate_context(line, vuln.column);
+        }
     }
 
-    // Try to extract key size
-    let key_size = RSA_KEY_SIZE
-        .captures(line)
-        .and_then(|cap| cap.get(1))
-        .and_then(|m| u32::from_str(m.as_str()).ok());
-
-    let (severity, risk_score, message) = match key_size {
-        Some(size) if size < 2048 => (
-            Severity::Critical,
-            100,
-            format!(
-                "RSA with {}-bit key is critically vulnerable to quantum attacks",
-                size
-            ),
-        ),
-        Some(size) if size < 4096 => (
-            Severity::High,
-            85,
-            format!(
-                "RSA with {}-bit key will be vulnerable to quantum computers",
-                size
-            ),
-        ),
-        Some(size) => (
-            Severity::High,
-            80,
-            format!(
-                "RSA with {}-bit key is quantum-vulnerable (Shor's algorithm)",
-                size
-            ),
-        ),
-        None => (
-            Severity::High,
-            85,
-            "RSA detected - vulnerable to quantum attacks via Shor's algorithm".to_string(),
-        ),
-    };
-
-    let column = line
-        .find(|c: char| c.to_lowercase().any(|c| c == 'r'))
-        .unwrap_or(0);
+    found
+}
 
-    Some(Vulnerability {
-        crypto_type: CryptoType::Rsa,
-        severity,
-        risk_score,
-        line: line_num,
-        column,
-        context: line.trim().to_string(),
-        message,
-        recommendation:
-            "Replace with CRYSTALS-Dilithium (signatures) or CRYSTALS-Kyber (encryption)"
-                .to_string(),
-        key_size,
-    })
+/// Every built-in rule match on a line and what became of it, in line order
+pub(crate) fn trace_line(line: &str) -> Vec<MatchTrace> {
+    let mut trace = Vec::new();
+    let candidates = resolve_line(line, Some(&mut trace));
+    let best = select_per_algorithm(line, candidates, Some(&mut trace));
+    trace.extend(
+        best.iter()
+            .map(|candidate| candidate.trace(line, MatchOutcome::Reported)),
+    );
+    trace.sort_by_key(|t| (t.start, t.end));
+    trace
 }
 
-/// Detect ECDSA usage
-fn detect_ecdsa(line: &str, line_num: usize) -> Option<Vulnerability> {
-    if !ECDSA_PATTERN.is_match(line) {
-        return None;
+/// Keep the most confident occurrence of each algorithm
+fn select_per_algorithm(
+    line: &str,
+    candidates: Vec<Candidate>,
+    mut trace: Option<&mut Vec<MatchTrace>>,
+) -> Vec<Candidate> {
+    let mut best: Vec<Candidate> = Vec::new();
+
+    for candidate in candidates {
+        let dropped = match best
+            .iter_mut()
+            .find(|b| b.crypto_type == candidate.crypto_type)
+        {
+            Some(current) => {
+                if candidate.match_kind.confidence() > current.match_kind.confidence() {
+                    Some(std::mem::replace(current, candidate))
+                } else {
+                    Some(candidate)
+                }
+            }
+            None => {
+                best.push(candidate);
+                None
+            }
+        };
+        if let (Some(dropped), Some(trace)) = (dropped, trace.as_deref_mut()) {
+            trace.push(dropped.trace(line, MatchOutcome::LessConfidentDuplicate));
+        }
     }
 
-    let column = line
-        .find(|c: char| c.to_uppercase().any(|c| "ECDSA".contains(c)))
-        .unwrap_or(0);
-
-    Some(Vulnerability {
-        crypto_type: CryptoType::Ecdsa,
-        severity: Severity::High,
-        risk_score: 85,
-        line: line_num,
-        column,
-        context: line.trim().to_string(),
-        message: "ECDSA (Elliptic Curve Digital Signature Algorithm) is quantum-vulnerable"
-            .to_string(),
-        recommendation: "Replace with CRYSTALS-Dilithium or SPHINCS+ for post-quantum signatures"
-            .to_string(),
-        key_size: None,
-    })
+    best
 }
 
-/// Detect ECDH usage
-fn detect_ecdh(line: &str, line_num: usize) -> Option<Vulnerability> {
-    if !ECDH_PATTERN.is_match(line) {
-        return None;
+/// All rule matches of a line after overlap resolution, in line order
+///
+/// Matches dropped along the way are recorded in `trace` when given.
+fn resolve_line(line: &str, mut trace: Option<&mut Vec<MatchTrace>>) -> Vec<Candidate> {
+    let mut candidates: Vec<Candidate> = Vec::new();
+    for rule in RULES.iter() {
+        for m in rule.pattern.find_iter(line) {
+            let candidate = Candidate {
+                crypto_type: rule.crypto_type.clone(),
+                pattern: rule.pattern.as_str(),
+                start: m.start(),
+                end: m.end(),
+                specificity: rule.specificity,
+                curve: rule.curve,
+                match_kind: MatchKind::default(),
+            };
+            if at_token_boundary(line, m.start(), m.end()) {
+                candidates.push(candidate);
+            } else if let Some(trace) = trace.as_deref_mut() {
+                trace.push(candidate.trace(line, MatchOutcome::NotAtTokenBoundary));
+            }
+        }
     }
 
-    let column = line
-        .find(|c: char| c.to_uppercase().any(|c| "ECDH".contains(c)))
-        .unwrap_or(0);
-
-    Some(Vulnerability {
-        crypto_type: CryptoType::Ecdh,
-        severity: Severity::High,
-        risk_score: 85,
-        line: line_num,
-        column,
-        context: line.trim().to_string(),
-        message: "ECDH (Elliptic Curve Diffie-Hellman) is quantum-vulnerable".to_string(),
-        recommendation: "Replace with CRYSTALS-Kyber or NTRU for quantum-safe key exchange"
-            .to_string(),
-        key_size: None,
-    })
-}
-
-/// Detect DSA usage
-fn detect_dsa(line: &str, line_num: usize) -> Option<Vulnerability> {
-    if !DSA_PATTERN.is_match(line) {
-        return None;
+    // Most specific (then longest) match claims its span first
+    candidates.sort_by(|a, b| {
+        b.specificity
+            .cmp(&a.specificity)
+            .then((b.end - b.start).cmp(&(a.end - a.start)))
+            .then(a.start.cmp(&b.start))
+    });
+    let mut accepted: Vec<Candidate> = Vec::new();
+    for candidate in candidates {
+        match accepted.iter().find(|a| a.overlaps(&candidate)) {
+            None => accepted.push(candidate),
+            Some(winner) => {
+                if let Some(trace) = trace.as_deref_mut() {
+                    let outcome = MatchOutcome::Overlapped {
+                        by: winner.crypto_type.clone(),
+                    };
+                    trace.push(candidate.trace(line, outcome));
+                }
+            }
+        }
     }
 
-    let column = line.find("DSA").or_else(|| line.find("dsa")).unwrap_or(0);
+    // `createECDH('prime256v1')` names the curve of the key exchange, not a signature
+    if accepted.iter().any(|c| c.crypto_type == CryptoType::Ecdh) {
+        accepted.retain(|c| {
+            if c.curve
+                && let Some(trace) = trace.as_deref_mut()
+            {
+                trace.push(c.trace(line, MatchOutcome::CurveOfKeyExchange));
+            }
+            !c.curve
+        });
+    }
 
-    Some(Vulnerability {
-        crypto_type: CryptoType::Dsa,
-        severity: Severity::High,
-        risk_score: 90,
-        line: line_num,
-        column,
-        context: line.trim().to_string(),
-        message: "DSA (Digital Signature Algorithm) is quantum-vulnerable".to_string(),
-        recommendation: "Replace with CRYSTALS-Dilithium for post-quantum digital signatures"
-            .to_string(),
-        key_size: None,
-    })
+    for candidate in &mut accepted {
+        candidate.match_kind = classify_match(line, candidate.start, candidate.end);
+    }
+    accepted.sort_by_key(|c| c.start);
+    accepted
 }
 
-/// Detect Diffie-Hellman usage
-fn detect_diffie_hellman(line: &str, line_num: usize) -> Option<Vulnerability> {
-    if !DH_PATTERN.is_match(line) {
-        return None;
-    }
+/// Whether a match starts and ends on an identifier token boundary
+///
+/// Accepts `rsa.GenerateKey`, `generateRsaKey`, `SHA256withRSA`, `RSAPrivateKey`
+/// and `DES_ede`; rejects `universal`, `modes.`, `ECDSA` (for DSA) and `DESCRIPTION`.
+fn at_token_boundary(line: &str, start: usize, end: usize) -> bool {
+    let token = &line[start..end];
+    let (Some(first), Some(last)) = (token.chars().next(), token.chars().last()) else {
+        return false;
+    };
 
-    let column = line.find("diffie").or_else(|| line.find("DH")).unwrap_or(0);
+    let start_ok = match line[..start].chars().next_back() {
+        None => true,
+        Some(prev) if !prev.is_alphanumeric() => true,
+        Some(prev) if prev.is_ascii_digit() => true,
+        // camelCase boundary: `generateRsa`, `SHA256withRSA`
+        Some(prev) => prev.is_lowercase() && first.is_uppercase(),
+    };
 
-    Some(Vulnerability {
-        crypto_type: CryptoType::DiffieHellman,
-        severity: Severity::High,
-        risk_score: 85,
-        line: line_num,
-        column,
-        context: line.trim().to_string(),
-        message: "Diffie-Hellman key exchange is quantum-vulnerable".to_string(),
-        recommendation:
-            "Replace with CRYSTALS-Kyber or FrodoKEM for quantum-safe key encapsulation".to_string(),
-        key_size: None,
-    })
+    let mut rest = line[end..].chars();
+    let end_ok = match rest.next() {
+        None => true,
+        Some(next) if !next.is_alphanumeric() => true,
+        Some(next) if last.is_ascii_digit() => !next.is_ascii_digit(),
+        Some(next) if next.is_ascii_digit() => true,
+        // camelCase boundary: `rsaKey`, `RSAPrivateKey` (but not `DESCRIPTION`)
+        Some(next) if next.is_uppercase() => {
+            last.is_lowercase() || rest.next().is_none_or(|c| !c.is_uppercase())
+        }
+        Some(_) => false,
+    };
+
+    start_ok && end_ok
 }
 
-/// Detect SHA-1 usage
-fn detect_sha1(line: &str, line_num: usize) -> Option<Vulnerability> {
-    if !SHA1_PATTERN.is_match(line) {
-        return None;
+/// Trimmed line, or a window of it centred on `column` when the line is too long to show
+pub(crate) fn truncate_context(line: &str, column: usize) -> String {
+    let trimmed = line.trim();
+    if trimmed.len() <= MAX_CONTEXT_LEN {
+        return trimmed.to_string();
     }
 
-    let column = line.find("SHA1").or_else(|| line.find("sha1")).unwrap_or(0);
-
-    Some(Vulnerability {
-        crypto_type: CryptoType::Sha1,
-        severity: Severity::Critical,
-        risk_score: 95,
-        line: line_num,
-        column,
-        context: line.trim().to_string(),
-        message: "SHA-1 is cryptographically broken and should not be used".to_string(),
-        recommendation: "Replace with SHA-256, SHA-384, or SHA-512".to_string(),
-        key_size: None,
-    })
+    let floor = |mut i: usize| {
+        while !line.is_char_boundary(i) {
+            i -= 1;
+        }
+        i
+    };
+    let start = floor(column.min(line.len()).saturating_sub(MAX_CONTEXT_LEN / 2));
+    let end = floor((start + MAX_CONTEXT_LEN).min(line.len()));
+
+    format!(
+        "{}{}{}",
+        if start > 0 { "..." } else { "" },
+        line[start..end].trim(),
+        if end < line.len() { "..." } else { "" }
+    )
 }
 
-/// Detect MD5 usage
-fn detect_md5(line: &str, line_num: usize) -> Option<Vulnerability> {
-    if !MD5_PATTERN.is_match(line) {
-        return None;
-    }
-
-    let column = line.find("MD5").or_else(|| line.find("md5")).unwrap_or(0);
+/// Build the finding for a resolved match
+fn build_finding(candidate: &Candidate, line: &str, line_num: usize) -> Vulnerability {
+    let mut key_size = None;
+
+    let (severity, risk_score, message) = match candidate.crypto_type {
+        CryptoType::Rsa => {
+            // Try to extract key size
+            key_size = rsa_key_size(line, candidate.start).map(|(size, _)| size);
+
+            let (severity, risk_score, message) = match key_size {
+                Some(size) if size < 2048 => (
+                    Severity::Critical,
+                    100,
+                    format!(
+                        "RSA with {}-bit key is critically vulnerable to quantum attacks",
+                        size
+                    ),
+                ),
+                Some(size) if size < 4096 => (
+                    Severity::High,
+                    85,
+                    format!(
+                        "RSA with {}-bit key will be vulnerable to quantum computers",
+                        size
+                    ),
+                ),
+                Some(size) => (
+                    Severity::High,
+                    80,
+                    format!(
+                        "RSA with {}-bit key is quantum-vulnerable (Shor's algorithm)",
+                        size
+                    ),
+                ),
+                None => (
+                    Severity::High,
+                    85,
+                    "RSA detected - vulnerable to quantum attacks via Shor's algorithm".to_string(),
+                ),
+            };
+            (severity, risk_score, message)
+        }
+        CryptoType::Ecdsa => (
+            Severity::High,
+            85,
+            "ECDSA (Elliptic Curve Digital Signature Algorithm) is quantum-vulnerable".to_string(),
+        ),
+        CryptoType::Ecdh => (
+            Severity::High,
+            85,
+            "ECDH (Elliptic Curve Diffie-Hellman) is quantum-vulnerable".to_string(),
+        ),
+        CryptoType::Dsa => (
+            Severity::High,
+            90,
+            "DSA (Digital Signature Algorithm) is quantum-vulnerable".to_string(),
+        ),
+        CryptoType::DiffieHellman => (
+            Severity::High,
+            85,
+            "Diffie-Hellman key exchange is quantum-vulnerable".to_string(),
+        ),
+        CryptoType::Sha1 => (
+            Severity::Critical,
+            95,
+            "SHA-1 is cryptographically broken and should not be used".to_string(),
+        ),
+        CryptoType::Md5 => (
+            Severity::Critical,
+            100,
+            "MD5 is cryptographically broken and must not be used".to_string(),
+        ),
+        CryptoType::Des => (
+            Severity::Critical,
+            95,
+            "DES is obsolete and cryptographically weak".to_string(),
+        ),
+        CryptoType::TripleDes => (
+            Severity::High,
+            80,
+            "3DES (Triple DES) is deprecated and should be replaced".to_string(),
+        ),
+        CryptoType::Rc4 => (
+            Severity::Critical,
+            95,
+            "RC4 is cryptographically broken and must not be used".to_string(),
+        ),
+        CryptoType::Md4 => (
+            Severity::Critical,
+            100,
+            "MD4 is cryptographically broken and must not be used".to_string(),
+        ),
+        CryptoType::Ripemd160 => (
+            Severity::Medium,
+            60,
+            "RIPEMD-160 is a legacy hash that is not NIST-approved".to_string(),
+        ),
+        CryptoType::Blowfish => (
+            Severity::High,
+            75,
+            "Blowfish has a 64-bit block and is vulnerable to birthday attacks".to_string(),
+        ),
+        CryptoType::Cast5 => (
+            Severity::High,
+            75,
+            "CAST5 has a 64-bit block and is vulnerable to birthday attacks".to_string(),
+        ),
+        CryptoType::DeprecatedApi => (
+            Severity::Medium,
+            50,
+            "Deprecated cryptographic API".to_string(),
+        ),
+        CryptoType::InsecureTls => (
+            Severity::Critical,
+            95,
+            "TLS certificate verification is disabled".to_string(),
+        ),
+    };
 
-    Some(Vulnerability {
-        crypto_type: CryptoType::Md5,
-        severity: Severity::Critical,
-        risk_score: 100,
-        line: line_num,
-        column,
+    Vulnerability {
+        end_column: candidate.end,
+        match_kind: candidate.match_kind,
+        confidence: candidate.match_kind.confidence(),
         context: line.trim().to_string(),
-        message: "MD5 is cryptographically broken and must not be used".to_string(),
-        recommendation: "Replace with SHA-256 or SHA-3".to_string(),
-        key_size: None,
-    })
+        message,
+        recommendation: recommendation_for(&candidate.crypto_type).to_string(),
+        key_size,
+        ..Vulnerability::new(
+            candidate.crypto_type.clone(),
+            severity,
+            risk_score,
+            line_num,
+            candidate.start,
+        )
+    }
 }
 
-/// Detect DES usage
-fn detect_des(line: &str, line_num: usize) -> Option<Vulnerability> {
-    if !DES_PATTERN.is_match(line) {
-        return None;
-    }
+/// RSA key size following an RSA match at `start`, with the byte offset it was read at
+pub(crate) fn rsa_key_size(line: &str, start: usize) -> Option<(u32, usize)> {
+    let size = RSA_KEY_SIZE.captures(&line[start..])?.get(1)?;
+    Some((u32::from_str(size.as_str()).ok()?, start + size.start()))
+}
 
-    let column = line.find("DES").or_else(|| line.find("des")).unwrap_or(0);
+/// Built-in rule patterns with their algorithm, for profiling
+pub(crate) fn builtin_rules() -> impl Iterator<Item = (&'static CryptoType, &'static Regex)> {
+    RULES.iter().map(|rule| (&rule.crypto_type, rule.pattern))
+}
 
-    Some(Vulnerability {
-        crypto_type: CryptoType::Des,
-        severity: Severity::Critical,
-        risk_score: 95,
-        line: line_num,
-        column,
-        context: line.trim().to_string(),
-        message: "DES is obsolete and cryptographically weak".to_string(),
-        recommendation: "Replace with AES-256 or ChaCha20".to_string(),
-        key_size: None,
-    })
+/// Regex sources of the built-in rules for an algorithm
+pub(crate) fn builtin_patterns(crypto_type: &CryptoType) -> Vec<&'static str> {
+    RULES
+        .iter()
+        .filter(|rule| rule.crypto_type == *crypto_type)
+        .map(|rule| rule.pattern.as_str())
+        .collect()
 }
 
-/// Detect 3DES usage
-fn detect_triple_des(line: &str, line_num: usize) -> Option<Vulnerability> {
-    if !TRIPLE_DES_PATTERN.is_match(line) {
-        return None;
+/// Migration advice for an algorithm
+pub(crate) fn recommendation_for(crypto_type: &CryptoType) -> &'static str {
+    match crypto_type {
+        CryptoType::Rsa => {
+            "Replace with CRYSTALS-Dilithium (signatures) or CRYSTALS-Kyber (encryption)"
+        }
+        CryptoType::Ecdsa => {
+            "Replace with CRYSTALS-Dilithium or SPHINCS+ for post-quantum signatures"
+        }
+        CryptoType::Ecdh => "Replace with CRYSTALS-Kyber or NTRU for quantum-safe key exchange",
+        CryptoType::Dsa => "Replace with CRYSTALS-Dilithium for post-quantum digital signatures",
+        CryptoType::DiffieHellman => {
+            "Replace with CRYSTALS-Kyber or FrodoKEM for quantum-safe key encapsulation"
+        }
+        CryptoType::Sha1 => "Replace with SHA-256, SHA-384, or SHA-512",
+        CryptoType::Md5 => "Replace with SHA-256 or SHA-3",
+        CryptoType::Des => "Replace with AES-256 or ChaCha20",
+        CryptoType::TripleDes => "Replace with AES-256 or ChaCha20-Poly1305",
+        CryptoType::Rc4 => "Replace with AES-GCM or ChaCha20-Poly1305",
+        CryptoType::Md4 => "Replace with SHA-256 or SHA-3",
+        CryptoType::Ripemd160 => "Replace with SHA-256 or SHA-3",
+        CryptoType::Blowfish | CryptoType::Cast5 => "Replace with AES-GCM or ChaCha20-Poly1305",
+        CryptoType::DeprecatedApi => "Migrate to the supported replacement API",
+        CryptoType::InsecureTls => "Enable TLS certificate and host name verification",
     }
-
-    let column = line
-        .find("3DES")
-        .or_else(|| line.find("TripleDES"))
-        .unwrap_or(0);
-
-    Some(Vulnerability {
-        crypto_type: CryptoType::TripleDes,
-        severity: Severity::High,
-        risk_score: 80,
-        line: line_num,
-        column,
-        context: line.trim().to_string(),
-        message: "3DES (Triple DES) is deprecated and should be replaced".to_string(),
-        recommendation: "Replace with AES-256 or ChaCha20-Poly1305".to_string(),
-        key_size: None,
-    })
 }
 
-/// Detect RC4 usage
-fn detect_rc4(line: &str, line_num: usize) -> Option<Vulnerability> {
-    if !RC4_PATTERN.is_match(line) {
-        return None;
+/// Severity band of a risk score, for findings without a fixed severity
+pub(crate) fn severity_for_score(risk_score: u32) -> Severity {
+    match risk_score {
+        95.. => Severity::Critical,
+        70.. => Severity::High,
+        40.. => Severity::Medium,
+        _ => Severity::Low,
     }
-
-    let column = line.find("RC4").or_else(|| line.find("rc4")).unwrap_or(0);
-
-    Some(Vulnerability {
-        crypto_type: CryptoType::Rc4,
-        severity: Severity::Critical,
-        risk_score: 95,
-        line: line_num,
-        column,
-        context: line.trim().to_string(),
-        message: "RC4 is cryptographically broken and must not be used".to_string(),
-        recommendation: "Replace with AES-GCM or ChaCha20-Poly1305".to_string(),
-        key_size: None,
-    })
 }
 
 /// Calculate risk score for a crypto type and key size
@@ -456,6 +739,11 @@ pub fn score_vulnerability(crypto_type: &CryptoType, key_size: Option<u32>) -> u
         CryptoType::Des => 95,                      // Critical (weak)
         CryptoType::TripleDes => 80,                // High (deprecated)
         CryptoType::Rc4 => 95,                      // Critical (broken)
+        CryptoType::Md4 => 100,                     // Critical (broken)
+        CryptoType::Ripemd160 => 60,                // Medium (legacy, not approved)
+        CryptoType::Blowfish | CryptoType::Cast5 => 75, // High (64-bit block)
+        CryptoType::DeprecatedApi => 50,            // Medium (deprecated API)
+        CryptoType::InsecureTls => 95,              // Critical (no peer authentication)
     }
 }
 
@@ -463,6 +751,13 @@ pub fn score_vulnerability(crypto_type: &CryptoType, key_size: Option<u32>) -> u
 mod tests {
     use super::*;
 
+    fn detect(line: &str, crypto_type: CryptoType) -> Vulnerability {
+        detect_line(line, 1)
+            .into_iter()
+            .find(|v| v.crypto_type == crypto_type)
+            .expect("expected finding")
+    }
+
     #[test]
     fn test_parse_language() {
         assert!(parse_language("rust").is_ok());
@@ -474,7 +769,7 @@ mod tests {
     #[test]
     fn test_detect_rsa_1024() {
         let line = "RSA.generate(1024)";
-        let vuln = detect_rsa(line, 1).unwrap();
+        let vuln = detect(line, CryptoType::Rsa);
         assert_eq!(vuln.crypto_type, CryptoType::Rsa);
         assert_eq!(vuln.severity, Severity::Critical);
         assert_eq!(vuln.key_size, Some(1024));
@@ -483,7 +778,7 @@ mod tests {
     #[test]
     fn test_detect_rsa_2048() {
         let line = "generateKeyPair('rsa', { modulusLength: 2048 })";
-        let vuln = detect_rsa(line, 1).unwrap();
+        let vuln = detect(line, CryptoType::Rsa);
         assert_eq!(vuln.crypto_type, CryptoType::Rsa);
         assert_eq!(vuln.severity, Severity::High);
         assert_eq!(vuln.key_size, Some(2048));
@@ -492,7 +787,7 @@ mod tests {
     #[test]
     fn test_detect_ecdsa() {
         let line = "crypto.createSign('ecdsa-with-SHA256')";
-        let vuln = detect_ecdsa(line, 1).unwrap();
+        let vuln = detect(line, CryptoType::Ecdsa);
         assert_eq!(vuln.crypto_type, CryptoType::Ecdsa);
         assert_eq!(vuln.severity, Severity::High);
     }
@@ -500,12 +795,65 @@ mod tests {
     #[test]
     fn test_detect_md5() {
         let line = "hashlib.md5(data).hexdigest()";
-        let vuln = detect_md5(line, 1).unwrap();
+        let vuln = detect(line, CryptoType::Md5);
         assert_eq!(vuln.crypto_type, CryptoType::Md5);
         assert_eq!(vuln.severity, Severity::Critical);
         assert_eq!(vuln.risk_score, 100);
     }
 
+    #[test]
+    fn test_overlapping_matches_resolved() {
+        let types = |line: &str| -> Vec<CryptoType> {
+            detect_line(line, 1)
+                .into_iter()
+                .map(|v| v.crypto_type)
+                .collect()
+        };
+
+        assert_eq!(
+            types("key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)"),
+            vec![CryptoType::Ecdsa]
+        );
+        assert_eq!(
+            types("const ecdh = crypto.createECDH('prime256v1')"),
+            vec![CryptoType::Ecdh]
+        );
+        assert_eq!(
+            types("suite := tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"),
+            vec![CryptoType::Ecdh, CryptoType::Rsa]
+        );
+        assert_eq!(
+            types("Cipher.getInstance(\"TripleDES/CBC/PKCS5Padding\")"),
+            vec![CryptoType::TripleDes]
+        );
+        assert_eq!(
+            types("Signature.getInstance(\"SHA1withDSA\")"),
+            vec![CryptoType::Sha1, CryptoType::Dsa]
+        );
+    }
+
+    #[test]
+    fn test_token_boundaries() {
+        assert!(detect_line("let universal = versa;", 1).is_empty());
+        assert!(detect_line("DESCRIPTION = \"modes of operation\"", 1).is_empty());
+        assert!(detect_line("return cursor.fetch(address)", 1).is_empty());
+        assert_eq!(detect_line("KeyFactory.getInstance(\"RSA\")", 1).len(), 1);
+        assert_eq!(detect_line("RSAPrivateKey key = load();", 1).len(), 1);
+        assert_eq!(detect_line("fn generateRsaKey()", 1).len(), 1);
+    }
+
+    #[test]
+    fn test_exact_match_span() {
+        let line = "    return rsa.GenerateKey(rand.Reader, 2048)";
+        let vuln = detect(line, CryptoType::Rsa);
+        assert_eq!(&line[vuln.column..vuln.end_column], "rsa");
+        assert_eq!(vuln.key_size, Some(2048));
+
+        let line = "h = hashlib.new('sha-1')";
+        let vuln = detect(line, CryptoType::Sha1);
+        assert_eq!(&line[vuln.column..vuln.end_column], "sha-1");
+    }
+
     #[test]
     fn test_analyze_with_multiple_vulns() {
         let source = r#"
@@ -548,4 +896,64 @@ mod tests {
         assert_eq!(result.vulnerabilities.len(), 0);
         assert_eq!(result.risk_score, 0);
     }
+
+    #[test]
+    fn test_minified_context_truncated() {
+        let line = format!(
+            "{}crypto.createHash('md5'){}",
+            "a=1;".repeat(100),
+            "b=2;".repeat(100)
+        );
+        let vulns = detect_line(&line, 1);
+        assert_eq!(vulns.len(), 1);
+        assert!(vulns[0].context.len() <= MAX_CONTEXT_LEN + 6);
+        assert!(vulns[0].context.starts_with("..."));
+        assert!(vulns[0].context.contains("createHash('md5')"));
+    }
+
+    #[test]
+    fn test_resource_limits() {
+        let source = "h = hashlib.md5(data)\n".repeat(1000);
+        let unlimited = analyze_with_limits(&source, "python", &[], &ResourceLimits::default());
+        assert_eq!(unlimited.unwrap().vulnerabilities.len(), 1000);
+
+        let limits = ResourceLimits {
+            memory_cap: Some(source.len() + 64 * 1024),
+            ..Default::default()
+        };
+        assert!(matches!(
+            analyze_with_limits(&source, "python", &[], &limits),
+            Err(AuditError::MemoryCapExceeded(_, _))
+        ));
+
+        let limits = ResourceLimits {
+            time_budget: Some(Duration::ZERO),
+            ..Default::default()
+        };
+        assert!(matches!(
+            analyze_with_limits(&source, "python", &[], &limits),
+            Err(AuditError::TimeBudgetExceeded(0, 1000, _))
+        ));
+    }
+
+    #[test]
+    fn test_custom_rule_replaces_overlapping_builtin() {
+        let import = crate::semgrep::import_semgrep_rules(
+            "rules:\n  - id: weak-rsa\n    languages: [go]\n    message: weak RSA\n    pattern: rsa.GenerateKey($R, $BITS)\n",
+        )
+        .unwrap();
+        let source = "k, _ := rsa.GenerateKey(rand.Reader, 1024)\nh := md5.New()\n";
+
+        let result = analyze_with_rules(source, "go", &import.rules).unwrap();
+        assert_eq!(result.vulnerabilities.len(), 2);
+        let rsa = &result.vulnerabilities[0];
+        assert_eq!(rsa.rule_id.as_deref(), Some("weak-rsa"));
+        assert_eq!(rsa.message, "weak RSA");
+        assert_eq!(rsa.key_size, Some(1024));
+        assert_eq!(result.vulnerabilities[1].crypto_type, CryptoType::Md5);
+
+        let python =
+            analyze_with_rules("rsa.GenerateKey(r, 1024)", "python", &import.rules).unwrap();
+        assert!(python.vulnerabilities[0].rule_id.is_none());
+    }
 }
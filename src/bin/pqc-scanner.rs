@@ -1,29 +1,318 @@
 // PQC Scanner - CLI Entry Point
 // Command-line interface for scanning directories for cryptographic vulnerabilities
 
+use pqc_scanner::encoding::{EncodingError, OffsetMap, SourceEncoding, decode_source};
+use pqc_scanner::evaluation::Metrics;
 use pqc_scanner::{
-    Language, analyze, export_oscal_json, export_sc13_json, generate_oscal_json,
-    generate_sc13_report,
+    AuditResult, CallGraphReport, CertificatePolicy, CertificateReport, Confidence, CustomRule,
+    DiagnosticKind, Evaluation, GoBuildReport, GoBuildTarget, GoModule, GoPackageReport,
+    HashContext, Language, LineExplanation, LogLevel, LogRecord, ManifestKind, PqStatus, Profiler,
+    ProjectFile, ProjectLayout, ProtocolCompliance, ProtocolType, ReachabilityConfig,
+    ReachabilityReport, ResourceLimits, RuleDescription, RuleOrigin, RuntimeEvidence,
+    RuntimeReport, ScanDiagnostic, ScanEvent, ScanProfile, ScanSummary, Severity, ShardResult,
+    ShardSpec, SourceMap, SourceMapReference, SubProject, SymbolResolutionSummary, Vulnerability,
+    analyze_certificates, analyze_go_packages, analyze_reachability, analyze_with_limits,
+    analyze_with_rules, apply_build_constraints, assess_runtimes, assign_fingerprints,
+    catalog::languages_label,
+    compare_evaluations,
+    explain::MatchOutcome,
+    explain_line, export_call_graph_json, export_catalog_json, export_catalog_markdown,
+    export_certificates_json, export_diagnostics_json, export_evaluation_json,
+    export_explanation_json, export_go_packages_json, export_oscal_json, export_profile_json,
+    export_project_rollup_json, export_protocol_compliance_json, export_reachability_json,
+    export_runtimes_json, export_sarif_json, export_sc13_json, export_shard_json,
+    filter_by_confidence, find_rule, find_source_mapping_url, generate_call_graph_report,
+    generate_oscal_json, generate_project_sc13_report, generate_sarif_report, import_semgrep_rules,
+    is_fingerprint, is_minified, is_runtime_file, merge_shards, parse_file, parse_labeled_source,
+    parse_shard_json, project_rollup, remap_findings, resolve_project_symbols, resolve_symbols_in,
+    rule_catalog, scan_runtime_file,
+    shard::{FileResult, PluginFileResult, definitions_only},
+    to_json_line,
+    types::ParsedSource,
 };
+use std::collections::BTreeMap;
 use std::env;
+use std::fmt;
 use std::fs;
 use std::path::{Path, PathBuf};
 use std::process;
+use std::sync::OnceLock;
+use std::time::{Duration, Instant};
 
 // File size limits
 const MAX_FILE_SIZE: u64 = 10 * 1024 * 1024; // 10MB
 
+// Diagnostics go to stderr through the logger; stdout carries only results
+macro_rules! log_error {
+    ($($arg:tt)*) => { logger().log(LogLevel::Error, format_args!($($arg)*)) };
+}
+macro_rules! log_warn {
+    ($($arg:tt)*) => { logger().log(LogLevel::Warn, format_args!($($arg)*)) };
+}
+macro_rules! log_info {
+    ($($arg:tt)*) => { logger().log(LogLevel::Info, format_args!($($arg)*)) };
+}
+macro_rules! log_debug {
+    ($($arg:tt)*) => { logger().log(LogLevel::Debug, format_args!($($arg)*)) };
+}
+
+/// Diagnostic output settings (`--quiet`, `--log-level`, `--log-format`)
+struct Logger {
+    level: LogLevel,
+    /// One `LogRecord` JSON object per line instead of text
+    json: bool,
+}
+
+static LOGGER: OnceLock<Logger> = OnceLock::new();
+
+fn logger() -> &'static Logger {
+    LOGGER.get_or_init(|| Logger {
+        level: LogLevel::Info,
+        json: false,
+    })
+}
+
+impl Logger {
+    fn enabled(&self, level: LogLevel) -> bool {
+        level <= self.level
+    }
+
+    fn log(&self, level: LogLevel, message: fmt::Arguments) {
+        if !self.enabled(level) {
+            return;
+        }
+        if self.json {
+            if let Ok(line) = to_json_line(&LogRecord::new(level, message.to_string())) {
+                eprintln!("{}", line);
+            }
+            return;
+        }
+        match level {
+            LogLevel::Error => eprintln!("Error: {}", message),
+            LogLevel::Warn => eprintln!("Warning: {}", message),
+            LogLevel::Info => eprintln!("{}", message),
+            LogLevel::Debug => eprintln!("Debug: {}", message),
+        }
+    }
+}
+
+/// Result format of `scan` on stdout
+#[derive(Clone, Copy, PartialEq, Eq)]
+enum OutputFormat {
+    /// Human-readable findings and summary
+    Text,
+    /// One `ScanEvent` JSON object per line
+    Jsonl,
+}
+
 struct ScanOptions {
     target_path: String,
     report_dir: String,
     report_name: Option<String>,
     is_repo_url: bool,
     cleanup_after_scan: bool,
+    call_graph: bool,
+    reachability: Option<ReachabilityConfig>,
+    min_confidence: Confidence,
+    /// WASM plugin detectors (`--plugin`, requires the `plugins` feature)
+    #[cfg_attr(not(feature = "plugins"), allow(dead_code))]
+    plugins: Vec<PathBuf>,
+    /// Semgrep-style YAML rule files (`--rules`)
+    rule_files: Vec<PathBuf>,
+    /// Only scan this shard of the files and write a partial result (`--shard 3/8`)
+    shard: Option<ShardSpec>,
+    /// Per-file time and memory limits (`--time-budget`, `--memory-cap`)
+    limits: ResourceLimits,
+    /// Time files, languages and rules (`--profile`)
+    profile: bool,
+    format: OutputFormat,
+    /// Attribute files to sub-projects and report each one (`--projects`)
+    projects: bool,
+    /// Aggregate Go files by package import path (`--go-packages`)
+    go_packages: bool,
+    /// Evaluate Go build constraints for this target (`--go-target`, `--go-tags`)
+    go_build: Option<GoBuildTarget>,
+    /// Report generated certificates and flag them against this policy
+    /// (`--certificates`, `--pq-cutoff`, `--max-cert-days`)
+    certificates: Option<CertificatePolicy>,
+    /// Assess runtime and toolchain versions for PQ key exchange (`--runtimes`)
+    runtimes: bool,
+}
+
+struct MergeOptions {
+    shard_files: Vec<PathBuf>,
+    report_dir: String,
+    report_name: Option<String>,
+}
+
+/// `rules` subcommands
+enum RulesCommand {
+    List,
+    Describe(String),
+    Export {
+        markdown: bool,
+        output: Option<String>,
+    },
+}
+
+struct RulesOptions {
+    command: RulesCommand,
+    rule_files: Vec<PathBuf>,
+}
+
+struct ExplainOptions {
+    file_path: PathBuf,
+    location: ExplainLocation,
+    json: bool,
+    min_confidence: Confidence,
+    rule_files: Vec<PathBuf>,
+}
+
+/// Line to explain, given directly or as the fingerprint of one of its findings
+enum ExplainLocation {
+    Line(usize),
+    Fingerprint(String),
+}
+
+struct EvalOptions {
+    corpus_path: String,
+    baseline: Option<String>,
+    output: Option<String>,
+    min_confidence: Confidence,
+    rule_files: Vec<PathBuf>,
+}
+
+/// Running totals and collected results of a directory scan
+#[derive(Default)]
+struct ScanState {
+    total_files: usize,
+    total_vulnerabilities: usize,
+    critical_count: usize,
+    high_count: usize,
+    /// Bundles skipped because their original sources are scanned instead
+    skipped_bundles: usize,
+    /// Files skipped or not analyzed, and why
+    diagnostics: Vec<ScanDiagnostic>,
+    /// Files transcoded to UTF-8 before scanning
+    transcoded_files: usize,
+    project_files: Vec<ProjectFile>,
+    /// Keep whole parse trees (`--call-graph`, `--reachability`, `--go-packages`);
+    /// otherwise only the constants that symbol resolution reads
+    keep_parse_trees: bool,
+    /// Constants of the files of other shards (`--shard`), for symbol resolution
+    definition_files: Vec<ProjectFile>,
+    /// Column map back to the original bytes, parallel to `project_files`
+    offset_maps: Vec<Option<OffsetMap>>,
+    /// Plugin findings in files without language support, by relative path
+    plugin_files: Vec<(String, Vec<Vulnerability>)>,
+    /// Timings collected with `--profile`
+    profiler: Option<Profiler>,
+    /// Stream `ScanEvent`s to stdout (`--format jsonl`)
+    events: bool,
+    /// Streamed findings are filtered as the final results will be
+    min_confidence: Confidence,
+    /// Serialized findings streamed per file, parallel to `project_files`
+    streamed: Vec<Vec<String>>,
+    /// Record project roots from manifests (`--projects`)
+    detect_projects: bool,
+    projects: Vec<SubProject>,
+    /// Record Go modules from `go.mod` (`--go-packages`)
+    detect_go_modules: bool,
+    go_modules: Vec<GoModule>,
+    /// Record runtime versions and PQ settings (`--runtimes`)
+    detect_runtimes: bool,
+    runtime_evidence: RuntimeEvidence,
+}
+
+impl ScanState {
+    fn skip(&mut self, path: &Path, kind: DiagnosticKind, message: String) {
+        log_warn!("{}", message);
+        let diagnostic = ScanDiagnostic {
+            path: path.to_string_lossy().to_string(),
+            kind,
+            message,
+        };
+        self.emit(&ScanEvent::from(&diagnostic));
+        self.diagnostics.push(diagnostic);
+    }
+
+    /// Stream the findings of a file just analyzed, filtered and located as they will be reported
+    fn stream_findings(&mut self, path: &str, audit: &AuditResult, offsets: Option<&OffsetMap>) {
+        if !self.events {
+            return;
+        }
+        let mut audit = audit.clone();
+        filter_by_confidence(&mut audit, self.min_confidence);
+        if let Some(offsets) = offsets {
+            offsets.remap_columns(&mut audit);
+        }
+        for vuln in &audit.vulnerabilities {
+            self.emit(&ScanEvent::Finding {
+                path: path.to_string(),
+                finding: Box::new(vuln.clone()),
+            });
+        }
+        self.streamed
+            .push(serialize_findings(&audit.vulnerabilities));
+    }
+
+    /// Send the final findings of every file that changed since it was streamed
+    fn revise_streamed_findings(&self) {
+        if !self.events {
+            return;
+        }
+        for (file, streamed) in self.project_files.iter().zip(&self.streamed) {
+            if serialize_findings(&file.audit.vulnerabilities) != *streamed {
+                self.emit(&ScanEvent::FindingsRevised {
+                    path: file.path.clone(),
+                    findings: file.audit.vulnerabilities.clone(),
+                });
+            }
+        }
+    }
+
+    fn emit(&self, event: &ScanEvent) {
+        if self.events {
+            match to_json_line(event) {
+                Ok(line) => println!("{}", line),
+                Err(e) => log_error!("Failed to serialize event: {}", e),
+            }
+        }
+    }
+}
+
+/// Source file read, transcoded and analyzed
+struct ScannedFile {
+    result: AuditResult,
+    content: String,
+    encoding: SourceEncoding,
+    offsets: Option<OffsetMap>,
+    /// Analysis time, excluding reading and transcoding
+    elapsed: Duration,
 }
 
 fn main() {
     let args: Vec<String> = env::args().collect();
 
+    // Logging options before the command; the command parses the ones after it
+    let mut log = LogOptions::default();
+    let mut command_index = 1;
+    loop {
+        match log.parse(&args, command_index) {
+            Ok(Some(taken)) => command_index += taken,
+            Ok(None) => break,
+            Err(e) => {
+                report_argument_error(&args, &mut log, &e);
+                process::exit(1);
+            }
+        }
+    }
+    let args: Vec<String> = args[..1]
+        .iter()
+        .chain(&args[command_index..])
+        .cloned()
+        .collect();
+
     // Handle --version and --help flags
     if args.len() == 2 {
         match args[1].as_str() {
@@ -48,29 +337,177 @@ fn main() {
 
     match command.as_str() {
         "scan" => {
-            let options = match parse_scan_args(&args[2..]) {
+            let options = match parse_scan_args(&args[2..], &mut log) {
                 Ok(opts) => opts,
                 Err(e) => {
-                    eprintln!("Error: {}", e);
+                    report_argument_error(&args, &mut log, &e);
                     print_usage(&args[0]);
                     process::exit(1);
                 }
             };
+            log.install();
 
             if let Err(e) = scan_directory(options) {
-                eprintln!("Error: {}", e);
+                log_error!("{}", e);
+                process::exit(1);
+            }
+        }
+        "eval" => {
+            let options = match parse_eval_args(&args[2..], &mut log) {
+                Ok(opts) => opts,
+                Err(e) => {
+                    report_argument_error(&args, &mut log, &e);
+                    print_usage(&args[0]);
+                    process::exit(1);
+                }
+            };
+            log.install();
+
+            match run_evaluation(options) {
+                Ok(false) => {}
+                Ok(true) => process::exit(1),
+                Err(e) => {
+                    log_error!("{}", e);
+                    process::exit(1);
+                }
+            }
+        }
+        "merge" => {
+            let options = match parse_merge_args(&args[2..], &mut log) {
+                Ok(opts) => opts,
+                Err(e) => {
+                    report_argument_error(&args, &mut log, &e);
+                    print_usage(&args[0]);
+                    process::exit(1);
+                }
+            };
+            log.install();
+
+            if let Err(e) = merge_shard_results(options) {
+                log_error!("{}", e);
+                process::exit(1);
+            }
+        }
+        "explain" => {
+            let options = match parse_explain_args(&args[2..], &mut log) {
+                Ok(opts) => opts,
+                Err(e) => {
+                    report_argument_error(&args, &mut log, &e);
+                    print_usage(&args[0]);
+                    process::exit(1);
+                }
+            };
+            log.install();
+
+            if let Err(e) = run_explain(options) {
+                log_error!("{}", e);
+                process::exit(1);
+            }
+        }
+        "rules" => {
+            let options = match parse_rules_args(&args[2..], &mut log) {
+                Ok(opts) => opts,
+                Err(e) => {
+                    report_argument_error(&args, &mut log, &e);
+                    print_usage(&args[0]);
+                    process::exit(1);
+                }
+            };
+            log.install();
+
+            if let Err(e) = run_rules_command(options) {
+                log_error!("{}", e);
                 process::exit(1);
             }
         }
         _ => {
-            eprintln!("Unknown command: {}", command);
+            report_argument_error(&args, &mut log, &format!("Unknown command: {}", command));
             print_usage(&args[0]);
             process::exit(1);
         }
     }
 }
 
-fn parse_scan_args(args: &[String]) -> Result<ScanOptions, String> {
+/// Global logging options, accepted before the command and among its options
+#[derive(Default)]
+struct LogOptions {
+    level: Option<LogLevel>,
+    quiet: bool,
+    json: bool,
+}
+
+impl LogOptions {
+    /// Parse the logging option at `args[i]`
+    ///
+    /// Returns the number of arguments the option takes, or `None` when
+    /// `args[i]` is not a logging option.
+    fn parse(&mut self, args: &[String], i: usize) -> Result<Option<usize>, String> {
+        let value = || {
+            args.get(i + 1)
+                .ok_or_else(|| format!("{} requires a value", args[i]))
+        };
+        match args.get(i).map(String::as_str) {
+            Some("--quiet" | "-q") => {
+                self.quiet = true;
+                Ok(Some(1))
+            }
+            Some("--log-level") => {
+                let level = value()?;
+                self.level = Some(LogLevel::from_string(level).ok_or_else(|| {
+                    format!(
+                        "Invalid log level: {} (expected error, warn, info or debug)",
+                        level
+                    )
+                })?);
+                Ok(Some(2))
+            }
+            Some("--log-format") => {
+                self.json = match value()?.as_str() {
+                    "text" => false,
+                    "json" => true,
+                    other => {
+                        return Err(format!(
+                            "Invalid log format: {} (expected text or json)",
+                            other
+                        ));
+                    }
+                };
+                Ok(Some(2))
+            }
+            _ => Ok(None),
+        }
+    }
+
+    /// Set the logger these options select
+    fn install(&self) {
+        let _ = LOGGER.set(Logger {
+            // Quiet keeps errors only, whatever the level
+            level: if self.quiet {
+                LogLevel::Error
+            } else {
+                self.level.unwrap_or(LogLevel::Info)
+            },
+            json: self.json,
+        });
+    }
+}
+
+/// Log an argument error with the logger the command line asks for
+///
+/// Parsing stops at the first bad argument, so a `--log-format json` after it
+/// has not been seen yet; look for it before installing the logger.
+fn report_argument_error(args: &[String], log: &mut LogOptions, error: &str) {
+    if args
+        .windows(2)
+        .any(|pair| pair[0] == "--log-format" && pair[1] == "json")
+    {
+        log.json = true;
+    }
+    log.install();
+    log_error!("{}", error);
+}
+
+fn parse_scan_args(args: &[String], log: &mut LogOptions) -> Result<ScanOptions, String> {
     if args.is_empty() {
         return Err("Missing target path or repository URL".to_string());
     }
@@ -79,9 +516,31 @@ fn parse_scan_args(args: &[String]) -> Result<ScanOptions, String> {
     let mut report_dir = "reports".to_string();
     let mut report_name = None;
     let mut cleanup_after_scan = true;
+    let mut call_graph = false;
+    let mut reachability = false;
+    let mut entry_points = None;
+    let mut min_confidence = Confidence::Low;
+    let mut plugins = Vec::new();
+    let mut rule_files = Vec::new();
+    let mut shard = None;
+    let mut limits = ResourceLimits::default();
+    let mut profile = false;
+    let mut format = OutputFormat::Text;
+    let mut projects = false;
+    let mut go_packages = false;
+    let mut go_platform = None;
+    let mut go_tags = None;
+    let mut certificates = false;
+    let mut pq_cutoff = None;
+    let mut max_cert_days = None;
+    let mut runtimes = false;
     let mut i = 0;
 
     while i < args.len() {
+        if let Some(taken) = log.parse(args, i)? {
+            i += taken;
+            continue;
+        }
         match args[i].as_str() {
             "--report-dir" => {
                 if i + 1 >= args.len() {
@@ -101,6 +560,155 @@ fn parse_scan_args(args: &[String]) -> Result<ScanOptions, String> {
                 cleanup_after_scan = false;
                 i += 1;
             }
+            "--call-graph" => {
+                call_graph = true;
+                i += 1;
+            }
+            "--reachability" => {
+                reachability = true;
+                i += 1;
+            }
+            "--entry-points" => {
+                if i + 1 >= args.len() {
+                    return Err("--entry-points requires a value".to_string());
+                }
+                entry_points = Some(ReachabilityConfig::from_kinds(&args[i + 1])?);
+                reachability = true;
+                i += 2;
+            }
+            "--min-confidence" => {
+                if i + 1 >= args.len() {
+                    return Err("--min-confidence requires a value".to_string());
+                }
+                min_confidence = Confidence::from_string(&args[i + 1]).ok_or_else(|| {
+                    format!(
+                        "Invalid confidence level: {} (expected low, medium or high)",
+                        args[i + 1]
+                    )
+                })?;
+                i += 2;
+            }
+            "--plugin" => {
+                if i + 1 >= args.len() {
+                    return Err("--plugin requires a value".to_string());
+                }
+                if !cfg!(feature = "plugins") {
+                    return Err("--plugin requires a build with the `plugins` feature".to_string());
+                }
+                plugins.push(PathBuf::from(&args[i + 1]));
+                i += 2;
+            }
+            "--rules" => {
+                if i + 1 >= args.len() {
+                    return Err("--rules requires a value".to_string());
+                }
+                rule_files.push(PathBuf::from(&args[i + 1]));
+                i += 2;
+            }
+            "--shard" => {
+                if i + 1 >= args.len() {
+                    return Err("--shard requires a value".to_string());
+                }
+                shard = Some(ShardSpec::parse(&args[i + 1]).map_err(|e| e.to_string())?);
+                i += 2;
+            }
+            "--time-budget" => {
+                if i + 1 >= args.len() {
+                    return Err("--time-budget requires a value".to_string());
+                }
+                let millis: u64 = args[i + 1].parse().map_err(|_| {
+                    format!(
+                        "Invalid time budget: {} (expected milliseconds)",
+                        args[i + 1]
+                    )
+                })?;
+                limits.time_budget = Some(Duration::from_millis(millis));
+                i += 2;
+            }
+            "--memory-cap" => {
+                if i + 1 >= args.len() {
+                    return Err("--memory-cap requires a value".to_string());
+                }
+                let megabytes: usize = args[i + 1].parse().map_err(|_| {
+                    format!("Invalid memory cap: {} (expected megabytes)", args[i + 1])
+                })?;
+                limits.memory_cap = Some(megabytes * 1024 * 1024);
+                i += 2;
+            }
+            "--profile" => {
+                profile = true;
+                i += 1;
+            }
+            "--projects" => {
+                projects = true;
+                i += 1;
+            }
+            "--go-packages" => {
+                go_packages = true;
+                i += 1;
+            }
+            "--go-target" => {
+                if i + 1 >= args.len() {
+                    return Err("--go-target requires a value".to_string());
+                }
+                go_platform = Some(GoBuildTarget::from_platform(&args[i + 1])?);
+                i += 2;
+            }
+            "--go-tags" => {
+                if i + 1 >= args.len() {
+                    return Err("--go-tags requires a value".to_string());
+                }
+                go_tags = Some(args[i + 1].clone());
+                i += 2;
+            }
+            "--certificates" => {
+                certificates = true;
+                i += 1;
+            }
+            "--runtimes" => {
+                runtimes = true;
+                i += 1;
+            }
+            "--pq-cutoff" => {
+                if i + 1 >= args.len() {
+                    return Err("--pq-cutoff requires a value".to_string());
+                }
+                let date =
+                    chrono::NaiveDate::parse_from_str(&args[i + 1], "%Y-%m-%d").map_err(|_| {
+                        format!("Invalid PQ cutoff: {} (expected YYYY-MM-DD)", args[i + 1])
+                    })?;
+                pq_cutoff = Some(date);
+                i += 2;
+            }
+            "--max-cert-days" => {
+                if i + 1 >= args.len() {
+                    return Err("--max-cert-days requires a value".to_string());
+                }
+                let days: u32 = args[i + 1].parse().map_err(|_| {
+                    format!(
+                        "Invalid certificate validity: {} (expected days)",
+                        args[i + 1]
+                    )
+                })?;
+                max_cert_days = Some(days);
+                i += 2;
+            }
+            "--format" => {
+                if i + 1 >= args.len() {
+                    return Err("--format requires a value".to_string());
+                }
+                format = match args[i + 1].as_str() {
+                    "text" => OutputFormat::Text,
+                    "jsonl" => OutputFormat::Jsonl,
+                    other => {
+                        return Err(format!(
+                            "Invalid format: {} (expected text or jsonl)",
+                            other
+                        ));
+                    }
+                };
+                i += 2;
+            }
             arg => {
                 if arg.starts_with("--") {
                     return Err(format!("Unknown option: {}", arg));
@@ -115,6 +723,38 @@ fn parse_scan_args(args: &[String]) -> Result<ScanOptions, String> {
         }
     }
 
+    // Tags alone evaluate constraints for the default linux/amd64 target
+    let go_build = match (go_platform, go_tags) {
+        (None, None) => None,
+        (platform, tags) => {
+            let target = platform.unwrap_or_default();
+            Some(match tags {
+                Some(tags) => target.with_tags(&tags),
+                None => target,
+            })
+        }
+    };
+
+    // Policy options alone enable the certificate report
+    let certificates =
+        (certificates || pq_cutoff.is_some() || max_cert_days.is_some()).then(|| {
+            let defaults = CertificatePolicy::default();
+            CertificatePolicy {
+                pq_cutoff: pq_cutoff.unwrap_or(defaults.pq_cutoff),
+                max_classical_validity_days: max_cert_days
+                    .unwrap_or(defaults.max_classical_validity_days),
+            }
+        });
+
+    // These analyses need every file of the project
+    if shard.is_some() && (call_graph || reachability || go_packages || runtimes) {
+        return Err(
+            "--shard cannot be combined with --call-graph, --reachability, --go-packages \
+             or --runtimes"
+                .to_string(),
+        );
+    }
+
     match target_path {
         Some(path) => {
             let is_repo_url = is_git_url(&path);
@@ -124,60 +764,386 @@ fn parse_scan_args(args: &[String]) -> Result<ScanOptions, String> {
                 report_name,
                 is_repo_url,
                 cleanup_after_scan,
+                call_graph,
+                reachability: reachability.then(|| entry_points.unwrap_or_default()),
+                min_confidence,
+                plugins,
+                rule_files,
+                shard,
+                limits,
+                profile,
+                format,
+                projects,
+                go_packages,
+                go_build,
+                certificates,
+                runtimes,
             })
         }
         None => Err("Missing target path or repository URL".to_string()),
     }
 }
 
-fn is_git_url(path: &str) -> bool {
-    path.starts_with("http://")
-        || path.starts_with("https://")
-        || path.starts_with("git@")
-        || path.starts_with("ssh://")
-        || path.ends_with(".git")
-}
+fn parse_merge_args(args: &[String], log: &mut LogOptions) -> Result<MergeOptions, String> {
+    let mut shard_files = Vec::new();
+    let mut report_dir = "reports".to_string();
+    let mut report_name = None;
+    let mut i = 0;
 
-fn print_version() {
-    println!("    ___                 ____        __    _ __ ");
-    println!("   /   |  ____________ / __ \\__  __/ /_  (_) /_");
-    println!("  / /| | / ___/ ___/ // / / / / / / __ \\/ / __/");
-    println!(" / ___ |/ /  / /__/ // /_/ / /_/ / /_/ / / /_  ");
-    println!("/_/  |_/_/   \\___/_/ \\___\\_\\__,_/_.___/_/\\__/  ");
-    println!();
-    println!("PQC Scanner - Quantum-Safe Cryptography Auditor");
-    println!("Version: {}", env!("CARGO_PKG_VERSION"));
-    println!("License: MIT");
-    println!("Repository: https://github.com/arcqubit/quantum-pqc");
+    while i < args.len() {
+        if let Some(taken) = log.parse(args, i)? {
+            i += taken;
+            continue;
+        }
+        match args[i].as_str() {
+            "--report-dir" | "--report-name" if i + 1 >= args.len() => {
+                return Err(format!("{} requires a value", args[i]));
+            }
+            "--report-dir" => {
+                report_dir = args[i + 1].clone();
+                i += 2;
+            }
+            "--report-name" => {
+                report_name = Some(args[i + 1].clone());
+                i += 2;
+            }
+            arg if arg.starts_with("--") => return Err(format!("Unknown option: {}", arg)),
+            arg => {
+                shard_files.push(PathBuf::from(arg));
+                i += 1;
+            }
+        }
+    }
+
+    if shard_files.is_empty() {
+        return Err("Missing shard result files to merge".to_string());
+    }
+    Ok(MergeOptions {
+        shard_files,
+        report_dir,
+        report_name,
+    })
 }
 
-fn print_usage(program: &str) {
-    eprintln!("    ___                 ____        __    _ __ ");
-    eprintln!("   /   |  ____________ / __ \\__  __/ /_  (_) /_");
-    eprintln!("  / /| | / ___/ ___/ // / / / / / / __ \\/ / __/");
-    eprintln!(" / ___ |/ /  / /__/ // /_/ / /_/ / /_/ / / /_  ");
-    eprintln!("/_/  |_/_/   \\___/_/ \\___\\_\\__,_/_.___/_/\\__/  ");
-    eprintln!();
-    eprintln!("PQC Scanner - Quantum-Safe Cryptography Auditor");
-    eprintln!("Version: {}", env!("CARGO_PKG_VERSION"));
-    eprintln!();
-    eprintln!(
-        "Usage: {} [OPTIONS] | {} scan <directory|repo-url> [OPTIONS]",
-        program, program
-    );
-    eprintln!();
-    eprintln!("Global Options:");
-    eprintln!("  -h, --help          Show this help message");
-    eprintln!("  -v, --version       Show version information");
-    eprintln!();
-    eprintln!("Commands:");
-    eprintln!("  scan <path>         Scan local directory for cryptographic vulnerabilities");
-    eprintln!("  scan <repo-url>     Clone and scan remote Git repository");
-    eprintln!();
+fn parse_eval_args(args: &[String], log: &mut LogOptions) -> Result<EvalOptions, String> {
+    let mut corpus_path = None;
+    let mut baseline = None;
+    let mut output = None;
+    let mut min_confidence = Confidence::Low;
+    let mut rule_files = Vec::new();
+    let mut i = 0;
+
+    while i < args.len() {
+        if let Some(taken) = log.parse(args, i)? {
+            i += taken;
+            continue;
+        }
+        match args[i].as_str() {
+            "--baseline" | "--output" | "--min-confidence" | "--rules" if i + 1 >= args.len() => {
+                return Err(format!("{} requires a value", args[i]));
+            }
+            "--baseline" => {
+                baseline = Some(args[i + 1].clone());
+                i += 2;
+            }
+            "--output" => {
+                output = Some(args[i + 1].clone());
+                i += 2;
+            }
+            "--min-confidence" => {
+                min_confidence = Confidence::from_string(&args[i + 1]).ok_or_else(|| {
+                    format!(
+                        "Invalid confidence level: {} (expected low, medium or high)",
+                        args[i + 1]
+                    )
+                })?;
+                i += 2;
+            }
+            "--rules" => {
+                rule_files.push(PathBuf::from(&args[i + 1]));
+                i += 2;
+            }
+            arg => {
+                if arg.starts_with("--") {
+                    return Err(format!("Unknown option: {}", arg));
+                }
+                if corpus_path.is_some() {
+                    return Err(format!("Unexpected argument: {}", arg));
+                }
+                corpus_path = Some(arg.to_string());
+                i += 1;
+            }
+        }
+    }
+
+    Ok(EvalOptions {
+        corpus_path: corpus_path.ok_or("Missing corpus directory")?,
+        baseline,
+        output,
+        min_confidence,
+        rule_files,
+    })
+}
+
+fn parse_rules_args(args: &[String], log: &mut LogOptions) -> Result<RulesOptions, String> {
+    let mut positional = Vec::new();
+    let mut rule_files = Vec::new();
+    let mut format = None;
+    let mut output = None;
+    let mut i = 0;
+
+    while i < args.len() {
+        if let Some(taken) = log.parse(args, i)? {
+            i += taken;
+            continue;
+        }
+        match args[i].as_str() {
+            "--rules" | "--format" | "--output" if i + 1 >= args.len() => {
+                return Err(format!("{} requires a value", args[i]));
+            }
+            "--rules" => {
+                rule_files.push(PathBuf::from(&args[i + 1]));
+                i += 2;
+            }
+            "--format" => {
+                format = Some(args[i + 1].clone());
+                i += 2;
+            }
+            "--output" => {
+                output = Some(args[i + 1].clone());
+                i += 2;
+            }
+            arg if arg.starts_with("--") => return Err(format!("Unknown option: {}", arg)),
+            arg => {
+                positional.push(arg.to_string());
+                i += 1;
+            }
+        }
+    }
+
+    let command = match positional.as_slice() {
+        [cmd] if cmd == "list" => RulesCommand::List,
+        [cmd, id] if cmd == "describe" => RulesCommand::Describe(id.clone()),
+        [cmd] if cmd == "describe" => return Err("rules describe requires a rule id".to_string()),
+        [cmd] if cmd == "export" => RulesCommand::Export {
+            markdown: match format.as_deref() {
+                Some("markdown") | Some("md") => true,
+                Some("json") => false,
+                Some(other) => {
+                    return Err(format!(
+                        "Invalid format: {} (expected markdown or json)",
+                        other
+                    ));
+                }
+                None => return Err("rules export requires --format markdown|json".to_string()),
+            },
+            output,
+        },
+        [] => return Err("Missing rules subcommand (list, describe, export)".to_string()),
+        other => return Err(format!("Unknown rules subcommand: {}", other.join(" "))),
+    };
+
+    Ok(RulesOptions {
+        command,
+        rule_files,
+    })
+}
+
+fn parse_explain_args(args: &[String], log: &mut LogOptions) -> Result<ExplainOptions, String> {
+    let mut target = None;
+    let mut json = false;
+    let mut min_confidence = Confidence::Low;
+    let mut rule_files = Vec::new();
+    let mut i = 0;
+
+    while i < args.len() {
+        if let Some(taken) = log.parse(args, i)? {
+            i += taken;
+            continue;
+        }
+        match args[i].as_str() {
+            "--rules" | "--format" | "--min-confidence" if i + 1 >= args.len() => {
+                return Err(format!("{} requires a value", args[i]));
+            }
+            "--rules" => {
+                rule_files.push(PathBuf::from(&args[i + 1]));
+                i += 2;
+            }
+            "--format" => {
+                json = match args[i + 1].as_str() {
+                    "json" => true,
+                    "text" => false,
+                    other => {
+                        return Err(format!("Invalid format: {} (expected text or json)", other));
+                    }
+                };
+                i += 2;
+            }
+            "--min-confidence" => {
+                min_confidence = Confidence::from_string(&args[i + 1]).ok_or_else(|| {
+                    format!(
+                        "Invalid confidence level: {} (expected low, medium or high)",
+                        args[i + 1]
+                    )
+                })?;
+                i += 2;
+            }
+            arg if arg.starts_with("--") => return Err(format!("Unknown option: {}", arg)),
+            arg => {
+                if target.is_some() {
+                    return Err(format!("Unexpected argument: {}", arg));
+                }
+                target = Some(arg.to_string());
+                i += 1;
+            }
+        }
+    }
+
+    let target = target.ok_or("Missing <file>:<line> to explain")?;
+    let (file_path, location) = target
+        .rsplit_once(':')
+        .and_then(|(path, location)| {
+            // A 16-digit line number is not plausible, so such a value is a fingerprint
+            if is_fingerprint(location) {
+                return Some((path, ExplainLocation::Fingerprint(location.to_string())));
+            }
+            Some((path, ExplainLocation::Line(location.parse().ok()?)))
+        })
+        .ok_or_else(|| {
+            format!(
+                "Expected <file>:<line> or <file>:<fingerprint>, got {}",
+                target
+            )
+        })?;
+
+    Ok(ExplainOptions {
+        file_path: PathBuf::from(file_path),
+        location,
+        json,
+        min_confidence,
+        rule_files,
+    })
+}
+
+fn is_git_url(path: &str) -> bool {
+    path.starts_with("http://")
+        || path.starts_with("https://")
+        || path.starts_with("git@")
+        || path.starts_with("ssh://")
+        || path.ends_with(".git")
+}
+
+fn print_version() {
+    println!("    ___                 ____        __    _ __ ");
+    println!("   /   |  ____________ / __ \\__  __/ /_  (_) /_");
+    println!("  / /| | / ___/ ___/ // / / / / / / __ \\/ / __/");
+    println!(" / ___ |/ /  / /__/ // /_/ / /_/ / /_/ / / /_  ");
+    println!("/_/  |_/_/   \\___/_/ \\___\\_\\__,_/_.___/_/\\__/  ");
+    println!();
+    println!("PQC Scanner - Quantum-Safe Cryptography Auditor");
+    println!("Version: {}", env!("CARGO_PKG_VERSION"));
+    println!("License: MIT");
+    println!("Repository: https://github.com/arcqubit/quantum-pqc");
+}
+
+fn print_usage(program: &str) {
+    eprintln!("    ___                 ____        __    _ __ ");
+    eprintln!("   /   |  ____________ / __ \\__  __/ /_  (_) /_");
+    eprintln!("  / /| | / ___/ ___/ // / / / / / / __ \\/ / __/");
+    eprintln!(" / ___ |/ /  / /__/ // /_/ / /_/ / /_/ / / /_  ");
+    eprintln!("/_/  |_/_/   \\___/_/ \\___\\_\\__,_/_.___/_/\\__/  ");
+    eprintln!();
+    eprintln!("PQC Scanner - Quantum-Safe Cryptography Auditor");
+    eprintln!("Version: {}", env!("CARGO_PKG_VERSION"));
+    eprintln!();
+    eprintln!(
+        "Usage: {} [OPTIONS] | {} scan <directory|repo-url> [OPTIONS]",
+        program, program
+    );
+    eprintln!();
+    eprintln!("Global Options:");
+    eprintln!("  -h, --help          Show this help message");
+    eprintln!("  -v, --version       Show version information");
+    eprintln!("  -q, --quiet         Only log errors");
+    eprintln!("  --log-level <lvl>   Diagnostics to log on stderr (error, warn, info, debug)");
+    eprintln!("  --log-format <fmt>  Diagnostic format on stderr (text, json)");
+    eprintln!();
+    eprintln!("Commands:");
+    eprintln!("  scan <path>         Scan local directory for cryptographic vulnerabilities");
+    eprintln!("  scan <repo-url>     Clone and scan remote Git repository");
+    eprintln!("  merge <shard.json>... Merge sharded scan results and generate reports");
+    eprintln!("  eval <corpus>       Measure precision/recall against a labeled corpus");
+    eprintln!("  explain <file>:<line> Show why the findings of a line were produced");
+    eprintln!("  explain <file>:<fingerprint> Same, for the line of a reported finding");
+    eprintln!("  rules list          List built-in and custom rules");
+    eprintln!("  rules describe <id> Show a rule's rationale, mappings and examples");
+    eprintln!("  rules export        Write the rule catalog (--format markdown|json)");
+    eprintln!();
     eprintln!("Scan Options:");
     eprintln!("  --report-dir <dir>     Output directory for reports (default: reports)");
     eprintln!("  --report-name <name>   Base name for report files (default: directory/repo name)");
     eprintln!("  --keep-clone           Keep cloned repository after scanning (default: cleanup)");
+    eprintln!("  --call-graph           Build a cross-file call graph and report crypto wrappers");
+    eprintln!("  --reachability         Label findings reachable from entry points or dead code");
+    eprintln!(
+        "  --entry-points <kinds> Entry points for reachability (main,exported,http,test,module)"
+    );
+    eprintln!(
+        "  --min-confidence <lvl> Only report findings of at least this confidence (low, medium, high)"
+    );
+    eprintln!("  --shard <i>/<n>        Scan shard i of n and write a partial result for merge");
+    eprintln!(
+        "  --time-budget <ms>     Skip files whose analysis takes longer (default: unlimited)"
+    );
+    eprintln!(
+        "  --memory-cap <MB>      Skip files whose analysis needs more memory (default: unlimited)"
+    );
+    eprintln!("  --profile              Report time spent per rule, language and file");
+    eprintln!("  --format <fmt>         Results on stdout (text, jsonl event stream)");
+    eprintln!(
+        "  --projects             Detect sub-projects (go.mod, package.json, pom.xml) and report each"
+    );
+    eprintln!("  --go-packages          Report Go findings and vulnerable imports per package");
+    eprintln!(
+        "  --go-target <os/arch>  Lower findings in Go files excluded from this build (e.g. linux/amd64)"
+    );
+    eprintln!(
+        "  --go-tags <tags>       Build tags for --go-target (comma-separated, e.g. cgo,netgo)"
+    );
+    eprintln!(
+        "  --certificates         Report Go certificate and CSR generation (key, signature, validity)"
+    );
+    eprintln!(
+        "  --pq-cutoff <date>     Flag classical certificates valid past this date (default: 2030-01-01)"
+    );
+    eprintln!(
+        "  --max-cert-days <n>    Longest classical validity allowed past the cutoff (default: 398)"
+    );
+    eprintln!(
+        "  --runtimes             Report PQ key exchange support of Go, JDK, OpenSSL and Node.js versions"
+    );
+    eprintln!();
+    eprintln!("Merge Options:");
+    eprintln!("  --report-dir <dir>     Output directory for reports (default: reports)");
+    eprintln!(
+        "  --report-name <name>   Base name for report files (default: scanned directory name)"
+    );
+    eprintln!();
+    eprintln!("Eval Options:");
+    eprintln!("  --baseline <file>      Previous eval result to check for regressions");
+    eprintln!("  --output <file>        Write the eval result as JSON");
+    eprintln!("  --min-confidence <lvl> Only count findings of at least this confidence");
+    eprintln!("  --rules <file.yaml>    Evaluate with imported Semgrep-style rules (repeatable)");
+    eprintln!();
+    eprintln!("Explain Options:");
+    eprintln!("  --rules <file.yaml>    Explain with imported Semgrep-style rules (repeatable)");
+    eprintln!("  --min-confidence <lvl> Confidence threshold to check findings against");
+    eprintln!("  --format <fmt>         Output format (text, json)");
+    eprintln!();
+    eprintln!("Rules Options:");
+    eprintln!("  --rules <file.yaml>    Include imported Semgrep-style rules (repeatable)");
+    eprintln!("  --format <fmt>         Catalog format for export (markdown, json)");
+    eprintln!("  --output <file>        Write the exported catalog to a file");
     eprintln!();
     eprintln!("Examples:");
     eprintln!("  {} --version", program);
@@ -188,152 +1154,1565 @@ fn print_usage(program: &str) {
         "  {} scan https://github.com/org/repo.git --report-name my-audit --keep-clone",
         program
     );
-}
+    eprintln!(
+        "  {} eval tests/fixtures/corpus --baseline eval-main.json",
+        program
+    );
+    eprintln!("  {} scan . --shard 3/8 --report-dir shards", program);
+    eprintln!("  {} merge shards/*-shard-*.json", program);
+    eprintln!("  {} explain src/crypto.go:42", program);
+    eprintln!("  {} scan . --time-budget 2000 --profile", program);
+    eprintln!("  {} scan . --format jsonl --quiet > events.jsonl", program);
+    eprintln!("  {} scan monorepo --projects", program);
+    eprintln!("  {} scan . --go-packages", program);
+    eprintln!("  {} scan . --go-target linux/arm64 --go-tags cgo", program);
+    eprintln!(
+        "  {} scan . --pq-cutoff 2030-01-01 --max-cert-days 90",
+        program
+    );
+    eprintln!("  {} scan . --runtimes", program);
+}
+
+fn scan_directory(mut options: ScanOptions) -> Result<(), String> {
+    // Shards of a cloned repository must agree on the target, not the clone's temp path
+    let target_label = options.target_path.clone();
+    let text = options.format == OutputFormat::Text;
+
+    // If it's a repository URL, clone it first
+    let cloned_path = if options.is_repo_url {
+        log_info!("Cloning repository {}", options.target_path);
+
+        match clone_repository(&options.target_path) {
+            Ok(path) => {
+                log_info!("Cloned to {}", path.display());
+
+                // Update target_path to the cloned directory
+                options.target_path = path.to_string_lossy().to_string();
+
+                // Extract repo name for default report naming if not specified
+                if options.report_name.is_none()
+                    && let Some(name) = extract_repo_name(&options.target_path)
+                {
+                    options.report_name = Some(name);
+                }
+
+                Some(path)
+            }
+            Err(e) => {
+                return Err(format!("Failed to clone repository: {}", e));
+            }
+        }
+    } else {
+        None
+    };
+
+    let target = PathBuf::from(&options.target_path);
+
+    if !target.exists() {
+        return Err(format!("Path does not exist: {}", options.target_path));
+    }
+
+    print_banner();
+    log_info!("Scanning {}", options.target_path);
+
+    let rules = load_rule_files(&options.rule_files)?;
+    let mut state = ScanState {
+        profiler: options.profile.then(Profiler::default),
+        events: options.format == OutputFormat::Jsonl,
+        min_confidence: options.min_confidence,
+        detect_projects: options.projects,
+        detect_go_modules: options.go_packages,
+        detect_runtimes: options.runtimes,
+        keep_parse_trees: options.call_graph
+            || options.reachability.is_some()
+            || options.go_packages,
+        ..Default::default()
+    };
+    let scan_started = Instant::now();
+
+    // Scan all supported files in directory
+    if target.is_dir() {
+        scan_dir_recursive(
+            &target,
+            &target,
+            &rules,
+            options.shard,
+            &options.limits,
+            &mut state,
+        )?;
+    } else {
+        return Err(format!(
+            "Expected directory, got file: {}",
+            options.target_path
+        ));
+    }
+
+    #[cfg(feature = "plugins")]
+    run_plugins(&target, &options.plugins, options.shard, &mut state)?;
+
+    let passes = run_project_passes(&options, &mut state);
+    tally_findings(&target, &mut state, text);
+    if text {
+        print_scan_summary(&options, &state, &passes);
+    }
+
+    // Determine base name for reports
+    let base_name = if let Some(name) = options.report_name.clone() {
+        name
+    } else {
+        // Extract directory name from target path
+        target
+            .file_name()
+            .and_then(|n| n.to_str())
+            .unwrap_or("scan")
+            .to_string()
+    };
+    let reports_dir = PathBuf::from(&options.report_dir);
+    let mut reports = write_analysis_reports(
+        &reports_dir,
+        &base_name,
+        &options,
+        &mut state,
+        &passes,
+        scan_started,
+    )?;
+
+    if let Some(shard) = options.shard {
+        reports.push(write_shard_result(
+            &reports_dir,
+            &base_name,
+            shard,
+            &target_label,
+            &state,
+        )?);
+    } else if state
+        .project_files
+        .iter()
+        .any(|file| file.audit.stats.total_vulnerabilities > 0)
+        || !state.plugin_files.is_empty()
+    {
+        let files = file_results(&state);
+        let plugin_files = plugin_results(&state);
+        reports.extend(write_reports(
+            &reports_dir,
+            &base_name,
+            &options.target_path,
+            &files,
+            &plugin_files,
+        )?);
+    }
+
+    if options.projects && options.shard.is_none() {
+        let layout = ProjectLayout::new(std::mem::take(&mut state.projects));
+        let files = file_results(&state);
+        let plugin_files = plugin_results(&state);
+        reports.extend(write_project_reports(
+            &reports_dir,
+            &base_name,
+            &target_label,
+            &layout,
+            &files,
+            &plugin_files,
+            text,
+        )?);
+    }
+
+    state.emit(&ScanEvent::Summary(ScanSummary {
+        target: target_label,
+        files_scanned: state.total_files,
+        files_skipped: state.diagnostics.len(),
+        total_vulnerabilities: state.total_vulnerabilities,
+        critical_count: state.critical_count,
+        high_count: state.high_count,
+        reports,
+    }));
+
+    // Cleanup cloned repository if requested
+    if let Some(cloned) = cloned_path {
+        if options.cleanup_after_scan {
+            match fs::remove_dir_all(&cloned) {
+                Ok(()) => log_info!("Removed cloned repository {}", cloned.display()),
+                Err(e) => log_warn!("Failed to remove cloned directory: {}", e),
+            }
+        } else {
+            log_info!("Cloned repository kept at {}", cloned.display());
+        }
+    }
+
+    // Shard jobs leave the verdict to `merge`
+    if options.shard.is_none() && (state.critical_count > 0 || state.high_count > 0) {
+        log_warn!(
+            "Critical or high severity vulnerabilities found; review the generated reports for remediation steps"
+        );
+        process::exit(1);
+    }
+
+    Ok(())
+}
+
+/// Findings per source file, as the report writers take them
+fn file_results(state: &ScanState) -> Vec<(&str, &AuditResult)> {
+    state
+        .project_files
+        .iter()
+        .map(|file| (file.path.as_str(), &file.audit))
+        .collect()
+}
+
+/// Plugin findings per file without language support, as the report writers take them
+fn plugin_results(state: &ScanState) -> Vec<(&str, &[Vulnerability])> {
+    state
+        .plugin_files
+        .iter()
+        .map(|(path, findings)| (path.as_str(), findings.as_slice()))
+        .collect()
+}
+
+/// Results of the project-wide passes that run once every file is scanned
+struct ProjectPasses {
+    resolution: SymbolResolutionSummary,
+    certificates: Option<CertificateReport>,
+    go_build: Option<GoBuildReport>,
+    reachability: Option<ReachabilityReport>,
+    /// Findings removed by `--min-confidence`
+    filtered: usize,
+}
+
+/// Run the passes over the scanned files that need the whole project,
+/// leaving the findings as they are reported
+fn run_project_passes(options: &ScanOptions, state: &mut ScanState) -> ProjectPasses {
+    // Resolve constants defined in one file and used in another, including
+    // definitions in the files of other shards
+    let reported = state.project_files.len();
+    state.project_files.append(&mut state.definition_files);
+    let resolution = resolve_symbols_in(&mut state.project_files, reported);
+    state.project_files.truncate(reported);
+
+    // Flagged certificates become findings, subject to build constraints and filtering
+    let certificates = options.certificates.as_ref().map(|policy| {
+        let today = chrono::Local::now().date_naive();
+        analyze_certificates(&mut state.project_files, policy, today)
+    });
+
+    // Excluded findings drop to low confidence, so mark them before filtering
+    let go_build = options.go_build.as_ref().map(|target| {
+        let report = apply_build_constraints(&mut state.project_files, target);
+        for excluded in &report.excluded_files {
+            log_debug!(
+                "{} excluded from {} by {}",
+                excluded.path,
+                report.target,
+                excluded.constraint
+            );
+        }
+        for invalid in &report.invalid_constraints {
+            log_warn!("{}", invalid);
+        }
+        report
+    });
+
+    // Project passes add findings, so number them again; --min-confidence must not
+    // change the fingerprints of the findings it keeps
+    for file in &mut state.project_files {
+        assign_fingerprints(&mut file.audit.vulnerabilities);
+    }
+
+    // From here on only Go package clauses and runtime settings are read from sources
+    let keep_go_sources = options.go_packages || options.runtimes;
+    for file in &mut state.project_files {
+        if !(keep_go_sources && file.audit.language == Language::Go) {
+            file.source = String::new();
+        }
+        if !state.keep_parse_trees {
+            file.parsed = ParsedSource::new(file.parsed.language);
+        }
+    }
+
+    let mut filtered: usize = state
+        .project_files
+        .iter_mut()
+        .map(|file| filter_by_confidence(&mut file.audit, options.min_confidence))
+        .sum();
+    for (_, findings) in &mut state.plugin_files {
+        let before = findings.len();
+        findings.retain(|v| v.confidence >= options.min_confidence);
+        filtered += before - findings.len();
+    }
+    state
+        .plugin_files
+        .retain(|(_, findings)| !findings.is_empty());
+
+    // Report columns as byte offsets into the original (non-UTF-8) files
+    for (file, offsets) in state.project_files.iter_mut().zip(&state.offset_maps) {
+        if let Some(offsets) = offsets {
+            offsets.remap_columns(&mut file.audit);
+        }
+    }
+
+    // Reachability labels the remaining findings; revise the stream once every label is set
+    let reachability = options
+        .reachability
+        .as_ref()
+        .map(|config| analyze_reachability(&mut state.project_files, config));
+    state.revise_streamed_findings();
+
+    ProjectPasses {
+        resolution,
+        certificates,
+        go_build,
+        reachability,
+        filtered,
+    }
+}
+
+/// Add up the findings of every file, listing them in text output
+fn tally_findings(target: &Path, state: &mut ScanState, text: bool) {
+    for file in &state.project_files {
+        let result = &file.audit;
+        state.total_files += 1;
+        state.total_vulnerabilities += result.stats.total_vulnerabilities;
+        state.critical_count += result.stats.critical_count;
+        state.high_count += result.stats.high_count;
+
+        if text && result.stats.total_vulnerabilities > 0 {
+            println!("\n{}", target.join(&file.path).display());
+            println!("  Vulnerabilities: {}", result.stats.total_vulnerabilities);
+            println!(
+                "  Critical: {}, High: {}",
+                result.stats.critical_count, result.stats.high_count
+            );
+
+            // Show first few vulnerabilities
+            for (i, vuln) in result.vulnerabilities.iter().take(3).enumerate() {
+                match &vuln.original_location {
+                    Some(original) => println!(
+                        "    {}. [{:?}] {} ({}:{})",
+                        i + 1,
+                        vuln.severity,
+                        vuln.crypto_type,
+                        original.file_path,
+                        original.line
+                    ),
+                    None => println!(
+                        "    {}. [{:?}] {} (line {})",
+                        i + 1,
+                        vuln.severity,
+                        vuln.crypto_type,
+                        vuln.line
+                    ),
+                }
+            }
+
+            if result.vulnerabilities.len() > 3 {
+                println!("    ... and {} more", result.vulnerabilities.len() - 3);
+            }
+        }
+    }
+
+    for (path, findings) in &state.plugin_files {
+        state.total_files += 1;
+        state.total_vulnerabilities += findings.len();
+        state.critical_count += findings
+            .iter()
+            .filter(|v| v.severity == Severity::Critical)
+            .count();
+        state.high_count += findings
+            .iter()
+            .filter(|v| v.severity == Severity::High)
+            .count();
+
+        if !text {
+            continue;
+        }
+        println!("\n{}", target.join(path).display());
+        println!("  Vulnerabilities: {}", findings.len());
+        for (i, vuln) in findings.iter().take(3).enumerate() {
+            println!(
+                "    {}. [{:?}] {} (line {})",
+                i + 1,
+                vuln.severity,
+                vuln.crypto_type,
+                vuln.line
+            );
+        }
+        if findings.len() > 3 {
+            println!("    ... and {} more", findings.len() - 3);
+        }
+    }
+}
+
+fn print_scan_summary(options: &ScanOptions, state: &ScanState, passes: &ProjectPasses) {
+    println!("\n=== Scan Summary ===");
+    if let Some(shard) = options.shard {
+        println!("Shard: {}", shard);
+    }
+    println!("Files scanned: {}", state.total_files);
+    if state.transcoded_files > 0 {
+        println!("Files transcoded to UTF-8: {}", state.transcoded_files);
+    }
+    if !state.diagnostics.is_empty() {
+        println!(
+            "Files skipped: {} ({})",
+            state.diagnostics.len(),
+            diagnostic_counts(&state.diagnostics)
+        );
+    }
+    if state.skipped_bundles > 0 {
+        println!(
+            "Bundles skipped in favor of their sources: {}",
+            state.skipped_bundles
+        );
+    }
+    println!("Total vulnerabilities: {}", state.total_vulnerabilities);
+    println!("  Critical: {}", state.critical_count);
+    println!("  High: {}", state.high_count);
+    if passes.filtered > 0 {
+        println!(
+            "Findings below {} confidence omitted: {}",
+            options.min_confidence, passes.filtered
+        );
+    }
+    let native = state
+        .project_files
+        .iter()
+        .flat_map(|file| &file.audit.vulnerabilities)
+        .filter(|v| v.native_code.is_some())
+        .count();
+    if native > 0 {
+        println!("Findings in native code (cgo): {}", native);
+    }
+    let hashes: Vec<&HashContext> = state
+        .project_files
+        .iter()
+        .flat_map(|file| &file.audit.vulnerabilities)
+        .filter_map(|v| v.hash_context.as_ref())
+        .collect();
+    if !hashes.is_empty() {
+        let exempt = hashes.iter().filter(|c| c.compliance_exempt).count();
+        println!(
+            "Weak hashes classified by usage: {} (outside compliance scope: {})",
+            hashes.len(),
+            exempt
+        );
+    }
+    let ssh: Vec<_> = state
+        .project_files
+        .iter()
+        .flat_map(|file| &file.audit.protocol_compliance)
+        .filter(|p| p.protocol.protocol_type == ProtocolType::Ssh)
+        .collect();
+    if !ssh.is_empty() {
+        let pq_hybrid = ssh
+            .iter()
+            .filter(|p| {
+                p.protocol
+                    .configuration
+                    .get("pq_hybrid_kex")
+                    .is_some_and(|status| status == "enabled")
+            })
+            .count();
+        println!(
+            "SSH configurations: {} (PQ hybrid key exchange enabled: {}, non-compliant: {})",
+            ssh.len(),
+            pq_hybrid,
+            ssh.iter().filter(|p| !p.compliant).count()
+        );
+    }
+    if let Some(report) = &passes.go_build {
+        println!(
+            "Go files excluded from the {} build: {} ({} finding(s) lowered to low severity)",
+            report.target,
+            report.excluded_files.len(),
+            report.excluded_findings
+        );
+    }
+    if passes.resolution.findings_added > 0 || passes.resolution.findings_updated > 0 {
+        println!(
+            "Resolved constants: {} finding(s) added, {} refined",
+            passes.resolution.findings_added, passes.resolution.findings_updated
+        );
+    }
+}
+
+/// Write the diagnostics, profile and optional analysis reports of a scan
+fn write_analysis_reports(
+    reports_dir: &Path,
+    base_name: &str,
+    options: &ScanOptions,
+    state: &mut ScanState,
+    passes: &ProjectPasses,
+    scan_started: Instant,
+) -> Result<Vec<String>, String> {
+    let text = options.format == OutputFormat::Text;
+    let mut reports = Vec::new();
+
+    if !state.diagnostics.is_empty() {
+        let json = export_diagnostics_json(&state.diagnostics).map_err(|e| e.to_string())?;
+        reports.push(write_report(
+            reports_dir,
+            &format!("{}-diagnostics.json", base_name),
+            "Diagnostics",
+            &json,
+        )?);
+    }
+
+    if let Some(profiler) = state.profiler.take() {
+        let profile = profiler.finish(scan_started.elapsed());
+        if text {
+            print_profile(&profile);
+        }
+
+        let json = export_profile_json(&profile).map_err(|e| e.to_string())?;
+        reports.push(write_report(
+            reports_dir,
+            &format!("{}-profile.json", base_name),
+            "Profile",
+            &json,
+        )?);
+    }
+
+    if options.call_graph {
+        let report = generate_call_graph_report(&state.project_files);
+        if text {
+            print_call_graph(&report);
+        }
+
+        match export_call_graph_json(&report) {
+            Ok(json) => reports.push(write_report(
+                reports_dir,
+                &format!("{}-call-graph.json", base_name),
+                "Call graph report",
+                &json,
+            )?),
+            Err(e) => log_error!("Failed to generate call graph report: {}", e),
+        }
+    }
+
+    if options.go_packages {
+        let report = analyze_go_packages(&state.go_modules, &state.project_files);
+        if text {
+            print_go_packages(&report);
+        }
+        match export_go_packages_json(&report) {
+            Ok(json) => reports.push(write_report(
+                reports_dir,
+                &format!("{}-go-packages.json", base_name),
+                "Go package report",
+                &json,
+            )?),
+            Err(e) => log_error!("Failed to generate Go package report: {}", e),
+        }
+    }
+
+    let protocols: BTreeMap<String, Vec<ProtocolCompliance>> = state
+        .project_files
+        .iter()
+        .filter(|file| !file.audit.protocol_compliance.is_empty())
+        .map(|file| (file.path.clone(), file.audit.protocol_compliance.clone()))
+        .collect();
+    if !protocols.is_empty() {
+        match export_protocol_compliance_json(&protocols) {
+            Ok(json) => reports.push(write_report(
+                reports_dir,
+                &format!("{}-protocols.json", base_name),
+                "Protocol compliance report",
+                &json,
+            )?),
+            Err(e) => log_error!("Failed to generate protocol compliance report: {}", e),
+        }
+    }
+
+    if options.runtimes {
+        let report = assess_runtimes(&state.runtime_evidence, &state.project_files);
+        if text {
+            print_runtimes(&report);
+        }
+        match export_runtimes_json(&report) {
+            Ok(json) => reports.push(write_report(
+                reports_dir,
+                &format!("{}-runtimes.json", base_name),
+                "Runtime report",
+                &json,
+            )?),
+            Err(e) => log_error!("Failed to generate runtime report: {}", e),
+        }
+    }
+
+    if let Some(report) = &passes.certificates {
+        if text {
+            print_certificates(report);
+        }
+        match export_certificates_json(report) {
+            Ok(json) => reports.push(write_report(
+                reports_dir,
+                &format!("{}-certificates.json", base_name),
+                "Certificate report",
+                &json,
+            )?),
+            Err(e) => log_error!("Failed to generate certificate report: {}", e),
+        }
+    }
+
+    if let Some(report) = &passes.reachability {
+        if text {
+            print_reachability(report);
+        }
+
+        match export_reachability_json(report) {
+            Ok(json) => reports.push(write_report(
+                reports_dir,
+                &format!("{}-reachability.json", base_name),
+                "Reachability report",
+                &json,
+            )?),
+            Err(e) => log_error!("Failed to generate reachability report: {}", e),
+        }
+    }
+
+    Ok(reports)
+}
+
+fn print_call_graph(report: &CallGraphReport) {
+    println!("\n=== Call Graph ===");
+    println!(
+        "Functions: {}, call sites: {}, entry points: {}",
+        report.total_functions, report.total_call_sites, report.entry_points
+    );
+    println!("Crypto wrappers: {}", report.wrappers.len());
+    for primitive in report.primitives.iter().take(5) {
+        println!(
+            "  {} at {}:{} reached by {} entry point(s)",
+            primitive.crypto_type, primitive.file_path, primitive.line, primitive.entry_point_count
+        );
+    }
+}
+
+fn print_reachability(report: &ReachabilityReport) {
+    println!("\n=== Reachability ===");
+    println!(
+        "Entry points: {}, reachable findings: {}, unreachable findings: {}",
+        report.entry_points, report.reachable_findings, report.unreachable_findings
+    );
+    for finding in report.prioritized.iter().take(5) {
+        println!(
+            "  [{:?}] {} at {}:{} reached by {} entry point(s)",
+            finding.severity,
+            finding.crypto_type,
+            finding.file_path,
+            finding.line,
+            finding.entry_point_count
+        );
+    }
+    for dead in report.dead_code.iter().take(5) {
+        println!(
+            "  Dead crypto code: {} at {}:{}",
+            dead.function, dead.file_path, dead.line
+        );
+    }
+}
+
+fn print_go_packages(report: &GoPackageReport) {
+    let affected: Vec<_> = report.packages.iter().filter(|p| p.is_affected()).collect();
+
+    println!("\n=== Go Packages ===");
+    println!(
+        "Modules: {}, packages: {}, using vulnerable crypto: {}",
+        report.modules.len(),
+        report.packages.len(),
+        affected.len()
+    );
+    for package in affected {
+        println!(
+            "  {} ({} finding(s), risk {})",
+            package.import_path,
+            package.findings.len(),
+            package.risk_score
+        );
+        for import in &package.direct_imports {
+            println!("    Imports {} ({})", import.package, import.crypto_type);
+        }
+        for import in &package.transitive_imports {
+            println!(
+                "    Reaches {} ({}) via {}",
+                import.package,
+                import.crypto_type,
+                import.via.join(" -> ")
+            );
+        }
+    }
+    if !report.unmapped_files.is_empty() {
+        println!(
+            "Go files outside any module: {}",
+            report.unmapped_files.len()
+        );
+    }
+}
+
+fn print_certificates(report: &CertificateReport) {
+    println!("\n=== Certificates ===");
+    println!(
+        "Generated: {}, flagged: {} (PQ cutoff {}, classical validity up to {} days)",
+        report.certificates.len(),
+        report.flagged,
+        report.policy.pq_cutoff,
+        report.policy.max_classical_validity_days
+    );
+    for certificate in &report.certificates {
+        let algorithm = match &certificate.signature_algorithm {
+            Some(name) if certificate.signature_algorithm_explicit => name.clone(),
+            Some(name) => format!("{} (derived from key)", name),
+            None => "unknown signature".to_string(),
+        };
+        let validity = match (certificate.validity_days, certificate.not_after) {
+            (Some(days), Some(not_after)) => format!(", valid {} days until {}", days, not_after),
+            _ => String::new(),
+        };
+        println!(
+            "  {}:{} {}: {}, {}{}{}",
+            certificate.file_path,
+            certificate.line,
+            certificate.kind,
+            algorithm,
+            certificate.key_label(),
+            validity,
+            if certificate.flagged {
+                " [flagged]"
+            } else {
+                ""
+            }
+        );
+    }
+}
+
+fn print_runtimes(report: &RuntimeReport) {
+    println!("\n=== Runtime PQ Capability ===");
+    println!(
+        "Versions: {} (enabled by default: {}, available: {}, disabled: {}, unavailable: {})",
+        report.assessments.len(),
+        report.count(PqStatus::EnabledByDefault),
+        report.count(PqStatus::Available),
+        report.count(PqStatus::Disabled),
+        report.count(PqStatus::Unavailable)
+    );
+    for assessment in &report.assessments {
+        let version = &assessment.version;
+        println!(
+            "  {}:{} {} {} ({}): {}",
+            version.path,
+            version.line,
+            version.runtime,
+            version.version,
+            version.source,
+            assessment.status
+        );
+        println!("    {}", assessment.detail);
+        if let Some(recommendation) = &assessment.recommendation {
+            println!("    Recommendation: {}", recommendation);
+        }
+    }
+}
+
+/// ASCII-art banner on stderr, for interactive text logging only
+fn print_banner() {
+    if logger().json || !logger().enabled(LogLevel::Info) {
+        return;
+    }
+    eprintln!("    ___                 ____        __    _ __ ");
+    eprintln!("   /   |  ____________ / __ \\__  __/ /_  (_) /_");
+    eprintln!("  / /| | / ___/ ___/ // / / / / / / __ \\/ / __/");
+    eprintln!(" / ___ |/ /  / /__/ // /_/ / /_/ / /_/ / / /_  ");
+    eprintln!("/_/  |_/_/   \\___/_/ \\___\\_\\__,_/_.___/_/\\__/  ");
+    eprintln!();
+}
+
+/// Write a report into `reports_dir` and log its path; returns the path
+fn write_report(
+    reports_dir: &Path,
+    file_name: &str,
+    label: &str,
+    contents: &str,
+) -> Result<String, String> {
+    fs::create_dir_all(reports_dir)
+        .map_err(|e| format!("Failed to create reports directory: {}", e))?;
+    let output_file = reports_dir.join(file_name);
+    fs::write(&output_file, contents).map_err(|e| e.to_string())?;
+    log_info!("{}: {}", label, output_file.display());
+    Ok(output_file.display().to_string())
+}
+
+/// Write SARIF, SC-13 and OSCAL reports for per-file results; returns the files written
+fn write_reports(
+    reports_dir: &Path,
+    base_name: &str,
+    target: &str,
+    files: &[(&str, &AuditResult)],
+    plugin_files: &[(&str, &[Vulnerability])],
+) -> Result<Vec<String>, String> {
+    log_info!("Generating compliance reports");
+    let mut written = Vec::new();
+
+    // Export SARIF for code scanning tools
+    let sarif = generate_sarif_report(
+        files
+            .iter()
+            .map(|(path, audit)| (*path, audit.vulnerabilities.as_slice()))
+            .chain(plugin_files.iter().copied()),
+    );
+    match export_sarif_json(&sarif) {
+        Ok(json) => written.push(write_report(
+            reports_dir,
+            &format!("{}.sarif", base_name),
+            "SARIF report",
+            &json,
+        )?),
+        Err(e) => log_error!("Failed to generate SARIF report: {}", e),
+    }
+
+    // One SC-13 assessment over every scanned file
+    if let Some(sc13_report) = generate_project_sc13_report(files.iter().copied()) {
+        // Export SC-13 JSON
+        match export_sc13_json(&sc13_report) {
+            Ok(json) => written.push(write_report(
+                reports_dir,
+                &format!("{}-sc13-compliance.json", base_name),
+                "SC-13 report",
+                &json,
+            )?),
+            Err(e) => log_error!("Failed to generate SC-13 report: {}", e),
+        }
+
+        // Export OSCAL JSON
+        let oscal = generate_oscal_json(&sc13_report, Some(target));
+        match export_oscal_json(&oscal) {
+            Ok(json) => written.push(write_report(
+                reports_dir,
+                &format!("{}-oscal-assessment.json", base_name),
+                "OSCAL report",
+                &json,
+            )?),
+            Err(e) => log_error!("Failed to generate OSCAL report: {}", e),
+        }
+    }
+
+    Ok(written)
+}
+
+/// Write SARIF, SC-13 and OSCAL reports per sub-project and the project rollup
+fn write_project_reports(
+    reports_dir: &Path,
+    base_name: &str,
+    target: &str,
+    layout: &ProjectLayout,
+    files: &[(&str, &AuditResult)],
+    plugin_files: &[(&str, &[Vulnerability])],
+    text: bool,
+) -> Result<Vec<String>, String> {
+    let Some(rollup) = project_rollup(target, layout, files.iter().copied()) else {
+        return Ok(Vec::new());
+    };
+
+    if text {
+        println!("\n=== Projects ===");
+        for project in &rollup.projects {
+            println!(
+                "  {} ({})",
+                project.name,
+                if project.root.is_empty() {
+                    "."
+                } else {
+                    &project.root
+                }
+            );
+            println!(
+                "    Files: {}, vulnerabilities: {} (critical: {}, high: {}), risk: {}, SC-13 compliance: {}",
+                project.files_scanned,
+                project.total_vulnerabilities,
+                project.critical_count,
+                project.high_count,
+                project.risk_score,
+                project.compliance_score
+            );
+        }
+        println!(
+            "  Total: {} project(s), risk: {}, SC-13 compliance: {}",
+            rollup.projects.len(),
+            rollup.total.risk_score,
+            rollup.total.compliance_score
+        );
+    }
+
+    let mut written = Vec::new();
+    let project_dir = reports_dir.join(format!("{}-projects", base_name));
+    let plugin_groups = layout.group(plugin_files.iter().copied());
+    for (project, members) in layout.group(files.iter().copied()) {
+        let plugin_members = plugin_groups
+            .iter()
+            .find(|(p, _)| p.root == project.root)
+            .map_or(&[][..], |(_, members)| members.as_slice());
+        log_info!("Reports for project {}", project.name);
+        written.extend(write_reports(
+            &project_dir,
+            &project.slug(),
+            target,
+            &members,
+            plugin_members,
+        )?);
+    }
+
+    let json = export_project_rollup_json(&rollup).map_err(|e| e.to_string())?;
+    written.push(write_report(
+        reports_dir,
+        &format!("{}-projects.json", base_name),
+        "Project rollup",
+        &json,
+    )?);
+    Ok(written)
+}
+
+/// Write the partial result of a `--shard` scan; returns its path
+fn write_shard_result(
+    reports_dir: &Path,
+    base_name: &str,
+    shard: ShardSpec,
+    target: &str,
+    state: &ScanState,
+) -> Result<String, String> {
+    let mut result = ShardResult::new(shard, target);
+    result.files = state
+        .project_files
+        .iter()
+        .map(|file| FileResult {
+            path: file.path.clone(),
+            audit: file.audit.clone(),
+        })
+        .collect();
+    result.plugin_files = state
+        .plugin_files
+        .iter()
+        .map(|(path, findings)| PluginFileResult {
+            path: path.clone(),
+            vulnerabilities: findings.clone(),
+        })
+        .collect();
+    result.diagnostics = state.diagnostics.clone();
+    result.projects = state.projects.clone();
+
+    let json = export_shard_json(&result).map_err(|e| e.to_string())?;
+    write_report(
+        reports_dir,
+        &format!(
+            "{}-shard-{}-of-{}.json",
+            base_name, shard.index, shard.count
+        ),
+        "Shard result",
+        &json,
+    )
+}
+
+/// Per-kind counts of skipped files, e.g. `binary: 2, time budget exceeded: 1`
+fn diagnostic_counts(diagnostics: &[ScanDiagnostic]) -> String {
+    let mut counts: Vec<(DiagnosticKind, usize)> = Vec::new();
+    for diagnostic in diagnostics {
+        match counts.iter_mut().find(|(kind, _)| *kind == diagnostic.kind) {
+            Some((_, count)) => *count += 1,
+            None => counts.push((diagnostic.kind, 1)),
+        }
+    }
+    counts
+        .iter()
+        .map(|(kind, count)| format!("{}: {}", diagnostic_label(*kind), count))
+        .collect::<Vec<_>>()
+        .join(", ")
+}
+
+fn diagnostic_label(kind: DiagnosticKind) -> &'static str {
+    match kind {
+        DiagnosticKind::TooLarge => "too large",
+        DiagnosticKind::Binary => "binary",
+        DiagnosticKind::Unreadable => "unreadable",
+        DiagnosticKind::TimeBudgetExceeded => "time budget exceeded",
+        DiagnosticKind::MemoryCapExceeded => "memory cap exceeded",
+        DiagnosticKind::AnalysisFailed => "analysis failed",
+    }
+}
+
+fn print_profile(profile: &ScanProfile) {
+    let millis = |d: Duration| d.as_secs_f64() * 1000.0;
+
+    println!("\n=== Profile ===");
+    println!("Total: {:.1} ms", millis(profile.total));
+    println!("Slowest rules:");
+    for rule in profile.rules.iter().take(5) {
+        println!(
+            "  {:<28} {:>9.2} ms  {} match(es) in {} file(s){}",
+            rule.rule_id,
+            millis(rule.elapsed),
+            rule.matches,
+            rule.files,
+            if rule.custom { " (custom)" } else { "" }
+        );
+    }
+    println!("Languages:");
+    for language in &profile.languages {
+        println!(
+            "  {:<28} {:>9.2} ms  {} file(s), {} line(s)",
+            language.language.to_string(),
+            millis(language.elapsed),
+            language.files,
+            language.lines
+        );
+    }
+    println!("Slowest files:");
+    for file in profile.files.iter().take(10) {
+        println!(
+            "  {:<28} {:>9.2} ms  {} line(s), {} finding(s)",
+            file.path,
+            millis(file.elapsed),
+            file.lines,
+            file.findings
+        );
+    }
+}
+
+/// Merge `--shard` results into one project result and generate its reports
+fn merge_shard_results(options: MergeOptions) -> Result<(), String> {
+    let mut shards = Vec::new();
+    for path in &options.shard_files {
+        let json = fs::read_to_string(path)
+            .map_err(|e| format!("Failed to read {}: {}", path.display(), e))?;
+        let shard = parse_shard_json(&json).map_err(|e| format!("{}: {}", path.display(), e))?;
+        shards.push(shard);
+    }
+    let merged = merge_shards(shards).map_err(|e| e.to_string())?;
+
+    println!("=== Merged Scan ===");
+    println!("Target: {}", merged.target);
+    for warning in &merged.warnings {
+        log_warn!("{}", warning);
+    }
+    if !merged.missing_shards.is_empty() {
+        log_warn!(
+            "Missing shard(s) {} of {}; results are incomplete",
+            merged
+                .missing_shards
+                .iter()
+                .map(|index| index.to_string())
+                .collect::<Vec<_>>()
+                .join(", "),
+            merged.shards[0].count
+        );
+    }
+
+    let plugin_findings: Vec<&Vulnerability> = merged
+        .plugin_files
+        .iter()
+        .flat_map(|file| &file.vulnerabilities)
+        .collect();
+    let project = merged.project_result();
+    let stats = project.as_ref().map(|p| p.stats.clone());
+    let count = |severity: Severity| {
+        plugin_findings
+            .iter()
+            .filter(|v| v.severity == severity)
+            .count()
+    };
+    let total = stats.as_ref().map_or(0, |s| s.total_vulnerabilities) + plugin_findings.len();
+    let critical = stats.as_ref().map_or(0, |s| s.critical_count) + count(Severity::Critical);
+    let high = stats.as_ref().map_or(0, |s| s.high_count) + count(Severity::High);
+
+    println!("Shards merged: {}", merged.shards.len());
+    println!(
+        "Files scanned: {}",
+        merged.files.len() + merged.plugin_files.len()
+    );
+    println!("Total vulnerabilities: {}", total);
+    println!("  Critical: {}", critical);
+    println!("  High: {}", high);
+    if let Some(project) = &project {
+        println!("Risk score: {}", project.risk_score);
+    }
+    if merged.duplicates_removed > 0 {
+        println!("Duplicate findings removed: {}", merged.duplicates_removed);
+    }
+    if !merged.diagnostics.is_empty() {
+        println!(
+            "Files skipped: {} ({})",
+            merged.diagnostics.len(),
+            diagnostic_counts(&merged.diagnostics)
+        );
+    }
 
-fn scan_directory(mut options: ScanOptions) -> Result<(), String> {
-    // If it's a repository URL, clone it first
-    let cloned_path = if options.is_repo_url {
-        println!("=== Cloning Repository ===");
-        println!("URL: {}\n", options.target_path);
+    let base_name = options.report_name.clone().unwrap_or_else(|| {
+        if is_git_url(&merged.target) {
+            extract_repo_name(&merged.target)
+        } else {
+            Path::new(&merged.target)
+                .file_name()
+                .map(|n| n.to_string_lossy().to_string())
+        }
+        .unwrap_or_else(|| "scan".to_string())
+    });
+    let reports_dir = Path::new(&options.report_dir);
 
-        match clone_repository(&options.target_path) {
-            Ok(path) => {
-                println!("✓ Cloned to: {}\n", path.display());
+    if !merged.diagnostics.is_empty() {
+        let json = export_diagnostics_json(&merged.diagnostics).map_err(|e| e.to_string())?;
+        write_report(
+            reports_dir,
+            &format!("{}-diagnostics.json", base_name),
+            "Diagnostics",
+            &json,
+        )?;
+    }
 
-                // Update target_path to the cloned directory
-                options.target_path = path.to_string_lossy().to_string();
+    if total > 0 {
+        let files: Vec<(&str, &AuditResult)> = merged
+            .files
+            .iter()
+            .map(|file| (file.path.as_str(), &file.audit))
+            .collect();
+        let plugin_files: Vec<(&str, &[Vulnerability])> = merged
+            .plugin_files
+            .iter()
+            .map(|file| (file.path.as_str(), file.vulnerabilities.as_slice()))
+            .collect();
+        write_reports(
+            reports_dir,
+            &base_name,
+            &merged.target,
+            &files,
+            &plugin_files,
+        )?;
+    }
 
-                // Extract repo name for default report naming if not specified
-                if options.report_name.is_none()
-                    && let Some(name) = extract_repo_name(&options.target_path)
-                {
-                    options.report_name = Some(name);
-                }
+    // Shards scanned with --projects carry the project roots
+    if !merged.projects.is_empty() {
+        let files: Vec<(&str, &AuditResult)> = merged
+            .files
+            .iter()
+            .map(|file| (file.path.as_str(), &file.audit))
+            .collect();
+        let plugin_files: Vec<(&str, &[Vulnerability])> = merged
+            .plugin_files
+            .iter()
+            .map(|file| (file.path.as_str(), file.vulnerabilities.as_slice()))
+            .collect();
+        write_project_reports(
+            reports_dir,
+            &base_name,
+            &merged.target,
+            &ProjectLayout::new(merged.projects.clone()),
+            &files,
+            &plugin_files,
+            true,
+        )?;
+    }
 
-                Some(path)
+    if critical > 0 || high > 0 {
+        log_warn!(
+            "Critical or high severity vulnerabilities found; review the generated reports for remediation steps"
+        );
+        process::exit(1);
+    }
+
+    Ok(())
+}
+
+/// List, describe or export the rule catalog
+fn run_rules_command(options: RulesOptions) -> Result<(), String> {
+    let custom = load_rule_files(&options.rule_files)?;
+    let catalog = rule_catalog(&custom);
+
+    match options.command {
+        RulesCommand::List => {
+            println!(
+                "{:<28} {:<9} {:<15} {:<9} LANGUAGES",
+                "ID", "ORIGIN", "ALGORITHM", "SEVERITY"
+            );
+            for rule in &catalog {
+                println!(
+                    "{:<28} {:<9} {:<15} {:<9} {}",
+                    rule.id,
+                    match rule.origin {
+                        RuleOrigin::BuiltIn => "built-in",
+                        RuleOrigin::Custom => "custom",
+                    },
+                    rule.crypto_type.to_string(),
+                    format!("{:?}", rule.severity),
+                    languages_label(&rule.languages)
+                );
             }
-            Err(e) => {
-                return Err(format!("Failed to clone repository: {}", e));
+        }
+        RulesCommand::Describe(id) => {
+            let rule = find_rule(&catalog, &id).ok_or_else(|| format!("Unknown rule: {}", id))?;
+            print_rule(rule);
+        }
+        RulesCommand::Export { markdown, output } => {
+            let document = if markdown {
+                export_catalog_markdown(&catalog)
+            } else {
+                export_catalog_json(&catalog).map_err(|e| e.to_string())?
+            };
+            match output {
+                Some(path) => {
+                    fs::write(&path, document).map_err(|e| e.to_string())?;
+                    log_info!("Rule catalog written to {}", path);
+                }
+                None => println!("{}", document),
             }
         }
-    } else {
-        None
-    };
+    }
 
-    let target = PathBuf::from(&options.target_path);
+    Ok(())
+}
 
-    if !target.exists() {
-        return Err(format!("Path does not exist: {}", options.target_path));
+fn print_rule(rule: &RuleDescription) {
+    println!("{} — {}", rule.id, rule.name);
+    println!("  Algorithm:    {} ({})", rule.crypto_type, rule.category);
+    println!(
+        "  Severity:     {:?} (risk score {} without a known key size)",
+        rule.severity, rule.risk_score
+    );
+    println!("  Languages:    {}", languages_label(&rule.languages));
+    println!("  NIST 800-53:  {}", rule.nist_controls.join(", "));
+    println!("  ITSG-33:      {}", rule.itsg33_controls.join(", "));
+    println!(
+        "  CCCS:         {} ({})",
+        rule.cccs.status, rule.cccs.itsp_reference
+    );
+    for condition in &rule.cccs.conditions {
+        println!("                - {}", condition);
     }
-
-    println!("    ___                 ____        __    _ __ ");
-    println!("   /   |  ____________ / __ \\__  __/ /_  (_) /_");
-    println!("  / /| | / ___/ ___/ // / / / / / / __ \\/ / __/");
-    println!(" / ___ |/ /  / /__/ // /_/ / /_/ / /_/ / / /_  ");
-    println!("/_/  |_/_/   \\___/_/ \\___\\_\\__,_/_.___/_/\\__/  ");
     println!();
-    println!("=== PQC Scanner ===");
-    println!("Scanning: {}\n", options.target_path);
+    println!("  {}", rule.rationale);
+    println!();
+    println!("  Recommendation: {}", rule.recommendation);
+    if !rule.references.is_empty() {
+        println!();
+        println!("  References:");
+        for reference in &rule.references {
+            println!("    - {}", reference);
+        }
+    }
+    println!();
+    println!("  Patterns:");
+    for pattern in &rule.patterns {
+        println!("    {}", pattern);
+    }
+    for (title, examples) in [
+        ("Matches", &rule.positive_examples),
+        ("Does not match", &rule.negative_examples),
+    ] {
+        if !examples.is_empty() {
+            println!();
+            println!("  {}:", title);
+            // Import-resolved rules have whole-source examples
+            for line in examples.iter().flat_map(|example| example.lines()) {
+                println!("    {}", line.trim_end());
+            }
+        }
+    }
+}
 
-    let mut total_files = 0;
-    let mut total_vulnerabilities = 0;
-    let mut critical_count = 0;
-    let mut high_count = 0;
-    let mut all_results = Vec::new();
+/// Re-run detection on one line with tracing and print why each finding was produced
+fn run_explain(options: ExplainOptions) -> Result<(), String> {
+    let path = &options.file_path;
+    let language = language_for_path(path)
+        .ok_or_else(|| format!("Unsupported file type: {}", path.display()))?;
+    let bytes = fs::read(path).map_err(|e| format!("Failed to read {}: {}", path.display(), e))?;
+    let decoded =
+        decode_source(&bytes).map_err(|_| format!("{} appears to be binary", path.display()))?;
 
-    // Scan all supported files in directory
-    if target.is_dir() {
-        scan_dir_recursive(
-            &target,
-            &mut total_files,
-            &mut total_vulnerabilities,
-            &mut critical_count,
-            &mut high_count,
-            &mut all_results,
-        )?;
+    let rules = load_rule_files(&options.rule_files)?;
+    let audit = analyze_with_rules(&decoded.text, &language.to_string(), &rules)
+        .map_err(|e| format!("Failed to analyze {}: {}", path.display(), e))?;
+    let parsed = parse_file(&decoded.text, &language.to_string())
+        .unwrap_or_else(|_| ParsedSource::new(language));
+    let mut files = vec![ProjectFile {
+        path: path.display().to_string(),
+        source: decoded.text,
+        parsed,
+        audit,
+    }];
+    // Key sizes defined by constants in the same file
+    resolve_project_symbols(&mut files);
+    assign_fingerprints(&mut files[0].audit.vulnerabilities);
+
+    let line = match &options.location {
+        ExplainLocation::Line(line) => *line,
+        ExplainLocation::Fingerprint(fingerprint) => files[0]
+            .audit
+            .vulnerabilities
+            .iter()
+            .find(|v| v.fingerprint.as_ref() == Some(fingerprint))
+            .map(|v| v.line)
+            .ok_or_else(|| {
+                format!(
+                    "No finding with fingerprint {} in {}",
+                    fingerprint,
+                    path.display()
+                )
+            })?,
+    };
+    let explanation =
+        explain_line(&files[0], line, &rules, options.min_confidence).map_err(|e| e.to_string())?;
+    if options.json {
+        println!(
+            "{}",
+            export_explanation_json(&explanation).map_err(|e| e.to_string())?
+        );
     } else {
-        return Err(format!(
-            "Expected directory, got file: {}",
-            options.target_path
-        ));
+        print_explanation(&explanation);
     }
 
-    println!("\n=== Scan Summary ===");
-    println!("Files scanned: {}", total_files);
-    println!("Total vulnerabilities: {}", total_vulnerabilities);
-    println!("  Critical: {}", critical_count);
-    println!("  High: {}", high_count);
-
-    // Generate reports if vulnerabilities found
-    if !all_results.is_empty() {
-        println!("\nGenerating compliance reports...");
-
-        // Create reports directory if it doesn't exist
-        let reports_dir = PathBuf::from(&options.report_dir);
-        fs::create_dir_all(&reports_dir)
-            .map_err(|e| format!("Failed to create reports directory: {}", e))?;
-
-        // Determine base name for reports
-        let base_name = if let Some(name) = options.report_name {
-            name
-        } else {
-            // Extract directory name from target path
-            target
-                .file_name()
-                .and_then(|n| n.to_str())
-                .unwrap_or("scan")
-                .to_string()
-        };
+    Ok(())
+}
 
-        // Use the first result for report generation (or combine them)
-        if let Some(first_result) = all_results.first() {
-            let sc13_report = generate_sc13_report(first_result, Some(&options.target_path));
+fn print_explanation(explanation: &LineExplanation) {
+    println!("{}:{}", explanation.file_path, explanation.line);
+    println!("  {}", explanation.source.trim_end());
+    println!();
 
-            // Export SC-13 JSON
-            match export_sc13_json(&sc13_report) {
-                Ok(json) => {
-                    let filename = format!("{}-sc13-compliance.json", base_name);
-                    let output_file = reports_dir.join(filename);
-                    fs::write(&output_file, json).map_err(|e| e.to_string())?;
-                    println!("  ✓ SC-13 Report: {}", output_file.display());
-                }
-                Err(e) => eprintln!("  ✗ Failed to generate SC-13 report: {}", e),
+    println!("Rule matches:");
+    if explanation.matches.is_empty() {
+        println!("  (none)");
+    }
+    for m in &explanation.matches {
+        let outcome = match &m.outcome {
+            MatchOutcome::Reported => match m.match_kind {
+                Some(kind) => format!("reported ({:?} match)", kind),
+                None => "reported".to_string(),
+            },
+            MatchOutcome::NotAtTokenBoundary => "dropped: inside a longer identifier".to_string(),
+            MatchOutcome::Overlapped { by } => format!("dropped: span claimed by {}", by),
+            MatchOutcome::CurveOfKeyExchange => {
+                "dropped: curve of an ECDH key exchange on this line".to_string()
             }
-
-            // Export OSCAL JSON
-            let oscal = generate_oscal_json(&sc13_report, Some(&options.target_path));
-            match export_oscal_json(&oscal) {
-                Ok(json) => {
-                    let filename = format!("{}-oscal-assessment.json", base_name);
-                    let output_file = reports_dir.join(filename);
-                    fs::write(&output_file, json).map_err(|e| e.to_string())?;
-                    println!("  ✓ OSCAL Report: {}", output_file.display());
-                }
-                Err(e) => eprintln!("  ✗ Failed to generate OSCAL report: {}", e),
+            MatchOutcome::LessConfidentDuplicate => {
+                "dropped: a more confident match of the same algorithm is reported".to_string()
             }
+            MatchOutcome::ReplacedByCustomRule { rule_id } => {
+                format!("replaced by custom rule {}", rule_id)
+            }
+        };
+        println!(
+            "  {:<20} cols {}-{} {:?}: {}",
+            m.rule_id,
+            m.start + 1,
+            m.end,
+            m.text,
+            outcome
+        );
+        println!("  {:<20} pattern: {}", "", m.pattern);
+    }
+
+    if explanation.findings.is_empty() {
+        println!();
+        println!("No findings on this line.");
+    }
+    for finding in &explanation.findings {
+        let vuln = &finding.finding;
+        println!();
+        println!(
+            "[{:?}] {} ({}) cols {}-{}",
+            vuln.severity,
+            vuln.crypto_type,
+            finding.rule_id,
+            vuln.column + 1,
+            vuln.end_column
+        );
+        println!("  {}", vuln.message);
+        println!();
+        println!(
+            "  Key size:     {} - {}",
+            finding
+                .key_size
+                .key_size
+                .map(|size| format!("{} bits", size))
+                .unwrap_or_else(|| "none".to_string()),
+            finding.key_size.detail
+        );
+        println!(
+            "  Risk score:   {} (score_vulnerability: {})",
+            finding.score.risk_score, finding.score.rule
+        );
+        for note in &finding.score.notes {
+            println!("                {}", note);
+        }
+        println!(
+            "  Severity:     {:?} ({})",
+            finding.score.severity, finding.score.severity_source
+        );
+        println!(
+            "  Confidence:   {} ({:?} match)",
+            finding.score.confidence, finding.score.match_kind
+        );
+        println!("  Suppressions:");
+        for check in &finding.suppressions {
+            println!(
+                "    {:<22} {} - {}",
+                check.name,
+                if check.applied {
+                    "applied"
+                } else {
+                    "not applied"
+                },
+                check.detail
+            );
         }
+        let controls = &finding.controls;
+        println!(
+            "  NIST 800-53:  {} ({})",
+            controls.nist_controls.join(", "),
+            controls.category
+        );
+        println!("  ITSG-33:      {}", controls.itsg33_controls.join(", "));
+        println!(
+            "  CCCS:         {} ({})",
+            controls.cccs.status, controls.cccs.itsp_reference
+        );
+        println!("  SC-13:        {}", controls.sc13_effect);
     }
+}
 
-    // Cleanup cloned repository if requested
-    if let Some(cloned) = cloned_path {
-        if options.cleanup_after_scan {
-            println!("\nCleaning up cloned repository...");
-            if let Err(e) = fs::remove_dir_all(&cloned) {
-                eprintln!("  ⚠ Warning: Failed to remove cloned directory: {}", e);
-            } else {
-                println!("  ✓ Removed: {}", cloned.display());
+/// Score the scanner against a labeled corpus; returns whether the baseline regressed
+fn run_evaluation(options: EvalOptions) -> Result<bool, String> {
+    let corpus = PathBuf::from(&options.corpus_path);
+    if !corpus.is_dir() {
+        return Err(format!(
+            "Expected corpus directory, got: {}",
+            options.corpus_path
+        ));
+    }
+
+    let mut files = Vec::new();
+    collect_corpus_files(&corpus, &mut files)?;
+    files.sort();
+
+    let rules = load_rule_files(&options.rule_files)?;
+    let mut evaluation = Evaluation::new();
+    for path in &files {
+        let Some(language) = language_for_path(path) else {
+            continue;
+        };
+        let source = match fs::read_to_string(path) {
+            Ok(source) => source,
+            Err(e) => {
+                log_warn!("Skipping {} - {}", path.display(), e);
+                continue;
             }
-        } else {
-            println!("\nCloned repository kept at: {}", cloned.display());
+        };
+        if source.trim().is_empty() {
+            continue;
         }
+
+        let labeled =
+            parse_labeled_source(&source).map_err(|e| format!("{}: {}", path.display(), e))?;
+        let mut audit = analyze_with_rules(&labeled.source, &language.to_string(), &rules)
+            .map_err(|e| format!("Failed to analyze {}: {}", path.display(), e))?;
+        filter_by_confidence(&mut audit, options.min_confidence);
+
+        let relative = path.strip_prefix(&corpus).unwrap_or(path);
+        evaluation.record(
+            &relative.to_string_lossy(),
+            language,
+            &labeled.expectations,
+            &audit.vulnerabilities,
+        );
     }
+    evaluation.finish();
 
-    if critical_count > 0 || high_count > 0 {
-        println!("\n⚠️  WARNING: Critical vulnerabilities found!");
-        println!("Review the generated reports for detailed remediation steps.");
-        process::exit(1);
+    println!("=== Evaluation ===");
+    println!(
+        "Corpus: {} ({} files)\n",
+        corpus.display(),
+        evaluation.files
+    );
+    println!(
+        "{:<18} {:>5} {:>5} {:>5} {:>10} {:>8} {:>7}",
+        "", "TP", "FP", "FN", "Precision", "Recall", "F1"
+    );
+    print_metrics_row("overall", &evaluation.overall);
+    for (name, metrics) in &evaluation.by_crypto_type {
+        print_metrics_row(name, metrics);
+    }
+    for (name, metrics) in &evaluation.by_language {
+        print_metrics_row(name, metrics);
+    }
+
+    if !evaluation.mismatches.is_empty() {
+        println!("\nMismatches:");
+        for mismatch in evaluation.mismatches.iter().take(20) {
+            println!(
+                "  {:?} {} at {}:{}",
+                mismatch.kind, mismatch.crypto_type, mismatch.file_path, mismatch.line
+            );
+        }
+        if evaluation.mismatches.len() > 20 {
+            println!("  ... and {} more", evaluation.mismatches.len() - 20);
+        }
+    }
+
+    if let Some(output) = &options.output {
+        let json = export_evaluation_json(&evaluation).map_err(|e| e.to_string())?;
+        fs::write(output, json).map_err(|e| format!("Failed to write {}: {}", output, e))?;
+        log_info!("Eval result: {}", output);
+    }
+
+    let Some(baseline) = &options.baseline else {
+        return Ok(false);
+    };
+    let previous: Evaluation = fs::read_to_string(baseline)
+        .map_err(|e| format!("Failed to read baseline {}: {}", baseline, e))
+        .and_then(|json| {
+            serde_json::from_str(&json).map_err(|e| format!("Invalid baseline {}: {}", baseline, e))
+        })?;
+    let comparison = compare_evaluations(&previous, &evaluation);
+
+    println!("\n=== Comparison with {} ===", baseline);
+    for delta in &comparison.improvements {
+        println!(
+            "  ↑ {} {}: {:.3} → {:.3}",
+            delta.scope, delta.metric, delta.previous, delta.current
+        );
+    }
+    for delta in &comparison.regressions {
+        println!(
+            "  ↓ {} {}: {:.3} → {:.3}",
+            delta.scope, delta.metric, delta.previous, delta.current
+        );
+    }
+    if comparison.has_regressions() {
+        log_warn!("Detection quality regressed");
+    } else {
+        println!("  No regressions");
     }
 
+    Ok(comparison.has_regressions())
+}
+
+fn print_metrics_row(name: &str, metrics: &Metrics) {
+    println!(
+        "{:<18} {:>5} {:>5} {:>5} {:>10.3} {:>8.3} {:>7.3}",
+        name,
+        metrics.true_positives,
+        metrics.false_positives,
+        metrics.false_negatives,
+        metrics.precision,
+        metrics.recall,
+        metrics.f1
+    );
+}
+
+fn collect_corpus_files(dir: &Path, files: &mut Vec<PathBuf>) -> Result<(), String> {
+    let entries = fs::read_dir(dir).map_err(|e| format!("Cannot read {}: {}", dir.display(), e))?;
+    for entry in entries {
+        let path = entry.map_err(|e| e.to_string())?.path();
+        if path.is_dir() {
+            collect_corpus_files(&path, files)?;
+        } else if language_for_path(&path).is_some() {
+            files.push(path);
+        }
+    }
     Ok(())
 }
 
@@ -393,18 +2772,40 @@ fn extract_repo_name(url: &str) -> Option<String> {
 }
 
 fn scan_dir_recursive(
+    root: &Path,
     dir: &Path,
-    total_files: &mut usize,
-    total_vulns: &mut usize,
-    critical: &mut usize,
-    high: &mut usize,
-    results: &mut Vec<pqc_scanner::AuditResult>,
+    rules: &[CustomRule],
+    shard: Option<ShardSpec>,
+    limits: &ResourceLimits,
+    state: &mut ScanState,
 ) -> Result<(), String> {
-    let entries = fs::read_dir(dir).map_err(|e| format!("Cannot read directory: {}", e))?;
+    let relative_dir = dir.strip_prefix(root).unwrap_or(dir);
+    let entries = match fs::read_dir(dir) {
+        Ok(entries) => entries,
+        Err(e) if dir == root => return Err(format!("Cannot read directory: {}", e)),
+        Err(e) => {
+            // An unreadable subdirectory must not abort the rest of the walk
+            state.skip(
+                relative_dir,
+                DiagnosticKind::Unreadable,
+                format!("Skipping {} - {}", dir.display(), e),
+            );
+            return Ok(());
+        }
+    };
 
     for entry in entries {
-        let entry = entry.map_err(|e| e.to_string())?;
-        let path = entry.path();
+        let path = match entry {
+            Ok(entry) => entry.path(),
+            Err(e) => {
+                state.skip(
+                    relative_dir,
+                    DiagnosticKind::Unreadable,
+                    format!("Skipping entry in {} - {}", dir.display(), e),
+                );
+                continue;
+            }
+        };
 
         if path.is_dir() {
             // Skip node_modules and common directories
@@ -414,49 +2815,330 @@ fn scan_dir_recursive(
                     continue;
                 }
             }
-            scan_dir_recursive(&path, total_files, total_vulns, critical, high, results)?;
-        } else if path.is_file()
-            && let Some(result) = scan_file(&path)?
-        {
-            *total_files += 1;
-            *total_vulns += result.stats.total_vulnerabilities;
-            *critical += result.stats.critical_count;
-            *high += result.stats.high_count;
-
-            if result.stats.total_vulnerabilities > 0 {
-                println!("\n{}", path.display());
-                println!("  Vulnerabilities: {}", result.stats.total_vulnerabilities);
-                println!(
-                    "  Critical: {}, High: {}",
-                    result.stats.critical_count, result.stats.high_count
-                );
+            scan_dir_recursive(root, &path, rules, shard, limits, state)?;
+        } else if path.is_file() {
+            let relative = path.strip_prefix(root).unwrap_or(&path);
+            // Every shard records every project root, so any shard can attribute files
+            if let Some(kind) = path
+                .file_name()
+                .and_then(|name| name.to_str())
+                .and_then(ManifestKind::from_file_name)
+                .filter(|kind| {
+                    state.detect_projects
+                        || (state.detect_go_modules && *kind == ManifestKind::GoMod)
+                })
+            {
+                let content = fs::read_to_string(&path).unwrap_or_default();
+                let project_root = relative.parent().unwrap_or(Path::new(""));
+                let project_root = project_root.to_string_lossy();
+                if state.detect_projects {
+                    let project = SubProject::from_manifest(&project_root, kind, &content);
+                    log_debug!("Project {} at {}", project.name, relative.display());
+                    state.projects.push(project);
+                }
+                if state.detect_go_modules
+                    && let Some(module) = GoModule::from_go_mod(&project_root, &content)
+                {
+                    state.go_modules.push(module);
+                }
+            }
+            if shard.is_some_and(|shard| !shard.contains(&relative.to_string_lossy())) {
+                if let Some(file) = read_definitions(&path, relative) {
+                    state.definition_files.push(file);
+                }
+                continue;
+            }
+            if state.detect_runtimes && is_runtime_file(&relative.to_string_lossy()) {
+                let content = fs::read_to_string(&path).unwrap_or_default();
+                let evidence = scan_runtime_file(&relative.to_string_lossy(), &content);
+                state.runtime_evidence.extend(evidence);
+            }
+            if let Some(language) = language_for_path(&path) {
+                log_debug!("Scanning {}", relative.display());
+                state.emit(&ScanEvent::FileStarted {
+                    path: relative.to_string_lossy().to_string(),
+                    language,
+                });
+            }
 
-                // Show first few vulnerabilities
-                for (i, vuln) in result.vulnerabilities.iter().take(3).enumerate() {
-                    println!(
-                        "    {}. [{:?}] {} (line {})",
-                        i + 1,
-                        vuln.severity,
-                        vuln.crypto_type,
-                        vuln.line
-                    );
+            let ScannedFile {
+                mut result,
+                content,
+                encoding,
+                offsets,
+                elapsed,
+            } = match scan_file(&path, rules, limits) {
+                Ok(Some(scanned)) => scanned,
+                Ok(None) => continue,
+                Err((kind, message)) => {
+                    state.skip(relative, kind, message);
+                    continue;
                 }
+            };
 
-                if result.vulnerabilities.len() > 3 {
-                    println!("    ... and {} more", result.vulnerabilities.len() - 3);
+            if let Some(profiler) = &mut state.profiler {
+                profiler.record_file(&relative.to_string_lossy(), &result, elapsed);
+                profiler.profile_rules(&content, result.language, rules);
+            }
+
+            if matches!(result.language, Language::JavaScript | Language::TypeScript)
+                && let Some((map, map_dir)) = load_source_map(&path, &content)
+            {
+                // Bundles whose sources are all in the tree are scanned through those sources
+                if original_sources_present(&map, &map_dir) {
+                    state.skipped_bundles += 1;
+                    continue;
                 }
+                remap_findings(&mut result, &map, &content);
+            }
+
+            if !matches!(encoding, SourceEncoding::Utf8 | SourceEncoding::Utf8Bom) {
+                state.transcoded_files += 1;
+            }
+
+            // Files the parser does not support still take part with their findings
+            let mut parsed = parse_file(&content, &result.language.to_string())
+                .unwrap_or_else(|_| ParsedSource::new(result.language));
+            if !state.keep_parse_trees {
+                parsed = ParsedSource {
+                    constants: parsed.constants,
+                    ..ParsedSource::new(result.language)
+                };
+            }
+            state.stream_findings(&relative.to_string_lossy(), &result, offsets.as_ref());
+            state.project_files.push(ProjectFile {
+                path: relative.to_string_lossy().to_string(),
+                source: content,
+                parsed,
+                audit: result,
+            });
+            state.offset_maps.push(offsets);
+        }
+    }
+
+    Ok(())
+}
+
+/// Constants defined in a file of another shard, or `None` for files not scanned
+fn read_definitions(path: &Path, relative: &Path) -> Option<ProjectFile> {
+    let language = language_for_path(path)?;
+    if !fs::metadata(path).is_ok_and(|m| m.len() <= MAX_FILE_SIZE) {
+        return None;
+    }
+    let decoded = decode_source(&fs::read(path).ok()?).ok()?;
+    let parsed = parse_file(&decoded.text, &language.to_string()).ok()?;
+    Some(definitions_only(
+        &relative.to_string_lossy(),
+        &decoded.text,
+        parsed,
+    ))
+}
+
+/// Import `--rules` files, reporting constructs that could not be imported
+fn load_rule_files(paths: &[PathBuf]) -> Result<Vec<CustomRule>, String> {
+    let mut rules = Vec::new();
+    for path in paths {
+        let yaml = fs::read_to_string(path)
+            .map_err(|e| format!("Failed to read rules {}: {}", path.display(), e))?;
+        let import = import_semgrep_rules(&yaml)
+            .map_err(|e| format!("Failed to import rules {}: {}", path.display(), e))?;
+
+        for issue in &import.issues {
+            log_warn!("{}: {}", path.display(), issue);
+        }
+        log_info!(
+            "Imported {} rule(s) from {} ({} skipped)",
+            import.rules.len(),
+            path.display(),
+            import.skipped().count()
+        );
+        rules.extend(import.rules);
+    }
+    Ok(rules)
+}
+
+/// Load `--plugin` modules and run them over the scanned tree
+#[cfg(feature = "plugins")]
+fn run_plugins(
+    root: &Path,
+    plugin_paths: &[PathBuf],
+    shard: Option<ShardSpec>,
+    state: &mut ScanState,
+) -> Result<(), String> {
+    use pqc_scanner::plugins::{PluginLimits, WasmPlugin};
+
+    let mut plugins = Vec::new();
+    for path in plugin_paths {
+        let wasm = fs::read(path)
+            .map_err(|e| format!("Failed to read plugin {}: {}", path.display(), e))?;
+        let name = path
+            .file_stem()
+            .map(|s| s.to_string_lossy().to_string())
+            .unwrap_or_else(|| path.display().to_string());
+        let plugin = WasmPlugin::load(&name, &wasm, PluginLimits::default())
+            .map_err(|e| format!("Failed to load plugin {}: {}", path.display(), e))?;
+        log_info!("Loaded plugin {}", plugin.name);
+        plugins.push(plugin);
+    }
+    if plugins.is_empty() {
+        return Ok(());
+    }
 
-                results.push(result);
+    // Files the built-in detectors already scanned
+    for file in &mut state.project_files {
+        let extension = Path::new(&file.path).extension().and_then(|e| e.to_str());
+        let mut added = false;
+        for plugin in plugins.iter().filter(|p| p.accepts(extension, true)) {
+            match plugin.scan(&file.path, &file.source) {
+                Ok(findings) => {
+                    added |= !findings.is_empty();
+                    for vuln in findings {
+                        file.audit.add_vulnerability(vuln);
+                    }
+                }
+                Err(e) => log_warn!("Plugin {} failed on {}: {}", plugin.name, file.path, e),
             }
         }
+        if added {
+            file.audit.refresh();
+        }
+    }
+
+    // Other files the plugins asked for by extension
+    if plugins.iter().any(|p| p.extensions.is_some()) {
+        scan_plugin_files(root, root, &plugins, shard, state);
     }
 
     Ok(())
 }
 
-fn scan_file(path: &Path) -> Result<Option<pqc_scanner::AuditResult>, String> {
-    // Determine language from file extension
-    let language = match path.extension().and_then(|s| s.to_str()) {
+/// Walk the tree for non-source files claimed by a plugin's extensions
+#[cfg(feature = "plugins")]
+fn scan_plugin_files(
+    root: &Path,
+    dir: &Path,
+    plugins: &[pqc_scanner::plugins::WasmPlugin],
+    shard: Option<ShardSpec>,
+    state: &mut ScanState,
+) {
+    let Ok(entries) = fs::read_dir(dir) else {
+        return;
+    };
+
+    for path in entries.flatten().map(|entry| entry.path()) {
+        if path.is_dir() {
+            if let Some(name) = path.file_name() {
+                let name_str = name.to_string_lossy();
+                if name_str == "node_modules" || name_str == ".git" || name_str == "target" {
+                    continue;
+                }
+            }
+            scan_plugin_files(root, &path, plugins, shard, state);
+            continue;
+        }
+        if !path.is_file() || language_for_path(&path).is_some() {
+            continue;
+        }
+        let relative = path
+            .strip_prefix(root)
+            .unwrap_or(&path)
+            .to_string_lossy()
+            .to_string();
+        if shard.is_some_and(|shard| !shard.contains(&relative)) {
+            continue;
+        }
+
+        let extension = path.extension().and_then(|e| e.to_str());
+        let interested: Vec<_> = plugins
+            .iter()
+            .filter(|p| p.accepts(extension, false))
+            .collect();
+        if interested.is_empty() {
+            continue;
+        }
+
+        // Same size and binary limits as source files
+        let within_limit = fs::metadata(&path).is_ok_and(|m| m.len() <= MAX_FILE_SIZE);
+        let decoded = match fs::read(&path) {
+            Ok(bytes) if within_limit => decode_source(&bytes).ok(),
+            _ => None,
+        };
+        let Some(decoded) = decoded else {
+            log_warn!("Plugins skipped {}", path.display());
+            continue;
+        };
+
+        let mut findings = Vec::new();
+        for plugin in interested {
+            match plugin.scan(&relative, &decoded.text) {
+                Ok(found) => findings.extend(found),
+                Err(e) => log_warn!("Plugin {} failed on {}: {}", plugin.name, relative, e),
+            }
+        }
+        if !findings.is_empty() {
+            assign_fingerprints(&mut findings);
+            for vuln in findings
+                .iter()
+                .filter(|v| v.confidence >= state.min_confidence)
+            {
+                state.emit(&ScanEvent::Finding {
+                    path: relative.clone(),
+                    finding: Box::new(vuln.clone()),
+                });
+            }
+            state.plugin_files.push((relative, findings));
+        }
+    }
+}
+
+/// Findings as JSON lines, to tell whether a file's findings changed
+fn serialize_findings(findings: &[Vulnerability]) -> Vec<String> {
+    findings
+        .iter()
+        .map(|vuln| to_json_line(vuln).unwrap_or_default())
+        .collect()
+}
+
+/// Source map of a bundle (inline, `sourceMappingURL` or adjacent `.map`) and its directory
+fn load_source_map(path: &Path, content: &str) -> Option<(SourceMap, PathBuf)> {
+    let bundle_dir = path.parent().unwrap_or(Path::new(".")).to_path_buf();
+
+    let (json, map_path) = match find_source_mapping_url(content) {
+        Some(SourceMapReference::Inline(json)) => (json, bundle_dir.join("inline")),
+        Some(SourceMapReference::External(url)) if !url.contains("://") => {
+            let map_path = bundle_dir.join(url.split(['?', '#']).next().unwrap_or(&url));
+            (fs::read_to_string(&map_path).ok()?, map_path)
+        }
+        _ => {
+            let map_path = PathBuf::from(format!("{}.map", path.display()));
+            if !map_path.is_file() || !is_minified(content) {
+                return None;
+            }
+            (fs::read_to_string(&map_path).ok()?, map_path)
+        }
+    };
+
+    match SourceMap::parse(&json) {
+        Ok(map) => Some((map, map_path.parent().unwrap_or(&bundle_dir).to_path_buf())),
+        Err(e) => {
+            log_warn!("Ignoring source map for {}: {}", path.display(), e);
+            None
+        }
+    }
+}
+
+/// Whether every original source of a bundle exists outside skipped directories
+fn original_sources_present(map: &SourceMap, map_dir: &Path) -> bool {
+    !map.sources.is_empty()
+        && map
+            .sources
+            .iter()
+            .all(|source| !source.contains("node_modules") && map_dir.join(source).is_file())
+}
+
+/// Determine language from file extension
+fn language_for_path(path: &Path) -> Option<Language> {
+    match path.extension().and_then(|s| s.to_str()) {
         Some("js") => Some(Language::JavaScript),
         Some("ts") => Some(Language::TypeScript),
         Some("py") => Some(Language::Python),
@@ -466,22 +3148,34 @@ fn scan_file(path: &Path) -> Result<Option<pqc_scanner::AuditResult>, String> {
         Some("cpp") | Some("cc") | Some("cxx") => Some(Language::Cpp),
         Some("cs") => Some(Language::Csharp),
         _ => None,
-    };
+    }
+}
 
-    if let Some(lang) = language {
+fn scan_file(
+    path: &Path,
+    rules: &[CustomRule],
+    limits: &ResourceLimits,
+) -> Result<Option<ScannedFile>, (DiagnosticKind, String)> {
+    if let Some(lang) = language_for_path(path) {
         // Check file size before reading
-        let metadata = fs::metadata(path)
-            .map_err(|e| format!("Failed to get metadata for {}: {}", path.display(), e))?;
+        let metadata = fs::metadata(path).map_err(|e| {
+            (
+                DiagnosticKind::Unreadable,
+                format!("Failed to get metadata for {}: {}", path.display(), e),
+            )
+        })?;
 
         let file_size = metadata.len();
         if file_size > MAX_FILE_SIZE {
-            eprintln!(
-                "Warning: Skipping {} - file too large ({} bytes, max {})",
-                path.display(),
-                file_size,
-                MAX_FILE_SIZE
-            );
-            return Ok(None);
+            return Err((
+                DiagnosticKind::TooLarge,
+                format!(
+                    "Skipping {} - file too large ({} bytes, max {})",
+                    path.display(),
+                    file_size,
+                    MAX_FILE_SIZE
+                ),
+            ));
         }
 
         if file_size == 0 {
@@ -489,34 +3183,36 @@ fn scan_file(path: &Path) -> Result<Option<pqc_scanner::AuditResult>, String> {
             return Ok(None);
         }
 
-        // Read file content
-        let content = fs::read_to_string(path).map_err(|e| {
-            // Check if error is due to binary file
-            if e.kind() == std::io::ErrorKind::InvalidData {
-                return format!("Skipping {} - appears to be binary", path.display());
-            }
-            format!("Failed to read {}: {}", path.display(), e)
+        // Read raw bytes and transcode to UTF-8
+        let bytes = fs::read(path).map_err(|e| {
+            (
+                DiagnosticKind::Unreadable,
+                format!("Failed to read {}: {}", path.display(), e),
+            )
+        })?;
+        let decoded = decode_source(&bytes).map_err(|e| match e {
+            EncodingError::Binary => (
+                DiagnosticKind::Binary,
+                format!("Skipping {} - appears to be binary", path.display()),
+            ),
         })?;
 
         // Analyze content
-        let lang_str = match lang {
-            Language::JavaScript => "javascript",
-            Language::TypeScript => "typescript",
-            Language::Python => "python",
-            Language::Rust => "rust",
-            Language::Java => "java",
-            Language::Go => "go",
-            Language::Cpp => "cpp",
-            Language::Csharp => "csharp",
-        };
-
-        match analyze(&content, lang_str) {
-            Ok(result) => Ok(Some(result)),
-            Err(e) => {
-                eprintln!("Warning: Failed to analyze {}: {}", path.display(), e);
-                Ok(None)
-            }
-        }
+        let started = Instant::now();
+        let result =
+            analyze_with_limits(&decoded.text, &lang.to_string(), rules, limits).map_err(|e| {
+                (
+                    DiagnosticKind::for_audit_error(&e),
+                    format!("Failed to analyze {}: {}", path.display(), e),
+                )
+            })?;
+        Ok(Some(ScannedFile {
+            result,
+            content: decoded.text,
+            encoding: decoded.encoding,
+            offsets: decoded.offsets,
+            elapsed: started.elapsed(),
+        }))
     } else {
         Ok(None)
     }
@@ -0,0 +1,439 @@
+//! Intra-project call graph
+//!
+//! Links function definitions and call sites across all files of a project so
+//! crypto usage hidden inside helper functions (e.g. `func NewSigner()
+//! *ecdsa.PrivateKey`) is attributed to the code that calls those helpers.
+//! Resolution is name-based and deliberately lightweight: a call is linked to
+//! every project function with the same name, preferring definitions in the
+//! calling file.
+
+use crate::types::*;
+use serde::{Deserialize, Serialize};
+use std::collections::{HashMap, HashSet};
+
+/// Reference to a vulnerability of a project file (`files[file].audit.vulnerabilities[index]`)
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash)]
+pub struct FindingRef {
+    pub file: usize,
+    pub index: usize,
+}
+
+/// A function in the project call graph
+#[derive(Debug, Clone)]
+pub struct FunctionNode {
+    pub file: usize,
+    /// Index into the file's `parsed.functions`
+    pub function: usize,
+    pub name: String,
+    pub line: usize,
+    pub end_line: usize,
+    pub callees: Vec<usize>,
+    pub callers: Vec<usize>,
+    /// Findings located directly inside this function's body
+    pub findings: Vec<FindingRef>,
+}
+
+/// A resolved call from one project function (or module scope) to another
+#[derive(Debug, Clone)]
+pub struct CallSite {
+    pub file: usize,
+    pub line: usize,
+    pub column: usize,
+    /// Calling function, `None` for module-level code
+    pub caller: Option<usize>,
+    pub callee: usize,
+}
+
+/// Cross-file call graph built from parsed project sources
+#[derive(Debug, Clone, Default)]
+pub struct CallGraph {
+    pub nodes: Vec<FunctionNode>,
+    pub call_sites: Vec<CallSite>,
+    /// Findings that are not inside any function (imports, module-level code)
+    pub module_findings: Vec<FindingRef>,
+}
+
+impl CallGraph {
+    /// Build the call graph for a set of parsed and audited project files
+    pub fn build(files: &[ProjectFile]) -> Self {
+        let mut graph = CallGraph::default();
+        let mut by_name: HashMap<&str, Vec<usize>> = HashMap::new();
+        let mut by_file: Vec<Vec<usize>> = vec![Vec::new(); files.len()];
+
+        for (file_idx, file) in files.iter().enumerate() {
+            for (function, func) in file.parsed.functions.iter().enumerate() {
+                let id = graph.nodes.len();
+                graph.nodes.push(FunctionNode {
+                    file: file_idx,
+                    function,
+                    name: func.name.clone(),
+                    line: func.line,
+                    end_line: func.end_line,
+                    callees: Vec::new(),
+                    callers: Vec::new(),
+                    findings: Vec::new(),
+                });
+                by_name.entry(func.name.as_str()).or_default().push(id);
+                by_file[file_idx].push(id);
+            }
+        }
+
+        for (file_idx, file) in files.iter().enumerate() {
+            let enclosing = |line: usize| -> Option<usize> {
+                by_file[file_idx]
+                    .iter()
+                    .copied()
+                    .filter(|&id| graph.nodes[id].line <= line && line <= graph.nodes[id].end_line)
+                    .min_by_key(|&id| graph.nodes[id].end_line - graph.nodes[id].line)
+            };
+
+            let mut edges = Vec::new();
+            for call in &file.parsed.function_calls {
+                let name = callee_name(&call.name);
+
+                // The definition itself matches the call regex; skip it
+                let is_definition = by_file[file_idx]
+                    .iter()
+                    .any(|&id| graph.nodes[id].line == call.line && graph.nodes[id].name == name);
+                if is_definition {
+                    continue;
+                }
+
+                let Some(candidates) = by_name.get(name) else {
+                    continue;
+                };
+                let local: Vec<usize> = candidates
+                    .iter()
+                    .copied()
+                    .filter(|&id| graph.nodes[id].file == file_idx)
+                    .collect();
+                let targets = if local.is_empty() {
+                    candidates.clone()
+                } else {
+                    local
+                };
+
+                let caller = enclosing(call.line);
+                for callee in targets {
+                    edges.push(CallSite {
+                        file: file_idx,
+                        line: call.line,
+                        column: call.column,
+                        caller,
+                        callee,
+                    });
+                }
+            }
+
+            let mut module_findings = Vec::new();
+            let mut function_findings = Vec::new();
+            for (index, vuln) in file.audit.vulnerabilities.iter().enumerate() {
+                let finding = FindingRef {
+                    file: file_idx,
+                    index,
+                };
+                match enclosing(vuln.line) {
+                    Some(id) => function_findings.push((id, finding)),
+                    None => module_findings.push(finding),
+                }
+            }
+
+            graph.call_sites.extend(edges);
+            graph.module_findings.extend(module_findings);
+            for (id, finding) in function_findings {
+                graph.nodes[id].findings.push(finding);
+            }
+        }
+
+        for site in &graph.call_sites {
+            if let Some(caller) = site.caller {
+                if !graph.nodes[caller].callees.contains(&site.callee) {
+                    graph.nodes[caller].callees.push(site.callee);
+                }
+                if !graph.nodes[site.callee].callers.contains(&caller) {
+                    graph.nodes[site.callee].callers.push(caller);
+                }
+            }
+        }
+
+        graph
+    }
+
+    /// Functions that no other project function calls
+    pub fn entry_points(&self) -> Vec<usize> {
+        (0..self.nodes.len())
+            .filter(|&id| self.nodes[id].callers.is_empty())
+            .collect()
+    }
+
+    /// All functions reachable from `start` (including `start` itself)
+    pub fn reachable_from(&self, start: usize) -> HashSet<usize> {
+        let mut visited = HashSet::new();
+        let mut stack = vec![start];
+        while let Some(id) = stack.pop() {
+            if visited.insert(id) {
+                stack.extend(self.nodes[id].callees.iter().copied());
+            }
+        }
+        visited
+    }
+
+    /// Findings inside `node` or any function it transitively calls
+    pub fn transitive_findings(&self, node: usize) -> Vec<FindingRef> {
+        let mut reachable: Vec<usize> = self.reachable_from(node).into_iter().collect();
+        reachable.sort_unstable();
+        reachable
+            .into_iter()
+            .flat_map(|id| self.nodes[id].findings.iter().copied())
+            .collect()
+    }
+
+    /// Function whose body contains the finding, if any
+    pub fn function_of(&self, finding: FindingRef) -> Option<usize> {
+        self.nodes
+            .iter()
+            .position(|node| node.findings.contains(&finding))
+    }
+
+    /// Call sites that invoke `node`
+    pub fn call_sites_of(&self, node: usize) -> impl Iterator<Item = &CallSite> {
+        self.call_sites
+            .iter()
+            .filter(move |site| site.callee == node)
+    }
+}
+
+/// Strip receivers and module paths: `pkg.NewSigner` / `signer::new` -> last segment
+pub(crate) fn callee_name(call: &str) -> &str {
+    call.rsplit(['.', ':']).next().unwrap_or(call)
+}
+
+/// Call-graph report: wrappers, their call sites and the blast radius of each primitive
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct CallGraphReport {
+    pub total_functions: usize,
+    pub total_call_sites: usize,
+    pub entry_points: usize,
+
+    /// Functions that use vulnerable crypto directly or through callees
+    pub wrappers: Vec<WrapperFunction>,
+
+    /// Vulnerable primitives ranked by how many entry points reach them
+    pub primitives: Vec<PrimitiveReach>,
+}
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct WrapperFunction {
+    pub file_path: String,
+    pub name: String,
+    pub line: usize,
+
+    /// True when the crypto is used in the body, false when only through callees
+    pub direct: bool,
+
+    pub crypto_types: Vec<CryptoType>,
+    pub call_sites: Vec<WrapperCallSite>,
+}
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct WrapperCallSite {
+    pub file_path: String,
+    pub line: usize,
+    pub column: usize,
+    pub caller: Option<String>,
+}
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct PrimitiveReach {
+    pub crypto_type: CryptoType,
+    pub severity: Severity,
+    pub file_path: String,
+    pub line: usize,
+    pub function: Option<String>,
+    pub entry_point_count: usize,
+    pub entry_points: Vec<String>,
+}
+
+/// Build the call graph for a project and summarize crypto propagation
+pub fn generate_call_graph_report(files: &[ProjectFile]) -> CallGraphReport {
+    let graph = CallGraph::build(files);
+    let location = |id: usize| {
+        let node = &graph.nodes[id];
+        format!("{}:{}", files[node.file].path, node.name)
+    };
+
+    let mut wrappers = Vec::new();
+    for (id, node) in graph.nodes.iter().enumerate() {
+        let findings = graph.transitive_findings(id);
+        if findings.is_empty() {
+            continue;
+        }
+
+        let mut crypto_types: Vec<CryptoType> = Vec::new();
+        for finding in &findings {
+            let crypto_type = &files[finding.file].audit.vulnerabilities[finding.index].crypto_type;
+            if !crypto_types.contains(crypto_type) {
+                crypto_types.push(crypto_type.clone());
+            }
+        }
+
+        let call_sites = graph
+            .call_sites_of(id)
+            .map(|site| WrapperCallSite {
+                file_path: files[site.file].path.clone(),
+                line: site.line,
+                column: site.column,
+                caller: site.caller.map(|caller| graph.nodes[caller].name.clone()),
+            })
+            .collect();
+
+        wrappers.push(WrapperFunction {
+            file_path: files[node.file].path.clone(),
+            name: node.name.clone(),
+            line: node.line,
+            direct: !node.findings.is_empty(),
+            crypto_types,
+            call_sites,
+        });
+    }
+
+    // Count, for every finding, the entry points whose call tree reaches it
+    let entry_points = graph.entry_points();
+    let mut reached_by: HashMap<FindingRef, Vec<usize>> = HashMap::new();
+    for &entry in &entry_points {
+        for id in graph.reachable_from(entry) {
+            for finding in &graph.nodes[id].findings {
+                reached_by.entry(*finding).or_default().push(entry);
+            }
+        }
+    }
+
+    let mut primitives = Vec::new();
+    for (file_idx, file) in files.iter().enumerate() {
+        for (index, vuln) in file.audit.vulnerabilities.iter().enumerate() {
+            let finding = FindingRef {
+                file: file_idx,
+                index,
+            };
+            let mut entries: Vec<String> = reached_by
+                .get(&finding)
+                .map(|ids| ids.iter().map(|&id| location(id)).collect())
+                .unwrap_or_default();
+            entries.sort();
+
+            primitives.push(PrimitiveReach {
+                crypto_type: vuln.crypto_type.clone(),
+                severity: vuln.severity,
+                file_path: file.path.clone(),
+                line: vuln.line,
+                function: graph
+                    .function_of(finding)
+                    .map(|id| graph.nodes[id].name.clone()),
+                entry_point_count: entries.len(),
+                entry_points: entries,
+            });
+        }
+    }
+    primitives.sort_by(|a, b| {
+        b.entry_point_count
+            .cmp(&a.entry_point_count)
+            .then_with(|| a.file_path.cmp(&b.file_path))
+            .then(a.line.cmp(&b.line))
+    });
+
+    CallGraphReport {
+        total_functions: graph.nodes.len(),
+        total_call_sites: graph.call_sites.len(),
+        entry_points: entry_points.len(),
+        wrappers,
+        primitives,
+    }
+}
+
+/// Export call-graph report to JSON string
+pub fn export_call_graph_json(report: &CallGraphReport) -> Result<String, serde_json::Error> {
+    serde_json::to_string_pretty(report)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::project_file;
+
+    fn sample_project() -> Vec<ProjectFile> {
+        vec![
+            project_file(
+                "signer/signer.go",
+                "package signer\n\nfunc NewSigner() *ecdsa.PrivateKey {\n\tkey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)\n\treturn key\n}\n",
+                "go",
+            ),
+            project_file(
+                "api/handlers.go",
+                "package api\n\nfunc SignOrder() {\n\tsigner.NewSigner()\n}\n\nfunc SignInvoice() {\n\tsigner.NewSigner()\n}\n\nfunc Unrelated() {\n\tfmt.Println(\"ok\")\n}\n",
+                "go",
+            ),
+        ]
+    }
+
+    #[test]
+    fn test_build_links_calls_across_files() {
+        let files = sample_project();
+        let graph = CallGraph::build(&files);
+
+        let new_signer = graph
+            .nodes
+            .iter()
+            .position(|n| n.name == "NewSigner")
+            .unwrap();
+        assert_eq!(graph.nodes[new_signer].callers.len(), 2);
+        assert!(!graph.nodes[new_signer].findings.is_empty());
+
+        let entry_points = graph.entry_points();
+        assert_eq!(entry_points.len(), 3); // SignOrder, SignInvoice, Unrelated
+    }
+
+    #[test]
+    fn test_wrapper_propagation() {
+        let report = generate_call_graph_report(&sample_project());
+
+        let wrapper = report
+            .wrappers
+            .iter()
+            .find(|w| w.name == "NewSigner")
+            .unwrap();
+        assert!(wrapper.direct);
+        assert!(wrapper.crypto_types.contains(&CryptoType::Ecdsa));
+        assert_eq!(wrapper.call_sites.len(), 2);
+
+        // Callers inherit the crypto usage of the wrapper
+        let caller = report
+            .wrappers
+            .iter()
+            .find(|w| w.name == "SignOrder")
+            .unwrap();
+        assert!(!caller.direct);
+        assert!(!report.wrappers.iter().any(|w| w.name == "Unrelated"));
+    }
+
+    #[test]
+    fn test_entry_points_per_primitive() {
+        let report = generate_call_graph_report(&sample_project());
+        let primitive = report
+            .primitives
+            .iter()
+            .find(|p| p.function.as_deref() == Some("NewSigner"))
+            .unwrap();
+        assert_eq!(primitive.entry_point_count, 2);
+        assert_eq!(
+            primitive.entry_points,
+            vec!["api/handlers.go:SignInvoice", "api/handlers.go:SignOrder"]
+        );
+    }
+
+    #[test]
+    fn test_callee_name() {
+        assert_eq!(callee_name("signer.NewSigner"), "NewSigner");
+        assert_eq!(callee_name("RsaPrivateKey::new"), "new");
+        assert_eq!(callee_name("main"), "main");
+    }
+}
@@ -4,6 +4,7 @@
 use crate::algorithm_database;
 use crate::types::*;
 use chrono::Utc;
+use std::collections::BTreeMap;
 use uuid::Uuid;
 
 const REPORT_VERSION: &str = "1.0.0";
@@ -18,9 +19,12 @@ pub fn generate_itsg33_report(
     let timestamp = now.to_rfc3339();
     let report_id = Uuid::new_v4().to_string();
 
+    // Status, summary and scores are assessed over the findings in SC-13 scope
+    let scope = crate::compliance::sc13_scope(audit_result);
+
     // Determine implementation status
     let (implementation_status, assessment_status) =
-        assess_canadian_implementation(audit_result, classification);
+        assess_canadian_implementation(&scope, classification);
 
     // Generate metadata
     let metadata = ReportMetadata {
@@ -42,24 +46,55 @@ pub fn generate_itsg33_report(
         classification,
     );
 
-    // Generate Canadian summary
-    let summary = generate_canadian_summary(audit_result, classification);
+    // TLS verification bypasses are assessed under ITSG-33 SC-8 and SC-23
+    let has_tls_bypass = crate::compliance::has_tls_bypass(audit_result);
+    let transport_assessments = if has_tls_bypass {
+        crate::compliance::TRANSPORT_CONTROLS
+            .iter()
+            .map(|control| ITSG33ControlAssessment {
+                control_id: format!("ITSG-33 {}", control.id.to_uppercase()),
+                control_name: control.name.to_string(),
+                control_family: "System and Communications Protection".to_string(),
+                control_description: control.description.to_string(),
+                implementation_status: ImplementationStatus::PartiallyImplemented,
+                assessment_status: AssessmentStatus::NotSatisfied,
+                assessment_method: vec!["TEST".to_string(), "EXAMINE".to_string()],
+                security_classification: classification,
+                nist_mapping: Some(format!("NIST 800-53 Rev. 5 {}", control.id.to_uppercase())),
+            })
+            .collect()
+    } else {
+        Vec::new()
+    };
+
+    // Generate Canadian summary; ITSP.40.062 requires TLS peers to be authenticated
+    let mut summary = generate_canadian_summary(&scope, classification);
+    summary.itsp_40_062_compliant &= !has_tls_bypass;
 
     // Generate Canadian findings
     let findings = generate_canadian_findings(audit_result, &timestamp, classification, file_path);
 
-    // Protocol compliance (empty for now, will be populated by protocol detection)
-    let protocol_compliance = Vec::new();
+    // Protocol configurations detected in the source
+    let protocol_compliance = audit_result.protocol_compliance.clone();
 
     // CMVP validations
     let cmvp_validations = generate_cmvp_validations(audit_result);
 
     // Generate recommendations
-    let recommendations = generate_canadian_recommendations(audit_result, classification);
+    let mut recommendations = generate_canadian_recommendations(&scope, classification);
+    if has_tls_bypass {
+        recommendations.insert(
+            1,
+            "CRITICAL: TLS certificate or host name verification is disabled. Enable it to meet \
+             ITSG-33 SC-8 and SC-23 and the server authentication requirements of ITSP.40.062."
+                .to_string(),
+        );
+    }
 
     ITSG33Report {
         metadata,
         control_assessment,
+        transport_assessments,
         summary,
         findings,
         protocol_compliance,
@@ -118,9 +153,11 @@ pub fn generate_unified_report(
             oscal_version: "1.1.2".to_string(),
         },
         nist_sc13_assessment: nist_report.control_assessment,
+        nist_transport_assessments: nist_report.transport_assessments,
         nist_summary: nist_report.summary,
         nist_findings: nist_report.findings,
         itsg33_sc13_assessment: canadian_report.control_assessment,
+        itsg33_transport_assessments: canadian_report.transport_assessments,
         canadian_summary: canadian_report.summary,
         canadian_findings: canadian_report.findings,
         control_mapping,
@@ -231,11 +268,17 @@ fn generate_canadian_summary(
             | CryptoType::Md5
             | CryptoType::Des
             | CryptoType::TripleDes
-            | CryptoType::Rc4 => {
+            | CryptoType::Rc4
+            | CryptoType::Md4
+            | CryptoType::Ripemd160
+            | CryptoType::Blowfish
+            | CryptoType::Cast5 => {
                 if !deprecated.contains(&crypto_name) {
                     deprecated.push(crypto_name.clone());
                 }
             }
+            // Not algorithms; the finding itself names the API or misuse
+            CryptoType::DeprecatedApi | CryptoType::InsecureTls => {}
         }
 
         // Categorize by CCCS status
@@ -280,7 +323,10 @@ fn generate_canadian_summary(
 
     // Check ITSP compliance
     let itsp_40_111_compliant = cccs_prohibited.is_empty() && weak_keys.is_empty();
-    let itsp_40_062_compliant = true; // Will be updated with protocol checks
+    let itsp_40_062_compliant = audit_result
+        .protocol_compliance
+        .iter()
+        .all(|protocol| protocol.compliant);
 
     // Check classification compliance
     let classification_compliant = weak_keys.is_empty();
@@ -302,6 +348,7 @@ fn generate_canadian_summary(
         weak_key_sizes: weak_keys,
         compliance_score,
         risk_score: audit_result.risk_score,
+        confidence: ConfidenceBreakdown::from_vulnerabilities(&audit_result.vulnerabilities),
         cccs_approved_algorithms: cccs_approved,
         cccs_deprecated_algorithms: cccs_deprecated_list,
         cccs_prohibited_algorithms: cccs_prohibited,
@@ -378,8 +425,6 @@ fn generate_canadian_findings(
 
     // Create findings for each crypto type
     for (crypto_type_str, vulns) in vuln_groups {
-        let finding_id = Uuid::new_v4().to_string();
-
         // Safety: vulns should never be empty since it comes from HashMap.entry().or_default().push()
         // but handle gracefully in case of logic errors
         let first_vuln = match vulns.first() {
@@ -435,92 +480,125 @@ fn generate_canadian_findings(
             }
         };
 
-        // Collect evidence
-        let mut evidence = Vec::new();
-        let mut related_vulns = Vec::new();
-
-        for (idx, vuln) in vulns.iter().enumerate() {
-            let evidence_id = format!("{}-{}", finding_id, idx);
-            related_vulns.push(format!(
-                "{}:{}:{}",
-                file_path.unwrap_or("source"),
-                vuln.line,
-                vuln.column
-            ));
-
-            let source_location = SourceLocation {
-                file_path: file_path.unwrap_or("source").to_string(),
-                line: vuln.line,
-                column: vuln.column,
-                snippet: vuln.context.clone(),
+        // One finding per control, each with its own evidence
+        for control_id in finding_controls(crypto_type) {
+            let finding_id = Uuid::new_v4().to_string();
+
+            // Collect evidence
+            let mut evidence = Vec::new();
+            let mut related_vulns = Vec::new();
+
+            for (idx, vuln) in vulns.iter().enumerate() {
+                let evidence_id = format!("{}-{}", finding_id, idx);
+                related_vulns.push(format!(
+                    "{}:{}:{}",
+                    file_path.unwrap_or("source"),
+                    vuln.line,
+                    vuln.column
+                ));
+
+                let source_location = SourceLocation {
+                    file_path: file_path.unwrap_or("source").to_string(),
+                    line: vuln.line,
+                    column: vuln.column,
+                    snippet: vuln.context.clone(),
+                };
+
+                let evidence_data = serde_json::json!({
+                    "crypto_type": crypto_type_str,
+                    "cccs_status": cccs_approval_status.to_string(),
+                    "severity": format!("{:?}", vuln.severity),
+                    "risk_score": vuln.risk_score,
+                    "end_column": vuln.end_column,
+                    "match_kind": vuln.match_kind,
+                    "confidence": vuln.confidence,
+                    "key_size": vuln.key_size,
+                    "classification": classification.to_string(),
+                    "reachability": vuln.reachability,
+                    "secondary_locations": vuln.secondary_locations,
+                    "original_location": vuln.original_location,
+                });
+
+                evidence.push(Evidence {
+                    evidence_id,
+                    evidence_type: EvidenceType::StaticScan,
+                    description: format!(
+                        "Detected {} (CCCS Status: {}) at line {} column {}: {}",
+                        crypto_type_str, cccs_approval_status, vuln.line, vuln.column, vuln.message
+                    ),
+                    source_location: Some(source_location),
+                    collected_at: timestamp.to_string(),
+                    data: evidence_data,
+                });
+            }
+
+            let description = if *crypto_type == CryptoType::InsecureTls {
+                format!(
+                    "Found {} instance(s) of disabled TLS certificate or host name verification. \
+                ITSP.40.062 requires TLS peers to be authenticated with validated certificates; \
+                without it, transmission protection (SC-8) and session authenticity (SC-23) \
+                are not provided.",
+                    vulns.len()
+                )
+            } else {
+                generate_canadian_finding_description(
+                    &crypto_type_str,
+                    vulns.len(),
+                    &cccs_approval_status,
+                    classification,
+                    vulns[0].key_size,
+                )
             };
 
-            let evidence_data = serde_json::json!({
-                "crypto_type": crypto_type_str,
-                "cccs_status": cccs_approval_status.to_string(),
-                "severity": format!("{:?}", vuln.severity),
-                "risk_score": vuln.risk_score,
-                "key_size": vuln.key_size,
-                "classification": classification.to_string(),
-            });
+            let remediation = if *crypto_type == CryptoType::InsecureTls {
+                first_vuln.recommendation.clone()
+            } else {
+                generate_canadian_remediation(crypto_type, &cccs_approval_status)
+            };
+
+            // CMVP validation (if applicable)
+            let cmvp_validation = match cccs_approval_status {
+                CCCSApprovalStatus::Approved | CCCSApprovalStatus::ConditionallyApproved => {
+                    Some(CMVPValidation {
+                        algorithm_used: crypto_type_str.clone(),
+                        implementation: None,
+                        cmvp_cert: None,
+                        requires_cmvp: algorithm_database::is_cmvp_required(classification),
+                        compliant: false, // Will be updated with actual validation
+                    })
+                }
+                _ => None,
+            };
 
-            evidence.push(Evidence {
-                evidence_id,
-                evidence_type: EvidenceType::StaticScan,
-                description: format!(
-                    "Detected {} (CCCS Status: {}) at line {} column {}: {}",
-                    crypto_type_str, cccs_approval_status, vuln.line, vuln.column, vuln.message
-                ),
-                source_location: Some(source_location),
-                collected_at: timestamp.to_string(),
-                data: evidence_data,
+            findings.push(CanadianFinding {
+                finding_id,
+                control_id: control_id.to_string(),
+                implementation_status: impl_status.clone(),
+                assessment_status: assess_status.clone(),
+                description,
+                related_vulnerabilities: related_vulns,
+                evidence,
+                remediation,
+                risk_level: highest_severity,
+                cccs_approval_status: cccs_approval_status.clone(),
+                itsp_references: itsp_references.clone(),
+                cmvp_validation,
+                applicable_classifications: applicable_classifications.clone(),
             });
         }
-
-        let description = generate_canadian_finding_description(
-            &crypto_type_str,
-            vulns.len(),
-            &cccs_approval_status,
-            classification,
-            vulns[0].key_size,
-        );
-
-        let remediation = generate_canadian_remediation(crypto_type, &cccs_approval_status);
-
-        // CMVP validation (if applicable)
-        let cmvp_validation = match cccs_approval_status {
-            CCCSApprovalStatus::Approved | CCCSApprovalStatus::ConditionallyApproved => {
-                Some(CMVPValidation {
-                    algorithm_used: crypto_type_str.clone(),
-                    implementation: None,
-                    cmvp_cert: None,
-                    requires_cmvp: algorithm_database::is_cmvp_required(classification),
-                    compliant: false, // Will be updated with actual validation
-                })
-            }
-            _ => None,
-        };
-
-        findings.push(CanadianFinding {
-            finding_id,
-            control_id: "ITSG-33 SC-13".to_string(),
-            implementation_status: impl_status,
-            assessment_status: assess_status,
-            description,
-            related_vulnerabilities: related_vulns,
-            evidence,
-            remediation,
-            risk_level: highest_severity,
-            cccs_approval_status,
-            itsp_references,
-            cmvp_validation,
-            applicable_classifications,
-        });
     }
 
     findings
 }
 
+/// ITSG-33 controls a finding is reported under (see `compliance::finding_controls`)
+fn finding_controls(crypto_type: &CryptoType) -> &'static [&'static str] {
+    match crypto_type {
+        CryptoType::InsecureTls => &["ITSG-33 SC-8", "ITSG-33 SC-23"],
+        _ => &["ITSG-33 SC-13"],
+    }
+}
+
 /// Determine which classification levels this algorithm/key size is acceptable for
 fn determine_applicable_classifications(
     crypto_type: &CryptoType,
@@ -747,6 +825,13 @@ pub fn export_unified_json(report: &UnifiedComplianceReport) -> Result<String, s
     serde_json::to_string_pretty(report)
 }
 
+/// Export ITSP.40.062 protocol compliance records, keyed by file path, to JSON
+pub fn export_protocol_compliance_json(
+    records: &BTreeMap<String, Vec<ProtocolCompliance>>,
+) -> Result<String, serde_json::Error> {
+    serde_json::to_string_pretty(records)
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
@@ -755,28 +840,25 @@ mod tests {
         let mut result = AuditResult::new(Language::JavaScript, 100);
 
         result.add_vulnerability(Vulnerability {
-            crypto_type: CryptoType::Rsa,
-            severity: Severity::High,
-            risk_score: 85,
-            line: 10,
-            column: 5,
+            end_column: 8,
+            match_kind: MatchKind::ApiCall,
+            confidence: Confidence::High,
             context: "const rsa = crypto.generateKeyPair('rsa', { modulusLength: 2048 })"
                 .to_string(),
             message: "RSA detected - quantum vulnerable".to_string(),
             recommendation: "Replace with CRYSTALS-Kyber".to_string(),
             key_size: Some(2048),
+            ..Vulnerability::new(CryptoType::Rsa, Severity::High, 85, 10, 5)
         });
 
         result.add_vulnerability(Vulnerability {
-            crypto_type: CryptoType::Md5,
-            severity: Severity::Critical,
-            risk_score: 100,
-            line: 15,
-            column: 10,
+            end_column: 13,
+            match_kind: MatchKind::ApiCall,
+            confidence: Confidence::High,
             context: "const hash = crypto.createHash('md5')".to_string(),
             message: "MD5 is cryptographically broken".to_string(),
             recommendation: "Replace with SHA-256".to_string(),
-            key_size: None,
+            ..Vulnerability::new(CryptoType::Md5, Severity::Critical, 100, 15, 10)
         });
 
         result.calculate_risk_score();
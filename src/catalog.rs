@@ -0,0 +1,620 @@
+//! Rule catalog
+//!
+//! Describes every rule the scanner runs: the built-in detectors and any
+//! imported custom rules. Each entry carries the algorithm, default severity,
+//! rationale, references, NIST 800-53 / ITSG-33 and CCCS mapping, and example
+//! snippets the rule does and does not match. The built-in examples are
+//! checked by the tests below, so the catalog cannot drift from the detectors.
+
+use crate::algorithm_database::{get_approval_conditions, get_cccs_status, get_itsp_reference};
+use crate::audit::{builtin_patterns, recommendation_for, score_vulnerability, severity_for_score};
+use crate::go_deprecated::deprecated_go_patterns;
+use crate::semgrep::CustomRule;
+use crate::tls_misuse::tls_misuse_patterns;
+use crate::types::*;
+use serde::Serialize;
+
+/// Where a rule comes from
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum RuleOrigin {
+    BuiltIn,
+    Custom,
+}
+
+/// Catalog entry for one rule
+#[derive(Debug, Clone, Serialize)]
+pub struct RuleDescription {
+    pub id: String,
+    pub name: String,
+    pub origin: RuleOrigin,
+    /// Languages the rule runs on; empty means every supported language
+    pub languages: Vec<Language>,
+    pub crypto_type: CryptoType,
+    /// `quantum-vulnerable`, `deprecated-algorithm`, `deprecated-api` or `transport-security`
+    pub category: String,
+    /// Severity and risk score when no key size is known
+    pub severity: Severity,
+    pub risk_score: u32,
+    pub rationale: String,
+    pub recommendation: String,
+    pub references: Vec<String>,
+    pub nist_controls: Vec<String>,
+    pub itsg33_controls: Vec<String>,
+    pub cccs: CccsMapping,
+    /// Regexes or Semgrep patterns the rule matches
+    pub patterns: Vec<String>,
+    pub positive_examples: Vec<String>,
+    pub negative_examples: Vec<String>,
+}
+
+/// CCCS (ITSP.40.111) status of the rule's algorithm
+#[derive(Debug, Clone, Serialize)]
+pub struct CccsMapping {
+    pub status: CCCSApprovalStatus,
+    pub itsp_reference: String,
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub conditions: Vec<String>,
+}
+
+/// Documentation of a built-in rule
+struct BuiltinDoc {
+    crypto_type: CryptoType,
+    /// Languages of import-resolved rules, whose examples are whole sources
+    languages: &'static [Language],
+    rationale: &'static str,
+    references: &'static [&'static str],
+    positive: &'static [&'static str],
+    negative: &'static [&'static str],
+}
+
+const BUILTIN_DOCS: &[BuiltinDoc] = &[
+    BuiltinDoc {
+        crypto_type: CryptoType::Rsa,
+        languages: &[],
+        rationale: "RSA's security rests on integer factorization, which Shor's algorithm \
+            solves in polynomial time on a cryptographically relevant quantum computer. \
+            Keys below 2048 bits are already within reach of classical attacks.",
+        references: &[
+            "NIST IR 8547 (Transition to Post-Quantum Cryptography Standards)",
+            "FIPS 203 (ML-KEM)",
+            "FIPS 204 (ML-DSA)",
+            "NIST SP 800-131A Rev. 2",
+        ],
+        positive: &[
+            "key, _ := rsa.GenerateKey(rand.Reader, 2048)",
+            "KeyPairGenerator kpg = KeyPairGenerator.getInstance(\"RSA\");",
+        ],
+        negative: &["let universal = true;", "traversal(nodes)"],
+    },
+    BuiltinDoc {
+        crypto_type: CryptoType::Ecdsa,
+        languages: &[],
+        rationale: "ECDSA relies on the elliptic curve discrete logarithm problem, which \
+            Shor's algorithm breaks. Signatures made today can be forged once large \
+            quantum computers exist.",
+        references: &[
+            "NIST IR 8547 (Transition to Post-Quantum Cryptography Standards)",
+            "FIPS 204 (ML-DSA)",
+            "FIPS 205 (SLH-DSA)",
+        ],
+        positive: &[
+            "priv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)",
+            "key = ec.generate_private_key(ec.SECP384R1())",
+        ],
+        negative: &["pub, priv, _ := ed25519.GenerateKey(rand.Reader)"],
+    },
+    BuiltinDoc {
+        crypto_type: CryptoType::Ecdh,
+        languages: &[],
+        rationale: "Elliptic curve Diffie-Hellman key agreement is broken by Shor's \
+            algorithm. Recorded traffic can be decrypted later (harvest now, decrypt later).",
+        references: &[
+            "NIST IR 8547 (Transition to Post-Quantum Cryptography Standards)",
+            "FIPS 203 (ML-KEM)",
+            "NIST SP 800-56A Rev. 3",
+        ],
+        positive: &[
+            "const alice = crypto.createECDH('prime256v1');",
+            "priv, _ := ecdh.P256().GenerateKey(rand.Reader)",
+        ],
+        negative: &["dk, _ := mlkem.GenerateKey768()"],
+    },
+    BuiltinDoc {
+        crypto_type: CryptoType::Dsa,
+        languages: &[],
+        rationale: "DSA relies on the finite field discrete logarithm problem, which \
+            Shor's algorithm breaks. FIPS 186-5 no longer approves DSA for signature generation.",
+        references: &["FIPS 186-5", "FIPS 204 (ML-DSA)"],
+        positive: &[
+            "KeyPairGenerator kpg = KeyPairGenerator.getInstance(\"DSA\");",
+            "key = dsa.generate_private_key(key_size=2048)",
+        ],
+        negative: &["r, s, _ := ecdsa.Sign(rand.Reader, priv, hash)"],
+    },
+    BuiltinDoc {
+        crypto_type: CryptoType::DiffieHellman,
+        languages: &[],
+        rationale: "Finite field Diffie-Hellman is broken by Shor's algorithm, exposing \
+            recorded key exchanges to later decryption.",
+        references: &[
+            "NIST IR 8547 (Transition to Post-Quantum Cryptography Standards)",
+            "FIPS 203 (ML-KEM)",
+            "NIST SP 800-56A Rev. 3",
+        ],
+        positive: &[
+            "const dh = crypto.createDiffieHellman(2048);",
+            "DiffieHellman exchange = new DiffieHellman(params);",
+        ],
+        negative: &["let dhcp_lease = renew();"],
+    },
+    BuiltinDoc {
+        crypto_type: CryptoType::Sha1,
+        languages: &[],
+        rationale: "SHA-1 collisions are practical (SHAttered, 2017); it must not be used \
+            for signatures or other collision-sensitive purposes.",
+        references: &["NIST SP 800-131A Rev. 2", "NIST SP 800-107 Rev. 1"],
+        positive: &[
+            "digest = hashlib.sha1(data).hexdigest()",
+            "MessageDigest md = MessageDigest.getInstance(\"SHA-1\");",
+        ],
+        negative: &["digest = hashlib.sha256(data).hexdigest()"],
+    },
+    BuiltinDoc {
+        crypto_type: CryptoType::Md5,
+        languages: &[],
+        rationale: "MD5 collisions can be computed in seconds; it provides no collision \
+            resistance and must not protect integrity or authenticity.",
+        references: &["RFC 6151", "NIST SP 800-131A Rev. 2"],
+        positive: &[
+            "const hash = crypto.createHash('md5');",
+            "digest = hashlib.md5(data).hexdigest()",
+        ],
+        negative: &["let cmd5 = parse(args);"],
+    },
+    BuiltinDoc {
+        crypto_type: CryptoType::Des,
+        languages: &[],
+        rationale: "DES has a 56-bit key that can be exhausted with commodity hardware.",
+        references: &["NIST SP 800-131A Rev. 2", "FIPS 46-3 (withdrawn)"],
+        positive: &[
+            "Cipher c = Cipher.getInstance(\"DES/CBC/PKCS5Padding\");",
+            "block, _ := des.NewCipher(key)",
+        ],
+        negative: &["let description = \"Data Encryption\";"],
+    },
+    BuiltinDoc {
+        crypto_type: CryptoType::TripleDes,
+        languages: &[],
+        rationale: "3DES has a 64-bit block, making it vulnerable to birthday attacks \
+            (Sweet32); NIST disallowed it for encryption after 2023.",
+        references: &[
+            "NIST SP 800-131A Rev. 2",
+            "NIST SP 800-67 Rev. 2 (withdrawn)",
+        ],
+        positive: &[
+            "block, _ := des.NewTripleDESCipher(key)",
+            "Cipher c = Cipher.getInstance(\"DESede/CBC/PKCS5Padding\");",
+        ],
+        negative: &["Cipher c = Cipher.getInstance(\"AES/GCM/NoPadding\");"],
+    },
+    BuiltinDoc {
+        crypto_type: CryptoType::Rc4,
+        languages: &[],
+        rationale: "RC4 keystream biases allow plaintext recovery; it is prohibited in TLS.",
+        references: &["RFC 7465"],
+        positive: &[
+            "c, _ := rc4.NewCipher(key)",
+            "Cipher c = Cipher.getInstance(\"ARCFOUR\");",
+        ],
+        negative: &["let src4 = load();"],
+    },
+    BuiltinDoc {
+        crypto_type: CryptoType::Md4,
+        languages: &[Language::Go],
+        rationale: "MD4 collisions can be computed by hand; golang.org/x/crypto/md4 is \
+            deprecated.",
+        references: &["RFC 6150 (MD4 to Historic Status)"],
+        positive: &["package p\n\nimport \"golang.org/x/crypto/md4\"\n\nvar h = md4.New()\n"],
+        negative: &["package p\n\nimport \"example.com/hash/md4\"\n"],
+    },
+    BuiltinDoc {
+        crypto_type: CryptoType::Ripemd160,
+        languages: &[Language::Go],
+        rationale: "RIPEMD-160 is a legacy 160-bit hash outside the NIST-approved \
+            families; golang.org/x/crypto/ripemd160 is deprecated.",
+        references: &["NIST SP 800-131A Rev. 2", "FIPS 180-4"],
+        positive: &["package p\n\nimport \"golang.org/x/crypto/ripemd160\"\n"],
+        negative: &["package p\n\nimport \"crypto/sha256\"\n"],
+    },
+    BuiltinDoc {
+        crypto_type: CryptoType::Blowfish,
+        languages: &[Language::Go],
+        rationale: "Blowfish has a 64-bit block, making long-lived keys vulnerable to \
+            birthday attacks (Sweet32); golang.org/x/crypto/blowfish is deprecated.",
+        references: &["CVE-2016-2183 (Sweet32)"],
+        positive: &["package p\n\nimport bf \"golang.org/x/crypto/blowfish\"\n"],
+        negative: &["package p\n\nimport \"golang.org/x/crypto/bcrypt\"\n"],
+    },
+    BuiltinDoc {
+        crypto_type: CryptoType::Cast5,
+        languages: &[Language::Go],
+        rationale: "CAST5 has a 64-bit block, making long-lived keys vulnerable to \
+            birthday attacks (Sweet32); golang.org/x/crypto/cast5 is deprecated.",
+        references: &["RFC 2144", "CVE-2016-2183 (Sweet32)"],
+        positive: &["package p\n\nimport \"golang.org/x/crypto/cast5\"\n"],
+        negative: &["package p\n\nimport \"crypto/aes\"\n"],
+    },
+    BuiltinDoc {
+        crypto_type: CryptoType::DeprecatedApi,
+        languages: &[Language::Go],
+        rationale: "Deprecated crypto APIs are frozen or unsafe by design: the low-level \
+            crypto/elliptic functions, legacy PEM encryption in crypto/x509, and the \
+            openpgp and bn256 packages of golang.org/x/crypto. Each finding names the \
+            replacement, such as crypto/ecdh or crypto/mlkem.",
+        references: &[
+            "Go 1.21 release notes (crypto/elliptic deprecations)",
+            "https://go.dev/issue/44226 (golang.org/x/crypto/openpgp deprecation)",
+        ],
+        positive: &[
+            "package p\n\nimport \"crypto/elliptic\"\n\nvar b = elliptic.Marshal(c, x, y)\n",
+            "package p\n\nimport \"golang.org/x/crypto/openpgp\"\n",
+        ],
+        negative: &[
+            "package p\n\nimport elliptic \"example.com/curves\"\n\nvar b = elliptic.Marshal(c, x, y)\n",
+        ],
+    },
+    BuiltinDoc {
+        crypto_type: CryptoType::InsecureTls,
+        languages: &[
+            Language::Go,
+            Language::Python,
+            Language::JavaScript,
+            Language::TypeScript,
+            Language::Java,
+        ],
+        rationale: "Disabling TLS certificate or host name verification lets a man in the \
+            middle impersonate the server, so the connection has neither confidentiality nor \
+            authenticity whatever key exchange it negotiates, post-quantum included.",
+        references: &[
+            "NIST SP 800-52 Rev. 2 (Section 4.5, server certificate validation)",
+            "RFC 6125 (host name verification)",
+            "CWE-295 (Improper Certificate Validation)",
+        ],
+        positive: &[
+            "package p\n\nvar c = &tls.Config{InsecureSkipVerify: true}\n",
+            "r = requests.get(url, verify=False)\n",
+            "const agent = new https.Agent({ rejectUnauthorized: false });\n",
+            "class T implements X509TrustManager {\n    public void checkServerTrusted(X509Certificate[] c, String a) {}\n}\n",
+        ],
+        negative: &[
+            "package p\n\nvar c = &tls.Config{InsecureSkipVerify: false}\n",
+            "r = requests.get(url, verify=\"/etc/ssl/ca.pem\")\n",
+        ],
+    },
+];
+
+/// Catalog of the built-in rules followed by `custom` rules
+pub fn rule_catalog(custom: &[CustomRule]) -> Vec<RuleDescription> {
+    BUILTIN_DOCS
+        .iter()
+        .map(describe_builtin)
+        .chain(custom.iter().map(describe_custom))
+        .collect()
+}
+
+/// Look up a rule by id; built-in rules also answer to their algorithm name (`rsa`, `SHA-1`)
+pub fn find_rule<'a>(catalog: &'a [RuleDescription], id: &str) -> Option<&'a RuleDescription> {
+    catalog.iter().find(|rule| rule.id == id).or_else(|| {
+        let crypto_type = CryptoType::from_string(id)?;
+        catalog
+            .iter()
+            .find(|rule| rule.origin == RuleOrigin::BuiltIn && rule.crypto_type == crypto_type)
+    })
+}
+
+fn describe_builtin(doc: &BuiltinDoc) -> RuleDescription {
+    let crypto_type = &doc.crypto_type;
+    let risk_score = score_vulnerability(crypto_type, None);
+
+    RuleDescription {
+        id: crypto_type.rule_id().to_string(),
+        name: crypto_type.to_string(),
+        origin: RuleOrigin::BuiltIn,
+        languages: doc.languages.to_vec(),
+        crypto_type: crypto_type.clone(),
+        category: category(crypto_type).to_string(),
+        severity: severity_for_score(risk_score),
+        risk_score,
+        rationale: doc.rationale.to_string(),
+        recommendation: recommendation_for(crypto_type).to_string(),
+        references: doc.references.iter().map(|r| r.to_string()).collect(),
+        nist_controls: nist_controls(crypto_type),
+        itsg33_controls: nist_controls(crypto_type)
+            .iter()
+            .map(|c| format!("ITSG-33 {}", c))
+            .collect(),
+        cccs: cccs_mapping(crypto_type),
+        patterns: builtin_patterns(crypto_type)
+            .into_iter()
+            .map(str::to_string)
+            .chain(deprecated_go_patterns(crypto_type))
+            .chain(tls_misuse_patterns(crypto_type))
+            .collect(),
+        positive_examples: doc.positive.iter().map(|e| e.to_string()).collect(),
+        negative_examples: doc.negative.iter().map(|e| e.to_string()).collect(),
+    }
+}
+
+fn describe_custom(rule: &CustomRule) -> RuleDescription {
+    let crypto_type = &rule.crypto_type;
+    let risk_score = score_vulnerability(crypto_type, None);
+
+    RuleDescription {
+        id: rule.id.clone(),
+        name: format!("{} (custom)", crypto_type),
+        origin: RuleOrigin::Custom,
+        languages: rule.languages.clone(),
+        crypto_type: crypto_type.clone(),
+        category: category(crypto_type).to_string(),
+        severity: rule
+            .severity
+            .unwrap_or_else(|| severity_for_score(risk_score)),
+        risk_score,
+        rationale: rule.message.clone(),
+        recommendation: rule.recommendation.clone(),
+        references: rule.references.clone(),
+        nist_controls: nist_controls(crypto_type),
+        itsg33_controls: nist_controls(crypto_type)
+            .iter()
+            .map(|c| format!("ITSG-33 {}", c))
+            .collect(),
+        cccs: cccs_mapping(crypto_type),
+        patterns: rule.patterns.iter().map(|p| p.source.clone()).collect(),
+        positive_examples: rule.positive_examples.clone(),
+        negative_examples: rule.negative_examples.clone(),
+    }
+}
+
+pub(crate) fn category(crypto_type: &CryptoType) -> &'static str {
+    match crypto_type {
+        CryptoType::Rsa
+        | CryptoType::Ecdsa
+        | CryptoType::Ecdh
+        | CryptoType::Dsa
+        | CryptoType::DiffieHellman => "quantum-vulnerable",
+        CryptoType::Sha1
+        | CryptoType::Md5
+        | CryptoType::Des
+        | CryptoType::TripleDes
+        | CryptoType::Rc4
+        | CryptoType::Md4
+        | CryptoType::Ripemd160
+        | CryptoType::Blowfish
+        | CryptoType::Cast5 => "deprecated-algorithm",
+        CryptoType::DeprecatedApi => "deprecated-api",
+        CryptoType::InsecureTls => "transport-security",
+    }
+}
+
+/// NIST 800-53 controls a finding bears on (SC-12 for key establishment algorithms,
+/// SC-8 and SC-23 instead of SC-13 for transport security)
+pub(crate) fn nist_controls(crypto_type: &CryptoType) -> Vec<String> {
+    if *crypto_type == CryptoType::InsecureTls {
+        return vec!["SC-8".to_string(), "SC-23".to_string()];
+    }
+    let mut controls = vec!["SC-13".to_string()];
+    if matches!(
+        crypto_type,
+        CryptoType::Rsa | CryptoType::Ecdh | CryptoType::DiffieHellman
+    ) {
+        controls.push("SC-12".to_string());
+    }
+    controls
+}
+
+pub(crate) fn cccs_mapping(crypto_type: &CryptoType) -> CccsMapping {
+    CccsMapping {
+        status: get_cccs_status(crypto_type),
+        itsp_reference: get_itsp_reference(crypto_type),
+        conditions: get_approval_conditions(crypto_type),
+    }
+}
+
+/// Export the catalog as JSON
+pub fn export_catalog_json(catalog: &[RuleDescription]) -> Result<String, serde_json::Error> {
+    serde_json::to_string_pretty(catalog)
+}
+
+/// Export the catalog as a Markdown document
+pub fn export_catalog_markdown(catalog: &[RuleDescription]) -> String {
+    let mut lines = vec![
+        "# PQC Scanner Rule Catalog".to_string(),
+        String::new(),
+        "| Rule | Origin | Algorithm | Severity | Languages |".to_string(),
+        "|------|--------|-----------|----------|-----------|".to_string(),
+    ];
+    for rule in catalog {
+        lines.push(format!(
+            "| [`{}`](#{}) | {} | {} | {:?} | {} |",
+            rule.id,
+            anchor(&rule.id),
+            origin_label(rule.origin),
+            rule.crypto_type,
+            rule.severity,
+            languages_label(&rule.languages)
+        ));
+    }
+
+    for rule in catalog {
+        lines.push(String::new());
+        lines.push(format!("## {}", rule.id));
+        lines.push(String::new());
+        lines.push(format!(
+            "- **Algorithm:** {} ({})",
+            rule.crypto_type, rule.category
+        ));
+        lines.push(format!("- **Origin:** {}", origin_label(rule.origin)));
+        lines.push(format!(
+            "- **Default severity:** {:?} (risk score {})",
+            rule.severity, rule.risk_score
+        ));
+        lines.push(format!(
+            "- **Languages:** {}",
+            languages_label(&rule.languages)
+        ));
+        lines.push(format!(
+            "- **NIST 800-53:** {} / **ITSG-33:** {}",
+            rule.nist_controls.join(", "),
+            rule.itsg33_controls.join(", ")
+        ));
+        lines.push(format!(
+            "- **CCCS:** {} ({})",
+            rule.cccs.status, rule.cccs.itsp_reference
+        ));
+        lines.extend(rule.cccs.conditions.iter().map(|c| format!("  - {}", c)));
+
+        lines.push(String::new());
+        lines.push(rule.rationale.clone());
+        lines.push(String::new());
+        lines.push(format!("**Recommendation:** {}", rule.recommendation));
+
+        if !rule.references.is_empty() {
+            lines.extend([String::new(), "**References:**".to_string(), String::new()]);
+            lines.extend(rule.references.iter().map(|r| format!("- {}", r)));
+        }
+
+        lines.extend([String::new(), "**Patterns:**".to_string(), String::new()]);
+        lines.extend(rule.patterns.iter().map(|p| format!("- `` {} ``", p)));
+
+        push_examples(&mut lines, "Matches", &rule.positive_examples);
+        push_examples(&mut lines, "Does not match", &rule.negative_examples);
+    }
+
+    lines.push(String::new());
+    lines.join("\n")
+}
+
+fn push_examples(lines: &mut Vec<String>, title: &str, examples: &[String]) {
+    if examples.is_empty() {
+        return;
+    }
+    lines.extend([String::new(), format!("**{}:**", title), String::new()]);
+    lines.push("```".to_string());
+    lines.extend(examples.iter().cloned());
+    lines.push("```".to_string());
+}
+
+fn origin_label(origin: RuleOrigin) -> &'static str {
+    match origin {
+        RuleOrigin::BuiltIn => "built-in",
+        RuleOrigin::Custom => "custom",
+    }
+}
+
+/// Comma-separated languages, or `all`
+pub fn languages_label(languages: &[Language]) -> String {
+    if languages.is_empty() {
+        "all".to_string()
+    } else {
+        languages
+            .iter()
+            .map(|l| l.to_string())
+            .collect::<Vec<_>>()
+            .join(", ")
+    }
+}
+
+/// GitHub-style heading anchor
+fn anchor(id: &str) -> String {
+    id.to_lowercase()
+        .chars()
+        .filter(|c| c.is_alphanumeric() || *c == '-' || *c == '_')
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::audit::detect_line;
+
+    #[test]
+    fn test_builtin_examples_match_detectors() {
+        for rule in rule_catalog(&[]) {
+            // Import-resolved and language-specific rules need the whole source,
+            // analyzed as each of the rule's languages
+            let detect = |example: &str| -> Vec<Vulnerability> {
+                if rule.languages.is_empty() {
+                    return detect_line(example, 1);
+                }
+                rule.languages
+                    .iter()
+                    .flat_map(|language| {
+                        crate::analyze(example, &language.to_string())
+                            .map(|audit| audit.vulnerabilities)
+                            .unwrap_or_default()
+                    })
+                    .collect()
+            };
+            assert!(!rule.positive_examples.is_empty(), "{}", rule.id);
+            for example in &rule.positive_examples {
+                assert!(
+                    detect(example)
+                        .iter()
+                        .any(|v| v.crypto_type == rule.crypto_type),
+                    "{} should match: {}",
+                    rule.id,
+                    example
+                );
+            }
+            for example in &rule.negative_examples {
+                assert!(
+                    !detect(example)
+                        .iter()
+                        .any(|v| v.crypto_type == rule.crypto_type),
+                    "{} should not match: {}",
+                    rule.id,
+                    example
+                );
+            }
+        }
+    }
+
+    #[test]
+    fn test_catalog_lookup_and_export() {
+        let import = crate::semgrep::import_semgrep_rules(
+            "rules:\n  - id: weak-md5\n    languages: [python]\n    message: MD5 digest\n    pattern: hashlib.md5(...)\n",
+        )
+        .unwrap();
+        let catalog = rule_catalog(&import.rules);
+        assert_eq!(catalog.len(), 17);
+
+        assert_eq!(
+            find_rule(&catalog, "pqc-rsa").unwrap().crypto_type,
+            CryptoType::Rsa
+        );
+        assert_eq!(find_rule(&catalog, "SHA-1").unwrap().id, "pqc-sha1");
+        let custom = find_rule(&catalog, "weak-md5").unwrap();
+        assert_eq!(custom.origin, RuleOrigin::Custom);
+        assert_eq!(custom.languages, vec![Language::Python]);
+        assert!(find_rule(&catalog, "nope").is_none());
+
+        let dh = find_rule(&catalog, "pqc-diffie-hellman").unwrap();
+        assert_eq!(dh.nist_controls, vec!["SC-13", "SC-12"]);
+        let api = find_rule(&catalog, "pqc-deprecated-api").unwrap();
+        assert_eq!(api.category, "deprecated-api");
+        assert!(
+            api.patterns
+                .contains(&"crypto/elliptic.Marshal".to_string())
+        );
+        let tls = find_rule(&catalog, "pqc-insecure-tls").unwrap();
+        assert_eq!(tls.category, "transport-security");
+        assert_eq!(tls.nist_controls, vec!["SC-8", "SC-23"]);
+
+        let markdown = export_catalog_markdown(&catalog);
+        assert!(markdown.contains("## pqc-rsa"));
+        assert!(markdown.contains("| [`weak-md5`](#weak-md5) | custom | MD5 |"));
+        let json = export_catalog_json(&catalog).unwrap();
+        assert!(json.contains("\"origin\": \"custom\""));
+    }
+}
@@ -0,0 +1,308 @@
+//! cgo linkage to native crypto libraries
+//!
+//! Go code can run crypto outside the standard library through cgo: C code
+//! in the preamble comment before `import "C"`, calls such as
+//! `C.RSA_new()`, or wrapper packages like `spacemonkeygo/openssl`. The
+//! built-in rules are language independent, so the embedded C lines are
+//! matched with the same rules as C/C++ sources; this module parses the
+//! preambles (`#cgo LDFLAGS`, `#cgo pkg-config`, `#include`) for the linked
+//! libraries and labels findings that run in native code. Such crypto is
+//! not covered by the Go module's FIPS 140 validation and needs its own
+//! CMVP evidence.
+
+use crate::go_packages::go_imports;
+use crate::types::*;
+use lazy_static::lazy_static;
+use regex::Regex;
+use serde::{Deserialize, Serialize};
+
+lazy_static! {
+    static ref CGO_DIRECTIVE: Regex =
+        Regex::new(r"^#cgo\s+(?:[\w!,]+\s+)*(LDFLAGS|pkg-config):(.*)$")
+            .expect("CGO_DIRECTIVE: Invalid regex pattern - this is a compile-time bug");
+    static ref INCLUDE: Regex = Regex::new(r#"^#\s*include\s*[<"]([^>"]+)[>"]"#)
+        .expect("INCLUDE: Invalid regex pattern - this is a compile-time bug");
+    static ref CGO_CALL: Regex = Regex::new(r"\bC\.\w+")
+        .expect("CGO_CALL: Invalid regex pattern - this is a compile-time bug");
+}
+
+/// Linker libraries (`-l` flags and pkg-config modules) of native crypto libraries
+const NATIVE_LIBRARIES: &[(&str, &str)] = &[
+    ("crypto", "openssl"),
+    ("ssl", "openssl"),
+    ("openssl", "openssl"),
+    ("libcrypto", "openssl"),
+    ("libssl", "openssl"),
+    ("gcrypt", "libgcrypt"),
+    ("libgcrypt", "libgcrypt"),
+    ("sodium", "libsodium"),
+    ("libsodium", "libsodium"),
+    ("mbedcrypto", "mbedtls"),
+    ("mbedtls", "mbedtls"),
+    ("wolfssl", "wolfssl"),
+    ("nettle", "nettle"),
+    ("hogweed", "nettle"),
+    ("botan-2", "botan"),
+    ("botan-3", "botan"),
+];
+
+/// Header prefixes of native crypto libraries
+const NATIVE_HEADERS: &[(&str, &str)] = &[
+    ("openssl/", "openssl"),
+    ("gcrypt.h", "libgcrypt"),
+    ("sodium.h", "libsodium"),
+    ("sodium/", "libsodium"),
+    ("mbedtls/", "mbedtls"),
+    ("wolfssl/", "wolfssl"),
+    ("nettle/", "nettle"),
+    ("botan/", "botan"),
+];
+
+/// Go packages wrapping a native crypto library, with their package name
+const WRAPPER_PACKAGES: &[(&str, &str, &str)] = &[
+    ("github.com/spacemonkeygo/openssl", "openssl", "openssl"),
+    ("github.com/libp2p/go-openssl", "openssl", "openssl"),
+    ("github.com/golang-fips/openssl", "openssl", "openssl"),
+    (
+        "github.com/microsoft/go-crypto-openssl/openssl",
+        "openssl",
+        "openssl",
+    ),
+    ("github.com/GoKillers/libsodium-go", "sodium", "libsodium"),
+    ("github.com/jamesruan/sodium", "sodium", "libsodium"),
+];
+
+/// C code embedded in a Go file
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct CgoPreamble {
+    /// First and last line of the preamble comment (1-based, inclusive)
+    pub start_line: usize,
+    pub end_line: usize,
+    /// Native crypto libraries linked by `#cgo` directives or included headers
+    pub libraries: Vec<String>,
+    /// Headers included by the preamble
+    pub includes: Vec<String>,
+}
+
+impl CgoPreamble {
+    fn contains(&self, line: usize) -> bool {
+        (self.start_line..=self.end_line).contains(&line)
+    }
+}
+
+/// Preambles of a Go source: the comment immediately preceding each `import "C"`
+pub fn parse_cgo_preambles(source: &str) -> Vec<CgoPreamble> {
+    let lines: Vec<&str> = source.lines().collect();
+    let mut preambles = Vec::new();
+
+    for (idx, line) in lines.iter().enumerate() {
+        if !is_import_c(line) || idx == 0 {
+            continue;
+        }
+
+        let end = idx - 1;
+        let previous = lines[end].trim();
+        let start = if previous.ends_with("*/") {
+            match (0..=end).rev().find(|&i| lines[i].contains("/*")) {
+                Some(start) => start,
+                None => continue,
+            }
+        } else if previous.starts_with("//") {
+            (0..=end)
+                .rev()
+                .take_while(|&i| lines[i].trim().starts_with("//"))
+                .last()
+                .unwrap_or(end)
+        } else {
+            continue;
+        };
+
+        let mut preamble = CgoPreamble {
+            start_line: start + 1,
+            end_line: end + 1,
+            libraries: Vec::new(),
+            includes: Vec::new(),
+        };
+        for line in &lines[start..=end] {
+            let code = strip_comment(line);
+            if let Some(captures) = CGO_DIRECTIVE.captures(code) {
+                let flags = captures[2].split_whitespace();
+                let names: Vec<&str> = if &captures[1] == "LDFLAGS" {
+                    flags.filter_map(|flag| flag.strip_prefix("-l")).collect()
+                } else {
+                    flags.collect()
+                };
+                for name in names {
+                    if let Some((_, library)) = NATIVE_LIBRARIES.iter().find(|(l, _)| *l == name) {
+                        push_unique(&mut preamble.libraries, library);
+                    }
+                }
+            } else if let Some(captures) = INCLUDE.captures(code) {
+                let header = &captures[1];
+                if let Some((_, library)) = NATIVE_HEADERS
+                    .iter()
+                    .find(|(prefix, _)| header.starts_with(prefix))
+                {
+                    push_unique(&mut preamble.libraries, library);
+                }
+                preamble.includes.push(header.to_string());
+            }
+        }
+        preambles.push(preamble);
+    }
+
+    preambles
+}
+
+fn is_import_c(line: &str) -> bool {
+    let line = line.trim();
+    let line = line.split("//").next().unwrap_or(line).trim_end();
+    line == "import \"C\""
+}
+
+/// A preamble line without its comment markers
+fn strip_comment(line: &str) -> &str {
+    let line = line.trim();
+    let line = line.strip_prefix("//").unwrap_or(line);
+    let line = line.strip_prefix("/*").unwrap_or(line);
+    line.strip_suffix("*/").unwrap_or(line).trim()
+}
+
+fn push_unique(libraries: &mut Vec<String>, library: &str) {
+    if !libraries.iter().any(|l| l == library) {
+        libraries.push(library.to_string());
+    }
+}
+
+/// Wrapper packages imported by a Go source, with the name they are used under
+fn wrapper_imports(source: &str) -> Vec<(&'static str, String, &'static str)> {
+    go_imports(source)
+        .into_iter()
+        .filter_map(|import| {
+            let (path, name, library) = WRAPPER_PACKAGES
+                .iter()
+                .find(|(path, _, _)| *path == import.path)?;
+            let name = import.alias.unwrap_or_else(|| name.to_string());
+            Some((*path, name, *library))
+        })
+        .collect()
+}
+
+/// Label the findings of a Go source that run in native code
+pub(crate) fn label_native_crypto(source: &str, vulnerabilities: &mut [Vulnerability]) {
+    let preambles = parse_cgo_preambles(source);
+    let wrappers = wrapper_imports(source);
+    if preambles.is_empty() && wrappers.is_empty() {
+        return;
+    }
+
+    let mut libraries = Vec::new();
+    for library in preambles.iter().flat_map(|p| &p.libraries) {
+        push_unique(&mut libraries, library);
+    }
+    let lines: Vec<&str> = source.lines().collect();
+
+    for vuln in vulnerabilities.iter_mut() {
+        let Some(line) = lines.get(vuln.line.wrapping_sub(1)) else {
+            continue;
+        };
+
+        vuln.native_code = if preambles.iter().any(|p| p.contains(vuln.line)) {
+            Some(NativeCode {
+                kind: NativeCodeKind::CgoPreamble,
+                libraries: libraries.clone(),
+                package: None,
+            })
+        } else if !preambles.is_empty() && CGO_CALL.is_match(line) {
+            Some(NativeCode {
+                kind: NativeCodeKind::CgoCall,
+                libraries: libraries.clone(),
+                package: None,
+            })
+        } else if let Some((path, _, library)) = wrappers
+            .iter()
+            .find(|(_, name, _)| line.contains(&format!("{}.", name)))
+        {
+            Some(NativeCode {
+                kind: NativeCodeKind::WrapperPackage,
+                libraries: vec![library.to_string()],
+                package: Some(path.to_string()),
+            })
+        } else {
+            continue;
+        };
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::analyze;
+
+    const CGO_SOURCE: &str = r#"package main
+
+/*
+#cgo linux LDFLAGS: -L/usr/lib -lcrypto
+#include <stdlib.h>
+#include <openssl/rsa.h>
+
+static RSA *make_key(void) { return RSA_new(); }
+*/
+import "C"
+
+import "crypto/sha1"
+
+func main() {
+	C.MD5_Init(nil)
+	_ = sha1.New()
+}
+"#;
+
+    #[test]
+    fn test_cgo_preambles() {
+        let preambles = parse_cgo_preambles(CGO_SOURCE);
+        assert_eq!(preambles.len(), 1);
+        assert_eq!((preambles[0].start_line, preambles[0].end_line), (3, 9));
+        assert_eq!(preambles[0].libraries, vec!["openssl"]);
+        assert_eq!(preambles[0].includes, vec!["stdlib.h", "openssl/rsa.h"]);
+
+        let line_comments =
+            "package main\n\n// #cgo pkg-config: libsodium\n// #include <sodium.h>\nimport \"C\"\n";
+        let preambles = parse_cgo_preambles(line_comments);
+        assert_eq!((preambles[0].start_line, preambles[0].end_line), (3, 4));
+        assert_eq!(preambles[0].libraries, vec!["libsodium"]);
+
+        // A blank line detaches the comment from `import "C"`
+        assert!(parse_cgo_preambles("// #include <openssl/rsa.h>\n\nimport \"C\"\n").is_empty());
+    }
+
+    #[test]
+    fn test_native_crypto_labels() {
+        let audit = analyze(CGO_SOURCE, "go").unwrap();
+        let native = |line: usize| {
+            audit
+                .vulnerabilities
+                .iter()
+                .find(|v| v.line == line)
+                .and_then(|v| v.native_code.as_ref())
+                .map(|n| n.kind)
+        };
+        assert_eq!(native(6), Some(NativeCodeKind::CgoPreamble));
+        assert_eq!(native(8), Some(NativeCodeKind::CgoPreamble));
+        assert_eq!(native(15), Some(NativeCodeKind::CgoCall));
+        // The standard library import stays Go crypto
+        let sha1 = audit.vulnerabilities.iter().find(|v| v.line == 16).unwrap();
+        assert!(sha1.native_code.is_none());
+
+        let wrapper = "package main\n\nimport ossl \"github.com/spacemonkeygo/openssl\"\n\nvar key, _ = ossl.GenerateRSAKey(2048)\n";
+        let audit = analyze(wrapper, "go").unwrap();
+        let vuln = audit.vulnerabilities.iter().find(|v| v.line == 5).unwrap();
+        let native = vuln.native_code.as_ref().unwrap();
+        assert_eq!(native.kind, NativeCodeKind::WrapperPackage);
+        assert_eq!(native.libraries, vec!["openssl"]);
+        assert_eq!(
+            native.package.as_deref(),
+            Some("github.com/spacemonkeygo/openssl")
+        );
+    }
+}
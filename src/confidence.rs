@@ -0,0 +1,216 @@
+//! Per-finding confidence
+//!
+//! A finding's confidence comes from the construct the algorithm name was
+//! matched in. `rsa.GenerateKey(rand.Reader, 1024)` and `createHash('md5')`
+//! are almost certainly real usage; `rsaKey` or a comment mentioning RSA
+//! may not be. Classification is line-local, like the detectors themselves.
+
+use crate::types::*;
+use lazy_static::lazy_static;
+use regex::Regex;
+
+lazy_static! {
+    // import/use/include statements, `require(...)` and Go import block entries
+    static ref IMPORT_RE: Regex = Regex::new(
+        r#"^\s*(import\b|from\s+[\w.]+\s+import\b|use\s+[\w:]+|#\s*include\b|using\s+[\w.]+\s*;|extern\s+crate\b|(?:[\w.]+\s+)?"[\w./-]+"\s*$)|\brequire\s*\(|\bimport\s*\("#
+    ).expect("IMPORT_RE: Invalid regex pattern - this is a compile-time bug");
+
+    // Preprocessor and attribute lines that start with `#` but are not comments
+    static ref HASH_DIRECTIVE_RE: Regex = Regex::new(
+        r"^#\s*(\[|!|include|import|define|undef|if|ifdef|ifndef|elif|else|endif|pragma|region|endregion)"
+    ).expect("HASH_DIRECTIVE_RE: Invalid regex pattern - this is a compile-time bug");
+}
+
+/// Drop findings below `min` confidence; returns the number removed
+pub fn filter_by_confidence(audit: &mut AuditResult, min: Confidence) -> usize {
+    let before = audit.vulnerabilities.len();
+    audit.vulnerabilities.retain(|v| v.confidence >= min);
+    let removed = before - audit.vulnerabilities.len();
+    if removed > 0 {
+        audit.refresh();
+    }
+    removed
+}
+
+/// Classify the construct containing the match `line[start..end]`
+pub(crate) fn classify_match(line: &str, start: usize, end: usize) -> MatchKind {
+    let scan = scan_line(line, start);
+
+    if scan.comment_start.is_some_and(|c| c <= start) || is_comment_line(line) {
+        return MatchKind::Comment;
+    }
+    if IMPORT_RE.is_match(line) {
+        return MatchKind::Import;
+    }
+    if let Some(quote) = scan.open_quote {
+        return if is_call_argument(line, quote, end) {
+            MatchKind::ApiCall
+        } else {
+            MatchKind::StringLiteral
+        };
+    }
+
+    // Identifier containing the match used as a callee or qualified path:
+    // `rsa.GenerateKey`, `RsaPrivateKey::new`, `crypto.createECDH` (not `rsaKey = ...`)
+    let is_word = |c: Option<char>| c.is_some_and(|c| c.is_ascii_alphanumeric() || c == '_');
+    let mut ident_start = start;
+    while is_word(line[..ident_start].chars().next_back()) {
+        ident_start -= 1;
+    }
+    let mut ident_end = end;
+    while is_word(line[ident_end..].chars().next()) {
+        ident_end += 1;
+    }
+
+    let before = line[..ident_start].trim_end();
+    let after = line[ident_end..].trim_start();
+    let qualified = before.ends_with('.')
+        || before.ends_with("::")
+        || before.ends_with("new")
+        || after.starts_with('(')
+        || after.starts_with('.')
+        || after.starts_with("::");
+
+    if qualified {
+        MatchKind::ApiCall
+    } else {
+        MatchKind::Identifier
+    }
+}
+
+/// Quote and comment state of a line up to a position
+struct LineScan {
+    /// Offset of the opening quote when `position` is inside a string literal
+    open_quote: Option<usize>,
+    /// Offset of a `//`, `/*` or ` #` comment outside string literals
+    comment_start: Option<usize>,
+}
+
+fn scan_line(line: &str, position: usize) -> LineScan {
+    let bytes = line.as_bytes();
+    let mut quote: Option<(u8, usize)> = None;
+    let mut open_quote = None;
+    let mut comment_start = None;
+    let mut i = 0;
+
+    while i < bytes.len() {
+        if i == position {
+            open_quote = quote.map(|(_, at)| at);
+        }
+        let b = bytes[i];
+        match quote {
+            Some((q, _)) => {
+                if b == b'\\' {
+                    i += 1;
+                } else if b == q {
+                    quote = None;
+                }
+            }
+            None => match b {
+                b'"' | b'\'' | b'`' => quote = Some((b, i)),
+                b'/' if matches!(bytes.get(i + 1), Some(b'/' | b'*')) => {
+                    comment_start = Some(i);
+                    break;
+                }
+                b'#' if i > 0 && bytes[i - 1].is_ascii_whitespace() => {
+                    comment_start = Some(i);
+                    break;
+                }
+                _ => {}
+            },
+        }
+        i += 1;
+    }
+
+    LineScan {
+        open_quote,
+        comment_start,
+    }
+}
+
+/// Line that is entirely a comment (`//`, `/*`, `*`, `#`, `--`)
+fn is_comment_line(line: &str) -> bool {
+    let trimmed = line.trim_start();
+    trimmed.starts_with("//")
+        || trimmed.starts_with("/*")
+        || trimmed.starts_with("--")
+        || trimmed == "*"
+        || trimmed.starts_with("* ")
+        || trimmed.starts_with("*/")
+        || (trimmed.starts_with('#') && !HASH_DIRECTIVE_RE.is_match(trimmed))
+}
+
+/// String literal (opened at `quote`) passed directly to a call: `getInstance("RSA")`
+fn is_call_argument(line: &str, quote: usize, end: usize) -> bool {
+    let delimiter = line.as_bytes()[quote];
+    let close = line[end..]
+        .find(delimiter as char)
+        .map(|i| end + i)
+        .unwrap_or(line.len());
+    // Prose such as log messages is not an algorithm argument
+    if line[quote + 1..close].contains(char::is_whitespace) {
+        return false;
+    }
+
+    let before = line[..quote].trim_end();
+    before.ends_with('(')
+        || (before.ends_with(',') && before.matches('(').count() > before.matches(')').count())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn kind(line: &str, token: &str) -> MatchKind {
+        let start = line.find(token).unwrap();
+        classify_match(line, start, start + token.len())
+    }
+
+    #[test]
+    fn test_classify_match_kinds() {
+        assert_eq!(
+            kind("key, _ := rsa.GenerateKey(rand.Reader, 1024)", "rsa"),
+            MatchKind::ApiCall
+        );
+        assert_eq!(
+            kind("const h = crypto.createHash('md5')", "md5"),
+            MatchKind::ApiCall
+        );
+        assert_eq!(kind("    \"crypto/rsa\"", "rsa"), MatchKind::Import);
+        assert_eq!(
+            kind("from Crypto.PublicKey import RSA", "RSA"),
+            MatchKind::Import
+        );
+        assert_eq!(
+            kind("ALGORITHMS = ['rsa', 'ed25519']", "rsa"),
+            MatchKind::StringLiteral
+        );
+        assert_eq!(
+            kind("println!(\"MD5 is mentioned here\");", "MD5"),
+            MatchKind::StringLiteral
+        );
+        assert_eq!(kind("let rsaKey = load();", "rsa"), MatchKind::Identifier);
+        assert_eq!(
+            kind("    // We used to use RSA here", "RSA"),
+            MatchKind::Comment
+        );
+        assert_eq!(
+            kind("x = compute()  # was md5 before", "md5"),
+            MatchKind::Comment
+        );
+    }
+
+    #[test]
+    fn test_filter_by_confidence() {
+        let mut audit = crate::analyze(
+            "let rsaKey = load();\nconst h = crypto.createHash('md5');\n",
+            "javascript",
+        )
+        .unwrap();
+        assert_eq!(audit.vulnerabilities.len(), 2);
+
+        assert_eq!(filter_by_confidence(&mut audit, Confidence::High), 1);
+        assert_eq!(audit.vulnerabilities[0].crypto_type, CryptoType::Md5);
+        assert_eq!(audit.stats.total_vulnerabilities, 1);
+    }
+}
@@ -0,0 +1,49 @@
+//! Scan diagnostics
+//!
+//! Files a scan skipped or could not analyze, and why. Oversized, binary
+//! and unreadable files and files over a resource limit are not errors for
+//! the scan as a whole; they are recorded so that a clean report with
+//! skipped files is not mistaken for a clean codebase.
+
+use crate::audit::AuditError;
+use serde::{Deserialize, Serialize};
+
+/// Why a file was skipped
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum DiagnosticKind {
+    TooLarge,
+    Binary,
+    Unreadable,
+    TimeBudgetExceeded,
+    MemoryCapExceeded,
+    AnalysisFailed,
+}
+
+impl DiagnosticKind {
+    pub fn for_audit_error(error: &AuditError) -> Self {
+        match error {
+            AuditError::TimeBudgetExceeded(..) => DiagnosticKind::TimeBudgetExceeded,
+            AuditError::MemoryCapExceeded(..) => DiagnosticKind::MemoryCapExceeded,
+            AuditError::SourceTooLarge(..) | AuditError::TooManyLines(..) => {
+                DiagnosticKind::TooLarge
+            }
+            _ => DiagnosticKind::AnalysisFailed,
+        }
+    }
+}
+
+/// A file skipped by the scan
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ScanDiagnostic {
+    pub path: String,
+    pub kind: DiagnosticKind,
+    pub message: String,
+}
+
+/// Export diagnostics as JSON
+pub fn export_diagnostics_json(
+    diagnostics: &[ScanDiagnostic],
+) -> Result<String, serde_json::Error> {
+    serde_json::to_string_pretty(diagnostics)
+}
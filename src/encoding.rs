@@ -0,0 +1,317 @@
+//! Source encoding detection and transcoding
+//!
+//! Source files are not always UTF-8: legacy Java and C# code is often
+//! Windows-1252 or Latin-1, and Visual Studio happily writes UTF-16. This
+//! module detects the encoding (BOM first, then heuristics), transcodes to
+//! UTF-8 for the detectors, and keeps a byte offset map so findings can be
+//! reported at their byte position in the original file. It also provides
+//! the binary-file heuristic used to skip non-text files.
+
+use crate::types::*;
+use serde::{Deserialize, Serialize};
+use std::fmt;
+use thiserror::Error;
+
+/// Number of leading bytes inspected by the binary and UTF-16 heuristics
+const SNIFF_LEN: usize = 8192;
+
+/// Share of control bytes above which a file is treated as binary (percent)
+const MAX_CONTROL_PERCENT: usize = 10;
+
+/// Magic numbers of common binary formats that may carry a source extension
+const BINARY_MAGIC: &[&[u8]] = &[
+    b"\x7fELF",
+    b"\x89PNG",
+    b"GIF8",
+    b"\xff\xd8\xff",
+    b"%PDF",
+    b"PK\x03\x04",
+    b"\x1f\x8b",
+    b"\xca\xfe\xba\xbe",
+    b"\xcf\xfa\xed\xfe",
+    b"MZ\x90\x00",
+    b"\x00asm",
+];
+
+/// Windows-1252 code points for bytes 0x80-0x9F (undefined bytes map as in Latin-1)
+const WINDOWS_1252_HIGH: [char; 32] = [
+    '\u{20AC}', '\u{0081}', '\u{201A}', '\u{0192}', '\u{201E}', '\u{2026}', '\u{2020}', '\u{2021}',
+    '\u{02C6}', '\u{2030}', '\u{0160}', '\u{2039}', '\u{0152}', '\u{008D}', '\u{017D}', '\u{008F}',
+    '\u{0090}', '\u{2018}', '\u{2019}', '\u{201C}', '\u{201D}', '\u{2022}', '\u{2013}', '\u{2014}',
+    '\u{02DC}', '\u{2122}', '\u{0161}', '\u{203A}', '\u{0153}', '\u{009D}', '\u{017E}', '\u{0178}',
+];
+
+#[derive(Error, Debug)]
+pub enum EncodingError {
+    #[error("Content appears to be binary")]
+    Binary,
+}
+
+/// Detected encoding of a source file
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum SourceEncoding {
+    Utf8,
+    Utf8Bom,
+    Utf16Le,
+    Utf16Be,
+    Windows1252,
+    Latin1,
+}
+
+impl fmt::Display for SourceEncoding {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            SourceEncoding::Utf8 => write!(f, "utf-8"),
+            SourceEncoding::Utf8Bom => write!(f, "utf-8-bom"),
+            SourceEncoding::Utf16Le => write!(f, "utf-16le"),
+            SourceEncoding::Utf16Be => write!(f, "utf-16be"),
+            SourceEncoding::Windows1252 => write!(f, "windows-1252"),
+            SourceEncoding::Latin1 => write!(f, "iso-8859-1"),
+        }
+    }
+}
+
+/// Maps byte offsets in the transcoded UTF-8 text back to the original bytes
+#[derive(Debug, Clone, Default)]
+pub struct OffsetMap {
+    /// UTF-8 offset of the start of each line
+    line_starts: Vec<usize>,
+    /// Original offset of every UTF-8 byte, plus one past the end
+    original: Vec<u32>,
+}
+
+impl OffsetMap {
+    /// Original byte offset of a UTF-8 byte offset
+    pub fn original_offset(&self, offset: usize) -> usize {
+        match self.original.get(offset).or_else(|| self.original.last()) {
+            Some(original) => *original as usize,
+            None => offset,
+        }
+    }
+
+    /// Original byte column of a (1-based line, UTF-8 byte column) position
+    pub fn original_column(&self, line: usize, column: usize) -> usize {
+        let Some(start) = line.checked_sub(1).and_then(|l| self.line_starts.get(l)) else {
+            return column;
+        };
+        self.original_offset(start + column) - self.original_offset(*start)
+    }
+
+    /// Rewrite finding columns from transcoded to original byte columns
+    pub fn remap_columns(&self, audit: &mut AuditResult) {
+        for vuln in &mut audit.vulnerabilities {
+            vuln.column = self.original_column(vuln.line, vuln.column);
+            vuln.end_column = self.original_column(vuln.line, vuln.end_column);
+        }
+    }
+}
+
+/// Source text transcoded to UTF-8
+#[derive(Debug, Clone)]
+pub struct DecodedSource {
+    pub text: String,
+    pub encoding: SourceEncoding,
+    /// `None` when columns are unchanged (UTF-8 with or without BOM)
+    pub offsets: Option<OffsetMap>,
+}
+
+/// Heuristic check for binary content: known magic numbers, NUL bytes or many control bytes
+pub fn is_binary(bytes: &[u8]) -> bool {
+    if BINARY_MAGIC.iter().any(|magic| bytes.starts_with(magic)) {
+        return true;
+    }
+    if detect_bom(bytes).is_some() || guess_utf16(bytes).is_some() {
+        return false;
+    }
+
+    let sample = &bytes[..bytes.len().min(SNIFF_LEN)];
+    if sample.contains(&0) {
+        return true;
+    }
+    let control = sample
+        .iter()
+        .filter(|b| matches!(b, 0x01..=0x08 | 0x0e..=0x1a | 0x1c..=0x1f | 0x7f))
+        .count();
+    control * 100 > sample.len() * MAX_CONTROL_PERCENT
+}
+
+/// Detect the encoding of raw source bytes and transcode them to UTF-8
+pub fn decode_source(bytes: &[u8]) -> Result<DecodedSource, EncodingError> {
+    if is_binary(bytes) {
+        return Err(EncodingError::Binary);
+    }
+
+    let (encoding, body, skipped) = match detect_bom(bytes) {
+        // Columns are unaffected by a UTF-8 BOM, only the first line's byte offsets
+        Some((SourceEncoding::Utf8Bom, len)) => {
+            return Ok(DecodedSource {
+                text: String::from_utf8_lossy(&bytes[len..]).into_owned(),
+                encoding: SourceEncoding::Utf8Bom,
+                offsets: None,
+            });
+        }
+        Some((encoding, len)) => (encoding, &bytes[len..], len),
+        None => match guess_utf16(bytes) {
+            Some(encoding) => (encoding, bytes, 0),
+            None => match std::str::from_utf8(bytes) {
+                Ok(text) => {
+                    return Ok(DecodedSource {
+                        text: text.to_string(),
+                        encoding: SourceEncoding::Utf8,
+                        offsets: None,
+                    });
+                }
+                Err(_) if bytes.iter().any(|b| (0x80..=0x9f).contains(b)) => {
+                    (SourceEncoding::Windows1252, bytes, 0)
+                }
+                Err(_) => (SourceEncoding::Latin1, bytes, 0),
+            },
+        },
+    };
+
+    let mut text = String::with_capacity(body.len());
+    let mut original = Vec::with_capacity(body.len() + 1);
+    let mut push = |c: char, offset: usize| {
+        text.push(c);
+        original.extend(std::iter::repeat_n((offset + skipped) as u32, c.len_utf8()));
+    };
+
+    match encoding {
+        SourceEncoding::Utf16Le | SourceEncoding::Utf16Be => {
+            let units = body.chunks_exact(2).map(|pair| match encoding {
+                SourceEncoding::Utf16Le => u16::from_le_bytes([pair[0], pair[1]]),
+                _ => u16::from_be_bytes([pair[0], pair[1]]),
+            });
+            let mut offset = 0;
+            for c in char::decode_utf16(units) {
+                let c = c.unwrap_or(char::REPLACEMENT_CHARACTER);
+                push(c, offset);
+                offset += if c.len_utf16() == 2 { 4 } else { 2 };
+            }
+        }
+        _ => {
+            for (offset, b) in body.iter().enumerate() {
+                let c = match (encoding, b) {
+                    (SourceEncoding::Windows1252, 0x80..=0x9f) => {
+                        WINDOWS_1252_HIGH[(b - 0x80) as usize]
+                    }
+                    _ => *b as char,
+                };
+                push(c, offset);
+            }
+        }
+    }
+    original.push(bytes.len() as u32);
+
+    let mut line_starts = vec![0];
+    line_starts.extend(text.match_indices('\n').map(|(i, _)| i + 1));
+
+    Ok(DecodedSource {
+        text,
+        encoding,
+        offsets: Some(OffsetMap {
+            line_starts,
+            original,
+        }),
+    })
+}
+
+fn detect_bom(bytes: &[u8]) -> Option<(SourceEncoding, usize)> {
+    if bytes.starts_with(&[0xef, 0xbb, 0xbf]) {
+        Some((SourceEncoding::Utf8Bom, 3))
+    } else if bytes.starts_with(&[0xff, 0xfe]) {
+        Some((SourceEncoding::Utf16Le, 2))
+    } else if bytes.starts_with(&[0xfe, 0xff]) {
+        Some((SourceEncoding::Utf16Be, 2))
+    } else {
+        None
+    }
+}
+
+/// BOM-less UTF-16: mostly-ASCII text leaves every other byte zero
+fn guess_utf16(bytes: &[u8]) -> Option<SourceEncoding> {
+    let sample = &bytes[..bytes.len().min(SNIFF_LEN) & !1];
+    if sample.len() < 4 {
+        return None;
+    }
+
+    let pairs = sample.len() / 2;
+    let even_zero = sample.iter().step_by(2).filter(|b| **b == 0).count();
+    let odd_zero = sample
+        .iter()
+        .skip(1)
+        .step_by(2)
+        .filter(|b| **b == 0)
+        .count();
+
+    if odd_zero * 10 >= pairs * 9 && even_zero * 10 < pairs {
+        Some(SourceEncoding::Utf16Le)
+    } else if even_zero * 10 >= pairs * 9 && odd_zero * 10 < pairs {
+        Some(SourceEncoding::Utf16Be)
+    } else {
+        None
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::analyze;
+
+    #[test]
+    fn test_utf8_passthrough() {
+        let decoded = decode_source("let x = md5(data);\n".as_bytes()).unwrap();
+        assert_eq!(decoded.encoding, SourceEncoding::Utf8);
+        assert!(decoded.offsets.is_none());
+    }
+
+    #[test]
+    fn test_windows_1252_columns() {
+        // "// café – note" in Windows-1252, then an MD5 call
+        let mut bytes = b"// caf\xe9 \x96 note\n".to_vec();
+        bytes.extend_from_slice(b"s = \"\xe9\"; h = MD5(s)\n");
+        let decoded = decode_source(&bytes).unwrap();
+        assert_eq!(decoded.encoding, SourceEncoding::Windows1252);
+        assert!(decoded.text.starts_with("// café – note"));
+
+        let mut audit = analyze(&decoded.text, "java").unwrap();
+        let utf8_column = audit.vulnerabilities[0].column;
+        decoded.offsets.as_ref().unwrap().remap_columns(&mut audit);
+        // The two-byte UTF-8 "é" is a single byte in the original
+        assert_eq!(audit.vulnerabilities[0].column, utf8_column - 1);
+        let line_start = bytes.iter().position(|b| *b == b'\n').unwrap() + 1;
+        assert_eq!(
+            &bytes[line_start + audit.vulnerabilities[0].column..][..3],
+            b"MD5"
+        );
+    }
+
+    #[test]
+    fn test_utf16_with_and_without_bom() {
+        let source = "var h = md5(x);\n";
+        let utf16: Vec<u8> = source.encode_utf16().flat_map(u16::to_le_bytes).collect();
+
+        let mut with_bom = vec![0xff, 0xfe];
+        with_bom.extend_from_slice(&utf16);
+        let decoded = decode_source(&with_bom).unwrap();
+        assert_eq!(decoded.encoding, SourceEncoding::Utf16Le);
+        assert_eq!(decoded.text, source);
+
+        let decoded = decode_source(&utf16).unwrap();
+        assert_eq!(decoded.encoding, SourceEncoding::Utf16Le);
+        let offsets = decoded.offsets.unwrap();
+        assert_eq!(offsets.original_column(1, 8), 16);
+    }
+
+    #[test]
+    fn test_binary_detection() {
+        assert!(is_binary(b"\x7fELF\x02\x01\x01"));
+        assert!(is_binary(b"abc\x00\x01\x02\x03def"));
+        assert!(!is_binary(b"fn main() {}\n"));
+        assert!(matches!(
+            decode_source(b"\x89PNG\r\n\x1a\n"),
+            Err(EncodingError::Binary)
+        ));
+    }
+}
@@ -0,0 +1,448 @@
+//! Detection quality evaluation against a labeled corpus
+//!
+//! Corpus files are ordinary source files annotated with inline markers:
+//!
+//! ```text
+//! key, _ := rsa.GenerateKey(rand.Reader, 1024) // expect: RSA key_size=1024
+//! // expect: MD5
+//! h := md5.New()
+//! ```
+//!
+//! A marker applies to its own line when it trails code, otherwise to the
+//! next line that is not itself a marker. `expect: none` documents a line
+//! that must not produce findings; unmarked lines are expected clean too.
+//! Markers are blanked out before detection so they cannot match themselves.
+//!
+//! Scoring is independent of how findings were produced, so custom rule
+//! authors can evaluate their own rules with the same runner.
+
+use crate::types::*;
+use lazy_static::lazy_static;
+use regex::Regex;
+use serde::{Deserialize, Serialize};
+use std::collections::BTreeMap;
+use thiserror::Error;
+
+/// Drop in F1, precision or recall that counts as a regression
+const REGRESSION_TOLERANCE: f64 = 0.001;
+
+lazy_static! {
+    static ref EXPECT_MARKER: Regex = Regex::new(r"(?://|#)\s*expect:\s*(.*)$")
+        .expect("EXPECT_MARKER: Invalid regex pattern - this is a compile-time bug");
+    static ref KEY_SIZE_ATTR: Regex = Regex::new(r"^key_size=(\d+)$")
+        .expect("KEY_SIZE_ATTR: Invalid regex pattern - this is a compile-time bug");
+}
+
+/// Crypto type and optional key size named by a marker
+type Label = (CryptoType, Option<u32>);
+
+/// A finding the corpus says must be reported
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct Expectation {
+    pub line: usize,
+    pub crypto_type: CryptoType,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub key_size: Option<u32>,
+}
+
+/// Corpus file with its markers parsed and blanked out
+#[derive(Debug, Clone)]
+pub struct LabeledSource {
+    /// Source with every marker replaced by spaces (line and column positions unchanged)
+    pub source: String,
+    pub expectations: Vec<Expectation>,
+}
+
+/// Errors in corpus markers
+#[derive(Error, Debug, Clone, PartialEq, Eq)]
+pub enum CorpusError {
+    #[error("line {0}: unknown crypto type '{1}' in expect marker")]
+    UnknownCryptoType(usize, String),
+
+    #[error("line {0}: invalid expect attribute '{1}'")]
+    InvalidAttribute(usize, String),
+}
+
+/// Precision, recall and F1 for one slice of the corpus
+#[derive(Debug, Clone, Default, PartialEq, Serialize, Deserialize)]
+pub struct Metrics {
+    pub true_positives: usize,
+    pub false_positives: usize,
+    pub false_negatives: usize,
+    /// Detected findings whose key size differs from the expected one
+    pub key_size_mismatches: usize,
+    pub precision: f64,
+    pub recall: f64,
+    pub f1: f64,
+}
+
+impl Metrics {
+    fn finish(&mut self) {
+        let tp = self.true_positives as f64;
+        let ratio = |n: f64, d: f64| if d == 0.0 { 1.0 } else { n / d };
+        self.precision = ratio(tp, tp + self.false_positives as f64);
+        self.recall = ratio(tp, tp + self.false_negatives as f64);
+        self.f1 = if self.precision + self.recall == 0.0 {
+            0.0
+        } else {
+            2.0 * self.precision * self.recall / (self.precision + self.recall)
+        };
+    }
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum MismatchKind {
+    FalsePositive,
+    FalseNegative,
+    KeySize,
+}
+
+/// A single disagreement between the corpus and the detector
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct EvalMismatch {
+    pub file_path: String,
+    pub line: usize,
+    pub crypto_type: CryptoType,
+    pub kind: MismatchKind,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub expected_key_size: Option<u32>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub detected_key_size: Option<u32>,
+}
+
+/// Evaluation results for a whole corpus
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct Evaluation {
+    pub files: usize,
+    pub overall: Metrics,
+    /// Keyed by `CryptoType` display name
+    pub by_crypto_type: BTreeMap<String, Metrics>,
+    /// Keyed by language name
+    pub by_language: BTreeMap<String, Metrics>,
+    pub mismatches: Vec<EvalMismatch>,
+}
+
+/// Change of one metric between two evaluations
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct MetricDelta {
+    /// `overall`, `type:<CryptoType>` or `language:<Language>`
+    pub scope: String,
+    pub metric: String,
+    pub previous: f64,
+    pub current: f64,
+}
+
+/// Regression comparison against a previous evaluation
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct EvalComparison {
+    pub regressions: Vec<MetricDelta>,
+    pub improvements: Vec<MetricDelta>,
+}
+
+impl EvalComparison {
+    pub fn has_regressions(&self) -> bool {
+        !self.regressions.is_empty()
+    }
+}
+
+/// Parse `expect:` markers and blank them out of the source
+pub fn parse_labeled_source(source: &str) -> Result<LabeledSource, CorpusError> {
+    let mut blanked = String::with_capacity(source.len());
+    let mut expectations = Vec::new();
+    // Markers on their own line wait for the next code line
+    let mut pending: Vec<Label> = Vec::new();
+
+    for (idx, line) in source.split_inclusive('\n').enumerate() {
+        let line_num = idx + 1;
+        let content = line.trim_end_matches(['\r', '\n']);
+        let Some(marker) = EXPECT_MARKER.captures(content) else {
+            if !line.trim().is_empty() {
+                expectations.extend(
+                    pending
+                        .drain(..)
+                        .map(|(crypto_type, key_size)| Expectation {
+                            line: line_num,
+                            crypto_type,
+                            key_size,
+                        }),
+                );
+            }
+            blanked.push_str(line);
+            continue;
+        };
+
+        let start = marker.get(0).map(|m| m.start()).unwrap_or(0);
+        let labels = parse_marker(marker.get(1).map(|m| m.as_str()).unwrap_or(""), line_num)?;
+        let trailing = !line[..start].trim().is_empty();
+
+        match (trailing, labels) {
+            (true, Some(labels)) => {
+                expectations.extend(labels.into_iter().map(|(crypto_type, key_size)| {
+                    Expectation {
+                        line: line_num,
+                        crypto_type,
+                        key_size,
+                    }
+                }));
+            }
+            (false, Some(labels)) => pending.extend(labels),
+            (_, None) => {}
+        }
+
+        blanked.push_str(&line[..start]);
+        blanked.extend(std::iter::repeat_n(' ', content.len() - start));
+        blanked.push_str(&line[content.len()..]);
+    }
+
+    Ok(LabeledSource {
+        source: blanked,
+        expectations,
+    })
+}
+
+/// Labels of one marker; `None` for `expect: none`
+fn parse_marker(text: &str, line: usize) -> Result<Option<Vec<Label>>, CorpusError> {
+    let text = text.trim();
+    if text.eq_ignore_ascii_case("none") {
+        return Ok(None);
+    }
+
+    let mut labels = Vec::new();
+    for label in text.split(',').map(str::trim).filter(|l| !l.is_empty()) {
+        let mut parts = label.split_whitespace();
+        let name = parts.next().unwrap_or_default();
+        let crypto_type = CryptoType::from_string(name)
+            .ok_or_else(|| CorpusError::UnknownCryptoType(line, name.to_string()))?;
+
+        let mut key_size = None;
+        for attr in parts {
+            let size = KEY_SIZE_ATTR
+                .captures(attr)
+                .and_then(|cap| cap[1].parse().ok())
+                .ok_or_else(|| CorpusError::InvalidAttribute(line, attr.to_string()))?;
+            key_size = Some(size);
+        }
+        labels.push((crypto_type, key_size));
+    }
+    Ok(Some(labels))
+}
+
+impl Evaluation {
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Score the findings of one corpus file against its expectations
+    pub fn record(
+        &mut self,
+        file_path: &str,
+        language: Language,
+        expectations: &[Expectation],
+        findings: &[Vulnerability],
+    ) {
+        self.files += 1;
+        let mut matched = vec![false; findings.len()];
+        let mut outcomes: Vec<(CryptoType, MismatchKind)> = Vec::new();
+        let mut true_positives: Vec<CryptoType> = Vec::new();
+
+        for expected in expectations {
+            let found = findings.iter().enumerate().find(|(i, f)| {
+                !matched[*i] && f.line == expected.line && f.crypto_type == expected.crypto_type
+            });
+            match found {
+                Some((i, finding)) => {
+                    matched[i] = true;
+                    true_positives.push(expected.crypto_type.clone());
+                    if expected.key_size.is_some() && expected.key_size != finding.key_size {
+                        outcomes.push((expected.crypto_type.clone(), MismatchKind::KeySize));
+                        self.mismatches.push(EvalMismatch {
+                            file_path: file_path.to_string(),
+                            line: expected.line,
+                            crypto_type: expected.crypto_type.clone(),
+                            kind: MismatchKind::KeySize,
+                            expected_key_size: expected.key_size,
+                            detected_key_size: finding.key_size,
+                        });
+                    }
+                }
+                None => {
+                    outcomes.push((expected.crypto_type.clone(), MismatchKind::FalseNegative));
+                    self.mismatches.push(EvalMismatch {
+                        file_path: file_path.to_string(),
+                        line: expected.line,
+                        crypto_type: expected.crypto_type.clone(),
+                        kind: MismatchKind::FalseNegative,
+                        expected_key_size: expected.key_size,
+                        detected_key_size: None,
+                    });
+                }
+            }
+        }
+
+        for (finding, _) in findings.iter().zip(&matched).filter(|(_, m)| !**m) {
+            outcomes.push((finding.crypto_type.clone(), MismatchKind::FalsePositive));
+            self.mismatches.push(EvalMismatch {
+                file_path: file_path.to_string(),
+                line: finding.line,
+                crypto_type: finding.crypto_type.clone(),
+                kind: MismatchKind::FalsePositive,
+                expected_key_size: None,
+                detected_key_size: finding.key_size,
+            });
+        }
+
+        let language = language.to_string();
+        for crypto_type in true_positives {
+            for metrics in self.slices(&crypto_type, &language) {
+                metrics.true_positives += 1;
+            }
+        }
+        for (crypto_type, kind) in outcomes {
+            for metrics in self.slices(&crypto_type, &language) {
+                match kind {
+                    MismatchKind::FalsePositive => metrics.false_positives += 1,
+                    MismatchKind::FalseNegative => metrics.false_negatives += 1,
+                    MismatchKind::KeySize => metrics.key_size_mismatches += 1,
+                }
+            }
+        }
+    }
+
+    /// Compute precision, recall and F1 from the recorded counts
+    pub fn finish(&mut self) {
+        self.overall.finish();
+        self.by_crypto_type.values_mut().for_each(Metrics::finish);
+        self.by_language.values_mut().for_each(Metrics::finish);
+        self.mismatches
+            .sort_by(|a, b| a.file_path.cmp(&b.file_path).then(a.line.cmp(&b.line)));
+    }
+
+    fn slices(&mut self, crypto_type: &CryptoType, language: &str) -> [&mut Metrics; 3] {
+        [
+            &mut self.overall,
+            self.by_crypto_type
+                .entry(crypto_type.to_string())
+                .or_default(),
+            self.by_language.entry(language.to_string()).or_default(),
+        ]
+    }
+}
+
+/// Compare an evaluation with a previous one; a drop in any metric is a regression
+pub fn compare_evaluations(previous: &Evaluation, current: &Evaluation) -> EvalComparison {
+    let mut comparison = EvalComparison::default();
+
+    let mut scopes = vec![("overall".to_string(), &previous.overall, &current.overall)];
+    for (name, metrics) in &current.by_crypto_type {
+        if let Some(before) = previous.by_crypto_type.get(name) {
+            scopes.push((format!("type:{}", name), before, metrics));
+        }
+    }
+    for (name, metrics) in &current.by_language {
+        if let Some(before) = previous.by_language.get(name) {
+            scopes.push((format!("language:{}", name), before, metrics));
+        }
+    }
+
+    for (scope, before, after) in scopes {
+        for (metric, previous, current) in [
+            ("precision", before.precision, after.precision),
+            ("recall", before.recall, after.recall),
+            ("f1", before.f1, after.f1),
+        ] {
+            let delta = MetricDelta {
+                scope: scope.clone(),
+                metric: metric.to_string(),
+                previous,
+                current,
+            };
+            if current < previous - REGRESSION_TOLERANCE {
+                comparison.regressions.push(delta);
+            } else if current > previous + REGRESSION_TOLERANCE {
+                comparison.improvements.push(delta);
+            }
+        }
+    }
+
+    comparison
+}
+
+/// Export evaluation results as JSON string
+pub fn export_evaluation_json(evaluation: &Evaluation) -> Result<String, serde_json::Error> {
+    serde_json::to_string_pretty(evaluation)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::analyze;
+
+    const CORPUS: &str = "\
+import hashlib
+# expect: MD5
+h = hashlib.md5(data)
+key = RSA.generate(1024)  # expect: RSA key_size=2048
+# expect: none
+label = 'nothing to see'
+x = hashlib.sha1(data)
+";
+
+    #[test]
+    fn test_parse_labeled_source() {
+        let labeled = parse_labeled_source(CORPUS).unwrap();
+        assert_eq!(
+            labeled.expectations,
+            vec![
+                Expectation {
+                    line: 3,
+                    crypto_type: CryptoType::Md5,
+                    key_size: None
+                },
+                Expectation {
+                    line: 4,
+                    crypto_type: CryptoType::Rsa,
+                    key_size: Some(2048)
+                },
+            ]
+        );
+        assert!(!labeled.source.contains("expect"));
+        assert_eq!(labeled.source.len(), CORPUS.len());
+
+        assert_eq!(
+            parse_labeled_source("x = 1 // expect: RSB").unwrap_err(),
+            CorpusError::UnknownCryptoType(1, "RSB".to_string())
+        );
+    }
+
+    #[test]
+    fn test_evaluation_metrics() {
+        let labeled = parse_labeled_source(CORPUS).unwrap();
+        let audit = analyze(&labeled.source, "python").unwrap();
+
+        let mut evaluation = Evaluation::new();
+        evaluation.record(
+            "corpus.py",
+            Language::Python,
+            &labeled.expectations,
+            &audit.vulnerabilities,
+        );
+        evaluation.finish();
+
+        // MD5 and RSA found, SHA-1 unexpected, RSA key size wrong
+        assert_eq!(evaluation.overall.true_positives, 2);
+        assert_eq!(evaluation.overall.false_positives, 1);
+        assert_eq!(evaluation.overall.false_negatives, 0);
+        assert_eq!(evaluation.overall.key_size_mismatches, 1);
+        assert!((evaluation.overall.precision - 2.0 / 3.0).abs() < 1e-9);
+        assert_eq!(evaluation.overall.recall, 1.0);
+        assert_eq!(evaluation.by_crypto_type["SHA-1"].precision, 0.0);
+        assert_eq!(evaluation.by_language["python"].true_positives, 2);
+
+        let mut previous = evaluation.clone();
+        previous.overall.precision = 1.0;
+        let comparison = compare_evaluations(&previous, &evaluation);
+        assert!(comparison.has_regressions());
+        assert_eq!(comparison.regressions[0].scope, "overall");
+    }
+}
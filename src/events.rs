@@ -0,0 +1,183 @@
+//! Scan events and log records
+//!
+//! Machine-readable output of the CLI. A scan run with `--format jsonl`
+//! writes one `ScanEvent` per line to stdout as it progresses (file
+//! started, finding, file error, summary), so an orchestrator can stream
+//! results without parsing the human summary. Findings are written as each
+//! file is analyzed; analyses over the whole tree can still change them, in
+//! which case the file's final findings follow before the summary.
+//! Diagnostics go to stderr as plain text or, with `--log-format json`, as
+//! one `LogRecord` per line.
+
+use crate::diagnostics::{DiagnosticKind, ScanDiagnostic};
+use crate::types::*;
+use chrono::{DateTime, Utc};
+use serde::{Deserialize, Serialize};
+use std::fmt;
+
+/// Severity of a log record, most severe first
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum LogLevel {
+    Error,
+    Warn,
+    Info,
+    Debug,
+}
+
+impl LogLevel {
+    pub fn from_string(s: &str) -> Option<Self> {
+        match s.to_lowercase().as_str() {
+            "error" => Some(LogLevel::Error),
+            "warn" | "warning" => Some(LogLevel::Warn),
+            "info" => Some(LogLevel::Info),
+            "debug" => Some(LogLevel::Debug),
+            _ => None,
+        }
+    }
+}
+
+impl fmt::Display for LogLevel {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        let name = match self {
+            LogLevel::Error => "error",
+            LogLevel::Warn => "warn",
+            LogLevel::Info => "info",
+            LogLevel::Debug => "debug",
+        };
+        write!(f, "{}", name)
+    }
+}
+
+/// One diagnostic message of `--log-format json`
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct LogRecord {
+    pub timestamp: DateTime<Utc>,
+    pub level: LogLevel,
+    pub message: String,
+}
+
+impl LogRecord {
+    pub fn new(level: LogLevel, message: impl Into<String>) -> Self {
+        Self {
+            timestamp: Utc::now(),
+            level,
+            message: message.into(),
+        }
+    }
+}
+
+/// Totals reported when a scan finishes
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+pub struct ScanSummary {
+    pub target: String,
+    pub files_scanned: usize,
+    pub files_skipped: usize,
+    pub total_vulnerabilities: usize,
+    pub critical_count: usize,
+    pub high_count: usize,
+    /// Reports written by the scan
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub reports: Vec<String>,
+}
+
+/// One line of the `--format jsonl` stream
+#[derive(Debug, Clone, Serialize, Deserialize)]
+#[serde(tag = "event", rename_all = "kebab-case")]
+pub enum ScanEvent {
+    /// A supported source file is about to be analyzed
+    FileStarted {
+        path: String,
+        language: Language,
+    },
+    /// A finding of the file just analyzed, at or above `--min-confidence`
+    Finding {
+        path: String,
+        finding: Box<Vulnerability>,
+    },
+    /// All findings of a file whose streamed findings were changed by analyses
+    /// over the whole tree (cross-file constants, plugins, certificates, build
+    /// constraints); replaces the file's earlier `finding` events
+    FindingsRevised {
+        path: String,
+        findings: Vec<Vulnerability>,
+    },
+    /// A file was skipped or could not be analyzed
+    FileError {
+        path: String,
+        kind: DiagnosticKind,
+        message: String,
+    },
+    Summary(ScanSummary),
+}
+
+impl From<&ScanDiagnostic> for ScanEvent {
+    fn from(diagnostic: &ScanDiagnostic) -> Self {
+        ScanEvent::FileError {
+            path: diagnostic.path.clone(),
+            kind: diagnostic.kind,
+            message: diagnostic.message.clone(),
+        }
+    }
+}
+
+/// Serialize an event or log record as a single JSON line (no trailing newline)
+pub fn to_json_line<T: Serialize>(value: &T) -> Result<String, serde_json::Error> {
+    serde_json::to_string(value)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_event_lines() {
+        let audit = crate::analyze("h := md5.New()", "go").unwrap();
+        let events = vec![
+            ScanEvent::FileStarted {
+                path: "main.go".to_string(),
+                language: Language::Go,
+            },
+            ScanEvent::Finding {
+                path: "main.go".to_string(),
+                finding: Box::new(audit.vulnerabilities[0].clone()),
+            },
+            ScanEvent::from(&ScanDiagnostic {
+                path: "blob.go".to_string(),
+                kind: DiagnosticKind::Binary,
+                message: "appears to be binary".to_string(),
+            }),
+            ScanEvent::Summary(ScanSummary {
+                target: ".".to_string(),
+                files_scanned: 1,
+                files_skipped: 1,
+                total_vulnerabilities: 1,
+                critical_count: 1,
+                ..Default::default()
+            }),
+        ];
+
+        let lines: Vec<String> = events.iter().map(|e| to_json_line(e).unwrap()).collect();
+        assert!(lines.iter().all(|line| !line.contains('\n')));
+        assert!(lines[0].starts_with("{\"event\":\"file-started\""));
+        assert!(lines[2].contains("\"kind\":\"binary\""));
+        assert!(!lines[3].contains("reports"));
+
+        let parsed: ScanEvent = serde_json::from_str(&lines[1]).unwrap();
+        assert!(
+            matches!(parsed, ScanEvent::Finding { finding, .. } if finding.crypto_type == CryptoType::Md5)
+        );
+
+        let revised = to_json_line(&ScanEvent::FindingsRevised {
+            path: "main.go".to_string(),
+            findings: audit.vulnerabilities.clone(),
+        })
+        .unwrap();
+        assert!(revised.starts_with("{\"event\":\"findings-revised\""));
+
+        assert!(LogLevel::Error < LogLevel::Debug);
+        assert_eq!(LogLevel::from_string("WARNING"), Some(LogLevel::Warn));
+        let record = to_json_line(&LogRecord::new(LogLevel::Warn, "skipped")).unwrap();
+        assert!(record.contains("\"level\":\"warn\""));
+    }
+}
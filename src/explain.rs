@@ -0,0 +1,598 @@
+//! Explain mode
+//!
+//! Re-runs detection on one line of a file with tracing, to show why a
+//! finding was (or was not) produced: every rule match and its span, how
+//! overlapping matches were resolved, where the key size came from, which
+//! suppressions were considered, how `score_vulnerability` arrives at the risk
+//! score, and which SC-13 / ITSG-33 controls the finding bears on.
+
+use crate::audit::{rsa_key_size, score_vulnerability, severity_for_score, trace_line};
+use crate::catalog::{CccsMapping, category, cccs_mapping, nist_controls};
+use crate::semgrep::CustomRule;
+use crate::tls_misuse::HOSTNAME_RISK_SCORE;
+use crate::types::*;
+use serde::Serialize;
+use thiserror::Error;
+
+#[derive(Error, Debug)]
+pub enum ExplainError {
+    #[error("Line {0} is out of range (file has {1} lines)")]
+    LineOutOfRange(usize, usize),
+}
+
+/// What became of a rule match
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+#[serde(tag = "outcome", rename_all = "kebab-case")]
+pub enum MatchOutcome {
+    /// Became a finding
+    Reported,
+    /// Part of a longer identifier (`universal`, `DESCRIPTION`)
+    NotAtTokenBoundary,
+    /// A more specific or longer match claimed the span (ECDSA over DSA)
+    Overlapped { by: CryptoType },
+    /// A curve name on a line that uses ECDH names the key exchange curve
+    CurveOfKeyExchange,
+    /// The algorithm is reported once per line, at its most confident occurrence
+    LessConfidentDuplicate,
+    /// An imported rule reported the same algorithm over this span
+    ReplacedByCustomRule { rule_id: String },
+}
+
+/// One rule match on the explained line
+#[derive(Debug, Clone, Serialize)]
+pub struct MatchTrace {
+    pub rule_id: String,
+    /// Regex (built-in) or Semgrep pattern (custom) that matched
+    pub pattern: String,
+    pub crypto_type: CryptoType,
+    /// Byte span of the match in the line
+    pub start: usize,
+    pub end: usize,
+    pub text: String,
+    /// Construct the match was classified as (reported matches only)
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub match_kind: Option<MatchKind>,
+    #[serde(flatten)]
+    pub outcome: MatchOutcome,
+}
+
+/// How a finding's key size was determined
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum KeySizeMethod {
+    /// Literal following the algorithm name on the line
+    Literal,
+    /// Number bound to a custom rule metavariable
+    Metavariable,
+    /// Constant defined elsewhere, resolved by symbol resolution
+    Constant,
+    /// The algorithm takes a key size but none was found
+    NotFound,
+    /// The algorithm is scored without a key size
+    NotApplicable,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct KeySizeResolution {
+    pub key_size: Option<u32>,
+    pub method: KeySizeMethod,
+    pub detail: String,
+}
+
+/// Risk score and severity of a finding, with the `score_vulnerability` branch taken
+#[derive(Debug, Clone, Serialize)]
+pub struct ScoreExplanation {
+    /// Score recorded on the finding
+    pub risk_score: u32,
+    /// `score_vulnerability(crypto_type, key_size)`
+    pub computed_score: u32,
+    pub rule: String,
+    pub severity: Severity,
+    pub severity_source: String,
+    pub confidence: Confidence,
+    pub match_kind: MatchKind,
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub notes: Vec<String>,
+}
+
+/// A suppression the finding was checked against
+#[derive(Debug, Clone, Serialize)]
+pub struct SuppressionCheck {
+    pub name: String,
+    /// Whether it removes the finding from scan results
+    pub applied: bool,
+    pub detail: String,
+}
+
+/// Controls a finding is assessed under
+#[derive(Debug, Clone, Serialize)]
+pub struct ControlMapping {
+    /// `quantum-vulnerable` or `deprecated-algorithm`
+    pub category: String,
+    pub nist_controls: Vec<String>,
+    pub itsg33_controls: Vec<String>,
+    pub cccs: CccsMapping,
+    /// Effect on the SC-13 implementation and assessment status
+    pub sc13_effect: String,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct FindingExplanation {
+    pub rule_id: String,
+    pub finding: Vulnerability,
+    pub key_size: KeySizeResolution,
+    pub score: ScoreExplanation,
+    pub suppressions: Vec<SuppressionCheck>,
+    pub controls: ControlMapping,
+}
+
+/// Why the findings of one line were produced
+#[derive(Debug, Clone, Serialize)]
+pub struct LineExplanation {
+    pub file_path: String,
+    pub line: usize,
+    pub language: Language,
+    pub source: String,
+    pub matches: Vec<MatchTrace>,
+    pub findings: Vec<FindingExplanation>,
+}
+
+/// Explain the findings of `line` (1-based) in an analyzed file
+///
+/// `file.audit` must come from a scan with the same `rules` (and, for key
+/// sizes resolved from constants, after symbol resolution); findings are
+/// explained as reported, before confidence filtering.
+pub fn explain_line(
+    file: &ProjectFile,
+    line: usize,
+    rules: &[CustomRule],
+    min_confidence: Confidence,
+) -> Result<LineExplanation, ExplainError> {
+    let line_count = file.source.lines().count();
+    let text = line
+        .checked_sub(1)
+        .and_then(|index| file.source.lines().nth(index))
+        .ok_or(ExplainError::LineOutOfRange(line, line_count))?;
+
+    let applicable: Vec<&CustomRule> = rules
+        .iter()
+        .filter(|rule| rule.applies_to(file.audit.language))
+        .collect();
+
+    let mut matches = trace_line(text);
+    let custom: Vec<MatchTrace> = applicable
+        .iter()
+        .filter_map(|rule| {
+            let found = rule.detect_line(text, line)?;
+            Some(MatchTrace {
+                rule_id: rule.id.clone(),
+                pattern: rule
+                    .patterns
+                    .iter()
+                    .map(|p| p.source.as_str())
+                    .collect::<Vec<_>>()
+                    .join(" | "),
+                crypto_type: found.crypto_type,
+                start: found.column,
+                end: found.end_column,
+                text: text[found.column..found.end_column].to_string(),
+                match_kind: Some(found.match_kind),
+                outcome: MatchOutcome::Reported,
+            })
+        })
+        .collect();
+    for built_in in matches
+        .iter_mut()
+        .filter(|m| m.outcome == MatchOutcome::Reported)
+    {
+        if let Some(rule) = custom.iter().find(|c| {
+            c.crypto_type == built_in.crypto_type
+                && c.start < built_in.end
+                && built_in.start < c.end
+        }) {
+            built_in.outcome = MatchOutcome::ReplacedByCustomRule {
+                rule_id: rule.rule_id.clone(),
+            };
+        }
+    }
+    matches.extend(custom);
+    matches.sort_by_key(|m| (m.start, m.end));
+
+    let findings = file
+        .audit
+        .vulnerabilities
+        .iter()
+        .filter(|vuln| vuln.line == line)
+        .map(|vuln| {
+            let rule = vuln
+                .rule_id
+                .as_ref()
+                .and_then(|id| applicable.iter().find(|rule| &rule.id == id).copied());
+            explain_finding(vuln, text, rule, &matches, min_confidence)
+        })
+        .collect();
+
+    Ok(LineExplanation {
+        file_path: file.path.clone(),
+        line,
+        language: file.audit.language,
+        source: text.to_string(),
+        matches,
+        findings,
+    })
+}
+
+fn explain_finding(
+    vuln: &Vulnerability,
+    line: &str,
+    rule: Option<&CustomRule>,
+    matches: &[MatchTrace],
+    min_confidence: Confidence,
+) -> FindingExplanation {
+    FindingExplanation {
+        rule_id: vuln
+            .rule_id
+            .clone()
+            .unwrap_or_else(|| vuln.crypto_type.rule_id().to_string()),
+        finding: vuln.clone(),
+        key_size: resolve_key_size(vuln, line, rule),
+        score: explain_score(vuln, rule),
+        suppressions: suppressions(vuln, matches, min_confidence),
+        controls: map_controls(vuln),
+    }
+}
+
+fn resolve_key_size(
+    vuln: &Vulnerability,
+    line: &str,
+    rule: Option<&CustomRule>,
+) -> KeySizeResolution {
+    let key_size = vuln.key_size;
+    let (method, detail) = match key_size {
+        Some(size) if !vuln.secondary_locations.is_empty() => (
+            KeySizeMethod::Constant,
+            format!(
+                "{}-bit resolved through {}",
+                size,
+                vuln.secondary_locations
+                    .iter()
+                    .map(|loc| format!("{}:{} `{}`", loc.file_path, loc.line, loc.snippet))
+                    .collect::<Vec<_>>()
+                    .join(" -> ")
+            ),
+        ),
+        Some(size) => match rule.and_then(|rule| rule.key_size_binding(line)) {
+            Some((name, _)) => (
+                KeySizeMethod::Metavariable,
+                format!(
+                    "{}-bit bound to ${} of rule {}",
+                    size,
+                    name,
+                    rule_name(vuln)
+                ),
+            ),
+            None => match rsa_key_size(line, vuln.column) {
+                Some((_, column)) => (
+                    KeySizeMethod::Literal,
+                    format!(
+                        "{}-bit literal at column {} after the RSA match",
+                        size,
+                        column + 1
+                    ),
+                ),
+                None => (KeySizeMethod::Literal, format!("{}-bit", size)),
+            },
+        },
+        None if vuln.crypto_type == CryptoType::Rsa => (
+            KeySizeMethod::NotFound,
+            "No key size of 512-8192 bits follows the match on this line and no constant \
+             resolved to one; scored as an RSA key of unknown size"
+                .to_string(),
+        ),
+        None => (
+            KeySizeMethod::NotApplicable,
+            format!("{} is scored without a key size", vuln.crypto_type),
+        ),
+    };
+
+    KeySizeResolution {
+        key_size,
+        method,
+        detail,
+    }
+}
+
+fn explain_score(vuln: &Vulnerability, rule: Option<&CustomRule>) -> ScoreExplanation {
+    let computed_score = score_vulnerability(&vuln.crypto_type, vuln.key_size);
+    let mut notes = Vec::new();
+    if computed_score != vuln.risk_score {
+        notes.push(match &vuln.hash_context {
+            Some(context) => format!(
+                "Rescored from {} to {} for {} usage",
+                computed_score, vuln.risk_score, context.usage
+            ),
+            None => format!(
+                "The built-in detector assigns {} where score_vulnerability gives {}",
+                vuln.risk_score, computed_score
+            ),
+        });
+    }
+
+    let severity_source = match (&vuln.rule_id, rule.and_then(|rule| rule.severity)) {
+        (Some(id), Some(_)) => format!("Fixed by rule {}", id),
+        (Some(_), None) => "Risk score band (>= 95 critical, >= 70 high, >= 40 medium)".to_string(),
+        (None, _) => match &vuln.hash_context {
+            Some(context) => format!("Adjusted for {}", context.reason),
+            None => format!("Built-in detector severity for {}", vuln.crypto_type),
+        },
+    };
+
+    ScoreExplanation {
+        risk_score: vuln.risk_score,
+        computed_score,
+        rule: score_rule(&vuln.crypto_type, vuln.key_size),
+        severity: vuln.severity,
+        severity_source,
+        confidence: vuln.confidence,
+        match_kind: vuln.match_kind,
+        notes,
+    }
+}
+
+/// The `score_vulnerability` branch for an algorithm and key size
+///
+/// Only the conditions and reasons are spelled out here; the score and band
+/// come from `score_vulnerability` and `severity_for_score`.
+fn score_rule(crypto_type: &CryptoType, key_size: Option<u32>) -> String {
+    let (condition, reason) = match crypto_type {
+        CryptoType::Rsa => match key_size {
+            Some(size) if size < 2048 => (format!("RSA key of {} bits (< 2048)", size), ""),
+            Some(size) if size < 4096 => (format!("RSA key of {} bits (2048-4095)", size), ""),
+            Some(size) => (
+                format!("RSA key of {} bits (>= 4096)", size),
+                " (any RSA is quantum-vulnerable)",
+            ),
+            None => (
+                "RSA key of unknown size".to_string(),
+                " (any RSA is quantum-vulnerable)",
+            ),
+        },
+        CryptoType::Ecdsa | CryptoType::Ecdh => (
+            "Elliptic-curve algorithm".to_string(),
+            " (Shor's algorithm)",
+        ),
+        CryptoType::Dsa | CryptoType::DiffieHellman => {
+            (crypto_type.to_string(), " (Shor's algorithm)")
+        }
+        CryptoType::Sha1 | CryptoType::Md5 | CryptoType::Rc4 | CryptoType::Md4 => {
+            (crypto_type.to_string(), " (broken)")
+        }
+        CryptoType::Des => (crypto_type.to_string(), " (weak)"),
+        CryptoType::TripleDes => (crypto_type.to_string(), " (deprecated)"),
+        CryptoType::Ripemd160 => (crypto_type.to_string(), " (legacy, not approved)"),
+        CryptoType::Blowfish | CryptoType::Cast5 => (crypto_type.to_string(), " (64-bit block)"),
+        CryptoType::DeprecatedApi => (crypto_type.to_string(), ""),
+        CryptoType::InsecureTls => (crypto_type.to_string(), " (no peer authentication)"),
+    };
+
+    let score = score_vulnerability(crypto_type, key_size);
+    let mut rule = format!("{}: {}, {}{}", condition, score, band(score), reason);
+    if *crypto_type == CryptoType::InsecureTls {
+        rule.push_str(&format!(
+            "; {}, {} when only host names go unchecked",
+            HOSTNAME_RISK_SCORE,
+            band(HOSTNAME_RISK_SCORE)
+        ));
+    }
+    rule
+}
+
+/// `severity_for_score` as the lowercase band name
+fn band(score: u32) -> String {
+    format!("{:?}", severity_for_score(score)).to_lowercase()
+}
+
+fn suppressions(
+    vuln: &Vulnerability,
+    matches: &[MatchTrace],
+    min_confidence: Confidence,
+) -> Vec<SuppressionCheck> {
+    let replaced: Vec<&str> = matches
+        .iter()
+        .filter(|m| m.crypto_type == vuln.crypto_type)
+        .filter_map(|m| match &m.outcome {
+            MatchOutcome::ReplacedByCustomRule { .. } => Some(m.rule_id.as_str()),
+            _ => None,
+        })
+        .collect();
+
+    vec![
+        SuppressionCheck {
+            name: "min-confidence".to_string(),
+            applied: vuln.confidence < min_confidence,
+            detail: format!(
+                "{} confidence ({:?} match) against a threshold of {}",
+                vuln.confidence, vuln.match_kind, min_confidence
+            ),
+        },
+        SuppressionCheck {
+            name: "custom-rule-override".to_string(),
+            applied: false,
+            detail: match (&vuln.rule_id, replaced.as_slice()) {
+                (Some(id), []) => format!("Reported by rule {}", id),
+                (Some(id), built_in) => format!(
+                    "Reported by rule {}, replacing built-in {}",
+                    id,
+                    built_in.join(", ")
+                ),
+                (None, _) => {
+                    "No imported rule matched this algorithm over the same span".to_string()
+                }
+            },
+        },
+        SuppressionCheck {
+            name: "inline-waiver".to_string(),
+            applied: false,
+            detail: "Inline suppression comments and waiver files are not supported; \
+                     findings are only removed by the checks above"
+                .to_string(),
+        },
+    ]
+}
+
+fn map_controls(vuln: &Vulnerability) -> ControlMapping {
+    let nist = nist_controls(&vuln.crypto_type);
+    let mut sc13_effect = match vuln.severity {
+        Severity::Critical => {
+            "Critical finding: SC-13 is partially implemented and not satisfied".to_string()
+        }
+        Severity::High => "High finding: SC-13 is partially implemented, and not satisfied \
+                           once more than 5 high findings are reported"
+            .to_string(),
+        Severity::Medium | Severity::Low => {
+            "Reported for review; does not change the SC-13 implementation status".to_string()
+        }
+    };
+    if vuln.key_size.is_some_and(|size| size < 2048) {
+        sc13_effect.push_str("; listed as a weak key size (< 2048 bits)");
+    }
+    if let Some(context) = vuln.hash_context.as_ref().filter(|c| c.compliance_exempt) {
+        sc13_effect = format!(
+            "{} use: not listed as a deprecated algorithm under SC-13 or ITSP.40.111",
+            context.usage
+        );
+    }
+    if vuln.crypto_type == CryptoType::InsecureTls {
+        sc13_effect = "Assessed under SC-8 and SC-23 (not satisfied); does not change the \
+                       SC-13 implementation status"
+            .to_string();
+    }
+
+    ControlMapping {
+        category: category(&vuln.crypto_type).to_string(),
+        itsg33_controls: nist.iter().map(|c| format!("ITSG-33 {}", c)).collect(),
+        nist_controls: nist,
+        cccs: cccs_mapping(&vuln.crypto_type),
+        sc13_effect,
+    }
+}
+
+fn rule_name(vuln: &Vulnerability) -> &str {
+    vuln.rule_id.as_deref().unwrap_or("(built-in)")
+}
+
+/// Export an explanation as JSON
+pub fn export_explanation_json(explanation: &LineExplanation) -> Result<String, serde_json::Error> {
+    serde_json::to_string_pretty(explanation)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::semgrep::import_semgrep_rules;
+    use crate::{analyze_with_rules, project_file};
+
+    #[test]
+    fn test_score_rule_matches_score_vulnerability() {
+        let types = [
+            CryptoType::Rsa,
+            CryptoType::Ecdsa,
+            CryptoType::Ecdh,
+            CryptoType::Dsa,
+            CryptoType::DiffieHellman,
+            CryptoType::Sha1,
+            CryptoType::Md5,
+            CryptoType::Des,
+            CryptoType::TripleDes,
+            CryptoType::Rc4,
+            CryptoType::Md4,
+            CryptoType::Ripemd160,
+            CryptoType::Blowfish,
+            CryptoType::Cast5,
+            CryptoType::DeprecatedApi,
+            CryptoType::InsecureTls,
+        ];
+        for crypto_type in &types {
+            for key_size in [None, Some(512), Some(1024), Some(2048), Some(4096)] {
+                let score = score_vulnerability(crypto_type, key_size);
+                let severity = format!("{:?}", severity_for_score(score)).to_lowercase();
+                let rule = score_rule(crypto_type, key_size);
+                assert!(
+                    rule.contains(&format!(": {}, {}", score, severity)),
+                    "{:?} {:?}: {}",
+                    crypto_type,
+                    key_size,
+                    rule
+                );
+            }
+        }
+    }
+
+    #[test]
+    fn test_explain_builtin_finding() {
+        let source =
+            "package main\nsig := \"SHA1withDSA\"\nk, _ := rsa.GenerateKey(rand.Reader, 1024)\n";
+        let file = project_file("main.go", source, "go");
+
+        let explanation = explain_line(&file, 2, &[], Confidence::Low).unwrap();
+        assert!(
+            explanation
+                .matches
+                .iter()
+                .any(|m| m.crypto_type == CryptoType::Dsa && m.outcome == MatchOutcome::Reported)
+        );
+
+        let explanation = explain_line(&file, 3, &[], Confidence::High).unwrap();
+        assert_eq!(explanation.findings.len(), 1);
+        let rsa = &explanation.findings[0];
+        assert_eq!(rsa.rule_id, "pqc-rsa");
+        assert_eq!(rsa.key_size.method, KeySizeMethod::Literal);
+        assert_eq!(rsa.score.computed_score, 100);
+        assert!(!rsa.suppressions[0].applied);
+        assert_eq!(rsa.controls.nist_controls, vec!["SC-13", "SC-12"]);
+        assert!(rsa.controls.sc13_effect.contains("weak key size"));
+
+        assert!(matches!(
+            explain_line(&file, 9, &[], Confidence::Low),
+            Err(ExplainError::LineOutOfRange(9, 3))
+        ));
+    }
+
+    #[test]
+    fn test_explain_dropped_matches_and_custom_rule() {
+        let file = project_file(
+            "main.js",
+            "const ecdh = crypto.createECDH('prime256v1')",
+            "javascript",
+        );
+        let explanation = explain_line(&file, 1, &[], Confidence::Low).unwrap();
+        assert!(
+            explanation
+                .matches
+                .iter()
+                .any(|m| m.text == "prime256v1" && m.outcome == MatchOutcome::CurveOfKeyExchange)
+        );
+
+        let import = import_semgrep_rules(
+            "rules:\n  - id: weak-rsa\n    languages: [go]\n    message: weak RSA\n    pattern: rsa.GenerateKey($R, $BITS)\n",
+        )
+        .unwrap();
+        let source = "k, _ := rsa.GenerateKey(rand.Reader, 2048)";
+        let mut file = project_file("main.go", source, "go");
+        file.audit = analyze_with_rules(source, "go", &import.rules).unwrap();
+        let explanation = explain_line(&file, 1, &import.rules, Confidence::Low).unwrap();
+        assert!(explanation.matches.iter().any(|m| m.outcome
+            == MatchOutcome::ReplacedByCustomRule {
+                rule_id: "weak-rsa".to_string()
+            }));
+        let finding = &explanation.findings[0];
+        assert_eq!(finding.rule_id, "weak-rsa");
+        assert_eq!(finding.key_size.method, KeySizeMethod::Metavariable);
+        assert!(finding.key_size.detail.contains("$BITS"));
+        assert!(
+            finding.suppressions[1]
+                .detail
+                .contains("replacing built-in pqc-rsa")
+        );
+    }
+}
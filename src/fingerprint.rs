@@ -0,0 +1,107 @@
+//! Stable finding fingerprints
+//!
+//! A fingerprint identifies a finding across scans while the code around it
+//! moves: it covers the algorithm, the rule that reported it and the trimmed
+//! source line, not the line or column. Identical findings in one file are
+//! told apart by their order of appearance. The hash is FNV-1a (64-bit),
+//! written out here so the value does not change with the Rust release.
+
+use crate::types::*;
+use std::collections::HashMap;
+
+const FNV_OFFSET_BASIS: u64 = 0xcbf2_9ce4_8422_2325;
+const FNV_PRIME: u64 = 0x0000_0100_0000_01b3;
+
+/// Set the fingerprint of every finding reported for one file
+pub fn assign_fingerprints(findings: &mut [Vulnerability]) {
+    let mut order: Vec<usize> = (0..findings.len()).collect();
+    order.sort_by_key(|&i| (findings[i].line, findings[i].column));
+
+    let mut occurrences: HashMap<u64, u32> = HashMap::new();
+    for i in order {
+        let key = finding_key(&findings[i]);
+        let occurrence = occurrences.entry(key).or_insert(0);
+        let hash = fnv1a(key, &occurrence.to_le_bytes());
+        *occurrence += 1;
+        findings[i].fingerprint = Some(format!("{:016x}", hash));
+    }
+}
+
+/// Whether `text` has the shape of a fingerprint (16 lowercase hex digits)
+pub fn is_fingerprint(text: &str) -> bool {
+    text.len() == 16
+        && text
+            .bytes()
+            .all(|b| b.is_ascii_digit() || (b'a'..=b'f').contains(&b))
+}
+
+fn finding_key(vuln: &Vulnerability) -> u64 {
+    let context: Vec<&str> = vuln.context.split_whitespace().collect();
+    let mut hash = FNV_OFFSET_BASIS;
+    for part in [
+        format!("{:?}", vuln.crypto_type).as_str(),
+        vuln.rule_id.as_deref().unwrap_or(""),
+        context.join(" ").as_str(),
+    ] {
+        hash = fnv1a(hash, part.as_bytes());
+        // Separator, so ("ab", "c") and ("a", "bc") differ
+        hash = fnv1a(hash, &[0]);
+    }
+    hash
+}
+
+fn fnv1a(mut hash: u64, bytes: &[u8]) -> u64 {
+    for &byte in bytes {
+        hash ^= u64::from(byte);
+        hash = hash.wrapping_mul(FNV_PRIME);
+    }
+    hash
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::analyze;
+
+    fn fingerprints(source: &str) -> Vec<(usize, String)> {
+        let audit = analyze(source, "go").unwrap();
+        audit
+            .vulnerabilities
+            .iter()
+            .map(|v| (v.line, v.fingerprint.clone().expect("fingerprint")))
+            .collect()
+    }
+
+    #[test]
+    fn test_fnv1a_reference_values() {
+        assert_eq!(fnv1a(FNV_OFFSET_BASIS, b""), 0xcbf2_9ce4_8422_2325);
+        assert_eq!(fnv1a(FNV_OFFSET_BASIS, b"a"), 0xaf63_dc4c_8601_ec8c);
+        assert_eq!(fnv1a(FNV_OFFSET_BASIS, b"foobar"), 0x8594_4171_f739_67e8);
+    }
+
+    #[test]
+    fn test_fingerprints_survive_moves_and_tell_duplicates_apart() {
+        let before = fingerprints("package main\nh = md5.New()\nh = md5.New()\n");
+        let after = fingerprints("package main\n\n// hashes\n    h = md5.New()\nh  =  md5.New()\n");
+
+        assert_eq!(before.len(), 2);
+        assert_ne!(before[0].1, before[1].1);
+        assert!(before.iter().all(|(_, fp)| is_fingerprint(fp)));
+        // Moved and re-indented, same order
+        assert_eq!(before[0].1, after[0].1);
+        assert_eq!(before[1].1, after[1].1);
+        assert_eq!(after[0].0, 4);
+
+        let edited = fingerprints("package main\nh = md5.New()\nk := md5.New()\n");
+        assert_eq!(before[0].1, edited[0].1);
+        assert_ne!(before[1].1, edited[1].1);
+    }
+
+    #[test]
+    fn test_is_fingerprint() {
+        assert!(is_fingerprint("0123456789abcdef"));
+        assert!(!is_fingerprint("0123456789ABCDEF"));
+        assert!(!is_fingerprint("12"));
+        assert!(!is_fingerprint("0123456789abcdeg"));
+    }
+}
@@ -0,0 +1,563 @@
+//! Go build constraints
+//!
+//! Evaluates `//go:build` lines (and legacy `// +build` lines), `_GOOS`,
+//! `_GOARCH` and `_GOOS_GOARCH` file name suffixes and `//go:build ignore`
+//! for a target platform and tag set, the way `go build` selects files.
+//! Findings in files the target build leaves out are marked with a
+//! `BuildExclusion` and lowered to low severity and confidence, so crypto in
+//! platform-specific code we don't ship no longer inflates the results while
+//! remaining visible in the inventory.
+
+use crate::types::*;
+use serde::{Deserialize, Serialize};
+use thiserror::Error;
+
+const KNOWN_OS: &[&str] = &[
+    "aix",
+    "android",
+    "darwin",
+    "dragonfly",
+    "freebsd",
+    "hurd",
+    "illumos",
+    "ios",
+    "js",
+    "linux",
+    "nacl",
+    "netbsd",
+    "openbsd",
+    "plan9",
+    "solaris",
+    "wasip1",
+    "windows",
+    "zos",
+];
+
+const KNOWN_ARCH: &[&str] = &[
+    "386",
+    "amd64",
+    "amd64p32",
+    "arm",
+    "armbe",
+    "arm64",
+    "arm64be",
+    "loong64",
+    "mips",
+    "mipsle",
+    "mips64",
+    "mips64le",
+    "mips64p32",
+    "mips64p32le",
+    "ppc",
+    "ppc64",
+    "ppc64le",
+    "riscv",
+    "riscv64",
+    "s390",
+    "s390x",
+    "sparc",
+    "sparc64",
+    "wasm",
+];
+
+/// Operating systems satisfying the `unix` tag
+const UNIX_OS: &[&str] = &[
+    "aix",
+    "android",
+    "darwin",
+    "dragonfly",
+    "freebsd",
+    "hurd",
+    "illumos",
+    "ios",
+    "linux",
+    "netbsd",
+    "openbsd",
+    "solaris",
+];
+
+#[derive(Error, Debug, Clone, PartialEq, Eq)]
+#[error("invalid build constraint {expression:?}: {message}")]
+pub struct BuildConstraintError {
+    pub expression: String,
+    pub message: String,
+}
+
+/// Platform and tags a Go build is evaluated for
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct GoBuildTarget {
+    pub goos: String,
+    pub goarch: String,
+    /// Extra build tags (`-tags`), including `cgo` when cgo is enabled
+    pub tags: Vec<String>,
+}
+
+impl Default for GoBuildTarget {
+    fn default() -> Self {
+        Self {
+            goos: "linux".to_string(),
+            goarch: "amd64".to_string(),
+            tags: Vec::new(),
+        }
+    }
+}
+
+impl GoBuildTarget {
+    /// Parse a `GOOS/GOARCH` platform such as `linux/arm64`
+    pub fn from_platform(platform: &str) -> Result<Self, String> {
+        let (goos, goarch) = platform
+            .split_once('/')
+            .ok_or_else(|| format!("Expected GOOS/GOARCH, got: {}", platform))?;
+        if !KNOWN_OS.contains(&goos) {
+            return Err(format!("Unknown GOOS: {}", goos));
+        }
+        if !KNOWN_ARCH.contains(&goarch) {
+            return Err(format!("Unknown GOARCH: {}", goarch));
+        }
+        Ok(Self {
+            goos: goos.to_string(),
+            goarch: goarch.to_string(),
+            tags: Vec::new(),
+        })
+    }
+
+    /// Add the tags of a comma-separated list such as `netgo,osusergo`
+    pub fn with_tags(mut self, list: &str) -> Self {
+        for tag in list.split(',').map(str::trim).filter(|t| !t.is_empty()) {
+            if !self.tags.iter().any(|t| t == tag) {
+                self.tags.push(tag.to_string());
+            }
+        }
+        self
+    }
+
+    /// Whether the build satisfies `tag`
+    ///
+    /// Besides GOOS, GOARCH and the extra tags, `unix`, `gc` and every
+    /// `go1.N` release tag are satisfied (the newest toolchain is assumed).
+    /// GOOS `android`, `illumos` and `ios` also satisfy `linux`, `solaris`
+    /// and `darwin`.
+    pub fn matches_tag(&self, tag: &str) -> bool {
+        self.matches_os(tag)
+            || tag == self.goarch
+            || tag == "gc"
+            || (tag == "unix" && UNIX_OS.contains(&self.goos.as_str()))
+            || tag
+                .strip_prefix("go1.")
+                .is_some_and(|minor| minor.parse::<u32>().is_ok())
+            || self.tags.iter().any(|t| t == tag)
+    }
+
+    fn matches_os(&self, os: &str) -> bool {
+        os == self.goos
+            || matches!(
+                (self.goos.as_str(), os),
+                ("android", "linux") | ("illumos", "solaris") | ("ios", "darwin")
+            )
+    }
+}
+
+impl std::fmt::Display for GoBuildTarget {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        write!(f, "{}/{}", self.goos, self.goarch)?;
+        if !self.tags.is_empty() {
+            write!(f, " ({})", self.tags.join(","))?;
+        }
+        Ok(())
+    }
+}
+
+/// A parsed `//go:build` expression
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum BuildExpr {
+    Tag(String),
+    Not(Box<BuildExpr>),
+    And(Box<BuildExpr>, Box<BuildExpr>),
+    Or(Box<BuildExpr>, Box<BuildExpr>),
+}
+
+impl BuildExpr {
+    /// Parse the expression of a `//go:build` line (`linux && (amd64 || arm64)`)
+    pub fn parse(expression: &str) -> Result<Self, BuildConstraintError> {
+        let mut parser = ExprParser {
+            expression,
+            tokens: tokenize(expression).map_err(|message| BuildConstraintError {
+                expression: expression.to_string(),
+                message,
+            })?,
+            pos: 0,
+        };
+        let expr = parser.or()?;
+        match parser.tokens.get(parser.pos) {
+            None => Ok(expr),
+            Some(token) => Err(parser.error(format!("unexpected {:?}", token))),
+        }
+    }
+
+    /// Parse the terms of a legacy `// +build` line: space-separated
+    /// alternatives of comma-separated conjunctions of (negated) tags
+    fn parse_plus_build(line: &str) -> Result<Self, BuildConstraintError> {
+        let error = |message: &str| BuildConstraintError {
+            expression: line.to_string(),
+            message: message.to_string(),
+        };
+        let mut alternatives = Vec::new();
+        for term in line.split_whitespace() {
+            let mut conjunction = Vec::new();
+            for tag in term.split(',') {
+                let expr = match tag.strip_prefix('!') {
+                    Some(tag) => BuildExpr::Not(Box::new(BuildExpr::Tag(tag.to_string()))),
+                    None => BuildExpr::Tag(tag.to_string()),
+                };
+                if tag.trim_start_matches('!').is_empty() {
+                    return Err(error("empty tag"));
+                }
+                conjunction.push(expr);
+            }
+            alternatives
+                .push(fold(conjunction, BuildExpr::And).ok_or_else(|| error("empty term"))?);
+        }
+        fold(alternatives, BuildExpr::Or).ok_or_else(|| error("empty constraint"))
+    }
+
+    pub fn eval(&self, target: &GoBuildTarget) -> bool {
+        match self {
+            BuildExpr::Tag(tag) => target.matches_tag(tag),
+            BuildExpr::Not(expr) => !expr.eval(target),
+            BuildExpr::And(left, right) => left.eval(target) && right.eval(target),
+            BuildExpr::Or(left, right) => left.eval(target) || right.eval(target),
+        }
+    }
+}
+
+fn fold(
+    exprs: Vec<BuildExpr>,
+    join: fn(Box<BuildExpr>, Box<BuildExpr>) -> BuildExpr,
+) -> Option<BuildExpr> {
+    exprs
+        .into_iter()
+        .reduce(|left, right| join(Box::new(left), Box::new(right)))
+}
+
+#[derive(Debug, Clone, PartialEq, Eq)]
+enum Token {
+    Tag(String),
+    Not,
+    And,
+    Or,
+    Open,
+    Close,
+}
+
+fn tokenize(expression: &str) -> Result<Vec<Token>, String> {
+    let mut tokens = Vec::new();
+    let mut chars = expression.chars().peekable();
+    while let Some(c) = chars.next() {
+        match c {
+            c if c.is_whitespace() => {}
+            '!' => tokens.push(Token::Not),
+            '(' => tokens.push(Token::Open),
+            ')' => tokens.push(Token::Close),
+            '&' | '|' => {
+                if chars.next() != Some(c) {
+                    return Err(format!("expected {}{}", c, c));
+                }
+                tokens.push(if c == '&' { Token::And } else { Token::Or });
+            }
+            c if c.is_alphanumeric() || c == '_' || c == '.' => {
+                let mut tag = c.to_string();
+                while let Some(&next) = chars.peek() {
+                    if !(next.is_alphanumeric() || next == '_' || next == '.') {
+                        break;
+                    }
+                    tag.push(next);
+                    chars.next();
+                }
+                tokens.push(Token::Tag(tag));
+            }
+            other => return Err(format!("unexpected character {:?}", other)),
+        }
+    }
+    Ok(tokens)
+}
+
+/// Recursive descent over `||` (lowest), `&&`, `!` and parentheses
+struct ExprParser<'a> {
+    expression: &'a str,
+    tokens: Vec<Token>,
+    pos: usize,
+}
+
+impl ExprParser<'_> {
+    fn or(&mut self) -> Result<BuildExpr, BuildConstraintError> {
+        let mut expr = self.and()?;
+        while self.eat(&Token::Or) {
+            expr = BuildExpr::Or(Box::new(expr), Box::new(self.and()?));
+        }
+        Ok(expr)
+    }
+
+    fn and(&mut self) -> Result<BuildExpr, BuildConstraintError> {
+        let mut expr = self.not()?;
+        while self.eat(&Token::And) {
+            expr = BuildExpr::And(Box::new(expr), Box::new(self.not()?));
+        }
+        Ok(expr)
+    }
+
+    fn not(&mut self) -> Result<BuildExpr, BuildConstraintError> {
+        if self.eat(&Token::Not) {
+            return Ok(BuildExpr::Not(Box::new(self.not()?)));
+        }
+        if self.eat(&Token::Open) {
+            let expr = self.or()?;
+            if !self.eat(&Token::Close) {
+                return Err(self.error("missing )".to_string()));
+            }
+            return Ok(expr);
+        }
+        match self.tokens.get(self.pos) {
+            Some(Token::Tag(tag)) => {
+                let tag = tag.clone();
+                self.pos += 1;
+                Ok(BuildExpr::Tag(tag))
+            }
+            Some(token) => Err(self.error(format!("unexpected {:?}", token))),
+            None => Err(self.error("unexpected end of expression".to_string())),
+        }
+    }
+
+    fn eat(&mut self, token: &Token) -> bool {
+        if self.tokens.get(self.pos) == Some(token) {
+            self.pos += 1;
+            true
+        } else {
+            false
+        }
+    }
+
+    fn error(&self, message: String) -> BuildConstraintError {
+        BuildConstraintError {
+            expression: self.expression.to_string(),
+            message,
+        }
+    }
+}
+
+/// Constraint of a file that the target build fails to satisfy, if any
+///
+/// The `//go:build` line takes precedence over `// +build` lines, and both
+/// are only honored in the comment header before the package clause.
+pub fn excluding_constraint(
+    file_path: &str,
+    source: &str,
+    target: &GoBuildTarget,
+) -> Result<Option<String>, BuildConstraintError> {
+    if let Some(suffix) = file_name_constraint(file_path)
+        && !suffix.iter().all(|tag| target.matches_tag(tag))
+    {
+        return Ok(Some(format!("_{}.go file name suffix", suffix.join("_"))));
+    }
+
+    let mut go_build = None;
+    let mut plus_build = Vec::new();
+    let mut in_block_comment = false;
+    for line in source.lines().map(str::trim) {
+        if in_block_comment {
+            in_block_comment = !line.contains("*/");
+            continue;
+        }
+        if line.is_empty() {
+            continue;
+        }
+        if line.starts_with("/*") {
+            in_block_comment = !line.contains("*/");
+            continue;
+        }
+        let Some(comment) = line.strip_prefix("//") else {
+            break;
+        };
+        if let Some(expression) = comment.strip_prefix("go:build ") {
+            go_build.get_or_insert(expression.trim());
+        } else if let Some(terms) = comment.trim_start().strip_prefix("+build ") {
+            plus_build.push(terms.trim());
+        }
+    }
+
+    if let Some(expression) = go_build {
+        if !BuildExpr::parse(expression)?.eval(target) {
+            return Ok(Some(format!("//go:build {}", expression)));
+        }
+        return Ok(None);
+    }
+    for terms in plus_build {
+        if !BuildExpr::parse_plus_build(terms)?.eval(target) {
+            return Ok(Some(format!("// +build {}", terms)));
+        }
+    }
+    Ok(None)
+}
+
+/// OS and/or architecture required by a `_GOOS`, `_GOARCH` or `_GOOS_GOARCH`
+/// suffix of the file name (ignoring `_test`)
+fn file_name_constraint(file_path: &str) -> Option<Vec<String>> {
+    let name = file_path.rsplit(['/', '\\']).next()?;
+    let stem = name.split('.').next()?;
+    let (_, rest) = stem.split_once('_')?;
+    let mut parts: Vec<&str> = rest.split('_').collect();
+    if parts.last() == Some(&"test") {
+        parts.pop();
+    }
+
+    let n = parts.len();
+    if n >= 2 && KNOWN_OS.contains(&parts[n - 2]) && KNOWN_ARCH.contains(&parts[n - 1]) {
+        return Some(vec![parts[n - 2].to_string(), parts[n - 1].to_string()]);
+    }
+    match parts.last() {
+        Some(last) if KNOWN_OS.contains(last) || KNOWN_ARCH.contains(last) => {
+            Some(vec![last.to_string()])
+        }
+        _ => None,
+    }
+}
+
+/// A Go file left out of the target build
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct ExcludedFile {
+    pub path: String,
+    pub constraint: String,
+    pub findings: usize,
+}
+
+/// Outcome of evaluating the build constraints of a project's Go files
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+pub struct GoBuildReport {
+    pub target: String,
+    pub excluded_files: Vec<ExcludedFile>,
+    pub excluded_findings: usize,
+    /// Files whose constraints could not be parsed (kept in the build)
+    pub invalid_constraints: Vec<String>,
+}
+
+/// Mark the findings of Go files that `target` does not build
+pub fn apply_build_constraints(files: &mut [ProjectFile], target: &GoBuildTarget) -> GoBuildReport {
+    let mut report = GoBuildReport {
+        target: target.to_string(),
+        ..Default::default()
+    };
+
+    for file in files
+        .iter_mut()
+        .filter(|f| f.audit.language == Language::Go)
+    {
+        let constraint = match excluding_constraint(&file.path, &file.source, target) {
+            Ok(Some(constraint)) => constraint,
+            Ok(None) => continue,
+            Err(e) => {
+                report
+                    .invalid_constraints
+                    .push(format!("{}: {}", file.path, e));
+                continue;
+            }
+        };
+
+        for vuln in &mut file.audit.vulnerabilities {
+            vuln.severity = Severity::Low;
+            vuln.confidence = Confidence::Low;
+            vuln.build_exclusion = Some(BuildExclusion {
+                target: report.target.clone(),
+                constraint: constraint.clone(),
+            });
+        }
+        if !file.audit.vulnerabilities.is_empty() {
+            file.audit.refresh();
+        }
+
+        report.excluded_findings += file.audit.vulnerabilities.len();
+        report.excluded_files.push(ExcludedFile {
+            path: file.path.clone(),
+            constraint,
+            findings: file.audit.vulnerabilities.len(),
+        });
+    }
+
+    report
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_build_expressions() {
+        let linux = GoBuildTarget::default();
+        let windows = GoBuildTarget::from_platform("windows/arm64").unwrap();
+
+        let expr = BuildExpr::parse("linux && (amd64 || arm64) && !purego").unwrap();
+        assert!(expr.eval(&linux));
+        assert!(!expr.eval(&linux.clone().with_tags("purego")));
+        assert!(!expr.eval(&windows));
+
+        assert!(BuildExpr::parse("unix && go1.21").unwrap().eval(&linux));
+        assert!(!BuildExpr::parse("unix").unwrap().eval(&windows));
+        assert!(BuildExpr::parse("linux &&").is_err());
+        assert!(BuildExpr::parse("linux & amd64").is_err());
+        assert!(GoBuildTarget::from_platform("linux").is_err());
+
+        let legacy = BuildExpr::parse_plus_build("linux,!cgo darwin").unwrap();
+        assert!(legacy.eval(&linux));
+        assert!(!legacy.eval(&linux.clone().with_tags("cgo")));
+    }
+
+    #[test]
+    fn test_excluded_files() {
+        let linux = GoBuildTarget::default();
+        let source = "// Copyright\n\n//go:build ignore\n\npackage main\n";
+        assert_eq!(
+            excluding_constraint("gen.go", source, &linux).unwrap(),
+            Some("//go:build ignore".to_string())
+        );
+        assert_eq!(
+            excluding_constraint("key_windows.go", "package key\n", &linux).unwrap(),
+            Some("_windows.go file name suffix".to_string())
+        );
+        assert_eq!(
+            excluding_constraint("key_darwin_arm64_test.go", "package key\n", &linux).unwrap(),
+            Some("_darwin_arm64.go file name suffix".to_string())
+        );
+        assert_eq!(
+            excluding_constraint("key_linux.go", "package key\n", &linux).unwrap(),
+            None
+        );
+        // Only the header before the package clause counts
+        let late = "package key\n\n//go:build windows\n";
+        assert_eq!(excluding_constraint("key.go", late, &linux).unwrap(), None);
+
+        let source = "//go:build windows\n\npackage key\n\nvar k, _ = rsa.GenerateKey(nil, 2048)\n";
+        let mut files = vec![crate::project_file("key.go", source, "go")];
+        assert!(files[0].audit.stats.high_count > 0);
+
+        let report = apply_build_constraints(&mut files, &linux);
+        assert_eq!(report.excluded_files.len(), 1);
+        assert_eq!(
+            report.excluded_findings,
+            files[0].audit.vulnerabilities.len()
+        );
+        assert_eq!(files[0].audit.stats.high_count, 0);
+        let vuln = &files[0].audit.vulnerabilities[0];
+        assert_eq!(vuln.severity, Severity::Low);
+        assert_eq!(
+            vuln.build_exclusion.as_ref().unwrap().constraint,
+            "//go:build windows"
+        );
+
+        let windows = GoBuildTarget::from_platform("windows/amd64").unwrap();
+        let mut files = vec![files.remove(0)];
+        files[0].audit = crate::analyze(source, "go").unwrap();
+        assert!(
+            apply_build_constraints(&mut files, &windows)
+                .excluded_files
+                .is_empty()
+        );
+    }
+}
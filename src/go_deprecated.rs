@@ -0,0 +1,302 @@
+//! Deprecated Go crypto APIs
+//!
+//! Flags deprecated standard library packages and functions (`crypto/dsa`,
+//! the low-level `crypto/elliptic` functions, legacy PEM encryption in
+//! `crypto/x509`) and the frozen `golang.org/x/crypto` legacy packages.
+//! Selectors are resolved through the file's imports, so `md4.New()` only
+//! matches when `md4` names `golang.org/x/crypto/md4`, and aliased imports
+//! are still found. Each finding carries the migration target.
+
+use crate::audit::{score_vulnerability, severity_for_score};
+use crate::go_packages::{GoImport, go_imports};
+use crate::types::*;
+use lazy_static::lazy_static;
+use regex::Regex;
+
+lazy_static! {
+    static ref SELECTOR: Regex = Regex::new(r"\b([A-Za-z_]\w*)\.([A-Z]\w*)")
+        .expect("SELECTOR: Invalid regex pattern - this is a compile-time bug");
+}
+
+/// A deprecated package; subpackages (`openpgp/armor`) are included
+pub(crate) struct DeprecatedPackage {
+    pub path: &'static str,
+    pub crypto_type: CryptoType,
+    pub message: &'static str,
+    pub recommendation: &'static str,
+}
+
+/// A deprecated function of an otherwise supported package
+struct DeprecatedFunction {
+    package: &'static str,
+    function: &'static str,
+    message: &'static str,
+    recommendation: &'static str,
+}
+
+const DEPRECATED_PACKAGES: &[DeprecatedPackage] = &[
+    DeprecatedPackage {
+        path: "crypto/dsa",
+        crypto_type: CryptoType::Dsa,
+        message: "crypto/dsa is deprecated: DSA is a legacy signature algorithm and quantum-vulnerable",
+        recommendation: "Use crypto/ed25519 or crypto/ecdsa today and plan the move to ML-DSA",
+    },
+    DeprecatedPackage {
+        path: "golang.org/x/crypto/md4",
+        crypto_type: CryptoType::Md4,
+        message: "golang.org/x/crypto/md4 is deprecated: MD4 is cryptographically broken",
+        recommendation: "Use crypto/sha256 or crypto/sha3",
+    },
+    DeprecatedPackage {
+        path: "golang.org/x/crypto/ripemd160",
+        crypto_type: CryptoType::Ripemd160,
+        message: "golang.org/x/crypto/ripemd160 is deprecated: RIPEMD-160 is a legacy hash that is not NIST-approved",
+        recommendation: "Use crypto/sha256 or crypto/sha3",
+    },
+    DeprecatedPackage {
+        path: "golang.org/x/crypto/blowfish",
+        crypto_type: CryptoType::Blowfish,
+        message: "golang.org/x/crypto/blowfish is deprecated: Blowfish has a 64-bit block (Sweet32)",
+        recommendation: "Use crypto/aes with cipher.NewGCM or golang.org/x/crypto/chacha20poly1305",
+    },
+    DeprecatedPackage {
+        path: "golang.org/x/crypto/cast5",
+        crypto_type: CryptoType::Cast5,
+        message: "golang.org/x/crypto/cast5 is deprecated: CAST5 has a 64-bit block (Sweet32)",
+        recommendation: "Use crypto/aes with cipher.NewGCM or golang.org/x/crypto/chacha20poly1305",
+    },
+    DeprecatedPackage {
+        path: "golang.org/x/crypto/openpgp",
+        crypto_type: CryptoType::DeprecatedApi,
+        message: "golang.org/x/crypto/openpgp is frozen and deprecated, with legacy RSA, DSA and CAST5 support",
+        recommendation: "Use a maintained OpenPGP implementation such as github.com/ProtonMail/go-crypto, \
+            or replace it with age or crypto/mlkem-based encryption",
+    },
+    DeprecatedPackage {
+        path: "golang.org/x/crypto/bn256",
+        crypto_type: CryptoType::DeprecatedApi,
+        message: "golang.org/x/crypto/bn256 is deprecated: the BN256 curve no longer provides 128-bit security",
+        recommendation: "Use a BLS12-381 implementation such as github.com/cloudflare/circl/ecc/bls12381",
+    },
+];
+
+const DEPRECATED_FUNCTIONS: &[DeprecatedFunction] = &[
+    DeprecatedFunction {
+        package: "crypto/elliptic",
+        function: "Marshal",
+        message: "elliptic.Marshal is deprecated: low-level elliptic curve operations are unsafe",
+        recommendation: "Use crypto/ecdh (PublicKey.Bytes), and crypto/mlkem for post-quantum key exchange",
+    },
+    DeprecatedFunction {
+        package: "crypto/elliptic",
+        function: "Unmarshal",
+        message: "elliptic.Unmarshal is deprecated: low-level elliptic curve operations are unsafe",
+        recommendation: "Use crypto/ecdh (Curve.NewPublicKey), and crypto/mlkem for post-quantum key exchange",
+    },
+    DeprecatedFunction {
+        package: "crypto/elliptic",
+        function: "GenerateKey",
+        message: "elliptic.GenerateKey is deprecated: low-level elliptic curve operations are unsafe",
+        recommendation: "Use crypto/ecdh (Curve.GenerateKey) or ecdsa.GenerateKey, and crypto/mlkem for post-quantum key exchange",
+    },
+    DeprecatedFunction {
+        package: "crypto/x509",
+        function: "IsEncryptedPEMBlock",
+        message: "x509.IsEncryptedPEMBlock is deprecated: legacy PEM encryption (RFC 1423) is insecure by design",
+        recommendation: "Store keys as encrypted PKCS #8 with a modern KDF, or in a KMS or HSM",
+    },
+    DeprecatedFunction {
+        package: "crypto/x509",
+        function: "DecryptPEMBlock",
+        message: "x509.DecryptPEMBlock is deprecated: legacy PEM encryption (RFC 1423) is insecure by design",
+        recommendation: "Store keys as encrypted PKCS #8 with a modern KDF, or in a KMS or HSM",
+    },
+    DeprecatedFunction {
+        package: "crypto/x509",
+        function: "EncryptPEMBlock",
+        message: "x509.EncryptPEMBlock is deprecated: legacy PEM encryption (RFC 1423) is insecure by design",
+        recommendation: "Store keys as encrypted PKCS #8 with a modern KDF, or in a KMS or HSM",
+    },
+];
+
+/// The deprecated package `import_path` is or belongs to
+pub(crate) fn deprecated_package(import_path: &str) -> Option<&'static DeprecatedPackage> {
+    DEPRECATED_PACKAGES.iter().find(|package| {
+        import_path == package.path
+            || import_path
+                .strip_prefix(package.path)
+                .is_some_and(|rest| rest.starts_with('/'))
+    })
+}
+
+/// Import paths and `package.Function` selectors flagged for an algorithm, for the rule catalog
+pub(crate) fn deprecated_go_patterns(crypto_type: &CryptoType) -> Vec<String> {
+    let packages = DEPRECATED_PACKAGES
+        .iter()
+        .filter(|package| package.crypto_type == *crypto_type)
+        .map(|package| format!("import \"{}\"", package.path));
+    let mut patterns: Vec<String> = packages.collect();
+    if *crypto_type == CryptoType::DeprecatedApi {
+        patterns.extend(
+            DEPRECATED_FUNCTIONS
+                .iter()
+                .map(|function| format!("{}.{}", function.package, function.function)),
+        );
+    }
+    patterns
+}
+
+/// Findings for imports of deprecated packages and calls of deprecated functions
+pub(crate) fn detect_deprecated_go_apis(source: &str) -> Vec<Vulnerability> {
+    let imports = go_imports(source);
+    let mut found = Vec::new();
+
+    for import in &imports {
+        if let Some(package) = deprecated_package(&import.path) {
+            let line = source.lines().nth(import.line - 1).unwrap_or_default();
+            found.push(finding(
+                &package.crypto_type,
+                package.message,
+                package.recommendation,
+                line,
+                import.line,
+                (import.start, import.end),
+                MatchKind::Import,
+            ));
+        }
+    }
+
+    // Dot and blank imports have no selector to resolve
+    let named: Vec<&GoImport> = imports
+        .iter()
+        .filter(|import| !matches!(import.alias.as_deref(), Some("_" | ".")))
+        .collect();
+    if named.is_empty() {
+        return found;
+    }
+
+    for (idx, line) in source.lines().enumerate() {
+        if line.trim_start().starts_with("//") || imports.iter().any(|i| i.line == idx + 1) {
+            continue;
+        }
+        for captures in SELECTOR.captures_iter(line) {
+            let Some(import) = named.iter().find(|i| i.name() == &captures[1]) else {
+                continue;
+            };
+            let Some(function) = DEPRECATED_FUNCTIONS
+                .iter()
+                .find(|f| f.package == import.path && f.function == &captures[2])
+            else {
+                continue;
+            };
+            let span = captures.get(0).expect("selector match");
+            found.push(finding(
+                &CryptoType::DeprecatedApi,
+                function.message,
+                function.recommendation,
+                line,
+                idx + 1,
+                (span.start(), span.end()),
+                MatchKind::ApiCall,
+            ));
+        }
+    }
+
+    found
+}
+
+fn finding(
+    crypto_type: &CryptoType,
+    message: &str,
+    recommendation: &str,
+    line: &str,
+    line_num: usize,
+    (start, end): (usize, usize),
+    match_kind: MatchKind,
+) -> Vulnerability {
+    let risk_score = score_vulnerability(crypto_type, None);
+    Vulnerability {
+        end_column: end,
+        match_kind,
+        confidence: match_kind.confidence(),
+        context: line.trim().to_string(),
+        message: message.to_string(),
+        recommendation: recommendation.to_string(),
+        ..Vulnerability::new(
+            crypto_type.clone(),
+            severity_for_score(risk_score),
+            risk_score,
+            line_num,
+            start,
+        )
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::analyze;
+
+    #[test]
+    fn test_deprecated_go_apis() {
+        let source = r#"package legacy
+
+import (
+	"crypto/elliptic"
+	"crypto/x509"
+	ossl "golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/md4"
+	bf "golang.org/x/crypto/blowfish"
+)
+
+func legacy(block *pem.Block) {
+	priv, x, y, _ := elliptic.GenerateKey(elliptic.P256(), rand.Reader)
+	_ = elliptic.Marshal(elliptic.P256(), x, y)
+	if x509.IsEncryptedPEMBlock(block) {
+	}
+	_ = md4.New()
+	c, _ := bf.NewCipher(priv)
+}
+"#;
+        let found = detect_deprecated_go_apis(source);
+        let at = |line: usize| -> Vec<CryptoType> {
+            found
+                .iter()
+                .filter(|v| v.line == line)
+                .map(|v| v.crypto_type.clone())
+                .collect()
+        };
+        assert_eq!(at(6), vec![CryptoType::DeprecatedApi]);
+        assert_eq!(at(7), vec![CryptoType::Md4]);
+        assert_eq!(at(8), vec![CryptoType::Blowfish]);
+        assert_eq!(at(12), vec![CryptoType::DeprecatedApi]);
+        assert_eq!(at(13), vec![CryptoType::DeprecatedApi]);
+        assert_eq!(at(14), vec![CryptoType::DeprecatedApi]);
+        // Uses of deprecated packages are reported at the import
+        assert!(at(16).is_empty() && at(17).is_empty());
+
+        let marshal = found.iter().find(|v| v.line == 13).unwrap();
+        assert_eq!(marshal.match_kind, MatchKind::ApiCall);
+        assert!(marshal.recommendation.contains("crypto/ecdh"));
+
+        // An unrelated package of the same name is not flagged
+        let other =
+            "package p\n\nimport elliptic \"example.com/curves\"\n\nvar b = elliptic.Marshal(c)\n";
+        assert!(detect_deprecated_go_apis(other).is_empty());
+    }
+
+    #[test]
+    fn test_deprecated_package_replaces_builtin_finding() {
+        let source = "package p\n\nimport \"crypto/dsa\"\n\nvar params dsa.Parameters\n";
+        let audit = analyze(source, "go").unwrap();
+        let import: Vec<&Vulnerability> = audit
+            .vulnerabilities
+            .iter()
+            .filter(|v| v.line == 3)
+            .collect();
+        assert_eq!(import.len(), 1);
+        assert_eq!(import[0].crypto_type, CryptoType::Dsa);
+        assert!(import[0].message.contains("deprecated"));
+        assert_eq!(import[0].match_kind, MatchKind::Import);
+    }
+}
@@ -0,0 +1,471 @@
+//! Go package aggregation
+//!
+//! Maps each `.go` file to the import path of its package (module path from
+//! `go.mod` plus the directory below the module root) and reports findings
+//! and crypto inventory per package. Imports of quantum-vulnerable or
+//! deprecated standard library packages are reported as direct, or as
+//! transitive when they are reached through other packages of the scanned
+//! modules, with the chain of packages that leads to them.
+
+use crate::go_deprecated::deprecated_package;
+use crate::projects::go_module_path;
+use crate::types::*;
+use lazy_static::lazy_static;
+use regex::Regex;
+use serde::{Deserialize, Serialize};
+use std::collections::{BTreeMap, HashMap, VecDeque};
+
+lazy_static! {
+    static ref IMPORT_DECL: Regex = Regex::new(r#"^\s*import\s+(?:([\w.]+)\s+)?"([^"]+)""#)
+        .expect("IMPORT_DECL: Invalid regex pattern - this is a compile-time bug");
+    static ref IMPORT_BLOCK: Regex = Regex::new(r"^\s*import\s*\(\s*$")
+        .expect("IMPORT_BLOCK: Invalid regex pattern - this is a compile-time bug");
+    static ref IMPORT_SPEC: Regex = Regex::new(r#"^\s*(?:([\w.]+)\s+)?"([^"]+)""#)
+        .expect("IMPORT_SPEC: Invalid regex pattern - this is a compile-time bug");
+}
+
+/// Standard library packages whose use is reported, with the algorithm they provide
+///
+/// Deprecated `golang.org/x/crypto` packages are reported from the deprecated API table.
+const VULNERABLE_STDLIB: &[(&str, CryptoType)] = &[
+    ("crypto/rsa", CryptoType::Rsa),
+    ("crypto/ecdsa", CryptoType::Ecdsa),
+    ("crypto/elliptic", CryptoType::Ecdsa),
+    ("crypto/ecdh", CryptoType::Ecdh),
+    ("crypto/dsa", CryptoType::Dsa),
+    ("crypto/md5", CryptoType::Md5),
+    ("crypto/sha1", CryptoType::Sha1),
+    ("crypto/des", CryptoType::Des),
+    ("crypto/rc4", CryptoType::Rc4),
+];
+
+/// A Go module found in the tree
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct GoModule {
+    /// Module path declared by `go.mod`
+    pub path: String,
+    /// Directory of the `go.mod` relative to the scan root, `/`-separated; empty for the root
+    pub root: String,
+}
+
+impl GoModule {
+    /// Module declared by a `go.mod` in directory `root`; `None` without a `module` line
+    pub fn from_go_mod(root: &str, content: &str) -> Option<Self> {
+        Some(Self {
+            path: go_module_path(content)?,
+            root: root.replace('\\', "/").trim_matches('/').to_string(),
+        })
+    }
+
+    /// Import path of the package in `dir` (relative to the scan root), if inside this module
+    fn import_path(&self, dir: &str) -> Option<String> {
+        if self.root.is_empty() {
+            return Some(join_import_path(&self.path, dir));
+        }
+        match dir.strip_prefix(&self.root) {
+            Some("") => Some(self.path.clone()),
+            Some(rest) => rest
+                .strip_prefix('/')
+                .map(|rest| join_import_path(&self.path, rest)),
+            None => None,
+        }
+    }
+}
+
+/// Import path of the package containing `file_path`, from the deepest enclosing module
+pub fn import_path_for(modules: &[GoModule], file_path: &str) -> Option<String> {
+    let file_path = file_path.replace('\\', "/");
+    let dir = file_path.rsplit_once('/').map_or("", |(dir, _)| dir);
+    modules
+        .iter()
+        .filter_map(|module| Some((module.root.len(), module.import_path(dir)?)))
+        .max_by_key(|(depth, _)| *depth)
+        .map(|(_, path)| path)
+}
+
+fn join_import_path(module: &str, dir: &str) -> String {
+    if dir.is_empty() {
+        module.to_string()
+    } else {
+        format!("{}/{}", module, dir)
+    }
+}
+
+/// An import declaration of a Go source
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub(crate) struct GoImport {
+    pub path: String,
+    /// Explicit package name: an alias, `_` or `.`
+    pub alias: Option<String>,
+    pub line: usize,
+    /// Byte range of the quoted path in the line
+    pub start: usize,
+    pub end: usize,
+}
+
+impl GoImport {
+    /// Name the package is referred to by: the alias, or the last path
+    /// element (before a `/vN` major version suffix)
+    pub fn name(&self) -> &str {
+        if let Some(alias) = &self.alias {
+            return alias;
+        }
+        let mut elements = self.path.rsplit('/');
+        let last = elements.next().unwrap_or(&self.path);
+        let is_version = last.len() > 1
+            && last.starts_with('v')
+            && last[1..].bytes().all(|b| b.is_ascii_digit());
+        match elements.next() {
+            Some(previous) if is_version => previous,
+            _ => last,
+        }
+    }
+}
+
+/// Import declarations of a Go source, single and grouped
+pub(crate) fn go_imports(source: &str) -> Vec<GoImport> {
+    let mut imports = Vec::new();
+    let mut in_block = false;
+
+    for (idx, line) in source.lines().enumerate() {
+        let captures = if in_block {
+            if line.trim_start().starts_with(')') {
+                in_block = false;
+                continue;
+            }
+            IMPORT_SPEC.captures(line)
+        } else if IMPORT_BLOCK.is_match(line) {
+            in_block = true;
+            continue;
+        } else {
+            IMPORT_DECL.captures(line)
+        };
+
+        if let Some(captures) = captures {
+            let path = captures.get(2).expect("import path group");
+            imports.push(GoImport {
+                path: path.as_str().to_string(),
+                alias: captures.get(1).map(|alias| alias.as_str().to_string()),
+                line: idx + 1,
+                start: path.start() - 1,
+                end: path.end() + 1,
+            });
+        }
+    }
+
+    imports
+}
+
+/// A finding located in a package file
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct PackageFinding {
+    pub file_path: String,
+    pub line: usize,
+    pub crypto_type: CryptoType,
+    pub severity: Severity,
+}
+
+/// Findings of one algorithm in a package
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct InventoryEntry {
+    pub crypto_type: CryptoType,
+    pub count: usize,
+}
+
+/// A vulnerable standard library package reached through other scanned packages
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct TransitiveImport {
+    pub package: String,
+    pub crypto_type: CryptoType,
+    /// Scanned packages from the direct import to the one importing `package`
+    pub via: Vec<String>,
+}
+
+/// A vulnerable standard library package imported by the package itself
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct DirectImport {
+    pub package: String,
+    pub crypto_type: CryptoType,
+}
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct GoPackage {
+    pub import_path: String,
+    /// Name from the `package` clause of its first file
+    pub name: String,
+    pub files: Vec<String>,
+    pub findings: Vec<PackageFinding>,
+    pub inventory: Vec<InventoryEntry>,
+    pub risk_score: u32,
+    /// Other scanned packages it imports
+    pub internal_imports: Vec<String>,
+    pub direct_imports: Vec<DirectImport>,
+    pub transitive_imports: Vec<TransitiveImport>,
+}
+
+impl GoPackage {
+    /// Whether the package uses vulnerable crypto itself or through its imports
+    pub fn is_affected(&self) -> bool {
+        !self.findings.is_empty()
+            || !self.direct_imports.is_empty()
+            || !self.transitive_imports.is_empty()
+    }
+}
+
+/// Packages of the scanned Go modules, in import path order
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct GoPackageReport {
+    pub modules: Vec<GoModule>,
+    pub packages: Vec<GoPackage>,
+    /// Go files outside every module
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub unmapped_files: Vec<String>,
+}
+
+/// Group the Go files of a project into packages of `modules`
+pub fn analyze_go_packages(modules: &[GoModule], files: &[ProjectFile]) -> GoPackageReport {
+    let mut packages: BTreeMap<String, GoPackage> = BTreeMap::new();
+    let mut imports: HashMap<String, Vec<String>> = HashMap::new();
+    let mut unmapped_files = Vec::new();
+
+    for file in files.iter().filter(|f| f.audit.language == Language::Go) {
+        let Some(import_path) = import_path_for(modules, &file.path) else {
+            unmapped_files.push(file.path.clone());
+            continue;
+        };
+
+        let package = packages
+            .entry(import_path.clone())
+            .or_insert_with(|| GoPackage {
+                import_path: import_path.clone(),
+                name: package_clause(&file.source).unwrap_or_default(),
+                files: Vec::new(),
+                findings: Vec::new(),
+                inventory: Vec::new(),
+                risk_score: 0,
+                internal_imports: Vec::new(),
+                direct_imports: Vec::new(),
+                transitive_imports: Vec::new(),
+            });
+        package.files.push(file.path.clone());
+        package.findings.extend(
+            file.audit
+                .vulnerabilities
+                .iter()
+                .map(|vuln| PackageFinding {
+                    file_path: file.path.clone(),
+                    line: vuln.line,
+                    crypto_type: vuln.crypto_type.clone(),
+                    severity: vuln.severity,
+                }),
+        );
+        package.risk_score = package.risk_score.max(file.audit.risk_score);
+
+        let package_imports = imports.entry(import_path).or_default();
+        for import in &file.parsed.imports {
+            if !package_imports.contains(import) {
+                package_imports.push(import.clone());
+            }
+        }
+    }
+
+    for (path, package) in packages.iter_mut() {
+        let own = imports.get(path).map(Vec::as_slice).unwrap_or_default();
+        package.direct_imports = own.iter().filter_map(|i| vulnerable(i)).collect();
+        package.internal_imports = own
+            .iter()
+            .filter(|import| *import != path && imports.contains_key(*import))
+            .cloned()
+            .collect();
+        package.internal_imports.sort();
+
+        for finding in &package.findings {
+            match package
+                .inventory
+                .iter_mut()
+                .find(|entry| entry.crypto_type == finding.crypto_type)
+            {
+                Some(entry) => entry.count += 1,
+                None => package.inventory.push(InventoryEntry {
+                    crypto_type: finding.crypto_type.clone(),
+                    count: 1,
+                }),
+            }
+        }
+    }
+
+    let transitive: Vec<(String, Vec<TransitiveImport>)> = packages
+        .keys()
+        .map(|path| (path.clone(), transitive_imports(path, &imports)))
+        .collect();
+    for (path, found) in transitive {
+        if let Some(package) = packages.get_mut(&path) {
+            package.transitive_imports = found
+                .into_iter()
+                .filter(|t| {
+                    !package
+                        .direct_imports
+                        .iter()
+                        .any(|d| d.package == t.package)
+                })
+                .collect();
+        }
+    }
+
+    GoPackageReport {
+        modules: modules.to_vec(),
+        packages: packages.into_values().collect(),
+        unmapped_files,
+    }
+}
+
+fn vulnerable(import: &str) -> Option<DirectImport> {
+    VULNERABLE_STDLIB
+        .iter()
+        .find(|(package, _)| *package == import)
+        .map(|(_, crypto_type)| crypto_type.clone())
+        .or_else(|| deprecated_package(import).map(|package| package.crypto_type.clone()))
+        .map(|crypto_type| DirectImport {
+            package: import.to_string(),
+            crypto_type,
+        })
+}
+
+/// Vulnerable packages reached from `start` through other scanned packages, by shortest chain
+fn transitive_imports(
+    start: &str,
+    imports: &HashMap<String, Vec<String>>,
+) -> Vec<TransitiveImport> {
+    let mut found: Vec<TransitiveImport> = Vec::new();
+    let mut chains: HashMap<&str, Vec<String>> = HashMap::new();
+    let mut queue: VecDeque<&str> = VecDeque::new();
+    chains.insert(start, Vec::new());
+    queue.push_back(start);
+
+    while let Some(current) = queue.pop_front() {
+        let chain = chains[current].clone();
+        for import in imports.get(current).into_iter().flatten() {
+            if !imports.contains_key(import) || chains.contains_key(import.as_str()) {
+                continue;
+            }
+            let mut next = chain.clone();
+            next.push(import.clone());
+
+            for direct in imports[import].iter().filter_map(|i| vulnerable(i)) {
+                if !found.iter().any(|t| t.package == direct.package) {
+                    found.push(TransitiveImport {
+                        package: direct.package,
+                        crypto_type: direct.crypto_type,
+                        via: next.clone(),
+                    });
+                }
+            }
+            chains.insert(import, next);
+            queue.push_back(import);
+        }
+    }
+
+    found.sort_by(|a, b| a.package.cmp(&b.package));
+    found
+}
+
+fn package_clause(source: &str) -> Option<String> {
+    source.lines().find_map(|line| {
+        let name = line.trim().strip_prefix("package ")?;
+        Some(name.split_whitespace().next()?.to_string())
+    })
+}
+
+/// Export a package report as JSON
+pub fn export_go_packages_json(report: &GoPackageReport) -> Result<String, serde_json::Error> {
+    serde_json::to_string_pretty(report)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::project_file;
+
+    #[test]
+    fn test_import_paths() {
+        let modules = vec![
+            GoModule::from_go_mod("", "module acme.io/mono\n").unwrap(),
+            GoModule::from_go_mod("services/pay", "module \"acme.io/pay\"\n").unwrap(),
+        ];
+        assert!(GoModule::from_go_mod("x", "go 1.22\n").is_none());
+        assert_eq!(
+            import_path_for(&modules, "services/pay/internal/sign/sign.go").as_deref(),
+            Some("acme.io/pay/internal/sign")
+        );
+        assert_eq!(
+            import_path_for(&modules, "services/pay/main.go").as_deref(),
+            Some("acme.io/pay")
+        );
+        assert_eq!(
+            import_path_for(&modules, "services/payroll/main.go").as_deref(),
+            Some("acme.io/mono/services/payroll")
+        );
+        assert_eq!(
+            import_path_for(&modules, "main.go").as_deref(),
+            Some("acme.io/mono")
+        );
+        assert_eq!(import_path_for(&modules[1..], "tools/gen.go"), None);
+    }
+
+    #[test]
+    fn test_direct_and_transitive_imports() {
+        let modules = vec![GoModule::from_go_mod("", "module acme.io/pay\n").unwrap()];
+        let files = vec![
+            project_file(
+                "cmd/server/main.go",
+                "package main\n\nimport (\n\t\"crypto/sha1\"\n\t\"acme.io/pay/internal/api\"\n)\n",
+                "go",
+            ),
+            project_file(
+                "internal/api/api.go",
+                "package api\n\nimport \"acme.io/pay/internal/sign\"\n",
+                "go",
+            ),
+            project_file(
+                "internal/sign/sign.go",
+                "package sign\n\nimport (\n\t\"crypto/rsa\"\n\t\"crypto/sha1\"\n)\n\nfunc New() { rsa.GenerateKey(rand.Reader, 2048) }\n",
+                "go",
+            ),
+            project_file("internal/sign/sign_test.go", "package sign\n", "go"),
+        ];
+
+        let report = analyze_go_packages(&modules, &files);
+        assert_eq!(report.packages.len(), 3);
+
+        let sign = &report.packages[2];
+        assert_eq!(sign.import_path, "acme.io/pay/internal/sign");
+        assert_eq!(sign.name, "sign");
+        assert_eq!(sign.files.len(), 2);
+        assert_eq!(sign.direct_imports.len(), 2);
+        assert!(
+            sign.inventory
+                .iter()
+                .any(|e| e.crypto_type == CryptoType::Rsa)
+        );
+
+        let main = &report.packages[0];
+        assert_eq!(main.import_path, "acme.io/pay/cmd/server");
+        assert_eq!(main.internal_imports, vec!["acme.io/pay/internal/api"]);
+        assert_eq!(main.direct_imports[0].package, "crypto/sha1");
+        assert_eq!(
+            main.transitive_imports,
+            vec![TransitiveImport {
+                package: "crypto/rsa".to_string(),
+                crypto_type: CryptoType::Rsa,
+                via: vec![
+                    "acme.io/pay/internal/api".to_string(),
+                    "acme.io/pay/internal/sign".to_string()
+                ],
+            }]
+        );
+        assert!(report.packages[1].is_affected());
+
+        let json = export_go_packages_json(&report).unwrap();
+        assert!(json.contains("\"transitive_imports\""));
+    }
+}
@@ -0,0 +1,732 @@
+//! golang.org/x/crypto/ssh client and server configuration
+//!
+//! Finds `ssh.Config`, `ssh.ServerConfig` and `ssh.ClientConfig` literals
+//! and later `config.KeyExchanges = ...` assignments, resolves the
+//! `KeyExchanges`, `Ciphers`, `MACs` and `HostKeyAlgorithms` lists (string
+//! literals, `ssh.KeyExchange*` constants, local constants) and the host
+//! and client keys added from `ssh.ParsePrivateKey` or
+//! `ssh.NewSignerFromKey` signers. Each configuration becomes an ITSP.40.062
+//! `ProtocolCompliance` record stating whether the post-quantum hybrid key
+//! exchange `mlkem768x25519-sha256` is enabled. Lists built at run time
+//! are reported as unresolved rather than as lacking the hybrid.
+
+use crate::go_packages::go_imports;
+use crate::go_x509::{
+    captures_of, definition, is_identifier, key_type_of, literal_fields, split_call,
+    split_top_level, statement,
+};
+use crate::parser::closing;
+use crate::types::*;
+use lazy_static::lazy_static;
+use regex::Regex;
+use std::collections::HashMap;
+
+lazy_static! {
+    static ref KEY_PACKAGE: Regex = Regex::new(r"\b(rsa|ecdsa|ed25519|dsa)\.")
+        .expect("KEY_PACKAGE: Invalid regex pattern - this is a compile-time bug");
+    static ref KEY_FILE_HINT: Regex = Regex::new(r"(?i)(ed25519|ecdsa|rsa|dsa)")
+        .expect("KEY_FILE_HINT: Invalid regex pattern - this is a compile-time bug");
+    static ref CONFIG_LITERAL: Regex =
+        Regex::new(r"\b([A-Za-z_]\w*)\.(Config|ServerConfig|ClientConfig)\s*\{")
+            .expect("CONFIG_LITERAL: Invalid regex pattern - this is a compile-time bug");
+    static ref CONFIG_ASSIGNMENT: Regex =
+        Regex::new(r"(?m)(?:^|[^.\w])([A-Za-z_]\w*)\.(?:Config\.)?(\w+)\s*=([^=].*)$")
+            .expect("CONFIG_ASSIGNMENT: Invalid regex pattern - this is a compile-time bug");
+}
+
+const SSH_PACKAGE: &str = "golang.org/x/crypto/ssh";
+
+/// Exported algorithm name constants of golang.org/x/crypto/ssh
+const SSH_CONSTANTS: &[(&str, &str)] = &[
+    ("KeyExchangeMLKEM768X25519", "mlkem768x25519-sha256"),
+    ("KeyExchangeCurve25519", "curve25519-sha256"),
+    ("KeyExchangeECDHP256", "ecdh-sha2-nistp256"),
+    ("KeyExchangeECDHP384", "ecdh-sha2-nistp384"),
+    ("KeyExchangeECDHP521", "ecdh-sha2-nistp521"),
+    ("KeyExchangeDH1SHA1", "diffie-hellman-group1-sha1"),
+    ("KeyExchangeDH14SHA1", "diffie-hellman-group14-sha1"),
+    ("KeyExchangeDH14SHA256", "diffie-hellman-group14-sha256"),
+    ("KeyExchangeDH16SHA512", "diffie-hellman-group16-sha512"),
+    ("KeyExchangeDHGEXSHA1", "diffie-hellman-group-exchange-sha1"),
+    (
+        "KeyExchangeDHGEXSHA256",
+        "diffie-hellman-group-exchange-sha256",
+    ),
+    ("CipherAES128GCM", "aes128-gcm@openssh.com"),
+    ("CipherAES256GCM", "aes256-gcm@openssh.com"),
+    ("CipherChaCha20Poly1305", "chacha20-poly1305@openssh.com"),
+    ("CipherAES128CTR", "aes128-ctr"),
+    ("CipherAES192CTR", "aes192-ctr"),
+    ("CipherAES256CTR", "aes256-ctr"),
+    ("InsecureCipherAES128CBC", "aes128-cbc"),
+    ("InsecureCipherTripleDESCBC", "3des-cbc"),
+    ("InsecureCipherRC4", "arcfour"),
+    ("InsecureCipherRC4128", "arcfour128"),
+    ("InsecureCipherRC4256", "arcfour256"),
+    ("HMACSHA256ETM", "hmac-sha2-256-etm@openssh.com"),
+    ("HMACSHA512ETM", "hmac-sha2-512-etm@openssh.com"),
+    ("HMACSHA256", "hmac-sha2-256"),
+    ("HMACSHA512", "hmac-sha2-512"),
+    ("HMACSHA1", "hmac-sha1"),
+    ("InsecureHMACSHA196", "hmac-sha1-96"),
+    ("KeyAlgoRSA", "ssh-rsa"),
+    ("KeyAlgoRSASHA256", "rsa-sha2-256"),
+    ("KeyAlgoRSASHA512", "rsa-sha2-512"),
+    ("KeyAlgoDSA", "ssh-dss"),
+    ("KeyAlgoECDSA256", "ecdsa-sha2-nistp256"),
+    ("KeyAlgoECDSA384", "ecdsa-sha2-nistp384"),
+    ("KeyAlgoECDSA521", "ecdsa-sha2-nistp521"),
+    ("KeyAlgoSKECDSA256", "sk-ecdsa-sha2-nistp256@openssh.com"),
+    ("KeyAlgoED25519", "ssh-ed25519"),
+    ("KeyAlgoSKED25519", "sk-ssh-ed25519@openssh.com"),
+];
+
+/// Hybrid key exchanges combining a post-quantum KEM with X25519
+const PQ_HYBRID_KEX: &[&str] = &[
+    "mlkem768x25519-sha256",
+    "sntrup761x25519-sha512",
+    "sntrup761x25519-sha512@openssh.com",
+];
+
+/// Algorithms ITSP.40.062 rules out, with the severity of using them
+const WEAK_ALGORITHMS: &[(&str, &str, Severity)] = &[
+    (
+        "KeyExchanges",
+        "diffie-hellman-group1-sha1",
+        Severity::Critical,
+    ),
+    (
+        "KeyExchanges",
+        "diffie-hellman-group14-sha1",
+        Severity::High,
+    ),
+    (
+        "KeyExchanges",
+        "diffie-hellman-group-exchange-sha1",
+        Severity::High,
+    ),
+    ("Ciphers", "aes128-cbc", Severity::High),
+    ("Ciphers", "3des-cbc", Severity::Critical),
+    ("Ciphers", "arcfour", Severity::Critical),
+    ("Ciphers", "arcfour128", Severity::Critical),
+    ("Ciphers", "arcfour256", Severity::Critical),
+    ("MACs", "hmac-sha1", Severity::Medium),
+    ("MACs", "hmac-sha1-96", Severity::High),
+    ("HostKeyAlgorithms", "ssh-rsa", Severity::High),
+    ("HostKeyAlgorithms", "ssh-dss", Severity::Critical),
+];
+
+const ITSP_40_062: &str = "ITSP.40.062";
+
+/// Post-quantum hybrid key exchange of a configuration
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+enum PqHybrid {
+    Enabled,
+    Disabled,
+    /// `KeyExchanges` is unset
+    LibraryDefault,
+    /// `KeyExchanges` is only known at run time and no known element is a hybrid
+    Unresolved,
+}
+
+/// Algorithm lists and keys of one SSH configuration
+#[derive(Default)]
+struct SshConfig {
+    config_type: String,
+    line: usize,
+    column: usize,
+    context: String,
+    key_exchanges: Option<Vec<String>>,
+    ciphers: Option<Vec<String>>,
+    macs: Option<Vec<String>>,
+    host_key_algorithms: Option<Vec<String>>,
+    /// Parameters whose list is, at least in part, only known at run time
+    unresolved: Vec<&'static str>,
+    host_keys: Vec<String>,
+    client_keys: Vec<String>,
+    insecure_host_key_callback: bool,
+}
+
+/// ITSP.40.062 records for the SSH configurations of a Go source
+pub fn detect_go_ssh_configs(source: &str) -> Vec<ProtocolCompliance> {
+    let Some(ssh) = go_imports(source)
+        .into_iter()
+        .find(|import| import.path == SSH_PACKAGE)
+        .map(|import| import.name().to_string())
+    else {
+        return Vec::new();
+    };
+    let mut configs = Vec::new();
+    let mut covered_until = 0;
+    for captures in CONFIG_LITERAL.captures_iter(source) {
+        if captures[1] != *ssh {
+            continue;
+        }
+        let start = captures.get(0).expect("literal match").start();
+        let open = captures.get(0).expect("literal match").end() - 1;
+        // `Config: ssh.Config{...}` inside a server or client config
+        if start < covered_until {
+            continue;
+        }
+        let Some(close) = closing(source, open) else {
+            continue;
+        };
+        covered_until = close;
+
+        let line_start = source[..start].rfind('\n').map_or(0, |i| i + 1);
+        let line_end = source[start..]
+            .find('\n')
+            .map_or(source.len(), |i| start + i);
+        let mut config = SshConfig {
+            config_type: captures[2].to_string(),
+            line: source[..start].matches('\n').count() + 1,
+            column: start - line_start,
+            context: source[line_start..line_end].trim().to_string(),
+            ..Default::default()
+        };
+
+        let mut fields = literal_fields(&source[start..=close]);
+        if let Some(index) = fields.iter().position(|(name, _)| name == "Config") {
+            let (_, nested) = fields.remove(index);
+            fields.extend(literal_fields(&nested));
+        }
+        for (name, value) in &fields {
+            config.set_field(name, value, source, start, &ssh);
+        }
+
+        // Assignments and keys added through the config variable
+        if let Some(variable) = assigned_variable(&source[line_start..start]) {
+            config.apply_variable(&variable, source, close, &ssh);
+        }
+        configs.push(config);
+    }
+
+    configs.iter().map(SshConfig::compliance).collect()
+}
+
+/// `config` of `config := &ssh.ServerConfig{`
+fn assigned_variable(prefix: &str) -> Option<String> {
+    let prefix = prefix
+        .trim_end()
+        .strip_suffix('&')
+        .unwrap_or(prefix)
+        .trim_end();
+    let prefix = prefix
+        .strip_suffix(":=")
+        .or_else(|| prefix.strip_suffix('='))?
+        .trim_end();
+    let name = prefix.rsplit(|c: char| c.is_whitespace()).next()?;
+    is_identifier(name).then(|| name.to_string())
+}
+
+impl SshConfig {
+    fn set_field(&mut self, name: &str, value: &str, source: &str, before: usize, ssh: &str) {
+        let mut list = |parameter: &'static str| {
+            let (names, complete) = algorithm_list(value, source, before, ssh);
+            self.unresolved.retain(|p| *p != parameter);
+            if !complete {
+                self.unresolved.push(parameter);
+            }
+            Some(names)
+        };
+        match name {
+            "KeyExchanges" => self.key_exchanges = list("KeyExchanges"),
+            "Ciphers" => self.ciphers = list("Ciphers"),
+            "MACs" => self.macs = list("MACs"),
+            "HostKeyAlgorithms" => self.host_key_algorithms = list("HostKeyAlgorithms"),
+            "HostKeyCallback" => {
+                self.insecure_host_key_callback = value.contains("InsecureIgnoreHostKey")
+            }
+            "Auth" => {
+                let publickeys = format!("{}.PublicKeys(", ssh);
+                let mut rest = value;
+                while let Some(index) = rest.find(&publickeys) {
+                    let open = index + publickeys.len() - 1;
+                    let Some(close) = closing(rest, open) else {
+                        break;
+                    };
+                    for signer in split_top_level(&rest[open + 1..close], b',') {
+                        self.client_keys
+                            .push(signer_key_type(signer, source, before, ssh, 0));
+                    }
+                    rest = &rest[close..];
+                }
+            }
+            _ => {}
+        }
+    }
+
+    fn apply_variable(&mut self, variable: &str, source: &str, after: usize, ssh: &str) {
+        for captures in captures_of(&CONFIG_ASSIGNMENT, &source[after..], variable) {
+            let value = captures.get(3).expect("assignment value");
+            let position = after + value.start();
+            self.set_field(
+                &captures[2],
+                statement(source, position),
+                source,
+                position,
+                ssh,
+            );
+        }
+
+        let add_host_key = format!("{}.AddHostKey(", variable);
+        let mut offset = after;
+        while let Some(index) = source[offset..].find(&add_host_key) {
+            let open = offset + index + add_host_key.len() - 1;
+            let Some(close) = closing(source, open) else {
+                break;
+            };
+            self.host_keys.push(signer_key_type(
+                &source[open + 1..close],
+                source,
+                open,
+                ssh,
+                0,
+            ));
+            offset = close;
+        }
+    }
+
+    /// Whether the key exchange offers a post-quantum hybrid
+    fn pq_hybrid(&self) -> PqHybrid {
+        match &self.key_exchanges {
+            None => PqHybrid::LibraryDefault,
+            Some(kex)
+                if kex
+                    .iter()
+                    .any(|algorithm| PQ_HYBRID_KEX.contains(&algorithm.as_str())) =>
+            {
+                PqHybrid::Enabled
+            }
+            Some(_) if self.unresolved.contains(&"KeyExchanges") => PqHybrid::Unresolved,
+            Some(_) => PqHybrid::Disabled,
+        }
+    }
+
+    fn compliance(&self) -> ProtocolCompliance {
+        let mut configuration = HashMap::new();
+        configuration.insert("config_type".to_string(), self.config_type.clone());
+        let pq_hybrid = match self.pq_hybrid() {
+            PqHybrid::Enabled => "enabled",
+            PqHybrid::Disabled => "disabled",
+            PqHybrid::LibraryDefault => "library default",
+            PqHybrid::Unresolved => "unresolved",
+        };
+        configuration.insert("pq_hybrid_kex".to_string(), pq_hybrid.to_string());
+        if !self.unresolved.is_empty() {
+            configuration.insert("unresolved".to_string(), self.unresolved.join(","));
+        }
+        for (name, list) in [
+            ("macs", &self.macs),
+            ("host_key_algorithms", &self.host_key_algorithms),
+        ] {
+            if let Some(list) = list {
+                configuration.insert(name.to_string(), list.join(","));
+            }
+        }
+        for (name, keys) in [
+            ("host_keys", &self.host_keys),
+            ("client_keys", &self.client_keys),
+        ] {
+            if !keys.is_empty() {
+                configuration.insert(name.to_string(), keys.join(","));
+            }
+        }
+
+        let mut violations = Vec::new();
+        let mut recommendations = Vec::new();
+        match self.pq_hybrid() {
+            PqHybrid::Enabled => {}
+            PqHybrid::Disabled => violations.push(ConfigurationViolation {
+                parameter: "KeyExchanges".to_string(),
+                current_value: self.key_exchanges.as_deref().unwrap_or_default().join(","),
+                required_value: "mlkem768x25519-sha256 (post-quantum hybrid key exchange)"
+                    .to_string(),
+                itsp_reference: ITSP_40_062.to_string(),
+                severity: Severity::High,
+            }),
+            PqHybrid::LibraryDefault => recommendations.push(
+                "KeyExchanges is unset, so the golang.org/x/crypto/ssh defaults apply: \
+                 recent releases offer mlkem768x25519-sha256 first; verify the module version \
+                 or list ssh.KeyExchangeMLKEM768X25519 explicitly"
+                    .to_string(),
+            ),
+            PqHybrid::Unresolved => recommendations.push(
+                "KeyExchanges is built at run time; verify that it offers \
+                 mlkem768x25519-sha256, or list ssh.KeyExchangeMLKEM768X25519 explicitly"
+                    .to_string(),
+            ),
+        }
+
+        for (parameter, list) in [
+            ("KeyExchanges", &self.key_exchanges),
+            ("Ciphers", &self.ciphers),
+            ("MACs", &self.macs),
+            ("HostKeyAlgorithms", &self.host_key_algorithms),
+        ] {
+            for algorithm in list.iter().flatten() {
+                if let Some((_, _, severity)) = WEAK_ALGORITHMS
+                    .iter()
+                    .find(|(p, name, _)| *p == parameter && name == algorithm)
+                {
+                    violations.push(ConfigurationViolation {
+                        parameter: parameter.to_string(),
+                        current_value: algorithm.clone(),
+                        required_value: "an algorithm approved by ITSP.40.062".to_string(),
+                        itsp_reference: ITSP_40_062.to_string(),
+                        severity: *severity,
+                    });
+                }
+            }
+        }
+
+        for key in self.host_keys.iter().filter(|key| *key == "DSA") {
+            violations.push(ConfigurationViolation {
+                parameter: "HostKey".to_string(),
+                current_value: key.clone(),
+                required_value: "an Ed25519, ECDSA or RSA (3072+ bit) host key".to_string(),
+                itsp_reference: ITSP_40_062.to_string(),
+                severity: Severity::Critical,
+            });
+        }
+        if self.insecure_host_key_callback {
+            violations.push(ConfigurationViolation {
+                parameter: "HostKeyCallback".to_string(),
+                current_value: "ssh.InsecureIgnoreHostKey()".to_string(),
+                required_value: "a callback verifying known host keys".to_string(),
+                itsp_reference: ITSP_40_062.to_string(),
+                severity: Severity::High,
+            });
+        }
+        if self
+            .host_keys
+            .iter()
+            .chain(&self.client_keys)
+            .any(|key| key != "unknown")
+        {
+            recommendations.push(
+                "SSH host and user key signatures remain classical and quantum-vulnerable; \
+                 track ML-DSA key support in golang.org/x/crypto/ssh"
+                    .to_string(),
+            );
+        }
+
+        ProtocolCompliance {
+            protocol: ProtocolDetection {
+                protocol_type: ProtocolType::Ssh,
+                version: "2.0".to_string(),
+                cipher_suites: self.ciphers.clone().unwrap_or_default(),
+                key_exchange: self.key_exchanges.clone().unwrap_or_default(),
+                configuration,
+                line: self.line,
+                column: self.column,
+                context: self.context.clone(),
+            },
+            compliant: violations.is_empty(),
+            violations,
+            recommendations,
+        }
+    }
+}
+
+/// Algorithm names of a `[]string{...}` value, resolving constants, and
+/// whether every element could be resolved
+///
+/// Lists returned by calls, slices of other lists and elements read from
+/// variables are only known at run time.
+fn algorithm_list(value: &str, source: &str, before: usize, ssh: &str) -> (Vec<String>, bool) {
+    let value = value.trim();
+    if is_identifier(value) {
+        return definition(source, before, value)
+            .filter(|value| !is_identifier(value))
+            .map(|value| algorithm_list(&value, source, before, ssh))
+            .unwrap_or((Vec::new(), false));
+    }
+    let Some(open) = value.find('{') else {
+        return (Vec::new(), false);
+    };
+    let Some(close) = closing(value, open) else {
+        return (Vec::new(), false);
+    };
+    let elements = split_top_level(&value[open + 1..close], b',');
+    let names: Vec<String> = elements
+        .iter()
+        .filter_map(|element| algorithm_name(element, source, before, ssh))
+        .collect();
+    let complete = names.len() == elements.len() && value[close + 1..].trim().is_empty();
+    (names, complete)
+}
+
+fn algorithm_name(element: &str, source: &str, before: usize, ssh: &str) -> Option<String> {
+    if let Some(name) = element.strip_prefix('"').and_then(|e| e.strip_suffix('"')) {
+        return Some(name.to_string());
+    }
+    if let Some((package, constant)) = element.split_once('.')
+        && package == ssh
+    {
+        return SSH_CONSTANTS
+            .iter()
+            .find(|(c, _)| *c == constant)
+            .map(|(_, name)| name.to_string());
+    }
+    if is_identifier(element)
+        && let Some(value) = definition(source, before, element)
+        && !is_identifier(&value)
+    {
+        return algorithm_name(&value, source, before, ssh);
+    }
+    None
+}
+
+/// Key type behind a signer expression, or `unknown` when it is only known at run time
+fn signer_key_type(expr: &str, source: &str, before: usize, ssh: &str, depth: usize) -> String {
+    let expr = expr.trim();
+    if depth > 3 {
+        return "unknown".to_string();
+    }
+    if is_identifier(expr) {
+        return match definition(source, before, expr) {
+            Some(value) => signer_key_type(&value, source, before, ssh, depth + 1),
+            None => "unknown".to_string(),
+        };
+    }
+
+    let Some((base, method, args)) = split_call(expr) else {
+        return "unknown".to_string();
+    };
+    let argument = split_top_level(args, b',')
+        .into_iter()
+        .next()
+        .unwrap_or_default();
+    // Expand the argument one level: the key generation or the key file read
+    let resolved = if is_identifier(argument) {
+        definition(source, before, argument).unwrap_or_default()
+    } else {
+        argument.to_string()
+    };
+    let key_type = match (base == ssh, method) {
+        (true, "NewSignerFromKey" | "NewSignerFromSigner") => KEY_PACKAGE
+            .captures(&resolved)
+            .and_then(|captures| key_type_of(&captures[1])),
+        (true, "ParsePrivateKey" | "ParsePrivateKeyWithPassphrase") => KEY_FILE_HINT
+            .captures(&resolved)
+            .and_then(|captures| key_type_of(&captures[1].to_ascii_lowercase())),
+        _ => None,
+    };
+    key_type.unwrap_or("unknown").to_string()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::analyze;
+
+    const SSH_SOURCE: &str = r#"package server
+
+import (
+	"crypto/ed25519"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const legacyKex = "diffie-hellman-group14-sha1"
+
+func serve() {
+	config := &ssh.ServerConfig{
+		Config: ssh.Config{
+			KeyExchanges: []string{ssh.KeyExchangeMLKEM768X25519, ssh.KeyExchangeCurve25519},
+			Ciphers:      []string{"aes256-gcm@openssh.com", ssh.InsecureCipherTripleDESCBC},
+		},
+	}
+	pemBytes, _ := os.ReadFile("/etc/ssh/ssh_host_rsa_key")
+	signer, _ := ssh.ParsePrivateKey(pemBytes)
+	config.AddHostKey(signer)
+
+	_, edKey, _ := ed25519.GenerateKey(nil)
+	edSigner, _ := ssh.NewSignerFromKey(edKey)
+	client := &ssh.ClientConfig{
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(edSigner)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}
+	client.KeyExchanges = []string{legacyKex, "curve25519-sha256"}
+}
+"#;
+
+    #[test]
+    fn test_ssh_configs() {
+        let records = detect_go_ssh_configs(SSH_SOURCE);
+        assert_eq!(records.len(), 2);
+
+        let server = &records[0];
+        assert_eq!(server.protocol.protocol_type, ProtocolType::Ssh);
+        assert_eq!(server.protocol.line, 13);
+        assert_eq!(
+            server.protocol.key_exchange,
+            vec!["mlkem768x25519-sha256", "curve25519-sha256"]
+        );
+        assert_eq!(server.protocol.configuration["pq_hybrid_kex"], "enabled");
+        assert_eq!(server.protocol.configuration["host_keys"], "RSA");
+        assert_eq!(server.violations.len(), 1);
+        assert_eq!(server.violations[0].current_value, "3des-cbc");
+        assert!(!server.compliant);
+
+        let client = &records[1];
+        assert_eq!(client.protocol.configuration["config_type"], "ClientConfig");
+        assert_eq!(client.protocol.configuration["pq_hybrid_kex"], "disabled");
+        assert_eq!(client.protocol.configuration["client_keys"], "Ed25519");
+        let parameters: Vec<&str> = client
+            .violations
+            .iter()
+            .map(|v| v.parameter.as_str())
+            .collect();
+        assert_eq!(
+            parameters,
+            vec!["KeyExchanges", "KeyExchanges", "HostKeyCallback"]
+        );
+        assert_eq!(
+            client.violations[1].current_value,
+            "diffie-hellman-group14-sha1"
+        );
+
+        // Without KeyExchanges the library defaults apply
+        let default = "package p\n\nimport \"golang.org/x/crypto/ssh\"\n\nvar c = ssh.Config{Ciphers: []string{\"aes128-ctr\"}}\n";
+        let records = detect_go_ssh_configs(default);
+        assert_eq!(
+            records[0].protocol.configuration["pq_hybrid_kex"],
+            "library default"
+        );
+        assert!(records[0].compliant);
+        assert!(detect_go_ssh_configs("package p\n\nvar c = ssh.Config{}\n").is_empty());
+    }
+
+    #[test]
+    fn test_ssh_default_key_exchanges() {
+        let source = r#"package p
+
+import "golang.org/x/crypto/ssh"
+
+func configs(signer ssh.Signer) {
+	server := &ssh.ServerConfig{}
+	server.Ciphers = []string{"aes256-gcm@openssh.com"}
+	server.AddHostKey(signer)
+
+	client := ssh.ClientConfig{
+		Config: ssh.Config{MACs: []string{"hmac-sha1-96"}},
+	}
+	_ = client
+}
+"#;
+        let records = detect_go_ssh_configs(source);
+        assert_eq!(records.len(), 2);
+        for record in &records {
+            assert_eq!(
+                record.protocol.configuration["pq_hybrid_kex"],
+                "library default"
+            );
+            assert!(record.protocol.key_exchange.is_empty());
+            assert!(
+                record
+                    .recommendations
+                    .iter()
+                    .any(|r| r.contains("defaults apply"))
+            );
+            assert!(!record.protocol.configuration.contains_key("unresolved"));
+        }
+        assert_eq!(
+            records[0].protocol.cipher_suites,
+            vec!["aes256-gcm@openssh.com"]
+        );
+        assert!(records[0].compliant);
+
+        // A weak MAC is still reported while the key exchange is left at its defaults
+        assert_eq!(records[1].violations.len(), 1);
+        assert_eq!(records[1].violations[0].parameter, "MACs");
+        assert!(!records[1].compliant);
+    }
+
+    #[test]
+    fn test_ssh_non_literal_algorithm_lists() {
+        let source = r#"package p
+
+import "golang.org/x/crypto/ssh"
+
+const hybrid = ssh.KeyExchangeMLKEM768X25519
+
+func configs(opts Options) {
+	kex := []string{hybrid, "curve25519-sha256"}
+	named := ssh.Config{KeyExchanges: kex}
+
+	called := ssh.Config{KeyExchanges: preferredKex(), Ciphers: opts.Ciphers}
+
+	mixed := ssh.Config{KeyExchanges: []string{opts.Kex, "diffie-hellman-group1-sha1"}}
+
+	appended := ssh.Config{}
+	appended.KeyExchanges = append(kex, opts.Kex)
+	_, _, _, _ = named, called, mixed, appended
+}
+"#;
+        let records = detect_go_ssh_configs(source);
+        assert_eq!(records.len(), 4);
+
+        // A list bound to a variable resolves through its definition
+        let named = &records[0];
+        assert_eq!(
+            named.protocol.key_exchange,
+            vec!["mlkem768x25519-sha256", "curve25519-sha256"]
+        );
+        assert_eq!(named.protocol.configuration["pq_hybrid_kex"], "enabled");
+        assert!(!named.protocol.configuration.contains_key("unresolved"));
+        assert!(named.compliant);
+
+        // Lists returned by calls are not reported as lacking the hybrid
+        let called = &records[1];
+        assert!(called.protocol.key_exchange.is_empty());
+        assert_eq!(called.protocol.configuration["pq_hybrid_kex"], "unresolved");
+        assert_eq!(
+            called.protocol.configuration["unresolved"],
+            "KeyExchanges,Ciphers"
+        );
+        assert!(called.violations.is_empty());
+        assert!(
+            called
+                .recommendations
+                .iter()
+                .any(|r| r.contains("run time"))
+        );
+
+        // Known elements of a partly resolved list are still checked
+        let mixed = &records[2];
+        assert_eq!(
+            mixed.protocol.key_exchange,
+            vec!["diffie-hellman-group1-sha1"]
+        );
+        assert_eq!(mixed.protocol.configuration["pq_hybrid_kex"], "unresolved");
+        assert_eq!(mixed.violations.len(), 1);
+        assert_eq!(
+            mixed.violations[0].current_value,
+            "diffie-hellman-group1-sha1"
+        );
+
+        let appended = &records[3];
+        assert_eq!(
+            appended.protocol.configuration["pq_hybrid_kex"],
+            "unresolved"
+        );
+        assert!(appended.violations.is_empty());
+    }
+
+    #[test]
+    fn test_ssh_records_in_audit() {
+        let audit = analyze(SSH_SOURCE, "go").unwrap();
+        assert_eq!(audit.protocol_compliance.len(), 2);
+
+        let report = crate::generate_itsg33_report(
+            &audit,
+            SecurityClassification::ProtectedA,
+            Some("server.go"),
+        );
+        assert_eq!(report.protocol_compliance.len(), 2);
+        assert!(!report.summary.itsp_40_062_compliant);
+    }
+}
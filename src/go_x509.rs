@@ -0,0 +1,1033 @@
+//! Go certificate and CSR generation
+//!
+//! Finds `x509.CreateCertificate` and `x509.CreateCertificateRequest` calls
+//! and resolves, within the file, the template they are given (an
+//! `x509.Certificate{...}` literal plus later `template.Field = ...`
+//! assignments) and the signing key (`rsa.GenerateKey`, `ecdsa.GenerateKey`,
+//! parsed keys, typed parameters). Each call is reported with its signature
+//! algorithm (explicit, or the one Go derives from the key), key type and
+//! validity period, evaluated from `time.Now().AddDate(...)`,
+//! `.Add(365 * 24 * time.Hour)` and `time.Date(...)` expressions.
+//!
+//! A [`CertificatePolicy`] flags long-lived certificates with classical
+//! signatures that remain valid past the organization's post-quantum
+//! cutoff; flagged certificates also become findings of their file.
+
+use crate::audit::{score_vulnerability, severity_for_score};
+use crate::go_packages::go_imports;
+use crate::parser::{closing, scan_code};
+use crate::types::*;
+use chrono::{Months, NaiveDate, TimeDelta};
+use lazy_static::lazy_static;
+use regex::{Captures, Regex};
+use serde::{Deserialize, Serialize};
+
+lazy_static! {
+    static ref CREATE_CALL: Regex =
+        Regex::new(r"\b([A-Za-z_]\w*)\.(CreateCertificate|CreateCertificateRequest)\s*\(")
+            .expect("CREATE_CALL: Invalid regex pattern - this is a compile-time bug");
+    static ref TYPE_ASSERTION: Regex = Regex::new(r"\.\(\*?(rsa|ecdsa|ed25519|dsa)\.")
+        .expect("TYPE_ASSERTION: Invalid regex pattern - this is a compile-time bug");
+    static ref CURVE: Regex = Regex::new(r"\bP(224|256|384|521)\b")
+        .expect("CURVE: Invalid regex pattern - this is a compile-time bug");
+    static ref FIELD_ASSIGNMENT: Regex =
+        Regex::new(r"(?m)(?:^|[^.\w])([A-Za-z_]\w*)\.([A-Z]\w*)\s*=([^=].*)$")
+            .expect("FIELD_ASSIGNMENT: Invalid regex pattern - this is a compile-time bug");
+    static ref TYPED_KEY: Regex = Regex::new(
+        r"(?:^|[(,\s])([A-Za-z_]\w*)\s+\*?(rsa|ecdsa|ed25519|dsa)\.(?:PrivateKey|PublicKey)\b"
+    )
+    .expect("TYPED_KEY: Invalid regex pattern - this is a compile-time bug");
+    static ref DEFINITION: Regex = Regex::new(
+        r"(?m)(?:^|[^.\w])([A-Za-z_]\w*)(?:\s*,\s*\w+)*(?:\s+[\w.*\[\]]+)?\s*:?=([^=].*)$"
+    )
+    .expect("DEFINITION: Invalid regex pattern - this is a compile-time bug");
+}
+
+/// Nanoseconds per `time` unit; month constants evaluate to their number
+const TIME_CONSTANTS: &[(&str, f64)] = &[
+    ("time.Nanosecond", 1.0),
+    ("time.Microsecond", 1e3),
+    ("time.Millisecond", 1e6),
+    ("time.Second", 1e9),
+    ("time.Minute", 60e9),
+    ("time.Hour", 3600e9),
+    ("time.January", 1.0),
+    ("time.February", 2.0),
+    ("time.March", 3.0),
+    ("time.April", 4.0),
+    ("time.May", 5.0),
+    ("time.June", 6.0),
+    ("time.July", 7.0),
+    ("time.August", 8.0),
+    ("time.September", 9.0),
+    ("time.October", 10.0),
+    ("time.November", 11.0),
+    ("time.December", 12.0),
+];
+
+const NANOS_PER_DAY: f64 = 86_400e9;
+
+/// Identifier definitions are followed this many levels deep
+const MAX_RESOLUTION_DEPTH: usize = 4;
+
+/// Signature algorithm, key type and validity policy for generated certificates
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct CertificatePolicy {
+    /// Date after which certificates with classical signatures must no longer be valid
+    pub pq_cutoff: NaiveDate,
+    /// Longest validity (in days) allowed for a classical certificate outliving the cutoff
+    pub max_classical_validity_days: u32,
+}
+
+impl Default for CertificatePolicy {
+    /// NIST IR 8547 deprecates 112-bit RSA and ECDSA after 2030; 398 days
+    /// is the CA/Browser Forum limit for TLS server certificates
+    fn default() -> Self {
+        Self {
+            pq_cutoff: NaiveDate::from_ymd_opt(2030, 1, 1).expect("valid cutoff date"),
+            max_classical_validity_days: 398,
+        }
+    }
+}
+
+impl CertificatePolicy {
+    /// Flag `certificate` if it is a long-lived classical certificate
+    /// valid past the cutoff
+    pub fn evaluate(&self, certificate: &mut CertificateGeneration) {
+        certificate.flagged = false;
+        certificate.reason = None;
+
+        let (Some(_), Some(validity_days), Some(not_after)) = (
+            certificate.classical_family(),
+            certificate.validity_days,
+            certificate.not_after,
+        ) else {
+            return;
+        };
+        if validity_days <= i64::from(self.max_classical_validity_days)
+            || not_after <= self.pq_cutoff
+        {
+            return;
+        }
+
+        let issued = not_after - TimeDelta::days(validity_days);
+        let when = if issued >= self.pq_cutoff {
+            format!("issued after the PQ cutoff {}", self.pq_cutoff)
+        } else {
+            format!("valid past the PQ cutoff {}", self.pq_cutoff)
+        };
+        certificate.flagged = true;
+        certificate.reason = Some(format!(
+            "Classical certificate valid for {} days until {}, {} (policy allows {} days)",
+            validity_days, not_after, when, self.max_classical_validity_days
+        ));
+    }
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum CertificateKind {
+    Certificate,
+    CertificateRequest,
+}
+
+impl std::fmt::Display for CertificateKind {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        match self {
+            CertificateKind::Certificate => write!(f, "Certificate"),
+            CertificateKind::CertificateRequest => write!(f, "CSR"),
+        }
+    }
+}
+
+/// A certificate or CSR generated by a Go source
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct CertificateGeneration {
+    pub file_path: String,
+    /// Line of the `CreateCertificate` or `CreateCertificateRequest` call
+    pub line: usize,
+    pub kind: CertificateKind,
+    /// Template variable, when the template is not an inline literal
+    pub template: Option<String>,
+    /// `x509.SignatureAlgorithm` constant name (`SHA256WithRSA`)
+    pub signature_algorithm: Option<String>,
+    /// False when the template leaves the algorithm to be derived from the key
+    pub signature_algorithm_explicit: bool,
+    /// Signing key type (`RSA`, `ECDSA`, `Ed25519`, `ML-DSA`)
+    pub key_type: Option<String>,
+    pub key_size: Option<u32>,
+    pub curve: Option<String>,
+    pub not_before: Option<NaiveDate>,
+    pub not_after: Option<NaiveDate>,
+    pub validity_days: Option<i64>,
+    /// The signature can be forged with a quantum computer
+    pub quantum_vulnerable: bool,
+    /// Violates the [`CertificatePolicy`]
+    pub flagged: bool,
+    pub reason: Option<String>,
+}
+
+impl CertificateGeneration {
+    /// Key type with its curve or size, such as `RSA 2048` or `ECDSA P-256`
+    pub fn key_label(&self) -> String {
+        match (&self.key_type, self.key_size, &self.curve) {
+            (Some(key_type), _, Some(curve)) => format!("{} {}", key_type, curve),
+            (Some(key_type), Some(size), None) => format!("{} {}", key_type, size),
+            (Some(key_type), None, None) => key_type.clone(),
+            (None, _, _) => "unknown key".to_string(),
+        }
+    }
+
+    /// Classical algorithm family of the signature, from the key or the algorithm name
+    pub fn classical_family(&self) -> Option<CryptoType> {
+        match self.key_type.as_deref() {
+            Some("RSA") => return Some(CryptoType::Rsa),
+            Some("ECDSA" | "Ed25519") => return Some(CryptoType::Ecdsa),
+            Some("DSA") => return Some(CryptoType::Dsa),
+            Some(_) => return None,
+            None => {}
+        }
+        let algorithm = self.signature_algorithm.as_deref()?;
+        if algorithm.contains("ECDSA") || algorithm.contains("Ed25519") {
+            Some(CryptoType::Ecdsa)
+        } else if algorithm.contains("RSA") {
+            Some(CryptoType::Rsa)
+        } else if algorithm.contains("DSA") {
+            Some(CryptoType::Dsa)
+        } else {
+            None
+        }
+    }
+}
+
+/// Certificates and CSRs generated by a project's Go files
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct CertificateReport {
+    pub policy: CertificatePolicy,
+    pub certificates: Vec<CertificateGeneration>,
+    pub flagged: usize,
+}
+
+/// Signing key resolved from a `CreateCertificate` argument
+struct KeyInfo {
+    key_type: &'static str,
+    size: Option<u32>,
+    curve: Option<String>,
+}
+
+/// Resolution of identifiers in one source, relative to a call site
+struct Context<'a> {
+    source: &'a str,
+    before: usize,
+    issued: NaiveDate,
+    /// Template variable and fields, for `template.NotBefore` references
+    template: Option<String>,
+    fields: Vec<(String, String)>,
+}
+
+impl Context<'_> {
+    fn field(&self, name: &str) -> Option<&str> {
+        self.fields
+            .iter()
+            .rev()
+            .find(|(field, _)| field == name)
+            .map(|(_, value)| value.as_str())
+    }
+}
+
+/// Certificates and CSRs generated in a Go source, assuming it runs on `issued`
+pub fn detect_go_certificates(
+    file_path: &str,
+    source: &str,
+    issued: NaiveDate,
+) -> Vec<CertificateGeneration> {
+    let x509 = go_imports(source)
+        .into_iter()
+        .find(|import| import.path == "crypto/x509")
+        .map(|import| import.name().to_string())
+        .unwrap_or_else(|| "x509".to_string());
+    let mut found = Vec::new();
+
+    for captures in CREATE_CALL.captures_iter(source) {
+        let call = captures.get(0).expect("call match");
+        if captures[1] != *x509 {
+            continue;
+        }
+        let line_start = source[..call.start()].rfind('\n').map_or(0, |i| i + 1);
+        if source[line_start..call.start()]
+            .trim_start()
+            .starts_with("//")
+        {
+            continue;
+        }
+        let open = call.end() - 1;
+        let Some(close) = closing(source, open) else {
+            continue;
+        };
+        let args = split_top_level(&source[open + 1..close], b',');
+        let kind = if &captures[2] == "CreateCertificate" {
+            CertificateKind::Certificate
+        } else {
+            CertificateKind::CertificateRequest
+        };
+        let mut ctx = Context {
+            source,
+            before: call.start(),
+            issued,
+            template: None,
+            fields: Vec::new(),
+        };
+        (ctx.template, ctx.fields) = args
+            .get(1)
+            .map(|arg| template_fields(arg, &ctx))
+            .unwrap_or_default();
+
+        // CreateCertificate(rand, template, parent, pub, priv) and
+        // CreateCertificateRequest(rand, template, priv)
+        let key_args: &[usize] = match kind {
+            CertificateKind::Certificate => &[4, 3],
+            CertificateKind::CertificateRequest => &[2],
+        };
+        let key = key_args
+            .iter()
+            .filter_map(|&i| args.get(i))
+            .find_map(|arg| key_info(arg, &ctx, 0));
+
+        let explicit_algorithm = ctx
+            .field("SignatureAlgorithm")
+            .and_then(|value| signature_algorithm(value, &ctx, 0))
+            .filter(|name| name != "UnknownSignatureAlgorithm");
+        let signature_algorithm_explicit = explicit_algorithm.is_some();
+        let signature_algorithm =
+            explicit_algorithm.or_else(|| key.as_ref().and_then(default_signature_algorithm));
+
+        let not_before = ctx
+            .field("NotBefore")
+            .and_then(|value| eval_time(value, &ctx, 0));
+        let not_after = ctx
+            .field("NotAfter")
+            .and_then(|value| eval_time(value, &ctx, 0));
+        let validity_days = not_after.map(|end| (end - not_before.unwrap_or(issued)).num_days());
+
+        let mut certificate = CertificateGeneration {
+            file_path: file_path.to_string(),
+            line: source[..call.start()].matches('\n').count() + 1,
+            kind,
+            template: ctx.template.clone(),
+            signature_algorithm,
+            signature_algorithm_explicit,
+            key_type: key.as_ref().map(|k| k.key_type.to_string()),
+            key_size: key.as_ref().and_then(|k| k.size),
+            curve: key.and_then(|k| k.curve),
+            not_before,
+            not_after,
+            validity_days,
+            quantum_vulnerable: false,
+            flagged: false,
+            reason: None,
+        };
+        certificate.quantum_vulnerable = certificate.classical_family().is_some();
+        found.push(certificate);
+    }
+
+    found
+}
+
+/// Detect the certificates of every Go file, evaluate them against
+/// `policy` and add a finding for each flagged certificate
+pub fn analyze_certificates(
+    files: &mut [ProjectFile],
+    policy: &CertificatePolicy,
+    issued: NaiveDate,
+) -> CertificateReport {
+    let mut certificates = Vec::new();
+
+    for file in files
+        .iter_mut()
+        .filter(|f| f.audit.language == Language::Go)
+    {
+        let mut found = detect_go_certificates(&file.path, &file.source, issued);
+        let mut added = false;
+        for certificate in &mut found {
+            policy.evaluate(certificate);
+            if let Some(vuln) = certificate_finding(certificate, &file.source, policy) {
+                file.audit.vulnerabilities.push(vuln);
+                added = true;
+            }
+        }
+        if added {
+            file.audit
+                .vulnerabilities
+                .sort_by(|a, b| a.line.cmp(&b.line).then(a.column.cmp(&b.column)));
+            file.audit.refresh();
+        }
+        certificates.append(&mut found);
+    }
+
+    CertificateReport {
+        policy: policy.clone(),
+        flagged: certificates.iter().filter(|c| c.flagged).count(),
+        certificates,
+    }
+}
+
+/// Export a certificate report as JSON
+pub fn export_certificates_json(report: &CertificateReport) -> Result<String, serde_json::Error> {
+    serde_json::to_string_pretty(report)
+}
+
+fn certificate_finding(
+    certificate: &CertificateGeneration,
+    source: &str,
+    policy: &CertificatePolicy,
+) -> Option<Vulnerability> {
+    let crypto_type = certificate
+        .classical_family()
+        .filter(|_| certificate.flagged)?;
+    let line = source.lines().nth(certificate.line - 1).unwrap_or_default();
+    let column = line.find("Create").unwrap_or(0);
+    let end_column = line[column..]
+        .find('(')
+        .map_or(line.len(), |end| column + end);
+
+    let algorithm = certificate
+        .signature_algorithm
+        .as_deref()
+        .unwrap_or("an unknown algorithm");
+    let risk_score = score_vulnerability(&crypto_type, certificate.key_size);
+
+    Some(Vulnerability {
+        end_column,
+        match_kind: MatchKind::ApiCall,
+        confidence: MatchKind::ApiCall.confidence(),
+        context: line.trim().to_string(),
+        message: format!(
+            "{} signed with {} ({}): {}",
+            certificate.kind,
+            algorithm,
+            certificate.key_label(),
+            certificate.reason.as_deref().unwrap_or_default()
+        ),
+        recommendation: format!(
+            "Limit classical certificates to {} days or let them expire by {}, \
+             and plan the migration to ML-DSA (FIPS 204) certificates",
+            policy.max_classical_validity_days, policy.pq_cutoff
+        ),
+        key_size: certificate.key_size,
+        ..Vulnerability::new(
+            crypto_type,
+            severity_for_score(risk_score),
+            risk_score,
+            certificate.line,
+            column,
+        )
+    })
+}
+
+/// Template variable and `Field: value` pairs of a template argument, in
+/// source order with later assignments last
+fn template_fields(arg: &str, ctx: &Context) -> (Option<String>, Vec<(String, String)>) {
+    let arg = arg.trim().trim_start_matches('&').trim();
+    if !is_identifier(arg) {
+        return (None, literal_fields(arg));
+    }
+
+    let mut fields = definition(ctx.source, ctx.before, arg)
+        .map(|value| literal_fields(&value))
+        .unwrap_or_default();
+    for captures in captures_of(&FIELD_ASSIGNMENT, &ctx.source[..ctx.before], arg) {
+        let value = captures.get(3).expect("assignment value");
+        fields.push((
+            captures[2].to_string(),
+            statement(ctx.source, value.start()).to_string(),
+        ));
+    }
+    (Some(arg.to_string()), fields)
+}
+
+/// Fields of an `x509.Certificate{...}` composite literal
+pub(crate) fn literal_fields(literal: &str) -> Vec<(String, String)> {
+    let Some(open) = literal.find('{') else {
+        return Vec::new();
+    };
+    let Some(close) = closing(literal, open) else {
+        return Vec::new();
+    };
+    split_top_level(&literal[open + 1..close], b',')
+        .into_iter()
+        .filter_map(|element| {
+            let (name, value) = element.split_once(':')?;
+            let name = name.trim();
+            is_identifier(name).then(|| (name.to_string(), value.trim().to_string()))
+        })
+        .collect()
+}
+
+fn signature_algorithm(value: &str, ctx: &Context, depth: usize) -> Option<String> {
+    let value = value.trim();
+    if is_identifier(value) {
+        if depth >= MAX_RESOLUTION_DEPTH {
+            return None;
+        }
+        return signature_algorithm(&definition(ctx.source, ctx.before, value)?, ctx, depth + 1);
+    }
+    let (_, name) = value.rsplit_once('.')?;
+    is_identifier(name).then(|| name.to_string())
+}
+
+/// The algorithm `x509.CreateCertificate` picks for a key when the template has none
+fn default_signature_algorithm(key: &KeyInfo) -> Option<String> {
+    let name = match (key.key_type, key.curve.as_deref()) {
+        ("RSA", _) => "SHA256WithRSA",
+        ("ECDSA", Some("P-384")) => "ECDSAWithSHA384",
+        ("ECDSA", Some("P-521")) => "ECDSAWithSHA512",
+        ("ECDSA", _) => "ECDSAWithSHA256",
+        ("Ed25519", _) => "PureEd25519",
+        _ => return None,
+    };
+    Some(name.to_string())
+}
+
+fn key_info(expr: &str, ctx: &Context, depth: usize) -> Option<KeyInfo> {
+    let expr = expr.trim().trim_start_matches('&').trim();
+    let expr = expr.strip_suffix(".PublicKey").unwrap_or(expr);
+    if let Some(captures) = TYPE_ASSERTION.captures(expr) {
+        return Some(KeyInfo {
+            key_type: key_type_of(&captures[1])?,
+            size: None,
+            curve: None,
+        });
+    }
+
+    if is_identifier(expr) {
+        if depth >= MAX_RESOLUTION_DEPTH {
+            return None;
+        }
+        if let Some(value) = definition(ctx.source, ctx.before, expr)
+            && let Some(key) = key_info(&value, ctx, depth + 1)
+        {
+            return Some(key);
+        }
+        // A typed parameter or declaration: `priv *rsa.PrivateKey`
+        let captures = captures_of(&TYPED_KEY, ctx.source, expr)
+            .into_iter()
+            .next()?;
+        return Some(KeyInfo {
+            key_type: key_type_of(&captures[2])?,
+            size: None,
+            curve: None,
+        });
+    }
+
+    let (base, method, args) = split_call(expr)?;
+    if method == "Public" {
+        return key_info(base, ctx, depth);
+    }
+    let package = base.rsplit('.').next().unwrap_or(base);
+    let args = split_top_level(args, b',');
+    let lower = package.to_ascii_lowercase();
+    if lower.starts_with("mldsa") || lower.starts_with("dilithium") {
+        return Some(KeyInfo {
+            key_type: "ML-DSA",
+            size: None,
+            curve: None,
+        });
+    }
+
+    match (package, method) {
+        ("rsa", "GenerateKey") => Some(KeyInfo {
+            key_type: "RSA",
+            size: args
+                .get(1)
+                .and_then(|bits| eval_number(bits, ctx, depth))
+                .map(|bits| bits as u32),
+            curve: None,
+        }),
+        ("rsa", "GenerateMultiPrimeKey") => Some(KeyInfo {
+            key_type: "RSA",
+            size: args
+                .get(2)
+                .and_then(|bits| eval_number(bits, ctx, depth))
+                .map(|bits| bits as u32),
+            curve: None,
+        }),
+        ("ecdsa", "GenerateKey") => {
+            let curve = args.first().and_then(|curve| curve_name(curve, ctx, depth));
+            Some(KeyInfo {
+                key_type: "ECDSA",
+                size: curve
+                    .as_deref()
+                    .and_then(|c| c.strip_prefix("P-"))
+                    .and_then(|bits| bits.parse().ok()),
+                curve,
+            })
+        }
+        ("ed25519", "GenerateKey" | "NewKeyFromSeed") => Some(KeyInfo {
+            key_type: "Ed25519",
+            size: None,
+            curve: None,
+        }),
+        ("x509", "ParsePKCS1PrivateKey" | "ParsePKCS1PublicKey") => Some(KeyInfo {
+            key_type: "RSA",
+            size: None,
+            curve: None,
+        }),
+        ("x509", "ParseECPrivateKey") => Some(KeyInfo {
+            key_type: "ECDSA",
+            size: None,
+            curve: None,
+        }),
+        _ => None,
+    }
+}
+
+pub(crate) fn key_type_of(package: &str) -> Option<&'static str> {
+    match package {
+        "rsa" => Some("RSA"),
+        "ecdsa" => Some("ECDSA"),
+        "ed25519" => Some("Ed25519"),
+        "dsa" => Some("DSA"),
+        _ => None,
+    }
+}
+
+fn curve_name(expr: &str, ctx: &Context, depth: usize) -> Option<String> {
+    let expr = expr.trim();
+    if let Some(captures) = CURVE.captures(expr) {
+        return Some(format!("P-{}", &captures[1]));
+    }
+    if is_identifier(expr) && depth < MAX_RESOLUTION_DEPTH {
+        return curve_name(&definition(ctx.source, ctx.before, expr)?, ctx, depth + 1);
+    }
+    None
+}
+
+/// Date of a `time.Time` expression
+fn eval_time(expr: &str, ctx: &Context, depth: usize) -> Option<NaiveDate> {
+    let expr = expr.trim();
+    if is_identifier(expr) {
+        if depth >= MAX_RESOLUTION_DEPTH {
+            return None;
+        }
+        return eval_time(&definition(ctx.source, ctx.before, expr)?, ctx, depth + 1);
+    }
+    if let Some((receiver, field)) = expr.split_once('.')
+        && ctx.template.as_deref() == Some(receiver)
+        && is_identifier(field)
+    {
+        if depth >= MAX_RESOLUTION_DEPTH {
+            return None;
+        }
+        return eval_time(ctx.field(field)?, ctx, depth + 1);
+    }
+
+    let (base, method, args) = split_call(expr)?;
+    let args = split_top_level(args, b',');
+    match method {
+        "Now" if base.ends_with("time") => Some(ctx.issued),
+        "UTC" | "Local" | "In" | "Truncate" | "Round" => eval_time(base, ctx, depth),
+        "Date" if base.ends_with("time") => {
+            let year = eval_number(args.first()?, ctx, depth)?;
+            let month = eval_number(args.get(1)?, ctx, depth)?;
+            let day = eval_number(args.get(2)?, ctx, depth)?;
+            NaiveDate::from_ymd_opt(year as i32, month as u32, day as u32)
+        }
+        "AddDate" => {
+            let date = eval_time(base, ctx, depth)?;
+            let years = eval_number(args.first()?, ctx, depth)? as i64;
+            let months = eval_number(args.get(1)?, ctx, depth)? as i64;
+            let days = eval_number(args.get(2)?, ctx, depth)? as i64;
+            let months = years * 12 + months;
+            let date = if months >= 0 {
+                date.checked_add_months(Months::new(u32::try_from(months).ok()?))?
+            } else {
+                date.checked_sub_months(Months::new(u32::try_from(-months).ok()?))?
+            };
+            date.checked_add_signed(TimeDelta::days(days))
+        }
+        "Add" => {
+            let date = eval_time(base, ctx, depth)?;
+            let nanos = eval_number(args.first()?, ctx, depth)?;
+            date.checked_add_signed(TimeDelta::days((nanos / NANOS_PER_DAY) as i64))
+        }
+        _ => None,
+    }
+}
+
+/// Value of a constant product such as `10 * 365 * 24 * time.Hour`, with
+/// durations in nanoseconds
+fn eval_number(expr: &str, ctx: &Context, depth: usize) -> Option<f64> {
+    let expr = expr.trim();
+    let factors = split_top_level(expr, b'*');
+    if factors.len() > 1 {
+        return factors
+            .iter()
+            .map(|factor| eval_number(factor, ctx, depth))
+            .product();
+    }
+
+    if let Some(inner) = expr.strip_prefix('-') {
+        return eval_number(inner, ctx, depth).map(|value| -value);
+    }
+    if let Some(inner) = expr.strip_prefix('(').and_then(|e| e.strip_suffix(')')) {
+        return eval_number(inner, ctx, depth);
+    }
+    if let Ok(value) = expr.replace('_', "").parse::<f64>() {
+        return Some(value);
+    }
+    if let Some((_, value)) = TIME_CONSTANTS.iter().find(|(name, _)| *name == expr) {
+        return Some(*value);
+    }
+    if let Some((base, "Duration", args)) = split_call(expr)
+        && base.ends_with("time")
+    {
+        return eval_number(args, ctx, depth);
+    }
+    if is_identifier(expr) && depth < MAX_RESOLUTION_DEPTH {
+        return eval_number(&definition(ctx.source, ctx.before, expr)?, ctx, depth + 1);
+    }
+    None
+}
+
+/// Right-hand side of the last assignment to `name` before the call site
+/// (or the first anywhere, for package-level declarations further down)
+pub(crate) fn definition(source: &str, before: usize, name: &str) -> Option<String> {
+    let starts: Vec<usize> = captures_of(&DEFINITION, source, name)
+        .iter()
+        .map(|captures| captures.get(2).expect("definition value").start())
+        .collect();
+    let start = starts
+        .iter()
+        .rev()
+        .find(|&&start| start < before)
+        .or_else(|| starts.first())?;
+    Some(statement(source, *start).to_string())
+}
+
+/// Matches of `pattern` whose first group is the identifier `name`, in source order
+///
+/// Every start position is tried, so a match for `name` is found even where
+/// a match for another identifier on the same line covers it.
+pub(crate) fn captures_of<'t>(pattern: &Regex, text: &'t str, name: &str) -> Vec<Captures<'t>> {
+    let mut found = Vec::new();
+    let mut at = 0;
+    while let Some(captures) = pattern.captures_at(text, at) {
+        let start = captures.get(0).expect("whole match").start();
+        at = start + text[start..].chars().next().map_or(1, char::len_utf8);
+        if &captures[1] == name {
+            found.push(captures);
+        }
+        if at >= text.len() {
+            break;
+        }
+    }
+    found
+}
+
+pub(crate) fn is_identifier(text: &str) -> bool {
+    text.chars()
+        .next()
+        .is_some_and(|c| c.is_alphabetic() || c == '_')
+        && text.chars().all(|c| c.is_alphanumeric() || c == '_')
+}
+
+/// `text` split at `separator`s outside brackets and literals
+pub(crate) fn split_top_level(text: &str, separator: u8) -> Vec<&str> {
+    let mut parts = Vec::new();
+    let mut start = 0;
+    scan_code(text, |i, byte, depth| {
+        if byte == separator && depth == 0 {
+            parts.push(text[start..i].trim());
+            start = i + 1;
+        }
+        true
+    });
+    parts.push(text[start..].trim());
+    parts.retain(|part| !part.is_empty());
+    parts
+}
+
+/// The expression starting at `start`, up to the end of its statement
+pub(crate) fn statement(source: &str, start: usize) -> &str {
+    let text = &source[start..];
+    let mut end = text.len();
+    scan_code(text, |i, byte, depth| {
+        if depth < 0 || (depth == 0 && (byte == b'\n' || byte == b';')) {
+            end = i;
+            return false;
+        }
+        true
+    });
+    text[..end].trim()
+}
+
+/// Receiver, method and arguments of an expression ending in a call:
+/// `time.Now().AddDate(1, 0, 0)` is `("time.Now()", "AddDate", "1, 0, 0")`
+pub(crate) fn split_call(expr: &str) -> Option<(&str, &str, &str)> {
+    let expr = expr.trim();
+    if !expr.ends_with(')') {
+        return None;
+    }
+    let mut openers = Vec::new();
+    let mut open = None;
+    scan_code(expr, |i, byte, _| {
+        match byte {
+            b'(' | b'[' | b'{' => openers.push(i),
+            b')' | b']' | b'}' => open = openers.pop(),
+            _ => {}
+        }
+        true
+    });
+    let open = open?;
+    let name_start = expr[..open]
+        .rfind(|c: char| !(c.is_alphanumeric() || c == '_'))
+        .map_or(0, |i| i + 1);
+    let method = &expr[name_start..open];
+    let base = expr[..name_start].strip_suffix('.')?;
+    Some((base, method, &expr[open + 1..expr.len() - 1]))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    const CERT_SOURCE: &str = r#"package ca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"time"
+)
+
+const validFor = 10 * 365 * 24 * time.Hour
+
+func issue(csrKey *ecdsa.PrivateKey) {
+	priv, _ := rsa.GenerateKey(rand.Reader, 4096)
+	notBefore := time.Now()
+	template := x509.Certificate{
+		SerialNumber:       serial,
+		Subject:            pkix.Name{Organization: []string{"Acme, Inc."}},
+		SignatureAlgorithm: x509.SHA1WithRSA,
+		NotBefore:          notBefore,
+		NotAfter:           notBefore.Add(validFor),
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+
+	leafKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	leaf := &x509.Certificate{NotBefore: time.Now()}
+	leaf.NotAfter = leaf.NotBefore.AddDate(0, 3, 0)
+	_, _ = x509.CreateCertificate(rand.Reader, leaf, &template, &leafKey.PublicKey, leafKey)
+
+	csr, _ := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, csrKey)
+}
+"#;
+
+    fn issued() -> NaiveDate {
+        NaiveDate::from_ymd_opt(2026, 1, 1).unwrap()
+    }
+
+    #[test]
+    fn test_certificate_generation() {
+        let found = detect_go_certificates("ca.go", CERT_SOURCE, issued());
+        assert_eq!(found.len(), 3);
+
+        let root = &found[0];
+        assert_eq!(root.line, 24);
+        assert_eq!(root.kind, CertificateKind::Certificate);
+        assert_eq!(root.template.as_deref(), Some("template"));
+        assert_eq!(root.signature_algorithm.as_deref(), Some("SHA1WithRSA"));
+        assert!(root.signature_algorithm_explicit);
+        assert_eq!(root.key_type.as_deref(), Some("RSA"));
+        assert_eq!(root.key_size, Some(4096));
+        assert_eq!(root.validity_days, Some(3650));
+        assert_eq!(root.not_after, NaiveDate::from_ymd_opt(2035, 12, 30));
+        assert!(root.quantum_vulnerable);
+
+        // Without SignatureAlgorithm, Go derives it from the key
+        let leaf = &found[1];
+        assert_eq!(leaf.signature_algorithm.as_deref(), Some("ECDSAWithSHA384"));
+        assert!(!leaf.signature_algorithm_explicit);
+        assert_eq!(leaf.curve.as_deref(), Some("P-384"));
+        assert_eq!(leaf.not_after, NaiveDate::from_ymd_opt(2026, 4, 1));
+
+        // The key of a typed parameter; CSRs carry no validity
+        let csr = &found[2];
+        assert_eq!(csr.kind, CertificateKind::CertificateRequest);
+        assert_eq!(csr.key_type.as_deref(), Some("ECDSA"));
+        assert_eq!(csr.validity_days, None);
+
+        let mut absolute = detect_go_certificates(
+            "ca.go",
+            "var t = x509.Certificate{NotAfter: time.Date(2049, time.December, 31, 0, 0, 0, 0, time.UTC)}\nvar d, _ = x509.CreateCertificate(r, &t, &t, pub, ed25519Key.(ed25519.PrivateKey))\n",
+            issued(),
+        );
+        assert_eq!(absolute.len(), 1);
+        assert_eq!(absolute[0].not_after, NaiveDate::from_ymd_opt(2049, 12, 31));
+        assert_eq!(absolute[0].key_type.as_deref(), Some("Ed25519"));
+        CertificatePolicy::default().evaluate(&mut absolute[0]);
+        assert!(absolute[0].flagged);
+    }
+
+    #[test]
+    fn test_certificate_policy() {
+        let mut files = vec![crate::project_file("ca.go", CERT_SOURCE, "go")];
+        let before = files[0].audit.vulnerabilities.len();
+
+        let report = analyze_certificates(&mut files, &CertificatePolicy::default(), issued());
+        assert_eq!(report.flagged, 1);
+        let root = &report.certificates[0];
+        assert!(root.flagged);
+        assert!(
+            root.reason
+                .as_deref()
+                .unwrap()
+                .contains("past the PQ cutoff 2030-01-01")
+        );
+        // A 3-month leaf expiring before the cutoff is fine
+        assert!(!report.certificates[1].flagged);
+
+        let audit = &files[0].audit;
+        assert_eq!(audit.vulnerabilities.len(), before + 1);
+        let finding = audit
+            .vulnerabilities
+            .iter()
+            .find(|v| v.line == 24 && v.message.starts_with("Certificate signed"))
+            .unwrap();
+        assert_eq!(finding.crypto_type, CryptoType::Rsa);
+        assert_eq!(finding.key_size, Some(4096));
+
+        // After the cutoff even a two-year certificate is flagged
+        let policy = CertificatePolicy {
+            pq_cutoff: NaiveDate::from_ymd_opt(2025, 1, 1).unwrap(),
+            max_classical_validity_days: 398,
+        };
+        let mut late = detect_go_certificates(
+            "ca.go",
+            "var t = x509.Certificate{NotAfter: time.Now().AddDate(2, 0, 0)}\nvar d, _ = x509.CreateCertificate(r, &t, &t, pub, priv.(*rsa.PrivateKey))\n",
+            issued(),
+        );
+        policy.evaluate(&mut late[0]);
+        assert!(
+            late[0]
+                .reason
+                .as_deref()
+                .unwrap()
+                .contains("issued after the PQ cutoff")
+        );
+    }
+
+    #[test]
+    fn test_certificate_request_generation() {
+        let source = r#"package pki
+
+func requests(caKey, key *rsa.PrivateKey) {
+	req := x509.CertificateRequest{Subject: pkix.Name{CommonName: "svc"}}
+	req.SignatureAlgorithm = x509.SHA384WithRSA
+	_, _ = x509.CreateCertificateRequest(rand.Reader, &req, key)
+
+	pqKey, _ := mldsa65.GenerateKey(rand.Reader)
+	_, _ = x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, pqKey)
+}
+"#;
+        let mut found = detect_go_certificates("csr.go", source, issued());
+        assert_eq!(found.len(), 2);
+
+        // A later field assignment, and a parameter sharing its type with another
+        let classical = &found[0];
+        assert_eq!(classical.kind, CertificateKind::CertificateRequest);
+        assert_eq!(classical.template.as_deref(), Some("req"));
+        assert_eq!(
+            classical.signature_algorithm.as_deref(),
+            Some("SHA384WithRSA")
+        );
+        assert!(classical.signature_algorithm_explicit);
+        assert_eq!(classical.key_type.as_deref(), Some("RSA"));
+        assert!(classical.quantum_vulnerable);
+
+        let pq = &found[1];
+        assert_eq!(pq.key_type.as_deref(), Some("ML-DSA"));
+        assert!(!pq.quantum_vulnerable);
+
+        // CSRs carry no validity, so the policy never flags them
+        for csr in &mut found {
+            assert_eq!(csr.validity_days, None);
+            CertificatePolicy::default().evaluate(csr);
+            assert!(!csr.flagged);
+        }
+    }
+
+    #[test]
+    fn test_pq_cutoff_validity() {
+        let certificate = |key: &str, fields: &str| {
+            let source = format!(
+                "var t = x509.Certificate{{{}}}\nvar d, _ = x509.CreateCertificate(r, &t, &t, pub, {})\n",
+                fields, key
+            );
+            let mut found = detect_go_certificates("ca.go", &source, issued());
+            assert_eq!(found.len(), 1, "{}", source);
+            CertificatePolicy::default().evaluate(&mut found[0]);
+            found.remove(0)
+        };
+        let rsa = "priv.(*rsa.PrivateKey)";
+        let date = |y: i32, m: &str, d: u32| {
+            format!("time.Date({}, time.{}, {}, 0, 0, 0, 0, time.UTC)", y, m, d)
+        };
+
+        // Five years from 2026 outlives the 2030 cutoff
+        let long = certificate(rsa, "NotAfter: time.Now().AddDate(5, 0, 0)");
+        assert!(long.flagged);
+        assert!(
+            long.reason
+                .as_deref()
+                .unwrap()
+                .contains("valid for 1826 days until 2031-01-01")
+        );
+
+        // Past the cutoff, the policy allows exactly 398 days
+        let issued_2029 = format!("NotBefore: {}, NotAfter: ", date(2029, "June", 1));
+        let at_limit = certificate(rsa, &(issued_2029.clone() + &date(2030, "July", 4)));
+        assert_eq!(at_limit.validity_days, Some(398));
+        assert!(!at_limit.flagged);
+        let over_limit = certificate(rsa, &(issued_2029 + &date(2030, "July", 5)));
+        assert_eq!(over_limit.validity_days, Some(399));
+        assert!(over_limit.flagged);
+
+        // A long validity that ends by the cutoff is fine
+        let ten_years = format!("NotBefore: {}, NotAfter: ", date(2020, "January", 1));
+        assert!(!certificate(rsa, &(ten_years.clone() + &date(2030, "January", 1))).flagged);
+        assert!(certificate(rsa, &(ten_years + &date(2030, "January", 2))).flagged);
+
+        // Post-quantum and unknown signatures are not classical
+        let decade = "NotAfter: time.Now().AddDate(10, 0, 0)";
+        assert!(!certificate("mldsa87.GenerateKey(rand.Reader)", decade).flagged);
+        let unknown = certificate("signer", decade);
+        assert_eq!(unknown.key_type, None);
+        assert!(!unknown.flagged);
+    }
+
+    #[test]
+    fn test_no_certificate_generation() {
+        // Parsing, commented-out calls and other packages' CreateCertificate are not generation
+        let source = r#"package tlsutil
+
+import "crypto/x509"
+
+func load(der []byte) (*x509.Certificate, error) {
+	// x509.CreateCertificate(rand.Reader, &t, &t, pub, priv)
+	ca.CreateCertificate(request)
+	return x509.ParseCertificate(der)
+}
+"#;
+        assert!(detect_go_certificates("load.go", source, issued()).is_empty());
+
+        let mut files = vec![crate::project_file("load.go", source, "go")];
+        let before = files[0].audit.vulnerabilities.len();
+        let report = analyze_certificates(&mut files, &CertificatePolicy::default(), issued());
+        assert!(report.certificates.is_empty());
+        assert_eq!(report.flagged, 0);
+        assert_eq!(files[0].audit.vulnerabilities.len(), before);
+    }
+}
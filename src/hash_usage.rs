@@ -0,0 +1,282 @@
+//! Usage context of weak hash findings
+//!
+//! MD5 and SHA-1 are broken for collision resistance, which matters for
+//! signatures and not for a cache key. This module classifies what a weak
+//! hash on a line is used for, from the line itself and the comment just
+//! above it: signature digests and password hashing keep their severity,
+//! HMAC is lowered (HMAC-SHA-1 does not rely on collision resistance and
+//! remains approved under SP 800-131A), checksums and identifiers drop to
+//! low and leave the cryptographic compliance scope. The reasoning is
+//! recorded on the finding.
+
+use crate::types::*;
+use lazy_static::lazy_static;
+use regex::Regex;
+
+lazy_static! {
+    static ref NOT_FOR_SECURITY: Regex = Regex::new(r"usedforsecurity\s*=\s*False")
+        .expect("NOT_FOR_SECURITY: Invalid regex pattern - this is a compile-time bug");
+    static ref PASSWORD_USAGE: Regex = Regex::new(
+        r"(?i)pass(?:word|wd|phrase)|pbkdf2?|\bkdf\b|\bpwd\b|hashpw|\bcrypt\("
+    )
+    .expect("PASSWORD_USAGE: Invalid regex pattern - this is a compile-time bug");
+    static ref HMAC_USAGE: Regex = Regex::new(r"(?i)hmac")
+        .expect("HMAC_USAGE: Invalid regex pattern - this is a compile-time bug");
+    static ref SIGNATURE_USAGE: Regex = Regex::new(
+        r"\b(?i:sign)|[a-z]Sign|(?i:with(?:rsa|dsa|ecdsa)\b)|(?i:x509|certificate)"
+    )
+    .expect("SIGNATURE_USAGE: Invalid regex pattern - this is a compile-time bug");
+    static ref CHECKSUM_USAGE: Regex = Regex::new(
+        r"(?i)checksum|md5sum|sha1sum|content-md5|\bcrc\b|file_?hash"
+    )
+    .expect("CHECKSUM_USAGE: Invalid regex pattern - this is a compile-time bug");
+    static ref IDENTIFIER_USAGE: Regex = Regex::new(
+        r"(?i)cache|etag|\bgit\b|object_?id|\boid\b|dedup|uuid[35]?\b|nameUUIDFromBytes|shard|bucket"
+    )
+    .expect("IDENTIFIER_USAGE: Invalid regex pattern - this is a compile-time bug");
+}
+
+/// Comment lines above a finding that are read as its context
+const COMMENT_LINES: usize = 2;
+
+/// Usage context of a weak hash on `lines[index]`, or `None` when nothing indicates it
+pub fn classify_hash_usage(
+    crypto_type: &CryptoType,
+    lines: &[&str],
+    index: usize,
+) -> Option<HashContext> {
+    if !is_weak_hash(crypto_type) {
+        return None;
+    }
+    let line = lines.get(index)?;
+    let comments: Vec<&str> = lines[..index]
+        .iter()
+        .rev()
+        .take(COMMENT_LINES)
+        .map(|l| l.trim())
+        .take_while(|l| is_comment(l))
+        .collect();
+
+    // The line is stronger evidence than a comment above it
+    let find = |pattern: &Regex| {
+        pattern
+            .find(line)
+            .map(|m| format!("`{}`", m.as_str()))
+            .or_else(|| {
+                comments.iter().find_map(|comment| {
+                    pattern
+                        .find(comment)
+                        .map(|m| format!("`{}` in the comment above", m.as_str()))
+                })
+            })
+    };
+
+    let (usage, evidence) = if let Some(m) = NOT_FOR_SECURITY.find(line) {
+        (HashUsage::NonSecurity, format!("`{}`", m.as_str()))
+    } else if let Some(evidence) = find(&PASSWORD_USAGE) {
+        (HashUsage::Password, evidence)
+    } else if let Some(evidence) = find(&HMAC_USAGE) {
+        (HashUsage::Hmac, evidence)
+    } else if let Some(evidence) = find(&SIGNATURE_USAGE) {
+        (HashUsage::Signature, evidence)
+    } else if let Some(evidence) = find(&CHECKSUM_USAGE) {
+        (HashUsage::Checksum, evidence)
+    } else if let Some(evidence) = find(&IDENTIFIER_USAGE) {
+        (HashUsage::NonSecurity, evidence)
+    } else {
+        return None;
+    };
+
+    let impact = match usage {
+        HashUsage::Signature => format!(
+            "chosen-prefix collisions let an attacker forge signatures over {} digests",
+            crypto_type
+        ),
+        HashUsage::Password => format!(
+            "{} is fast and unsalted, so stored passwords fall to offline guessing",
+            crypto_type
+        ),
+        HashUsage::Hmac if hmac_approved(crypto_type) => format!(
+            "HMAC does not rely on collision resistance and HMAC-{} remains approved \
+             under NIST SP 800-131A",
+            crypto_type
+        ),
+        HashUsage::Hmac => format!(
+            "HMAC does not rely on collision resistance, but HMAC-{} is not an approved MAC",
+            crypto_type
+        ),
+        HashUsage::Checksum => "guards against accidental corruption only; anyone who can \
+                                replace the data can replace the checksum"
+            .to_string(),
+        HashUsage::NonSecurity => {
+            "used as an identifier, not for cryptographic protection".to_string()
+        }
+    };
+    let compliance_exempt = match usage {
+        HashUsage::Checksum | HashUsage::NonSecurity => true,
+        HashUsage::Hmac => hmac_approved(crypto_type),
+        HashUsage::Signature | HashUsage::Password => false,
+    };
+
+    Some(HashContext {
+        usage,
+        reason: format!("{} usage ({}): {}", usage, evidence, impact),
+        compliance_exempt,
+    })
+}
+
+/// Classify the weak hash findings of `lines[index]` and rescore them for their usage
+///
+/// Custom rule findings keep the severity their rule assigns.
+pub(crate) fn apply_hash_usage(lines: &[&str], index: usize, found: &mut [Vulnerability]) {
+    for vuln in found.iter_mut().filter(|v| v.rule_id.is_none()) {
+        let Some(context) = classify_hash_usage(&vuln.crypto_type, lines, index) else {
+            continue;
+        };
+
+        if let Some((severity, risk_score)) = rescore(context.usage, &vuln.crypto_type) {
+            vuln.severity = severity;
+            vuln.risk_score = risk_score;
+        }
+        vuln.recommendation =
+            recommendation_for_usage(context.usage, &vuln.crypto_type).to_string();
+        vuln.hash_context = Some(context);
+    }
+}
+
+fn is_weak_hash(crypto_type: &CryptoType) -> bool {
+    matches!(
+        crypto_type,
+        CryptoType::Md5 | CryptoType::Sha1 | CryptoType::Md4 | CryptoType::Ripemd160
+    )
+}
+
+fn hmac_approved(crypto_type: &CryptoType) -> bool {
+    *crypto_type == CryptoType::Sha1
+}
+
+fn is_comment(line: &str) -> bool {
+    ["//", "#", "/*", "*", "--"]
+        .iter()
+        .any(|marker| line.starts_with(marker))
+}
+
+/// Severity and risk score for a usage; `None` keeps the algorithm's own
+fn rescore(usage: HashUsage, crypto_type: &CryptoType) -> Option<(Severity, u32)> {
+    match usage {
+        HashUsage::Signature | HashUsage::Password => None,
+        HashUsage::Hmac if matches!(crypto_type, CryptoType::Md5 | CryptoType::Md4) => {
+            Some((Severity::High, 70))
+        }
+        HashUsage::Hmac => Some((Severity::Medium, 45)),
+        HashUsage::Checksum => Some((Severity::Low, 30)),
+        HashUsage::NonSecurity => Some((Severity::Low, 10)),
+    }
+}
+
+fn recommendation_for_usage(usage: HashUsage, crypto_type: &CryptoType) -> &'static str {
+    match usage {
+        HashUsage::Signature => {
+            "Sign with SHA-256 or stronger (e.g. SHA256withRSA), and plan ML-DSA for \
+             post-quantum signatures"
+        }
+        HashUsage::Password => {
+            "Store passwords with Argon2id, scrypt, bcrypt or PBKDF2-HMAC-SHA-256 with a high \
+             iteration count"
+        }
+        HashUsage::Hmac if hmac_approved(crypto_type) => {
+            "Migrate to HMAC-SHA-256 when the protocol allows; no urgent replacement needed"
+        }
+        HashUsage::Hmac => "Replace with HMAC-SHA-256",
+        HashUsage::Checksum => "Use SHA-256 for checksums that must detect tampering",
+        HashUsage::NonSecurity => {
+            "No cryptographic replacement needed; mark the call as non-security \
+             (usedforsecurity=False) or use a non-cryptographic hash such as xxHash"
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn usage(crypto_type: CryptoType, source: &str) -> Option<HashUsage> {
+        let lines: Vec<&str> = source.lines().collect();
+        classify_hash_usage(&crypto_type, &lines, lines.len() - 1).map(|c| c.usage)
+    }
+
+    #[test]
+    fn test_classify_hash_usage() {
+        use CryptoType::*;
+        assert_eq!(
+            usage(Md5, "h = hashlib.md5(data, usedforsecurity=False)"),
+            Some(HashUsage::NonSecurity)
+        );
+        assert_eq!(
+            usage(Sha1, "Signature.getInstance(\"SHA1withRSA\")"),
+            Some(HashUsage::Signature)
+        );
+        assert_eq!(
+            usage(Sha1, "mac = hmac.new(key, msg, hashlib.sha1)"),
+            Some(HashUsage::Hmac)
+        );
+        assert_eq!(
+            usage(Sha1, "SecretKeyFactory.getInstance(\"PBKDF2WithHmacSHA1\")"),
+            Some(HashUsage::Password)
+        );
+        assert_eq!(
+            usage(Md5, "stored = md5(password).hexdigest()"),
+            Some(HashUsage::Password)
+        );
+        assert_eq!(
+            usage(Md5, "# verify the download checksum\nh := md5.Sum(body)"),
+            Some(HashUsage::Checksum)
+        );
+        assert_eq!(
+            usage(Sha1, "cacheKey := sha1.Sum([]byte(url))"),
+            Some(HashUsage::NonSecurity)
+        );
+        // `assign` is not a signature, and a code line above is not context
+        assert_eq!(usage(Md5, "assign(md5(x))"), None);
+        assert_eq!(usage(Md5, "checksum := 1\nh := md5.New()"), None);
+        // Ciphers are not hashes
+        assert_eq!(usage(Des, "cache = DES.new(key)"), None);
+    }
+
+    #[test]
+    fn test_hash_usage_severity() {
+        let source = "import hashlib\n\
+                      etag = hashlib.md5(body, usedforsecurity=False).hexdigest()\n\
+                      tag = hmac.new(key, body, hashlib.sha1).digest()\n\
+                      sig = \"SHA1withRSA\"\n";
+        let audit = crate::analyze(source, "python").unwrap();
+        let find = |line: usize| {
+            audit
+                .vulnerabilities
+                .iter()
+                .find(|v| v.line == line)
+                .unwrap()
+        };
+
+        let etag = find(2);
+        assert_eq!(etag.severity, Severity::Low);
+        assert_eq!(etag.risk_score, 10);
+        assert!(etag.compliance_exempt());
+        assert!(
+            etag.hash_context
+                .as_ref()
+                .unwrap()
+                .reason
+                .contains("`usedforsecurity=False`")
+        );
+
+        let hmac = find(3);
+        assert_eq!(hmac.severity, Severity::Medium);
+        assert!(hmac.compliance_exempt());
+
+        let signature = find(4);
+        assert_eq!(signature.severity, Severity::Critical);
+        assert!(!signature.compliance_exempt());
+        assert_eq!(audit.stats.critical_count, 1);
+    }
+}
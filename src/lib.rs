@@ -3,28 +3,126 @@
 
 pub mod algorithm_database;
 pub mod audit;
+pub mod call_graph;
 pub mod canadian_compliance;
+pub mod catalog;
+pub mod cgo;
 pub mod compliance;
+pub mod confidence;
 pub mod detector;
+pub mod diagnostics;
+pub mod encoding;
+pub mod evaluation;
+pub mod events;
+pub mod explain;
+pub mod fingerprint;
+pub mod go_build;
+pub mod go_deprecated;
+pub mod go_packages;
+pub mod go_ssh;
+pub mod go_x509;
+pub mod hash_usage;
 pub mod parser;
+pub mod plugins;
+pub mod profile;
+pub mod projects;
+pub mod reachability;
 pub mod remediation;
+pub mod runtimes;
+pub mod sarif;
+pub mod semgrep;
+pub mod shard;
+pub mod sourcemap;
+pub mod symbols;
+pub mod tls_misuse;
 pub mod types;
+mod yaml;
 
 // Re-export public API
-pub use audit::{AuditError, analyze, score_vulnerability};
+pub use audit::{
+    AuditError, ResourceLimits, analyze, analyze_with_limits, analyze_with_rules,
+    score_vulnerability,
+};
+pub use call_graph::{
+    CallGraph, CallGraphReport, export_call_graph_json, generate_call_graph_report,
+};
 pub use canadian_compliance::{
-    export_itsg33_json, export_unified_json, generate_itsg33_report, generate_unified_report,
+    export_itsg33_json, export_protocol_compliance_json, export_unified_json,
+    generate_itsg33_report, generate_unified_report,
 };
+pub use catalog::{
+    RuleDescription, RuleOrigin, export_catalog_json, export_catalog_markdown, find_rule,
+    rule_catalog,
+};
+pub use cgo::{CgoPreamble, parse_cgo_preambles};
 pub use compliance::{
-    export_oscal_json, export_sc13_json, generate_oscal_json, generate_sc13_report,
+    export_oscal_json, export_sc13_json, generate_oscal_json, generate_project_sc13_report,
+    generate_sc13_report,
+};
+pub use confidence::filter_by_confidence;
+pub use diagnostics::{DiagnosticKind, ScanDiagnostic, export_diagnostics_json};
+pub use encoding::{DecodedSource, SourceEncoding, decode_source, is_binary};
+pub use evaluation::{
+    EvalComparison, Evaluation, compare_evaluations, export_evaluation_json, parse_labeled_source,
 };
+pub use events::{LogLevel, LogRecord, ScanEvent, ScanSummary, to_json_line};
+pub use explain::{ExplainError, LineExplanation, explain_line, export_explanation_json};
+pub use fingerprint::{assign_fingerprints, is_fingerprint};
+pub use go_build::{BuildConstraintError, GoBuildReport, GoBuildTarget, apply_build_constraints};
+pub use go_packages::{
+    GoModule, GoPackage, GoPackageReport, analyze_go_packages, export_go_packages_json,
+};
+pub use go_ssh::detect_go_ssh_configs;
+pub use go_x509::{
+    CertificateGeneration, CertificateKind, CertificatePolicy, CertificateReport,
+    analyze_certificates, detect_go_certificates, export_certificates_json,
+};
+pub use hash_usage::classify_hash_usage;
 pub use parser::{ParseError, parse_file};
+pub use plugins::{PluginError, PluginLimits, decode_plugin_output};
+pub use profile::{Profiler, ScanProfile, export_profile_json};
+pub use projects::{
+    ManifestKind, ProjectLayout, ProjectRollup, ProjectSummary, SubProject,
+    export_project_rollup_json, project_rollup,
+};
+pub use reachability::{
+    ReachabilityConfig, ReachabilityReport, analyze_reachability, export_reachability_json,
+};
 pub use remediation::{CodeFix, RemediationResult, RemediationSummary, generate_remediations};
+pub use runtimes::{
+    PqSetting, PqStatus, Runtime, RuntimeAssessment, RuntimeEvidence, RuntimeReport,
+    RuntimeVersion, assess_runtimes, export_runtimes_json, is_runtime_file, scan_runtime_file,
+};
+pub use sarif::{SarifLog, export_sarif_json, generate_sarif_report};
+pub use semgrep::{CustomRule, ImportIssue, RuleImport, SemgrepError, import_semgrep_rules};
+pub use shard::{
+    MergedScan, ShardError, ShardResult, ShardSpec, export_shard_json, merge_shards,
+    parse_shard_json,
+};
+pub use sourcemap::{
+    SourceMap, SourceMapReference, find_source_mapping_url, is_minified, remap_findings,
+};
+pub use symbols::{
+    SymbolResolutionSummary, SymbolTable, resolve_project_symbols, resolve_symbols_in,
+};
 pub use types::{
-    AuditResult, AuditStats, CryptoType, ITSG33Report, Language, OscalAssessmentResults,
-    SC13AssessmentReport, SecurityClassification, Severity, UnifiedComplianceReport, Vulnerability,
+    AuditResult, AuditStats, BuildExclusion, Confidence, ConfidenceBreakdown, CryptoType,
+    HashContext, HashUsage, ITSG33Report, Language, MatchKind, OscalAssessmentResults, ProjectFile,
+    ProtocolCompliance, ProtocolType, SC13AssessmentReport, SecurityClassification, Severity,
+    UnifiedComplianceReport, Vulnerability,
 };
 
+/// A parsed and analyzed project file, for tests of project-wide passes
+#[cfg(test)]
+pub(crate) fn project_file(path: &str, source: &str, language: &str) -> ProjectFile {
+    ProjectFile {
+        path: path.to_string(),
+        source: source.to_string(),
+        parsed: parse_file(source, language).unwrap(),
+        audit: analyze(source, language).unwrap(),
+    }
+}
+
 #[cfg(target_arch = "wasm32")]
 use wasm_bindgen::prelude::*;
 
@@ -13,6 +13,8 @@ lazy_static! {
         .expect("RUST_USE_RE: Invalid regex pattern - this is a compile-time bug");
     static ref RUST_FN_CALL_RE: Regex = Regex::new(r"(\w+(?:::\w+)?)\s*\(")
         .expect("RUST_FN_CALL_RE: Invalid regex pattern - this is a compile-time bug");
+    static ref RUST_FN_DEF_RE: Regex = Regex::new(r"^\s*(?:pub(?:\([^)]*\))?\s+)?(?:const\s+)?(?:async\s+)?(?:unsafe\s+)?(?:extern\s+\S+\s+)?fn\s+(\w+)")
+        .expect("RUST_FN_DEF_RE: Invalid regex pattern - this is a compile-time bug");
     static ref RUST_STRUCT_RE: Regex = Regex::new(r"^\s*(?:pub\s+)?struct\s+(\w+)")
         .expect("RUST_STRUCT_RE: Invalid regex pattern - this is a compile-time bug");
 
@@ -25,8 +27,12 @@ lazy_static! {
         .expect("JS_FN_CALL_RE: Invalid regex pattern - this is a compile-time bug");
     static ref JS_CLASS_RE: Regex = Regex::new(r"^\s*class\s+(\w+)")
         .expect("JS_CLASS_RE: Invalid regex pattern - this is a compile-time bug");
-    static ref JS_FUNCTION_RE: Regex = Regex::new(r"^\s*(?:async\s+)?function\s+(\w+)")
+    static ref JS_FUNCTION_RE: Regex = Regex::new(r"^\s*(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s*(\w+)")
         .expect("JS_FUNCTION_RE: Invalid regex pattern - this is a compile-time bug");
+    static ref JS_ARROW_FN_RE: Regex = Regex::new(r"^\s*(?:export\s+)?(?:const|let|var)\s+(\w+)\s*=\s*(?:async\s+)?(?:function\b|\([^)]*\)\s*=>|\w+\s*=>)")
+        .expect("JS_ARROW_FN_RE: Invalid regex pattern - this is a compile-time bug");
+    static ref JS_METHOD_RE: Regex = Regex::new(r"^\s*(?:static\s+)?(?:async\s+)?(\w+)\s*\([^)]*\)\s*\{")
+        .expect("JS_METHOD_RE: Invalid regex pattern - this is a compile-time bug");
 
     // Python patterns
     static ref PY_IMPORT_RE: Regex = Regex::new(r"^\s*(?:import|from)\s+([\w.]+)")
@@ -35,7 +41,7 @@ lazy_static! {
         .expect("PY_FN_CALL_RE: Invalid regex pattern - this is a compile-time bug");
     static ref PY_CLASS_RE: Regex = Regex::new(r"^\s*class\s+(\w+)")
         .expect("PY_CLASS_RE: Invalid regex pattern - this is a compile-time bug");
-    static ref PY_FUNCTION_RE: Regex = Regex::new(r"^\s*def\s+(\w+)")
+    static ref PY_FUNCTION_RE: Regex = Regex::new(r"^\s*(?:async\s+)?def\s+(\w+)")
         .expect("PY_FUNCTION_RE: Invalid regex pattern - this is a compile-time bug");
 
     // Java patterns
@@ -45,6 +51,8 @@ lazy_static! {
         .expect("JAVA_FN_CALL_RE: Invalid regex pattern - this is a compile-time bug");
     static ref JAVA_CLASS_RE: Regex = Regex::new(r"^\s*(?:public\s+)?class\s+(\w+)")
         .expect("JAVA_CLASS_RE: Invalid regex pattern - this is a compile-time bug");
+    static ref JAVA_METHOD_RE: Regex = Regex::new(r"^\s*(?:(?:public|private|protected|static|final|synchronized|abstract|native|default)\s+)*(?:<[^>]+>\s+)?[\w.]+(?:<[^>]*>)?(?:\[\])*\s+(\w+)\s*\([^;]*$")
+        .expect("JAVA_METHOD_RE: Invalid regex pattern - this is a compile-time bug");
 
     // Go patterns
     static ref GO_IMPORT_RE: Regex = Regex::new(r#"^\s*import\s+(?:\(|"([^"]+)")"#)
@@ -53,8 +61,34 @@ lazy_static! {
         .expect("GO_FN_CALL_RE: Invalid regex pattern - this is a compile-time bug");
     static ref GO_STRUCT_RE: Regex = Regex::new(r"^\s*type\s+(\w+)\s+struct")
         .expect("GO_STRUCT_RE: Invalid regex pattern - this is a compile-time bug");
-    static ref GO_FUNCTION_RE: Regex = Regex::new(r"^\s*func\s+(\w+)")
+    static ref GO_FUNCTION_RE: Regex = Regex::new(r"^\s*func\s+(?:\([^)]*\)\s*)?(\w+)")
         .expect("GO_FUNCTION_RE: Invalid regex pattern - this is a compile-time bug");
+    static ref GO_CONST_RE: Regex = Regex::new(r"^\s*(?:const|var)\s+(\w+)(?:\s+[\w.\[\]*]+)?\s*=\s*(.+)$")
+        .expect("GO_CONST_RE: Invalid regex pattern - this is a compile-time bug");
+    static ref GO_BLOCK_CONST_RE: Regex = Regex::new(r"^\s*(\w+)(?:\s+[\w.\[\]*]+)?\s*=\s*(.+)$")
+        .expect("GO_BLOCK_CONST_RE: Invalid regex pattern - this is a compile-time bug");
+
+    // Constant and config value patterns
+    static ref RUST_CONST_RE: Regex = Regex::new(r"^\s*(?:pub(?:\([^)]*\))?\s+)?(?:const|static)\s+(?:mut\s+)?(\w+)\s*:[^=]+=\s*(.+)$")
+        .expect("RUST_CONST_RE: Invalid regex pattern - this is a compile-time bug");
+    static ref JS_CONST_RE: Regex = Regex::new(r"^\s*(?:export\s+)?(?:const|let|var)\s+(\w+)(?:\s*:[^=]+)?\s*=\s*(.+)$")
+        .expect("JS_CONST_RE: Invalid regex pattern - this is a compile-time bug");
+    static ref PY_CONST_RE: Regex = Regex::new(r"^\s*(\w+)\s*(?::[^=]+)?=\s*([^=].*)$")
+        .expect("PY_CONST_RE: Invalid regex pattern - this is a compile-time bug");
+    static ref JAVA_CONST_RE: Regex = Regex::new(r"^\s*(?:(?:public|private|protected|static|final)\s+)+[\w.<>\[\]]+\s+(\w+)\s*=\s*(.+)$")
+        .expect("JAVA_CONST_RE: Invalid regex pattern - this is a compile-time bug");
+    static ref FIELD_INIT_RE: Regex = Regex::new(r#"^\s*["']?(\w+)["']?\s*:\s*(.+)$"#)
+        .expect("FIELD_INIT_RE: Invalid regex pattern - this is a compile-time bug");
+    static ref ENV_LOOKUP_RE: Regex = Regex::new(r#"(?i)(?:\w*env\w*(?:\.get)?|env::var)\s*[(\[]\s*["'](\w+)["']|process\.env\.(\w+)"#)
+        .expect("ENV_LOOKUP_RE: Invalid regex pattern - this is a compile-time bug");
+    static ref LITERAL_RE: Regex = Regex::new(r#""([^"\\]*)"|'([^'\\]*)'|`([^`]*)`|\b(\d[\d_]*)\b"#)
+        .expect("LITERAL_RE: Invalid regex pattern - this is a compile-time bug");
+    static ref INTEGER_RE: Regex = Regex::new(r"^(\d[\d_]*)(?:[uUlL]+|[ui](?:8|16|32|64|size))?$")
+        .expect("INTEGER_RE: Invalid regex pattern - this is a compile-time bug");
+    static ref IDENTIFIER_RE: Regex = Regex::new(r"^[A-Za-z_]\w*(?:(?:\.|::)[A-Za-z_]\w*)*$")
+        .expect("IDENTIFIER_RE: Invalid regex pattern - this is a compile-time bug");
+    static ref CONVERSION_RE: Regex = Regex::new(r"^(?:u?int(?:8|16|32|64)?|u(?:16|32|64|size)|str|String|Number|parseInt|Integer\.parseInt|Long\.parseLong|strconv\.Atoi)\(\s*(.+?)\s*\)$")
+        .expect("CONVERSION_RE: Invalid regex pattern - this is a compile-time bug");
 }
 
 /// Parser errors
@@ -147,12 +181,15 @@ fn parse_rust(source: &str) -> Result<ParsedSource, ParseError> {
                     name: fn_name.clone(),
                     line: line_num,
                     column,
-                    args: vec![],
+                    args: extract_call_args(trimmed, caps.get(0).map_or(0, |m| m.end())),
                 });
             }
         }
     }
 
+    collect_function_definitions(&mut parsed, source);
+    collect_constant_definitions(&mut parsed, source);
+
     Ok(parsed)
 }
 
@@ -201,11 +238,14 @@ fn parse_javascript(source: &str) -> Result<ParsedSource, ParseError> {
                 name: fn_name.clone(),
                 line: line_num,
                 column,
-                args: vec![],
+                args: extract_call_args(trimmed, caps.get(0).unwrap().end()),
             });
         }
     }
 
+    collect_function_definitions(&mut parsed, source);
+    collect_constant_definitions(&mut parsed, source);
+
     Ok(parsed)
 }
 
@@ -257,11 +297,14 @@ fn parse_python(source: &str) -> Result<ParsedSource, ParseError> {
                 name: fn_name.clone(),
                 line: line_num,
                 column,
-                args: vec![],
+                args: extract_call_args(trimmed, caps.get(0).unwrap().end()),
             });
         }
     }
 
+    collect_function_definitions(&mut parsed, source);
+    collect_constant_definitions(&mut parsed, source);
+
     Ok(parsed)
 }
 
@@ -297,19 +340,25 @@ fn parse_java(source: &str) -> Result<ParsedSource, ParseError> {
                 name: fn_name.clone(),
                 line: line_num,
                 column,
-                args: vec![],
+                args: extract_call_args(trimmed, caps.get(0).unwrap().end()),
             });
         }
     }
 
+    collect_function_definitions(&mut parsed, source);
+    collect_constant_definitions(&mut parsed, source);
+
     Ok(parsed)
 }
 
 fn parse_go(source: &str) -> Result<ParsedSource, ParseError> {
     let mut parsed = ParsedSource::new(Language::Go);
 
-    let import_re = Regex::new(r#"^\s*import\s+"([^"]+)""#).unwrap();
+    // `import "p"`, `import alias "p"`, and the specs of an `import ( ... )` block
+    let import_re = Regex::new(r#"^\s*import\s+(?:[\w.]+\s+)?"([^"]+)""#).unwrap();
+    let import_spec_re = Regex::new(r#"^\s*(?:[\w.]+\s+)?"([^"]+)""#).unwrap();
     let fn_call_re = Regex::new(r"(\w+(?:\.\w+)?)\s*\(").unwrap();
+    let mut in_import_block = false;
 
     for (line_num, line) in source.lines().enumerate() {
         let line_num = line_num + 1;
@@ -319,7 +368,22 @@ fn parse_go(source: &str) -> Result<ParsedSource, ParseError> {
             continue;
         }
 
-        if let Some(caps) = import_re.captures(trimmed) {
+        let import = if in_import_block {
+            if trimmed.starts_with(')') {
+                in_import_block = false;
+                continue;
+            }
+            import_spec_re.captures(trimmed)
+        } else if GO_IMPORT_RE
+            .captures(trimmed)
+            .is_some_and(|caps| caps.get(1).is_none())
+        {
+            in_import_block = true;
+            continue;
+        } else {
+            import_re.captures(trimmed)
+        };
+        if let Some(caps) = import {
             let import = caps.get(1).unwrap().as_str().to_string();
             parsed.imports.push(import.clone());
             parsed.ast_nodes.push(AstNode {
@@ -337,14 +401,466 @@ fn parse_go(source: &str) -> Result<ParsedSource, ParseError> {
                 name: fn_name.clone(),
                 line: line_num,
                 column,
-                args: vec![],
+                args: extract_call_args(trimmed, caps.get(0).unwrap().end()),
             });
         }
     }
 
+    collect_function_definitions(&mut parsed, source);
+    collect_constant_definitions(&mut parsed, source);
+
     Ok(parsed)
 }
 
+/// Split the argument list of a call whose opening parenthesis ends at `args_start`
+///
+/// Only arguments on the same line are returned; nested calls, brackets and
+/// string literals are kept intact as single arguments.
+fn extract_call_args(line: &str, args_start: usize) -> Vec<String> {
+    let mut args = Vec::new();
+    let mut current = String::new();
+    let mut depth = 0usize;
+    let mut quote: Option<char> = None;
+
+    for ch in line[args_start.min(line.len())..].chars() {
+        if let Some(q) = quote {
+            current.push(ch);
+            if ch == q {
+                quote = None;
+            }
+            continue;
+        }
+
+        match ch {
+            '"' | '\'' | '`' => {
+                quote = Some(ch);
+                current.push(ch);
+            }
+            '(' | '[' | '{' => {
+                depth += 1;
+                current.push(ch);
+            }
+            ')' | ']' | '}' if depth == 0 => break,
+            ')' | ']' | '}' => {
+                depth -= 1;
+                current.push(ch);
+            }
+            ',' if depth == 0 => {
+                args.push(current.trim().to_string());
+                current.clear();
+            }
+            _ => current.push(ch),
+        }
+    }
+
+    if !current.trim().is_empty() {
+        args.push(current.trim().to_string());
+    }
+
+    args
+}
+
+/// Keywords that look like calls or method headers but never name a function
+const CONTROL_KEYWORDS: &[&str] = &[
+    "if",
+    "for",
+    "while",
+    "switch",
+    "catch",
+    "function",
+    "return",
+    "with",
+    "else",
+    "do",
+    "try",
+    "synchronized",
+    "new",
+    "super",
+    "this",
+];
+
+/// Record function definitions and the line ranges of their bodies
+fn collect_function_definitions(parsed: &mut ParsedSource, source: &str) {
+    let lines: Vec<&str> = source.lines().collect();
+
+    for (idx, line) in lines.iter().enumerate() {
+        let trimmed = line.trim_start();
+        if trimmed.starts_with("//") || trimmed.starts_with('#') || trimmed.starts_with('*') {
+            continue;
+        }
+
+        let name_match = match parsed.language {
+            Language::Rust => RUST_FN_DEF_RE.captures(line),
+            Language::JavaScript | Language::TypeScript => JS_FUNCTION_RE
+                .captures(line)
+                .or_else(|| JS_ARROW_FN_RE.captures(line))
+                .or_else(|| JS_METHOD_RE.captures(line)),
+            Language::Python => PY_FUNCTION_RE.captures(line),
+            Language::Java => JAVA_METHOD_RE.captures(line),
+            Language::Go => GO_FUNCTION_RE.captures(line),
+            _ => None,
+        }
+        .and_then(|caps| caps.get(1));
+
+        let Some(name_match) = name_match else {
+            continue;
+        };
+
+        let name = name_match.as_str();
+        if CONTROL_KEYWORDS.contains(&name) {
+            continue;
+        }
+
+        let end_idx = match parsed.language {
+            Language::Python => indent_block_end(&lines, idx),
+            _ => brace_block_end(&lines, idx),
+        };
+
+        parsed.ast_nodes.push(AstNode {
+            node_type: NodeType::FunctionDeclaration,
+            line: idx + 1,
+            column: name_match.start(),
+            content: name.to_string(),
+        });
+        parsed.functions.push(FunctionDefinition {
+            name: name.to_string(),
+            line: idx + 1,
+            end_line: end_idx + 1,
+            column: name_match.start(),
+            signature: line.trim().to_string(),
+            annotations: preceding_annotations(&lines, idx),
+        });
+    }
+}
+
+/// Record constants, top-level variables and config fields initialized with literals
+fn collect_constant_definitions(parsed: &mut ParsedSource, source: &str) {
+    let mut in_go_block = false;
+
+    for (idx, line) in source.lines().enumerate() {
+        let trimmed = line.trim_start();
+        if trimmed.starts_with("//") || trimmed.starts_with('#') || trimmed.starts_with('*') {
+            continue;
+        }
+
+        // Go groups declarations in `const ( ... )` and `var ( ... )` blocks
+        if parsed.language == Language::Go {
+            if trimmed.starts_with("const (") || trimmed.starts_with("var (") {
+                in_go_block = true;
+                continue;
+            }
+            if in_go_block && trimmed.starts_with(')') {
+                in_go_block = false;
+                continue;
+            }
+        }
+
+        let declaration = match parsed.language {
+            Language::Rust => RUST_CONST_RE.captures(line),
+            Language::JavaScript | Language::TypeScript => JS_CONST_RE.captures(line),
+            Language::Java => JAVA_CONST_RE.captures(line),
+            Language::Go if in_go_block => GO_BLOCK_CONST_RE.captures(line),
+            Language::Go => GO_CONST_RE.captures(line),
+            Language::Python => PY_CONST_RE.captures(line).filter(|caps| {
+                // Module-level assignments and upper-case class attributes
+                !line.starts_with([' ', '\t'])
+                    || caps[1]
+                        .chars()
+                        .all(|c| c.is_ascii_uppercase() || c.is_ascii_digit() || c == '_')
+            }),
+            _ => None,
+        };
+
+        // Config struct and object literal fields only count when set to a literal
+        let (caps, allow_reference) = match declaration {
+            Some(caps) => (caps, true),
+            None => match FIELD_INIT_RE.captures(line) {
+                Some(caps)
+                    if parsed.language != Language::Python || trimmed.starts_with(['"', '\'']) =>
+                {
+                    (caps, false)
+                }
+                _ => continue,
+            },
+        };
+
+        let (Some(name_match), Some(value_match)) = (caps.get(1), caps.get(2)) else {
+            continue;
+        };
+
+        let Some((value, env_var)) = parse_constant_value(value_match.as_str()) else {
+            continue;
+        };
+        if !allow_reference && matches!(value, ConstantValue::Reference(_)) {
+            continue;
+        }
+
+        let name = name_match.as_str().to_string();
+        parsed.ast_nodes.push(AstNode {
+            node_type: NodeType::VariableDeclaration,
+            line: idx + 1,
+            column: name_match.start(),
+            content: name.clone(),
+        });
+        parsed.constants.push(ConstantDefinition {
+            name,
+            value,
+            line: idx + 1,
+            column: name_match.start(),
+            env_var,
+            field: !allow_reference,
+        });
+    }
+}
+
+/// Interpret the right-hand side of a declaration as a literal, identifier or env lookup default
+fn parse_constant_value(raw: &str) -> Option<(ConstantValue, Option<String>)> {
+    let value = strip_trailing_comment(raw)
+        .trim()
+        .trim_end_matches([';', ','])
+        .trim();
+
+    if let Some(caps) = ENV_LOOKUP_RE.captures(value) {
+        let env_var = caps.get(1).or_else(|| caps.get(2))?.as_str().to_string();
+        let rest = &value[caps.get(0)?.end()..];
+        let default = LITERAL_RE.captures(rest)?;
+        let literal = (1..=4)
+            .find_map(|i| default.get(i))?
+            .as_str()
+            .replace('_', "");
+        let value = match literal.parse::<u64>() {
+            Ok(number) => ConstantValue::Integer(number),
+            Err(_) => ConstantValue::String(literal),
+        };
+        return Some((value, Some(env_var)));
+    }
+
+    parse_literal(value).map(|v| (v, None))
+}
+
+fn parse_literal(value: &str) -> Option<ConstantValue> {
+    if let Some(caps) = INTEGER_RE.captures(value) {
+        return caps[1]
+            .replace('_', "")
+            .parse()
+            .ok()
+            .map(ConstantValue::Integer);
+    }
+
+    let unprefixed = value.trim_start_matches(['r', 'b', 'f', '@']);
+    for quote in ['"', '\'', '`'] {
+        if unprefixed.len() >= 2 && unprefixed.starts_with(quote) && unprefixed.ends_with(quote) {
+            let inner = &unprefixed[1..unprefixed.len() - 1];
+            if inner.contains(quote) {
+                return None;
+            }
+            return Some(ConstantValue::String(inner.to_string()));
+        }
+    }
+
+    // Conversions such as `int(2048)` or `uint(2048)`
+    if let Some(caps) = CONVERSION_RE.captures(value)
+        && let Some(inner @ (ConstantValue::Integer(_) | ConstantValue::String(_))) =
+            parse_literal(&caps[1])
+    {
+        return Some(inner);
+    }
+
+    const NON_REFERENCES: &[&str] = &["true", "false", "True", "False", "nil", "null", "None"];
+    if IDENTIFIER_RE.is_match(value) && !NON_REFERENCES.contains(&value) {
+        return Some(ConstantValue::Reference(value.to_string()));
+    }
+
+    None
+}
+
+/// Cut a trailing `//` or `#` comment that is not inside a string literal
+fn strip_trailing_comment(value: &str) -> &str {
+    let bytes = value.as_bytes();
+    let mut quote = None;
+    let mut i = 0;
+
+    while i < bytes.len() {
+        match (quote, bytes[i]) {
+            (Some(_), b'\\') => i += 1,
+            (Some(q), c) if c == q => quote = None,
+            (None, c @ (b'"' | b'\'' | b'`')) => quote = Some(c),
+            (None, b'/') if bytes.get(i + 1) == Some(&b'/') => return &value[..i],
+            (None, b'#') if i > 0 && bytes[i - 1] == b' ' => return &value[..i],
+            _ => {}
+        }
+        i += 1;
+    }
+
+    value
+}
+
+/// Decorators and attributes (`@app.route(...)`, `@Test`, `#[test]`) directly above a definition
+fn preceding_annotations(lines: &[&str], def_idx: usize) -> Vec<String> {
+    let mut annotations = Vec::new();
+    for line in lines[..def_idx].iter().rev() {
+        let trimmed = line.trim();
+        if trimmed.starts_with('@') || trimmed.starts_with("#[") {
+            annotations.push(trimmed.to_string());
+        } else if !trimmed.starts_with("//") || trimmed.is_empty() {
+            break;
+        }
+    }
+    annotations.reverse();
+    annotations
+}
+
+/// Find the index of the line closing the brace block opened at or after `start`
+fn brace_block_end(lines: &[&str], start: usize) -> usize {
+    let mut depth = 0i32;
+    let mut opened = false;
+
+    for (idx, line) in lines.iter().enumerate().skip(start) {
+        depth += brace_delta(line);
+        if depth > 0 {
+            opened = true;
+        }
+        if opened && depth <= 0 {
+            return idx;
+        }
+        // Declarations without a body (interfaces, abstract methods, prototypes)
+        if !opened && line.trim_end().ends_with(';') {
+            return idx;
+        }
+    }
+
+    lines.len().saturating_sub(1).max(start)
+}
+
+/// Net brace depth change of a line, ignoring braces in strings and line comments
+fn brace_delta(line: &str) -> i32 {
+    let bytes = line.as_bytes();
+    let mut delta = 0;
+    let mut i = 0;
+
+    while i < bytes.len() {
+        match bytes[i] {
+            b'/' if bytes.get(i + 1) == Some(&b'/') => break,
+            quote @ (b'"' | b'\'' | b'`') => {
+                // Skip to the closing quote; an unclosed quote is treated as code
+                let mut j = i + 1;
+                while j < bytes.len() && bytes[j] != quote {
+                    if bytes[j] == b'\\' {
+                        j += 1;
+                    }
+                    j += 1;
+                }
+                if j < bytes.len() {
+                    i = j;
+                }
+            }
+            b'{' => delta += 1,
+            b'}' => delta -= 1,
+            _ => {}
+        }
+        i += 1;
+    }
+
+    delta
+}
+
+/// Visit the bytes of `text` outside comments and string and rune literals
+/// with their bracket depth; brackets get the depth outside them. Stops
+/// when `visit` returns false.
+pub(crate) fn scan_code(text: &str, mut visit: impl FnMut(usize, u8, i32) -> bool) {
+    let bytes = text.as_bytes();
+    let mut depth = 0;
+    let mut i = 0;
+    while i < bytes.len() {
+        let byte = bytes[i];
+        match byte {
+            b'/' if bytes.get(i + 1) == Some(&b'/') => {
+                // Up to (and visiting) the newline
+                while i + 1 < bytes.len() && bytes[i + 1] != b'\n' {
+                    i += 1;
+                }
+            }
+            b'/' if bytes.get(i + 1) == Some(&b'*') => {
+                i += 2;
+                while i + 1 < bytes.len() && !(bytes[i] == b'*' && bytes[i + 1] == b'/') {
+                    i += 1;
+                }
+                i += 1;
+            }
+            b'"' | b'\'' | b'`' => {
+                i += 1;
+                while i < bytes.len() && bytes[i] != byte {
+                    if bytes[i] == b'\\' && byte != b'`' {
+                        i += 1;
+                    }
+                    i += 1;
+                }
+            }
+            b'(' | b'[' | b'{' => {
+                if !visit(i, byte, depth) {
+                    return;
+                }
+                depth += 1;
+            }
+            b')' | b']' | b'}' => {
+                depth -= 1;
+                if !visit(i, byte, depth) {
+                    return;
+                }
+            }
+            _ => {
+                if !visit(i, byte, depth) {
+                    return;
+                }
+            }
+        }
+        i += 1;
+    }
+}
+
+/// Offset of the bracket closing the one at `open`
+pub(crate) fn closing(text: &str, open: usize) -> Option<usize> {
+    let mut found = None;
+    scan_code(&text[open..], |i, byte, depth| {
+        if i > 0 && depth == 0 && matches!(byte, b')' | b']' | b'}') {
+            found = Some(open + i);
+            return false;
+        }
+        true
+    });
+    found
+}
+
+/// Find the index of the last line of an indentation-delimited (Python) block
+fn indent_block_end(lines: &[&str], start: usize) -> usize {
+    let indent_of = |line: &str| line.len() - line.trim_start().len();
+    let def_indent = indent_of(lines[start]);
+
+    // Skip over a signature spanning multiple lines
+    let mut body_start = start;
+    while body_start < lines.len() {
+        let code = lines[body_start].split('#').next().unwrap_or("").trim_end();
+        if code.ends_with(':') {
+            break;
+        }
+        body_start += 1;
+    }
+
+    let mut end = body_start.min(lines.len().saturating_sub(1));
+    for (idx, line) in lines.iter().enumerate().skip(body_start + 1) {
+        let trimmed = line.trim();
+        if trimmed.is_empty() || trimmed.starts_with('#') {
+            continue;
+        }
+        if indent_of(line) <= def_indent {
+            break;
+        }
+        end = idx;
+    }
+
+    end
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
@@ -387,4 +903,97 @@ mod tests {
         let result = parse_file("code", "cobol");
         assert!(result.is_err());
     }
+
+    #[test]
+    fn test_parse_go_imports() {
+        let source = "package main\n\nimport \"fmt\"\nimport legacy \"crypto/md5\"\n\nimport (\n\t\"crypto/rsa\"\n\t// comment\n\tsigner \"acme.io/pay/internal/sign\"\n\t_ \"embed\"\n)\n\nfunc main() { fmt.Println(\"(\") }\n";
+        let result = parse_file(source, "go").unwrap();
+        assert_eq!(
+            result.imports,
+            vec![
+                "fmt",
+                "crypto/md5",
+                "crypto/rsa",
+                "acme.io/pay/internal/sign",
+                "embed"
+            ]
+        );
+    }
+
+    #[test]
+    fn test_function_ranges_go() {
+        let source = "package signer\n\nfunc NewSigner() *ecdsa.PrivateKey {\n\tkey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)\n\treturn key\n}\n\nfunc (s *Service) Sign() {\n\tNewSigner()\n}\n";
+        let result = parse_file(source, "go").unwrap();
+        assert_eq!(result.functions.len(), 2);
+        assert_eq!(result.functions[0].name, "NewSigner");
+        assert_eq!(
+            (result.functions[0].line, result.functions[0].end_line),
+            (3, 6)
+        );
+        assert_eq!(result.functions[1].name, "Sign");
+        assert_eq!(result.enclosing_function(9).unwrap().name, "Sign");
+        assert!(
+            result.functions[1]
+                .signature
+                .starts_with("func (s *Service)")
+        );
+    }
+
+    #[test]
+    fn test_annotations_and_call_args() {
+        let source = "@app.route('/sign', methods=['POST'])\ndef sign():\n    register(handler, \"a,b\", f(1, 2))\n";
+        let result = parse_file(source, "python").unwrap();
+        assert_eq!(
+            result.functions[0].annotations,
+            vec!["@app.route('/sign', methods=['POST'])"]
+        );
+        let call = result
+            .function_calls
+            .iter()
+            .find(|c| c.name == "register")
+            .unwrap();
+        assert_eq!(call.args, vec!["handler", "\"a,b\"", "f(1, 2)"]);
+    }
+
+    #[test]
+    fn test_function_ranges_python() {
+        let source = "def weak_hash(data):\n    return hashlib.md5(data)\n\n\ndef main():\n    weak_hash(b'x')\n";
+        let result = parse_file(source, "python").unwrap();
+        assert_eq!(result.functions.len(), 2);
+        assert_eq!(
+            (result.functions[0].line, result.functions[0].end_line),
+            (1, 2)
+        );
+        assert_eq!(
+            (result.functions[1].line, result.functions[1].end_line),
+            (5, 6)
+        );
+    }
+
+    #[test]
+    fn test_constant_definitions() {
+        let source = "package config\n\nconst (\n\tKeyBits = 2048 // bits\n\tAlg     = \"RSA\"\n)\n\nvar Bits = KeyBits\n\nvar cfg = Config{\n\tCurve: os.Getenv(\"CURVE\"),\n\tHash:  getEnv(\"HASH\", \"SHA1\"),\n}\n";
+        let result = parse_file(source, "go").unwrap();
+        let constants: Vec<(&str, &ConstantValue)> = result
+            .constants
+            .iter()
+            .map(|c| (c.name.as_str(), &c.value))
+            .collect();
+        assert_eq!(
+            constants,
+            vec![
+                ("KeyBits", &ConstantValue::Integer(2048)),
+                ("Alg", &ConstantValue::String("RSA".to_string())),
+                ("Bits", &ConstantValue::Reference("KeyBits".to_string())),
+                ("Hash", &ConstantValue::String("SHA1".to_string())),
+            ]
+        );
+        assert_eq!(result.constants[3].env_var.as_deref(), Some("HASH"));
+
+        let source = "import os\n\nKEY_SIZE = int(os.environ.get(\"KEY_SIZE\", \"1024\"))\n\nclass Settings:\n    CURVE = \"secp256k1\"\n    timeout = 30\n";
+        let result = parse_file(source, "python").unwrap();
+        let names: Vec<&str> = result.constants.iter().map(|c| c.name.as_str()).collect();
+        assert_eq!(names, vec!["KEY_SIZE", "CURVE"]);
+        assert_eq!(result.constants[0].value, ConstantValue::Integer(1024));
+    }
 }
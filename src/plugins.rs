@@ -0,0 +1,589 @@
+//! WASM plugin detectors
+//!
+//! Plugins are WebAssembly modules that implement a small ABI (version 1):
+//!
+//! | export                                  | signature                       |
+//! |-----------------------------------------|---------------------------------|
+//! | `memory`                                | linear memory                   |
+//! | `pqc_abi_version`                       | `() -> i32`, must return `1`    |
+//! | `pqc_alloc`                             | `(len: i32) -> i32`             |
+//! | `pqc_scan`                              | `(path_ptr, path_len, content_ptr, content_len: i32) -> i64` |
+//! | `pqc_file_extensions` (optional)        | `() -> i64`                     |
+//!
+//! The host copies the file path and content into buffers obtained from
+//! `pqc_alloc` and calls `pqc_scan`, which returns `(ptr << 32) | len` of a
+//! UTF-8 JSON document:
+//!
+//! ```json
+//! {"findings": [{"crypto_type": "RSA", "line": 3, "column": 4, "end_column": 7,
+//!                "severity": "high", "message": "...", "key_size": 1024}]}
+//! ```
+//!
+//! `pqc_file_extensions` returns a comma-separated list (`"conf,ini"`) of
+//! extensions the plugin wants to see, including files the scanner has no
+//! language support for. Without it the plugin receives every scanned source file.
+//!
+//! Modules run in a sandbox: no host functions are linked (modules with
+//! imports, including WASI, fail to instantiate), execution is bounded by
+//! fuel, and linear memory by a size cap. Every call gets a fresh instance.
+//! The runtime needs the `plugins` feature; decoding of plugin output is
+//! always available.
+
+use crate::audit::{score_vulnerability, severity_for_score};
+use crate::types::*;
+use serde::Deserialize;
+use thiserror::Error;
+
+/// ABI version implemented by the host
+pub const PLUGIN_ABI_VERSION: i32 = 1;
+
+/// Largest JSON document accepted from a plugin
+const MAX_OUTPUT_LEN: usize = 4 * 1024 * 1024;
+
+#[derive(Error, Debug)]
+pub enum PluginError {
+    #[error("Invalid plugin module: {0}")]
+    InvalidModule(String),
+
+    #[error("Plugin ABI version {0} is not supported (expected {expected})", expected = PLUGIN_ABI_VERSION)]
+    UnsupportedAbi(i32),
+
+    #[error("Plugin is missing export: {0}")]
+    MissingExport(String),
+
+    #[error("Plugin trapped: {0}")]
+    Trap(String),
+
+    #[error("Plugin output too large: {0} bytes (max: {max})", max = MAX_OUTPUT_LEN)]
+    OutputTooLarge(usize),
+
+    #[error("Invalid plugin output: {0}")]
+    InvalidOutput(String),
+}
+
+/// Sandbox limits applied to every plugin call
+#[derive(Debug, Clone, Copy)]
+pub struct PluginLimits {
+    /// Fuel units per call (roughly one per executed instruction)
+    pub fuel: u64,
+    /// Maximum linear memory in bytes
+    pub memory_bytes: usize,
+}
+
+impl Default for PluginLimits {
+    fn default() -> Self {
+        Self {
+            fuel: 500_000_000,
+            memory_bytes: 64 * 1024 * 1024,
+        }
+    }
+}
+
+#[derive(Debug, Deserialize)]
+struct PluginOutput {
+    #[serde(default)]
+    findings: Vec<PluginFinding>,
+}
+
+#[derive(Debug, Deserialize)]
+struct PluginFinding {
+    crypto_type: String,
+    line: usize,
+    #[serde(default)]
+    column: usize,
+    #[serde(default)]
+    end_column: Option<usize>,
+    #[serde(default)]
+    severity: Option<Severity>,
+    message: String,
+    #[serde(default)]
+    recommendation: Option<String>,
+    #[serde(default)]
+    key_size: Option<u32>,
+    #[serde(default)]
+    confidence: Option<Confidence>,
+    #[serde(default)]
+    context: Option<String>,
+}
+
+/// Convert the JSON document returned by `pqc_scan` into findings
+///
+/// `content` is the scanned file, used for context snippets when the plugin
+/// does not provide one.
+pub fn decode_plugin_output(
+    plugin: &str,
+    json: &str,
+    content: &str,
+) -> Result<Vec<Vulnerability>, PluginError> {
+    if json.len() > MAX_OUTPUT_LEN {
+        return Err(PluginError::OutputTooLarge(json.len()));
+    }
+    let output: PluginOutput =
+        serde_json::from_str(json).map_err(|e| PluginError::InvalidOutput(e.to_string()))?;
+
+    output
+        .findings
+        .into_iter()
+        .map(|finding| {
+            let crypto_type = CryptoType::from_string(&finding.crypto_type).ok_or_else(|| {
+                PluginError::InvalidOutput(format!("unknown crypto type '{}'", finding.crypto_type))
+            })?;
+            if finding.line == 0 {
+                return Err(PluginError::InvalidOutput(
+                    "line numbers are 1-based".to_string(),
+                ));
+            }
+
+            let risk_score = score_vulnerability(&crypto_type, finding.key_size);
+            let severity = finding
+                .severity
+                .unwrap_or_else(|| severity_for_score(risk_score));
+            let context = finding.context.unwrap_or_else(|| {
+                content
+                    .lines()
+                    .nth(finding.line - 1)
+                    .unwrap_or_default()
+                    .trim()
+                    .to_string()
+            });
+
+            Ok(Vulnerability {
+                end_column: finding.end_column.unwrap_or(finding.column),
+                match_kind: MatchKind::ApiCall,
+                confidence: finding.confidence.unwrap_or_default(),
+                context,
+                message: format!("{} [plugin: {}]", finding.message, plugin),
+                recommendation: finding.recommendation.unwrap_or_default(),
+                key_size: finding.key_size,
+                ..Vulnerability::new(
+                    crypto_type,
+                    severity,
+                    risk_score,
+                    finding.line,
+                    finding.column,
+                )
+            })
+        })
+        .collect()
+}
+
+#[cfg(feature = "plugins")]
+pub use runtime::WasmPlugin;
+
+#[cfg(feature = "plugins")]
+mod runtime {
+    use super::*;
+    use wasmi::{Config, Engine, Instance, Linker, Module, Store, StoreLimits, StoreLimitsBuilder};
+
+    struct HostState {
+        limits: StoreLimits,
+    }
+
+    /// A compiled plugin module
+    pub struct WasmPlugin {
+        pub name: String,
+        /// Extensions requested through `pqc_file_extensions`; `None` means all source files
+        pub extensions: Option<Vec<String>>,
+        engine: Engine,
+        module: Module,
+        limits: PluginLimits,
+    }
+
+    impl WasmPlugin {
+        /// Compile a module and check its ABI version
+        pub fn load(name: &str, wasm: &[u8], limits: PluginLimits) -> Result<Self, PluginError> {
+            let mut config = Config::default();
+            config.consume_fuel(true);
+            let engine = Engine::new(&config);
+            let module = Module::new(&engine, wasm)
+                .map_err(|e| PluginError::InvalidModule(e.to_string()))?;
+            if module.imports().next().is_some() {
+                return Err(PluginError::InvalidModule(
+                    "plugins must not import host functions".to_string(),
+                ));
+            }
+
+            let mut plugin = Self {
+                name: name.to_string(),
+                extensions: None,
+                engine,
+                module,
+                limits,
+            };
+
+            let (mut store, instance) = plugin.instantiate()?;
+            let version = instance
+                .get_typed_func::<(), i32>(&store, "pqc_abi_version")
+                .map_err(|_| PluginError::MissingExport("pqc_abi_version".to_string()))?
+                .call(&mut store, ())
+                .map_err(|e| PluginError::Trap(e.to_string()))?;
+            if version != PLUGIN_ABI_VERSION {
+                return Err(PluginError::UnsupportedAbi(version));
+            }
+
+            if let Ok(extensions) =
+                instance.get_typed_func::<(), i64>(&store, "pqc_file_extensions")
+            {
+                let packed = extensions
+                    .call(&mut store, ())
+                    .map_err(|e| PluginError::Trap(e.to_string()))?;
+                let list = read_packed(&store, &instance, packed)?;
+                plugin.extensions = Some(
+                    list.split(',')
+                        .map(|ext| ext.trim().trim_start_matches('.').to_lowercase())
+                        .filter(|ext| !ext.is_empty())
+                        .collect(),
+                );
+            }
+
+            Ok(plugin)
+        }
+
+        /// Whether the plugin wants to see a file with this extension
+        pub fn accepts(&self, extension: Option<&str>, supported_source: bool) -> bool {
+            match (&self.extensions, extension) {
+                (Some(extensions), Some(ext)) => {
+                    extensions.iter().any(|e| e.eq_ignore_ascii_case(ext))
+                }
+                (Some(_), None) => false,
+                (None, _) => supported_source,
+            }
+        }
+
+        /// Run the plugin on one file in a fresh, limited instance
+        pub fn scan(&self, path: &str, content: &str) -> Result<Vec<Vulnerability>, PluginError> {
+            let (mut store, instance) = self.instantiate()?;
+            let path_ptr = write_input(&mut store, &instance, path.as_bytes())?;
+            let content_ptr = write_input(&mut store, &instance, content.as_bytes())?;
+
+            let scan = instance
+                .get_typed_func::<(i32, i32, i32, i32), i64>(&store, "pqc_scan")
+                .map_err(|_| PluginError::MissingExport("pqc_scan".to_string()))?;
+            let packed = scan
+                .call(
+                    &mut store,
+                    (
+                        path_ptr,
+                        path.len() as i32,
+                        content_ptr,
+                        content.len() as i32,
+                    ),
+                )
+                .map_err(|e| PluginError::Trap(e.to_string()))?;
+
+            let json = read_packed(&store, &instance, packed)?;
+            decode_plugin_output(&self.name, &json, content)
+        }
+
+        fn instantiate(&self) -> Result<(Store<HostState>, Instance), PluginError> {
+            let limits = StoreLimitsBuilder::new()
+                .memory_size(self.limits.memory_bytes)
+                .build();
+            let mut store = Store::new(&self.engine, HostState { limits });
+            store.limiter(|state| &mut state.limits);
+            store
+                .set_fuel(self.limits.fuel)
+                .map_err(|e| PluginError::InvalidModule(e.to_string()))?;
+
+            // No host functions: the module only sees its own memory
+            let linker = <Linker<HostState>>::new(&self.engine);
+            let instance = linker
+                .instantiate(&mut store, &self.module)
+                .and_then(|pre| pre.start(&mut store))
+                .map_err(|e| PluginError::Trap(e.to_string()))?;
+            Ok((store, instance))
+        }
+    }
+
+    fn write_input(
+        store: &mut Store<HostState>,
+        instance: &Instance,
+        bytes: &[u8],
+    ) -> Result<i32, PluginError> {
+        let alloc = instance
+            .get_typed_func::<i32, i32>(&*store, "pqc_alloc")
+            .map_err(|_| PluginError::MissingExport("pqc_alloc".to_string()))?;
+        let ptr = alloc
+            .call(&mut *store, bytes.len() as i32)
+            .map_err(|e| PluginError::Trap(e.to_string()))?;
+        memory(instance, store)?
+            .write(&mut *store, ptr as u32 as usize, bytes)
+            .map_err(|e| PluginError::Trap(e.to_string()))?;
+        Ok(ptr)
+    }
+
+    fn read_packed(
+        store: &Store<HostState>,
+        instance: &Instance,
+        packed: i64,
+    ) -> Result<String, PluginError> {
+        let ptr = (packed as u64 >> 32) as usize;
+        let len = (packed as u64 & 0xffff_ffff) as usize;
+        if len > MAX_OUTPUT_LEN {
+            return Err(PluginError::OutputTooLarge(len));
+        }
+        let mut buffer = vec![0u8; len];
+        memory(instance, store)?
+            .read(store, ptr, &mut buffer)
+            .map_err(|e| PluginError::Trap(e.to_string()))?;
+        String::from_utf8(buffer).map_err(|e| PluginError::InvalidOutput(e.to_string()))
+    }
+
+    fn memory(instance: &Instance, store: &Store<HostState>) -> Result<wasmi::Memory, PluginError> {
+        instance
+            .get_memory(store, "memory")
+            .ok_or_else(|| PluginError::MissingExport("memory".to_string()))
+    }
+
+    #[cfg(test)]
+    mod tests {
+        use super::*;
+
+        const TYPE_I32: u8 = 0; // () -> i32
+        const TYPE_ALLOC: u8 = 1; // (i32) -> i32
+        const TYPE_SCAN: u8 = 2; // (i32, i32, i32, i32) -> i64
+        const OUTPUT: &str = r#"{"findings":[{"crypto_type":"MD5","line":1,"message":"md5"}]}"#;
+
+        fn leb(mut value: u64) -> Vec<u8> {
+            let mut bytes = Vec::new();
+            loop {
+                let byte = (value & 0x7f) as u8;
+                value >>= 7;
+                if value == 0 {
+                    bytes.push(byte);
+                    return bytes;
+                }
+                bytes.push(byte | 0x80);
+            }
+        }
+
+        fn sleb(mut value: i64) -> Vec<u8> {
+            let mut bytes = Vec::new();
+            loop {
+                let byte = (value & 0x7f) as u8;
+                value >>= 7;
+                let done = (value == 0 && byte & 0x40 == 0) || (value == -1 && byte & 0x40 != 0);
+                bytes.push(if done { byte } else { byte | 0x80 });
+                if done {
+                    return bytes;
+                }
+            }
+        }
+
+        fn i32_const(value: i32) -> Vec<u8> {
+            [vec![0x41], sleb(value.into())].concat()
+        }
+
+        fn i64_const(value: i64) -> Vec<u8> {
+            [vec![0x42], sleb(value)].concat()
+        }
+
+        fn section(id: u8, items: &[Vec<u8>]) -> Vec<u8> {
+            let mut content = leb(items.len() as u64);
+            content.extend(items.concat());
+            [vec![id], leb(content.len() as u64), content].concat()
+        }
+
+        fn name(text: &str) -> Vec<u8> {
+            [leb(text.len() as u64), text.as_bytes().to_vec()].concat()
+        }
+
+        /// Assemble a module exporting `memory` and the given functions
+        ///
+        /// Each function is `(export name, type index, body)`, the body being
+        /// its instructions without locals or the final `end`. `OUTPUT` is
+        /// stored at address 0; `import` adds an `env.log` host function.
+        fn module(memory_pages: u8, import: bool, functions: &[(&str, u8, Vec<u8>)]) -> Vec<u8> {
+            let types = section(
+                1,
+                &[
+                    vec![0x60, 0, 1, 0x7f],
+                    vec![0x60, 1, 0x7f, 1, 0x7f],
+                    vec![0x60, 4, 0x7f, 0x7f, 0x7f, 0x7f, 1, 0x7e],
+                    vec![0x60, 0, 1, 0x7e],
+                ],
+            );
+            let imports = section(
+                2,
+                &[[name("env"), name("log"), vec![0x00, TYPE_I32]].concat()],
+            );
+            let first_index = u64::from(import);
+
+            let mut exports = vec![[name("memory"), vec![0x02, 0x00]].concat()];
+            exports.extend(functions.iter().enumerate().map(|(i, (export, _, _))| {
+                [name(export), vec![0x00], leb(first_index + i as u64)].concat()
+            }));
+            let bodies: Vec<Vec<u8>> = functions
+                .iter()
+                .map(|(_, _, body)| {
+                    let code = [vec![0x00], body.clone(), vec![0x0b]].concat();
+                    [leb(code.len() as u64), code].concat()
+                })
+                .collect();
+            let data = [vec![0x00], i32_const(0), vec![0x0b], name(OUTPUT)].concat();
+
+            let mut wasm = b"\0asm\x01\0\0\0".to_vec();
+            wasm.extend(types);
+            if import {
+                wasm.extend(imports);
+            }
+            wasm.extend(section(
+                3,
+                &functions
+                    .iter()
+                    .map(|(_, ty, _)| vec![*ty])
+                    .collect::<Vec<_>>(),
+            ));
+            wasm.extend(section(5, &[vec![0x00, memory_pages]]));
+            wasm.extend(section(7, &exports));
+            wasm.extend(section(10, &bodies));
+            wasm.extend(section(11, &[data]));
+            wasm
+        }
+
+        /// A one-page plugin whose `pqc_scan` body is `scan`
+        fn plugin_module(abi_version: i32, scan: Vec<u8>) -> Vec<u8> {
+            module(
+                1,
+                false,
+                &[
+                    ("pqc_abi_version", TYPE_I32, i32_const(abi_version)),
+                    ("pqc_alloc", TYPE_ALLOC, i32_const(1024)),
+                    ("pqc_scan", TYPE_SCAN, scan),
+                ],
+            )
+        }
+
+        fn limits() -> PluginLimits {
+            PluginLimits {
+                fuel: 10_000,
+                memory_bytes: 64 * 1024,
+            }
+        }
+
+        #[test]
+        fn test_scan_reads_findings() {
+            let wasm = plugin_module(1, i64_const(OUTPUT.len() as i64));
+            let plugin = WasmPlugin::load("md5", &wasm, limits()).unwrap();
+            assert!(plugin.extensions.is_none());
+
+            let findings = plugin.scan("a.conf", "hash = md5\n").unwrap();
+            assert_eq!(findings.len(), 1);
+            assert_eq!(findings[0].crypto_type, CryptoType::Md5);
+            assert_eq!(findings[0].context, "hash = md5");
+        }
+
+        #[test]
+        fn test_fuel_exhaustion_traps() {
+            // loop br 0 end, then an unreachable result
+            let spin = [vec![0x03, 0x40, 0x0c, 0x00, 0x0b], i64_const(0)].concat();
+            let plugin = WasmPlugin::load("spin", &plugin_module(1, spin), limits()).unwrap();
+            assert!(matches!(
+                plugin.scan("a.conf", ""),
+                Err(PluginError::Trap(_))
+            ));
+        }
+
+        #[test]
+        fn test_memory_cap() {
+            let scan = i64_const(OUTPUT.len() as i64);
+            let wasm = module(
+                2,
+                false,
+                &[
+                    ("pqc_abi_version", TYPE_I32, i32_const(1)),
+                    ("pqc_alloc", TYPE_ALLOC, i32_const(1024)),
+                    ("pqc_scan", TYPE_SCAN, scan),
+                ],
+            );
+            // Two pages do not fit a one-page cap
+            assert!(matches!(
+                WasmPlugin::load("big", &wasm, limits()),
+                Err(PluginError::Trap(_))
+            ));
+            let roomy = PluginLimits {
+                memory_bytes: 2 * 64 * 1024,
+                ..limits()
+            };
+            assert!(WasmPlugin::load("big", &wasm, roomy).is_ok());
+        }
+
+        #[test]
+        fn test_host_imports_rejected() {
+            let wasm = module(1, true, &[("pqc_abi_version", TYPE_I32, i32_const(1))]);
+            assert!(matches!(
+                WasmPlugin::load("wasi", &wasm, limits()),
+                Err(PluginError::InvalidModule(_))
+            ));
+        }
+
+        #[test]
+        fn test_abi_version_checked() {
+            let wasm = plugin_module(2, i64_const(0));
+            assert!(matches!(
+                WasmPlugin::load("future", &wasm, limits()),
+                Err(PluginError::UnsupportedAbi(2))
+            ));
+        }
+
+        #[test]
+        fn test_out_of_bounds_output_rejected() {
+            // 16 bytes at 0x7fff0000, far past the single page
+            let wasm = plugin_module(1, i64_const((0x7fff_0000_i64 << 32) | 16));
+            let plugin = WasmPlugin::load("oob", &wasm, limits()).unwrap();
+            assert!(matches!(
+                plugin.scan("a.conf", ""),
+                Err(PluginError::Trap(_))
+            ));
+
+            let wasm = plugin_module(1, i64_const((MAX_OUTPUT_LEN + 1) as i64));
+            let plugin = WasmPlugin::load("huge", &wasm, limits()).unwrap();
+            assert!(matches!(
+                plugin.scan("a.conf", ""),
+                Err(PluginError::OutputTooLarge(_))
+            ));
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_decode_plugin_output() {
+        let content = "[tls]\ncipher = RC4-SHA\nkey = rsa:1024\n";
+        let json = r#"{"findings": [
+            {"crypto_type": "RC4", "line": 2, "column": 9, "end_column": 12,
+             "message": "RC4 cipher configured"},
+            {"crypto_type": "RSA", "line": 3, "column": 6, "key_size": 1024,
+             "severity": "critical", "confidence": "high", "message": "Weak RSA key"}
+        ]}"#;
+
+        let findings = decode_plugin_output("legacy-conf", json, content).unwrap();
+        assert_eq!(findings.len(), 2);
+        assert_eq!(findings[0].crypto_type, CryptoType::Rc4);
+        assert_eq!(findings[0].context, "cipher = RC4-SHA");
+        assert_eq!(findings[0].confidence, Confidence::Medium);
+        assert!(findings[0].message.ends_with("[plugin: legacy-conf]"));
+        assert_eq!(findings[1].severity, Severity::Critical);
+        assert_eq!(findings[1].risk_score, 100);
+        assert_eq!(findings[1].confidence, Confidence::High);
+    }
+
+    #[test]
+    fn test_decode_rejects_invalid_output() {
+        assert!(matches!(
+            decode_plugin_output(
+                "p",
+                r#"{"findings": [{"crypto_type": "ROT13", "line": 1, "message": "x"}]}"#,
+                ""
+            ),
+            Err(PluginError::InvalidOutput(_))
+        ));
+        assert!(matches!(
+            decode_plugin_output("p", "not json", ""),
+            Err(PluginError::InvalidOutput(_))
+        ));
+    }
+}
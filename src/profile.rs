@@ -0,0 +1,224 @@
+//! Scan profiling
+//!
+//! Collects where a scan spends its time: per file, per language and per
+//! rule. File and language times are the analysis time of each file. Rule
+//! times come from a separate pass that runs every rule's pattern over the
+//! file on its own, so an expensive built-in regex or imported rule stands
+//! out even though the detectors run all rules in one pass per line.
+
+use crate::audit::builtin_rules;
+use crate::semgrep::CustomRule;
+use crate::types::*;
+use serde::{Serialize, Serializer};
+use std::time::{Duration, Instant};
+
+/// Time spent in one rule across the scan
+#[derive(Debug, Clone, Serialize)]
+pub struct RuleTiming {
+    pub rule_id: String,
+    pub crypto_type: CryptoType,
+    pub custom: bool,
+    #[serde(rename = "elapsed_ms", serialize_with = "millis")]
+    pub elapsed: Duration,
+    /// Pattern matches (built-in) or findings (custom)
+    pub matches: usize,
+    pub files: usize,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct LanguageTiming {
+    pub language: Language,
+    #[serde(rename = "elapsed_ms", serialize_with = "millis")]
+    pub elapsed: Duration,
+    pub files: usize,
+    pub lines: usize,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct FileTiming {
+    pub path: String,
+    pub language: Language,
+    #[serde(rename = "elapsed_ms", serialize_with = "millis")]
+    pub elapsed: Duration,
+    pub lines: usize,
+    pub findings: usize,
+}
+
+/// Profile of a scan, each list ordered slowest first
+#[derive(Debug, Clone, Serialize)]
+pub struct ScanProfile {
+    #[serde(rename = "total_ms", serialize_with = "millis")]
+    pub total: Duration,
+    pub rules: Vec<RuleTiming>,
+    pub languages: Vec<LanguageTiming>,
+    pub files: Vec<FileTiming>,
+}
+
+/// Accumulates timings while a scan runs
+#[derive(Debug, Default)]
+pub struct Profiler {
+    rules: Vec<RuleTiming>,
+    languages: Vec<LanguageTiming>,
+    files: Vec<FileTiming>,
+}
+
+impl Profiler {
+    /// Record the analysis time of a file
+    pub fn record_file(&mut self, path: &str, audit: &AuditResult, elapsed: Duration) {
+        let lines = audit.stats.lines_scanned;
+        match self
+            .languages
+            .iter_mut()
+            .find(|l| l.language == audit.language)
+        {
+            Some(timing) => {
+                timing.elapsed += elapsed;
+                timing.files += 1;
+                timing.lines += lines;
+            }
+            None => self.languages.push(LanguageTiming {
+                language: audit.language,
+                elapsed,
+                files: 1,
+                lines,
+            }),
+        }
+
+        self.files.push(FileTiming {
+            path: path.to_string(),
+            language: audit.language,
+            elapsed,
+            lines,
+            findings: audit.vulnerabilities.len(),
+        });
+    }
+
+    /// Time every built-in rule and the `rules` that apply to `language` over `source`
+    pub fn profile_rules(&mut self, source: &str, language: Language, rules: &[CustomRule]) {
+        let lines: Vec<&str> = source.lines().collect();
+        let mut timings: Vec<RuleTiming> = Vec::new();
+
+        for (crypto_type, pattern) in builtin_rules() {
+            let started = Instant::now();
+            let matches: usize = lines
+                .iter()
+                .map(|line| pattern.find_iter(line).count())
+                .sum();
+            let elapsed = started.elapsed();
+
+            // Several built-in patterns can share a rule (ECDSA names and curves)
+            match timings
+                .iter_mut()
+                .find(|t| t.rule_id == crypto_type.rule_id())
+            {
+                Some(timing) => {
+                    timing.elapsed += elapsed;
+                    timing.matches += matches;
+                }
+                None => timings.push(RuleTiming {
+                    rule_id: crypto_type.rule_id().to_string(),
+                    crypto_type: crypto_type.clone(),
+                    custom: false,
+                    elapsed,
+                    matches,
+                    files: 1,
+                }),
+            }
+        }
+
+        for rule in rules.iter().filter(|rule| rule.applies_to(language)) {
+            let started = Instant::now();
+            let matches = lines
+                .iter()
+                .enumerate()
+                .filter(|(idx, line)| rule.detect_line(line, idx + 1).is_some())
+                .count();
+            timings.push(RuleTiming {
+                rule_id: rule.id.clone(),
+                crypto_type: rule.crypto_type.clone(),
+                custom: true,
+                elapsed: started.elapsed(),
+                matches,
+                files: 1,
+            });
+        }
+
+        for timing in timings {
+            match self
+                .rules
+                .iter_mut()
+                .find(|r| r.rule_id == timing.rule_id && r.custom == timing.custom)
+            {
+                Some(total) => {
+                    total.elapsed += timing.elapsed;
+                    total.matches += timing.matches;
+                    total.files += 1;
+                }
+                None => self.rules.push(timing),
+            }
+        }
+    }
+
+    pub fn finish(mut self, total: Duration) -> ScanProfile {
+        self.rules.sort_by(|a, b| b.elapsed.cmp(&a.elapsed));
+        self.languages.sort_by(|a, b| b.elapsed.cmp(&a.elapsed));
+        self.files.sort_by(|a, b| b.elapsed.cmp(&a.elapsed));
+        ScanProfile {
+            total,
+            rules: self.rules,
+            languages: self.languages,
+            files: self.files,
+        }
+    }
+}
+
+fn millis<S: Serializer>(duration: &Duration, serializer: S) -> Result<S::Ok, S::Error> {
+    serializer.serialize_f64(duration.as_secs_f64() * 1000.0)
+}
+
+/// Export a profile as JSON
+pub fn export_profile_json(profile: &ScanProfile) -> Result<String, serde_json::Error> {
+    serde_json::to_string_pretty(profile)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::analyze;
+    use crate::semgrep::import_semgrep_rules;
+
+    #[test]
+    fn test_profiler() {
+        let import = import_semgrep_rules(
+            "rules:\n  - id: go-md5\n    languages: [go]\n    message: md5\n    pattern: md5.New()\n",
+        )
+        .unwrap();
+        let source = "h := md5.New()\ncurve := elliptic.P256() // ecdsa\n";
+        let audit = analyze(source, "go").unwrap();
+
+        let mut profiler = Profiler::default();
+        for path in ["a.go", "b.go"] {
+            profiler.record_file(path, &audit, Duration::from_millis(2));
+            profiler.profile_rules(source, Language::Go, &import.rules);
+        }
+        profiler.profile_rules(source, Language::Python, &import.rules);
+        let profile = profiler.finish(Duration::from_millis(10));
+
+        let ecdsa = profile
+            .rules
+            .iter()
+            .find(|r| r.rule_id == "pqc-ecdsa")
+            .unwrap();
+        assert_eq!(ecdsa.files, 3);
+        assert_eq!(ecdsa.matches, 6);
+        let custom = profile.rules.iter().find(|r| r.custom).unwrap();
+        assert_eq!((custom.files, custom.matches), (2, 2));
+
+        assert_eq!(profile.languages.len(), 1);
+        assert_eq!(profile.languages[0].elapsed, Duration::from_millis(4));
+        assert_eq!(profile.files.len(), 2);
+
+        let json = export_profile_json(&profile).unwrap();
+        assert!(json.contains("\"total_ms\": 10.0"));
+    }
+}
@@ -0,0 +1,372 @@
+//! Monorepo sub-projects
+//!
+//! A repository can hold many services, each rooted at its own manifest
+//! (`go.mod`, `package.json` or `pom.xml`). Files are attributed to the
+//! deepest project root that contains them, so each service gets its own
+//! aggregate, SC-13 report and compliance score next to the repository-wide
+//! rollup. Files outside every manifest belong to an implicit root project.
+
+use crate::compliance::generate_project_sc13_report;
+use crate::types::*;
+use lazy_static::lazy_static;
+use regex::Regex;
+use serde::{Deserialize, Serialize};
+
+lazy_static! {
+    static ref POM_PARENT: Regex = Regex::new(r"(?s)<parent>.*?</parent>")
+        .expect("POM_PARENT: Invalid regex pattern - this is a compile-time bug");
+    static ref POM_ARTIFACT_ID: Regex = Regex::new(r"<artifactId>\s*([^<\s]+)\s*</artifactId>")
+        .expect("POM_ARTIFACT_ID: Invalid regex pattern - this is a compile-time bug");
+}
+
+/// Build manifest that marks a project root
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord, Serialize, Deserialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum ManifestKind {
+    GoMod,
+    PomXml,
+    PackageJson,
+}
+
+impl ManifestKind {
+    pub fn from_file_name(name: &str) -> Option<Self> {
+        match name {
+            "go.mod" => Some(ManifestKind::GoMod),
+            "pom.xml" => Some(ManifestKind::PomXml),
+            "package.json" => Some(ManifestKind::PackageJson),
+            _ => None,
+        }
+    }
+
+    pub fn file_name(&self) -> &'static str {
+        match self {
+            ManifestKind::GoMod => "go.mod",
+            ManifestKind::PomXml => "pom.xml",
+            ManifestKind::PackageJson => "package.json",
+        }
+    }
+}
+
+/// A project root within the scanned tree
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct SubProject {
+    /// Module path, package name or artifactId; the directory name if the manifest has none
+    pub name: String,
+    /// Directory relative to the scan root with `/` separators; empty for the root
+    pub root: String,
+    /// `None` for the implicit project of files outside every manifest
+    pub manifest: Option<ManifestKind>,
+}
+
+impl SubProject {
+    /// Project declared by the manifest `content` in directory `root`
+    pub fn from_manifest(root: &str, kind: ManifestKind, content: &str) -> Self {
+        let root = normalize(root);
+        let declared = match kind {
+            ManifestKind::GoMod => go_module_path(content),
+            ManifestKind::PackageJson => serde_json::from_str::<serde_json::Value>(content)
+                .ok()
+                .and_then(|json| json.get("name")?.as_str().map(str::to_string)),
+            ManifestKind::PomXml => {
+                let without_parent = POM_PARENT.replace_all(content, "");
+                POM_ARTIFACT_ID
+                    .captures(&without_parent)
+                    .map(|caps| caps[1].to_string())
+            }
+        };
+
+        Self {
+            name: declared
+                .filter(|name| !name.is_empty())
+                .unwrap_or_else(|| directory_name(&root)),
+            root,
+            manifest: Some(kind),
+        }
+    }
+
+    /// Project of the files outside every manifest
+    pub fn implicit_root() -> Self {
+        Self {
+            name: "(root)".to_string(),
+            root: String::new(),
+            manifest: None,
+        }
+    }
+
+    /// Whether `path` (relative to the scan root) is inside this project's root
+    pub fn contains(&self, path: &str) -> bool {
+        let path = normalize(path);
+        self.root.is_empty()
+            || path
+                .strip_prefix(&self.root)
+                .is_some_and(|rest| rest.starts_with('/'))
+    }
+
+    /// File-name-safe identifier derived from the root (`services/payments` -> `services-payments`)
+    pub fn slug(&self) -> String {
+        if self.root.is_empty() {
+            return "root".to_string();
+        }
+        self.root
+            .chars()
+            .map(|c| {
+                if c.is_ascii_alphanumeric() || c == '-' || c == '_' || c == '.' {
+                    c
+                } else {
+                    '-'
+                }
+            })
+            .collect()
+    }
+}
+
+impl Default for SubProject {
+    fn default() -> Self {
+        Self::implicit_root()
+    }
+}
+
+/// Project roots of a tree, for attributing files
+#[derive(Debug, Clone, Default)]
+pub struct ProjectLayout {
+    projects: Vec<SubProject>,
+    implicit: SubProject,
+}
+
+impl ProjectLayout {
+    /// A directory with several manifests is one project, named after the first of
+    /// `go.mod`, `pom.xml` and `package.json`
+    pub fn new(mut projects: Vec<SubProject>) -> Self {
+        projects.sort_by(|a, b| a.root.cmp(&b.root).then(a.manifest.cmp(&b.manifest)));
+        projects.dedup_by(|later, first| later.root == first.root);
+        Self {
+            projects,
+            implicit: SubProject::implicit_root(),
+        }
+    }
+
+    pub fn projects(&self) -> &[SubProject] {
+        &self.projects
+    }
+
+    /// Deepest project containing `path`
+    pub fn project_for(&self, path: &str) -> &SubProject {
+        self.projects
+            .iter()
+            .filter(|project| project.contains(path))
+            .max_by_key(|project| project.root.len())
+            .unwrap_or(&self.implicit)
+    }
+
+    /// Group `(path, item)` pairs by project, in project root order
+    pub fn group<'a, T>(
+        &self,
+        items: impl IntoIterator<Item = (&'a str, T)>,
+    ) -> Vec<(&SubProject, Vec<(&'a str, T)>)> {
+        let mut groups: Vec<(&SubProject, Vec<(&'a str, T)>)> = Vec::new();
+        for (path, item) in items {
+            let project = self.project_for(path);
+            match groups.iter_mut().find(|(p, _)| p.root == project.root) {
+                Some((_, members)) => members.push((path, item)),
+                None => groups.push((project, vec![(path, item)])),
+            }
+        }
+        groups.sort_by(|a, b| a.0.root.cmp(&b.0.root));
+        groups
+    }
+}
+
+/// Totals and compliance of one project, or of the whole tree
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ProjectSummary {
+    pub name: String,
+    pub root: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub manifest: Option<ManifestKind>,
+    pub files_scanned: usize,
+    pub lines_scanned: usize,
+    pub total_vulnerabilities: usize,
+    pub critical_count: usize,
+    pub high_count: usize,
+    pub risk_score: u32,
+    /// SC-13 compliance score (0-100)
+    pub compliance_score: u32,
+    pub implementation_status: ImplementationStatus,
+}
+
+/// Per-project summaries with the repository-wide totals
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ProjectRollup {
+    pub target: String,
+    pub projects: Vec<ProjectSummary>,
+    pub total: ProjectSummary,
+}
+
+/// Summarize the files of one project; `None` when there are none
+pub fn summarize_project(
+    project: &SubProject,
+    files: &[(&str, &AuditResult)],
+) -> Option<ProjectSummary> {
+    let audit = AuditResult::aggregate(files.iter().map(|(_, audit)| *audit))?;
+    let report = generate_project_sc13_report(files.iter().copied())?;
+
+    Some(ProjectSummary {
+        name: project.name.clone(),
+        root: project.root.clone(),
+        manifest: project.manifest,
+        files_scanned: files.len(),
+        lines_scanned: audit.stats.lines_scanned,
+        total_vulnerabilities: audit.stats.total_vulnerabilities,
+        critical_count: audit.stats.critical_count,
+        high_count: audit.stats.high_count,
+        risk_score: audit.risk_score,
+        compliance_score: report.summary.compliance_score,
+        implementation_status: report.control_assessment.implementation_status,
+    })
+}
+
+/// Summaries of every project in `layout` with files, and of the whole tree
+pub fn project_rollup<'a>(
+    target: &str,
+    layout: &ProjectLayout,
+    files: impl IntoIterator<Item = (&'a str, &'a AuditResult)>,
+) -> Option<ProjectRollup> {
+    let files: Vec<(&str, &AuditResult)> = files.into_iter().collect();
+    let projects = layout
+        .group(files.iter().copied())
+        .into_iter()
+        .filter_map(|(project, members)| summarize_project(project, &members))
+        .collect();
+    let whole = SubProject {
+        name: directory_name(target),
+        root: String::new(),
+        manifest: None,
+    };
+
+    Some(ProjectRollup {
+        target: target.to_string(),
+        projects,
+        total: summarize_project(&whole, &files)?,
+    })
+}
+
+/// Export a project rollup as JSON
+pub fn export_project_rollup_json(rollup: &ProjectRollup) -> Result<String, serde_json::Error> {
+    serde_json::to_string_pretty(rollup)
+}
+
+/// Module path declared by a `go.mod`
+pub(crate) fn go_module_path(content: &str) -> Option<String> {
+    content.lines().find_map(|line| {
+        let line = line.split("//").next()?.trim();
+        let path = line.strip_prefix("module")?;
+        path.starts_with(char::is_whitespace)
+            .then(|| path.trim().trim_matches('"').to_string())
+    })
+}
+
+fn normalize(path: &str) -> String {
+    path.replace('\\', "/").trim_matches('/').to_string()
+}
+
+fn directory_name(path: &str) -> String {
+    normalize(path)
+        .rsplit('/')
+        .next()
+        .filter(|name| !name.is_empty())
+        .unwrap_or(".")
+        .to_string()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::analyze;
+
+    #[test]
+    fn test_manifest_names_and_attribution() {
+        let go = SubProject::from_manifest(
+            "services/payments",
+            ManifestKind::GoMod,
+            "// payments\nmodule github.com/acme/payments // v2 soon\n\ngo 1.24\n",
+        );
+        assert_eq!(go.name, "github.com/acme/payments");
+        let node = SubProject::from_manifest(
+            "web\\frontend",
+            ManifestKind::PackageJson,
+            r#"{"name": "@acme/frontend", "version": "1.0.0"}"#,
+        );
+        assert_eq!(
+            (node.name.as_str(), node.root.as_str()),
+            ("@acme/frontend", "web/frontend")
+        );
+        let pom = SubProject::from_manifest(
+            "services/billing",
+            ManifestKind::PomXml,
+            "<project><parent><artifactId>acme-parent</artifactId></parent>\n<artifactId>billing</artifactId><dependencies><dependency><artifactId>bcprov</artifactId></dependency></dependencies></project>",
+        );
+        assert_eq!(pom.name, "billing");
+        let unnamed = SubProject::from_manifest("tools/cli", ManifestKind::PackageJson, "{}");
+        assert_eq!(unnamed.name, "cli");
+
+        let nested = SubProject::from_manifest("services/payments/worker", ManifestKind::GoMod, "");
+        let layout = ProjectLayout::new(vec![
+            go.clone(),
+            node,
+            pom,
+            nested,
+            SubProject::from_manifest("services/payments", ManifestKind::PackageJson, "{}"),
+        ]);
+        assert_eq!(layout.projects().len(), 4);
+        assert_eq!(layout.project_for("services/payments/main.go"), &go);
+        assert_eq!(
+            layout.project_for("services/payments/worker/run.go").name,
+            "worker"
+        );
+        assert_eq!(
+            layout.project_for("services/payments-v2/main.go").name,
+            "(root)"
+        );
+        assert_eq!(
+            layout.project_for("services/payments-v2/main.go").slug(),
+            "root"
+        );
+        assert_eq!(go.slug(), "services-payments");
+    }
+
+    #[test]
+    fn test_project_rollup() {
+        let payments = analyze("k, _ := rsa.GenerateKey(r, 1024)\n", "go").unwrap();
+        let clean = analyze("fmt.Println(\"ok\")\n", "go").unwrap();
+        let frontend = analyze("const h = crypto.createHash('md5');\n", "javascript").unwrap();
+        let layout = ProjectLayout::new(vec![
+            SubProject::from_manifest("payments", ManifestKind::GoMod, "module acme/payments\n"),
+            SubProject::from_manifest("frontend", ManifestKind::PackageJson, "{\"name\":\"web\"}"),
+        ]);
+
+        let rollup = project_rollup(
+            "/src/monorepo",
+            &layout,
+            [
+                ("payments/main.go", &payments),
+                ("payments/util.go", &clean),
+                ("frontend/app.js", &frontend),
+                ("scripts/gen.go", &clean),
+            ],
+        )
+        .unwrap();
+
+        let names: Vec<&str> = rollup.projects.iter().map(|p| p.name.as_str()).collect();
+        assert_eq!(names, vec!["(root)", "web", "acme/payments"]);
+        let payments = &rollup.projects[2];
+        assert_eq!((payments.files_scanned, payments.critical_count), (2, 1));
+        assert_eq!(rollup.projects[0].total_vulnerabilities, 0);
+        assert_eq!(rollup.projects[0].compliance_score, 100);
+        assert_eq!(rollup.total.name, "monorepo");
+        assert_eq!(rollup.total.files_scanned, 4);
+        assert_eq!(rollup.total.total_vulnerabilities, 2);
+
+        let json = export_project_rollup_json(&rollup).unwrap();
+        assert!(json.contains("\"manifest\": \"go-mod\""));
+    }
+}
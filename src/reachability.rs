@@ -0,0 +1,576 @@
+//! Reachability analysis
+//!
+//! Uses the project call graph to label every finding as reachable from an
+//! entry point (`main`, exported API, HTTP handler registration, test,
+//! module-level code) or as apparently dead code. Reachable findings can then
+//! be prioritized, and dead crypto code listed for deletion.
+
+use crate::call_graph::{CallGraph, callee_name};
+use crate::types::*;
+use serde::{Deserialize, Serialize};
+use std::collections::HashSet;
+
+/// Maximum number of entry point names recorded on a single finding
+const MAX_ENTRY_POINT_SAMPLES: usize = 10;
+
+/// Route registration calls whose handler arguments become entry points
+const ROUTE_REGISTRATIONS: &[&str] = &[
+    "HandleFunc",
+    "Handle",
+    "HandlerFunc",
+    "GET",
+    "POST",
+    "PUT",
+    "DELETE",
+    "PATCH",
+    "Any",
+    "get",
+    "post",
+    "put",
+    "delete",
+    "patch",
+    "all",
+    "route",
+    "add_url_rule",
+    "add_api_route",
+    "add_route",
+];
+
+/// Annotation fragments that mark a function as an HTTP route handler
+const ROUTE_ANNOTATIONS: &[&str] = &[
+    ".route(",
+    ".get(",
+    ".post(",
+    ".put(",
+    ".delete(",
+    ".patch(",
+    "@GetMapping",
+    "@PostMapping",
+    "@PutMapping",
+    "@DeleteMapping",
+    "@PatchMapping",
+    "@RequestMapping",
+    "@Path",
+    "@GET",
+    "@POST",
+    "#[get(",
+    "#[post(",
+    "#[put(",
+    "#[delete(",
+    "#[route(",
+];
+
+/// Which kinds of entry points seed the reachability analysis
+#[derive(Debug, Clone)]
+pub struct ReachabilityConfig {
+    pub entry_kinds: Vec<EntryPointKind>,
+}
+
+impl Default for ReachabilityConfig {
+    fn default() -> Self {
+        Self {
+            entry_kinds: vec![
+                EntryPointKind::Main,
+                EntryPointKind::ExportedApi,
+                EntryPointKind::HttpHandler,
+                EntryPointKind::Test,
+                EntryPointKind::ModuleScope,
+            ],
+        }
+    }
+}
+
+impl ReachabilityConfig {
+    /// Parse a comma-separated list such as `main,http,test`
+    pub fn from_kinds(list: &str) -> Result<Self, String> {
+        let mut entry_kinds = Vec::new();
+        for kind in list.split(',').map(str::trim).filter(|k| !k.is_empty()) {
+            let parsed = match kind.to_lowercase().as_str() {
+                "main" => EntryPointKind::Main,
+                "exported" | "exported-api" | "api" => EntryPointKind::ExportedApi,
+                "http" | "http-handler" => EntryPointKind::HttpHandler,
+                "test" | "tests" => EntryPointKind::Test,
+                "module" | "module-scope" => EntryPointKind::ModuleScope,
+                other => return Err(format!("Unknown entry point kind: {}", other)),
+            };
+            if !entry_kinds.contains(&parsed) {
+                entry_kinds.push(parsed);
+            }
+        }
+
+        if entry_kinds.is_empty() {
+            return Err("At least one entry point kind is required".to_string());
+        }
+
+        Ok(Self { entry_kinds })
+    }
+
+    fn enabled(&self, kind: EntryPointKind) -> bool {
+        self.entry_kinds.contains(&kind)
+    }
+}
+
+/// Reachability report for a project
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ReachabilityReport {
+    pub entry_points: usize,
+    pub reachable_findings: usize,
+    pub unreachable_findings: usize,
+
+    /// Reachable findings, most urgent first
+    pub prioritized: Vec<PrioritizedFinding>,
+
+    /// Functions with crypto findings that no entry point reaches
+    pub dead_code: Vec<DeadCryptoCode>,
+}
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct PrioritizedFinding {
+    pub file_path: String,
+    pub line: usize,
+    pub crypto_type: CryptoType,
+    pub severity: Severity,
+    pub risk_score: u32,
+    pub function: Option<String>,
+    pub entry_point_count: usize,
+}
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct DeadCryptoCode {
+    pub file_path: String,
+    pub function: String,
+    pub line: usize,
+    pub end_line: usize,
+    pub crypto_types: Vec<CryptoType>,
+    pub finding_count: usize,
+}
+
+/// Classify a function as an entry point, if it is one
+pub fn classify_entry_point(
+    file: &ProjectFile,
+    func: &FunctionDefinition,
+    http_handlers: &HashSet<String>,
+) -> Option<EntryPointKind> {
+    let language = file.parsed.language;
+    let name = func.name.as_str();
+
+    let is_test = match language {
+        Language::Go => {
+            file.path.ends_with("_test.go")
+                && ["Test", "Benchmark", "Fuzz", "Example"]
+                    .iter()
+                    .any(|prefix| name.starts_with(prefix))
+        }
+        Language::Python => is_python_test_file(&file.path) && name.starts_with("test"),
+        Language::Rust => func.annotations.iter().any(|a| a.contains("test]")),
+        Language::Java | Language::Csharp => func
+            .annotations
+            .iter()
+            .any(|a| a.starts_with("@Test") || a.starts_with("@ParameterizedTest")),
+        _ => false,
+    };
+    if is_test {
+        return Some(EntryPointKind::Test);
+    }
+
+    if name == "main" || (language == Language::Go && name == "init") {
+        return Some(EntryPointKind::Main);
+    }
+
+    let is_handler = http_handlers.contains(name)
+        || func
+            .annotations
+            .iter()
+            .any(|a| ROUTE_ANNOTATIONS.iter().any(|r| a.contains(r)));
+    if is_handler {
+        return Some(EntryPointKind::HttpHandler);
+    }
+
+    let signature = func.signature.as_str();
+    let is_exported = match language {
+        Language::Go => name.starts_with(|c: char| c.is_ascii_uppercase()),
+        Language::Rust => signature.starts_with("pub ") && !signature.starts_with("pub(crate)"),
+        Language::JavaScript | Language::TypeScript => signature.starts_with("export "),
+        Language::Java | Language::Csharp => signature.split_whitespace().any(|w| w == "public"),
+        // Module-level functions without a leading underscore are importable
+        Language::Python => !name.starts_with('_') && is_module_level(func),
+        Language::Cpp => false,
+    };
+    if is_exported {
+        return Some(EntryPointKind::ExportedApi);
+    }
+
+    None
+}
+
+/// Whether a Python function is defined without indentation, outside classes and functions
+///
+/// The signature is the trimmed definition line, so the name is at the same
+/// offset in it as in the source line only when the line is not indented.
+fn is_module_level(func: &FunctionDefinition) -> bool {
+    let signature = func.signature.as_str();
+    let after_async = signature
+        .strip_prefix("async")
+        .filter(|rest| rest.starts_with(char::is_whitespace))
+        .map_or(signature, str::trim_start);
+    let Some(after_def) = after_async.strip_prefix("def") else {
+        return false;
+    };
+    func.column == signature.len() - after_def.trim_start().len()
+}
+
+/// Whether pytest collects `path` as a test module (`test_*.py` or `*_test.py`)
+fn is_python_test_file(path: &str) -> bool {
+    let file_name = path.rsplit(['/', '\\']).next().unwrap_or(path);
+    file_name.ends_with(".py")
+        && (file_name.starts_with("test_") || file_name.ends_with("_test.py"))
+}
+
+/// Names of functions passed as handlers to route registration calls
+fn collect_http_handlers(files: &[ProjectFile]) -> HashSet<String> {
+    let mut handlers = HashSet::new();
+
+    for file in files {
+        for call in &file.parsed.function_calls {
+            if !ROUTE_REGISTRATIONS.contains(&callee_name(&call.name)) {
+                continue;
+            }
+            let is_route = call.args.len() >= 2
+                && call
+                    .args
+                    .first()
+                    .is_some_and(|a| a.starts_with(['"', '\'', '`']));
+            let args = if is_route {
+                &call.args[1..]
+            } else if callee_name(&call.name) == "HandlerFunc" {
+                &call.args[..]
+            } else {
+                continue;
+            };
+
+            for arg in args {
+                let arg = arg.trim_start_matches('&');
+                if !arg.is_empty()
+                    && arg
+                        .chars()
+                        .all(|c| c.is_alphanumeric() || c == '_' || c == '.' || c == ':')
+                {
+                    handlers.insert(callee_name(arg).to_string());
+                }
+            }
+        }
+    }
+
+    handlers
+}
+
+/// Label every finding of the project with its reachability and build a report
+pub fn analyze_reachability(
+    files: &mut [ProjectFile],
+    config: &ReachabilityConfig,
+) -> ReachabilityReport {
+    let graph = CallGraph::build(files);
+    let http_handlers = collect_http_handlers(files);
+
+    // Seed entry points: classified functions plus callees of module-level code
+    let mut entries: Vec<(usize, String)> = Vec::new();
+    for (id, node) in graph.nodes.iter().enumerate() {
+        let file = &files[node.file];
+        let func = &file.parsed.functions[node.function];
+        if let Some(kind) = classify_entry_point(file, func, &http_handlers)
+            && config.enabled(kind)
+        {
+            entries.push((id, format!("{}:{} ({})", file.path, node.name, kind)));
+        }
+    }
+    if config.enabled(EntryPointKind::ModuleScope) {
+        for site in graph.call_sites.iter().filter(|s| s.caller.is_none()) {
+            let label = format!(
+                "{}:<module> ({})",
+                files[site.file].path,
+                EntryPointKind::ModuleScope
+            );
+            if !entries
+                .iter()
+                .any(|(id, l)| *id == site.callee && *l == label)
+            {
+                entries.push((site.callee, label));
+            }
+        }
+    }
+
+    let mut reached_by: Vec<Vec<&str>> = vec![Vec::new(); graph.nodes.len()];
+    for (entry, label) in &entries {
+        for id in graph.reachable_from(*entry) {
+            if !reached_by[id].contains(&label.as_str()) {
+                reached_by[id].push(label);
+            }
+        }
+    }
+
+    let mut reachable_findings = 0;
+    let mut unreachable_findings = 0;
+    let mut prioritized = Vec::new();
+    let mut dead_code = Vec::new();
+
+    for (id, node) in graph.nodes.iter().enumerate() {
+        if node.findings.is_empty() {
+            continue;
+        }
+
+        let labels = &reached_by[id];
+        let reachability = Reachability {
+            status: if labels.is_empty() {
+                ReachabilityStatus::Unreachable
+            } else {
+                ReachabilityStatus::Reachable
+            },
+            entry_point_count: labels.len(),
+            entry_points: labels
+                .iter()
+                .take(MAX_ENTRY_POINT_SAMPLES)
+                .map(|l| l.to_string())
+                .collect(),
+        };
+
+        let mut crypto_types: Vec<CryptoType> = Vec::new();
+        for finding in &node.findings {
+            let vuln = &mut files[finding.file].audit.vulnerabilities[finding.index];
+            vuln.reachability = Some(reachability.clone());
+            if labels.is_empty() {
+                unreachable_findings += 1;
+                if !crypto_types.contains(&vuln.crypto_type) {
+                    crypto_types.push(vuln.crypto_type.clone());
+                }
+            } else {
+                reachable_findings += 1;
+                prioritized.push(PrioritizedFinding {
+                    file_path: files[finding.file].path.clone(),
+                    line: vuln.line,
+                    crypto_type: vuln.crypto_type.clone(),
+                    severity: vuln.severity,
+                    risk_score: vuln.risk_score,
+                    function: Some(node.name.clone()),
+                    entry_point_count: labels.len(),
+                });
+            }
+        }
+
+        if labels.is_empty() {
+            dead_code.push(DeadCryptoCode {
+                file_path: files[node.file].path.clone(),
+                function: node.name.clone(),
+                line: node.line,
+                end_line: node.end_line,
+                crypto_types,
+                finding_count: node.findings.len(),
+            });
+        }
+    }
+
+    // Findings outside any function run when the module is loaded
+    let module_scope = config.enabled(EntryPointKind::ModuleScope);
+    for finding in &graph.module_findings {
+        let path = files[finding.file].path.clone();
+        let vuln = &mut files[finding.file].audit.vulnerabilities[finding.index];
+        vuln.reachability = Some(Reachability {
+            status: if module_scope {
+                ReachabilityStatus::Reachable
+            } else {
+                ReachabilityStatus::Unreachable
+            },
+            entry_point_count: usize::from(module_scope),
+            entry_points: if module_scope {
+                vec![format!(
+                    "{}:<module> ({})",
+                    path,
+                    EntryPointKind::ModuleScope
+                )]
+            } else {
+                Vec::new()
+            },
+        });
+
+        if module_scope {
+            reachable_findings += 1;
+            prioritized.push(PrioritizedFinding {
+                file_path: path,
+                line: vuln.line,
+                crypto_type: vuln.crypto_type.clone(),
+                severity: vuln.severity,
+                risk_score: vuln.risk_score,
+                function: None,
+                entry_point_count: 1,
+            });
+        } else {
+            unreachable_findings += 1;
+        }
+    }
+
+    prioritized.sort_by(|a, b| {
+        b.severity
+            .cmp(&a.severity)
+            .then(b.entry_point_count.cmp(&a.entry_point_count))
+            .then(b.risk_score.cmp(&a.risk_score))
+            .then_with(|| a.file_path.cmp(&b.file_path))
+            .then(a.line.cmp(&b.line))
+    });
+    dead_code.sort_by(|a, b| a.file_path.cmp(&b.file_path).then(a.line.cmp(&b.line)));
+
+    ReachabilityReport {
+        entry_points: entries.len(),
+        reachable_findings,
+        unreachable_findings,
+        prioritized,
+        dead_code,
+    }
+}
+
+/// Export reachability report to JSON string
+pub fn export_reachability_json(report: &ReachabilityReport) -> Result<String, serde_json::Error> {
+    serde_json::to_string_pretty(report)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::project_file;
+
+    fn go_project() -> Vec<ProjectFile> {
+        vec![
+            project_file(
+                "crypto/legacy.go",
+                "package crypto\n\nfunc newSigner() *ecdsa.PrivateKey {\n\treturn nil\n}\n\nfunc oldHash(b []byte) {\n\tmd5.Sum(b)\n}\n",
+                "go",
+            ),
+            project_file(
+                "cmd/server/main.go",
+                "package main\n\nfunc main() {\n\thttp.HandleFunc(\"/sign\", signHandler)\n}\n\nfunc signHandler(w http.ResponseWriter, r *http.Request) {\n\tnewSigner()\n}\n",
+                "go",
+            ),
+        ]
+    }
+
+    #[test]
+    fn test_reachable_and_dead_findings() {
+        let mut files = go_project();
+        let report = analyze_reachability(&mut files, &ReachabilityConfig::default());
+
+        let signer = files[0]
+            .audit
+            .vulnerabilities
+            .iter()
+            .find(|v| v.crypto_type == CryptoType::Ecdsa)
+            .unwrap();
+        let reachability = signer.reachability.as_ref().unwrap();
+        assert_eq!(reachability.status, ReachabilityStatus::Reachable);
+        assert!(
+            reachability
+                .entry_points
+                .iter()
+                .any(|e| e.contains("signHandler (http)"))
+        );
+
+        let md5 = files[0]
+            .audit
+            .vulnerabilities
+            .iter()
+            .find(|v| v.crypto_type == CryptoType::Md5)
+            .unwrap();
+        assert_eq!(
+            md5.reachability.as_ref().unwrap().status,
+            ReachabilityStatus::Unreachable
+        );
+
+        assert_eq!(report.dead_code.len(), 1);
+        assert_eq!(report.dead_code[0].function, "oldHash");
+        assert!(report.reachable_findings > 0);
+    }
+
+    #[test]
+    fn test_classify_entry_points() {
+        let files = vec![project_file(
+            "pkg/signer_test.go",
+            "package pkg\n\nfunc TestSign(t *testing.T) {\n}\n\nfunc Exported() {\n}\n\nfunc internal() {\n}\n",
+            "go",
+        )];
+        let handlers = HashSet::new();
+        let kinds: Vec<Option<EntryPointKind>> = files[0]
+            .parsed
+            .functions
+            .iter()
+            .map(|f| classify_entry_point(&files[0], f, &handlers))
+            .collect();
+        assert_eq!(
+            kinds,
+            vec![
+                Some(EntryPointKind::Test),
+                Some(EntryPointKind::ExportedApi),
+                None
+            ]
+        );
+    }
+
+    #[test]
+    fn test_classify_python_entry_points() {
+        let source = "def test_sign():\n    pass\n\ndef sign(data):\n    pass\n\ndef _digest(data):\n    pass\n\nclass Signer:\n    def sign(self):\n        pass\n";
+        let handlers = HashSet::new();
+        let kinds = |path: &str| -> Vec<Option<EntryPointKind>> {
+            let file = project_file(path, source, "python");
+            file.parsed
+                .functions
+                .iter()
+                .map(|f| classify_entry_point(&file, f, &handlers))
+                .collect()
+        };
+
+        // Outside test modules, `test*` functions are ordinary module-level functions
+        assert_eq!(
+            kinds("app/signing.py"),
+            vec![
+                Some(EntryPointKind::ExportedApi),
+                Some(EntryPointKind::ExportedApi),
+                None,
+                None
+            ]
+        );
+        for path in ["tests/test_signing.py", "app/signing_test.py"] {
+            assert_eq!(kinds(path)[0], Some(EntryPointKind::Test), "{path}");
+        }
+        assert!(!is_python_test_file("app/testing.py"));
+        assert!(!is_python_test_file("app/test_data.json"));
+
+        // Names that also occur in the `def` keyword, and coroutines
+        let short = "def f():\n    pass\n\ndef e():\n    pass\n\nasync def a():\n    pass\n\n\
+                     async  def fetch():\n    pass\n\nclass C:\n    def d(self):\n        pass\n\n    \
+                     async def e(self):\n        pass\n";
+        let file = project_file("app/short.py", short, "python");
+        let kinds: Vec<Option<EntryPointKind>> = file
+            .parsed
+            .functions
+            .iter()
+            .map(|f| classify_entry_point(&file, f, &handlers))
+            .collect();
+        assert_eq!(
+            kinds,
+            vec![
+                Some(EntryPointKind::ExportedApi),
+                Some(EntryPointKind::ExportedApi),
+                Some(EntryPointKind::ExportedApi),
+                Some(EntryPointKind::ExportedApi),
+                None,
+                None
+            ]
+        );
+    }
+
+    #[test]
+    fn test_config_from_kinds() {
+        let config = ReachabilityConfig::from_kinds("main, http").unwrap();
+        assert_eq!(
+            config.entry_kinds,
+            vec![EntryPointKind::Main, EntryPointKind::HttpHandler]
+        );
+        assert!(ReachabilityConfig::from_kinds("bogus").is_err());
+    }
+}
@@ -311,7 +311,7 @@ fn remediate_des_3des(vuln: &Vulnerability, file_path: &str) -> Option<CodeFix>
 #[cfg(test)]
 mod tests {
     use super::*;
-    use crate::types::{Language, Severity};
+    use crate::types::{Confidence, Language, MatchKind, Severity};
 
     fn create_test_vulnerability(
         crypto_type: CryptoType,
@@ -319,15 +319,14 @@ mod tests {
         key_size: Option<u32>,
     ) -> Vulnerability {
         Vulnerability {
-            crypto_type,
-            severity: Severity::High,
-            risk_score: 80,
-            line: 42,
-            column: 10,
+            end_column: 13,
+            match_kind: MatchKind::ApiCall,
+            confidence: Confidence::High,
             context: context.to_string(),
             message: "Test vulnerability".to_string(),
             recommendation: "Test recommendation".to_string(),
             key_size,
+            ..Vulnerability::new(crypto_type, Severity::High, 80, 42, 10)
         }
     }
 
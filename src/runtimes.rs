@@ -0,0 +1,1029 @@
+//! Runtime and toolchain post-quantum capability
+//!
+//! Hybrid post-quantum TLS usually arrives with a toolchain or runtime
+//! upgrade rather than a code change: Go 1.24 offers X25519MLKEM768 by
+//! default, OpenSSL 3.5 adds it to the default groups, JDK 24 ships ML-KEM
+//! and Node.js follows its bundled OpenSSL. This module reads the versions
+//! a project pins (the `go` directive, `pom.xml` and Gradle Java levels,
+//! Dockerfile base images (resolving `ARG` defaults) and packages, `.nvmrc`, `engines.node`,
+//! lockfiles) and the settings that turn PQ key exchange off again
+//! (`GODEBUG=tlsmlkem=0`, `CurvePreferences` without ML-KEM,
+//! `jdk.tls.namedGroups`, OpenSSL `Groups`), and assesses each version as
+//! unavailable, available, enabled by default or disabled, with the
+//! upgrade that changes it.
+
+use crate::parser::closing;
+use crate::types::*;
+use lazy_static::lazy_static;
+use regex::{Captures, Regex};
+use serde::{Deserialize, Serialize};
+use std::collections::HashMap;
+
+lazy_static! {
+    static ref GO_DIRECTIVE: Regex = Regex::new(r"^go\s+(\d+\.\d+(?:\.\d+)?)\s*$")
+        .expect("GO_DIRECTIVE: Invalid regex pattern - this is a compile-time bug");
+    static ref TOOLCHAIN_DIRECTIVE: Regex = Regex::new(r"^toolchain\s+go(\d+\.\d+(?:\.\d+)?)")
+        .expect("TOOLCHAIN_DIRECTIVE: Invalid regex pattern - this is a compile-time bug");
+    static ref GODEBUG_PQ_OFF: Regex = Regex::new(r"\btls(mlkem|kyber)=0\b")
+        .expect("GODEBUG_PQ_OFF: Invalid regex pattern - this is a compile-time bug");
+    static ref POM_JAVA_VERSION: Regex = Regex::new(
+        r"<(maven\.compiler\.release|maven\.compiler\.target|maven\.compiler\.source|java\.version|release)>\s*([\d.]+)\s*<"
+    )
+    .expect("POM_JAVA_VERSION: Invalid regex pattern - this is a compile-time bug");
+    static ref GRADLE_JAVA_VERSION: Regex = Regex::new(
+        r#"(?:JavaLanguageVersion\.of\(\s*|(?:source|target)Compatibility\s*=\s*(?:JavaVersion\.VERSION_)?['"]?)(\d+(?:[._]\d+)?)"#
+    )
+    .expect("GRADLE_JAVA_VERSION: Invalid regex pattern - this is a compile-time bug");
+    static ref DOCKER_FROM: Regex = Regex::new(r"(?i)^FROM\s+(?:--platform=\S+\s+)?(\S+)")
+        .expect("DOCKER_FROM: Invalid regex pattern - this is a compile-time bug");
+    static ref DOCKER_ARG: Regex = Regex::new(r#"(?i)^ARG\s+(\w+)=["']?([^"'\s]*)"#)
+        .expect("DOCKER_ARG: Invalid regex pattern - this is a compile-time bug");
+    static ref ARG_REFERENCE: Regex = Regex::new(r"\$\{(\w+)(?::?-([^}]*))?\}|\$(\w+)")
+        .expect("ARG_REFERENCE: Invalid regex pattern - this is a compile-time bug");
+    static ref JDK_TAG: Regex = Regex::new(r"(?:^|[-_])(?:temurin|jdk|jre|corretto|openjdk|zulu)-?(\d+)")
+        .expect("JDK_TAG: Invalid regex pattern - this is a compile-time bug");
+    static ref OPENSSL_PACKAGE: Regex =
+        Regex::new(r"\b(?:openssl|libssl)[=@ -]v?(\d+\.\d+(?:\.\d+)?)|OPENSSL_VERSION[= ]+v?(\d+\.\d+(?:\.\d+)?)")
+            .expect("OPENSSL_PACKAGE: Invalid regex pattern - this is a compile-time bug");
+    static ref OPENSSL_SRC_VERSION: Regex =
+        Regex::new(r#"(?m)^name = "openssl-src"\s*\nversion = "[^"+]*\+(\d+\.\d+\.\d+)""#)
+            .expect("OPENSSL_SRC_VERSION: Invalid regex pattern - this is a compile-time bug");
+    static ref CONAN_OPENSSL: Regex = Regex::new(r"\bopenssl/(\d+\.\d+\.\d+)")
+        .expect("CONAN_OPENSSL: Invalid regex pattern - this is a compile-time bug");
+    static ref VCPKG_OPENSSL: Regex =
+        Regex::new(r#"(?s)"name"\s*:\s*"openssl"[^}]*?"version[^"]*"\s*:\s*"(\d+\.\d+\.\d+)"#)
+            .expect("VCPKG_OPENSSL: Invalid regex pattern - this is a compile-time bug");
+    static ref JAVA_NAMED_GROUPS: Regex = Regex::new(r#"jdk\.tls\.namedGroups\s*=\s*"?([\w,\s]+)"#)
+        .expect("JAVA_NAMED_GROUPS: Invalid regex pattern - this is a compile-time bug");
+    static ref OPENSSL_GROUPS: Regex = Regex::new(r"(?i)^\s*(?:Groups|Curves)\s*=\s*(\S+)")
+        .expect("OPENSSL_GROUPS: Invalid regex pattern - this is a compile-time bug");
+}
+
+/// Node.js LTS code names used in `.nvmrc` (`lts/iron`)
+const NODE_LTS_NAMES: &[(&str, u32)] = &[
+    ("argon", 4),
+    ("boron", 6),
+    ("carbon", 8),
+    ("dubnium", 10),
+    ("erbium", 12),
+    ("fermium", 14),
+    ("gallium", 16),
+    ("hydrogen", 18),
+    ("iron", 20),
+    ("jod", 22),
+    ("krypton", 24),
+];
+
+/// Base images of JDK distributions (last path segment of the image name)
+const JDK_IMAGES: &[&str] = &[
+    "eclipse-temurin",
+    "openjdk",
+    "amazoncorretto",
+    "ibm-semeru-runtimes",
+    "zulu-openjdk",
+    "sapmachine",
+    "maven",
+    "gradle",
+];
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord, Serialize, Deserialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum Runtime {
+    Go,
+    Java,
+    OpenSsl,
+    NodeJs,
+}
+
+impl std::fmt::Display for Runtime {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        match self {
+            Runtime::Go => write!(f, "Go"),
+            Runtime::Java => write!(f, "Java"),
+            Runtime::OpenSsl => write!(f, "OpenSSL"),
+            Runtime::NodeJs => write!(f, "Node.js"),
+        }
+    }
+}
+
+/// Post-quantum key exchange support of a runtime version
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum PqStatus {
+    /// The version has no PQ key exchange
+    Unavailable,
+    /// Supported, but applications or TLS stacks must opt in
+    Available,
+    /// Offered in TLS handshakes without configuration
+    EnabledByDefault,
+    /// Supported, but turned off by the go line or a setting
+    Disabled,
+}
+
+impl std::fmt::Display for PqStatus {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        match self {
+            PqStatus::Unavailable => write!(f, "unavailable"),
+            PqStatus::Available => write!(f, "available"),
+            PqStatus::EnabledByDefault => write!(f, "enabled by default"),
+            PqStatus::Disabled => write!(f, "disabled"),
+        }
+    }
+}
+
+/// A runtime or toolchain version pinned by a project file
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct RuntimeVersion {
+    pub runtime: Runtime,
+    pub version: String,
+    pub path: String,
+    pub line: usize,
+    /// Where the version comes from (`go directive`, `FROM node:20-alpine`)
+    pub source: String,
+    /// Minimum toolchain of a `go.mod` (`toolchain go1.24.2`)
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub toolchain: Option<String>,
+}
+
+/// A setting that turns PQ key exchange off
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct PqSetting {
+    pub runtime: Runtime,
+    pub setting: String,
+    pub path: String,
+    pub line: usize,
+}
+
+/// Versions and settings found in a project's files
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+pub struct RuntimeEvidence {
+    pub versions: Vec<RuntimeVersion>,
+    pub settings: Vec<PqSetting>,
+}
+
+impl RuntimeEvidence {
+    pub fn extend(&mut self, other: RuntimeEvidence) {
+        self.versions.extend(other.versions);
+        self.settings.extend(other.settings);
+    }
+
+    fn version(
+        &mut self,
+        runtime: Runtime,
+        version: &str,
+        path: &str,
+        line: usize,
+        source: String,
+    ) {
+        self.versions.push(RuntimeVersion {
+            runtime,
+            version: version.to_string(),
+            path: path.to_string(),
+            line,
+            source,
+            toolchain: None,
+        });
+    }
+
+    fn setting(&mut self, runtime: Runtime, setting: String, path: &str, line: usize) {
+        self.settings.push(PqSetting {
+            runtime,
+            setting,
+            path: path.to_string(),
+            line,
+        });
+    }
+}
+
+/// PQ capability of one runtime version
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct RuntimeAssessment {
+    pub version: RuntimeVersion,
+    pub status: PqStatus,
+    pub detail: String,
+    /// Settings that turn the runtime's PQ key exchange off
+    pub disabled_by: Vec<PqSetting>,
+    pub recommendation: Option<String>,
+}
+
+/// PQ capability of every runtime version of a project
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+pub struct RuntimeReport {
+    pub assessments: Vec<RuntimeAssessment>,
+    pub settings: Vec<PqSetting>,
+}
+
+impl RuntimeReport {
+    pub fn count(&self, status: PqStatus) -> usize {
+        self.assessments
+            .iter()
+            .filter(|a| a.status == status)
+            .count()
+    }
+}
+
+/// Whether a file can pin a runtime version or disable PQ key exchange
+pub fn is_runtime_file(path: &str) -> bool {
+    let name = path.rsplit(['/', '\\']).next().unwrap_or(path);
+    let lower = name.to_ascii_lowercase();
+    matches!(
+        name,
+        "go.mod"
+            | "pom.xml"
+            | "build.gradle"
+            | "build.gradle.kts"
+            | ".java-version"
+            | ".nvmrc"
+            | ".node-version"
+            | ".tool-versions"
+            | "package.json"
+            | "Cargo.lock"
+            | "conanfile.txt"
+            | "vcpkg.json"
+    ) || lower.starts_with("dockerfile")
+        || lower.ends_with(".dockerfile")
+        || lower == "containerfile"
+        || lower == ".env"
+        || lower.ends_with(".env")
+        || lower.ends_with(".cnf")
+        || lower.ends_with(".yml")
+        || lower.ends_with(".yaml")
+}
+
+/// Versions and PQ settings of a runtime file
+pub fn scan_runtime_file(path: &str, content: &str) -> RuntimeEvidence {
+    let name = path.rsplit(['/', '\\']).next().unwrap_or(path);
+    let lower = name.to_ascii_lowercase();
+    let mut evidence = RuntimeEvidence::default();
+
+    match name {
+        "go.mod" => scan_go_mod(path, content, &mut evidence),
+        "pom.xml" => {
+            if let Some(captures) = POM_JAVA_VERSION.captures(content) {
+                let line = line_of(content, captures.get(0).expect("pom match").start());
+                evidence.version(
+                    Runtime::Java,
+                    &captures[2],
+                    path,
+                    line,
+                    format!("<{}>", &captures[1]),
+                );
+            }
+        }
+        "build.gradle" | "build.gradle.kts" => {
+            if let Some(captures) = GRADLE_JAVA_VERSION.captures(content) {
+                let line = line_of(content, captures.get(0).expect("gradle match").start());
+                let version = captures[1].replace('_', ".");
+                evidence.version(Runtime::Java, &version, path, line, "Gradle".to_string());
+            }
+        }
+        ".java-version" | ".nvmrc" | ".node-version" => {
+            let runtime = if name == ".java-version" {
+                Runtime::Java
+            } else {
+                Runtime::NodeJs
+            };
+            let version = content.lines().next().unwrap_or_default().trim();
+            if let Some(version) = normalize_version(runtime, version) {
+                evidence.version(runtime, &version, path, 1, name.to_string());
+            }
+        }
+        ".tool-versions" => {
+            for (idx, line) in content.lines().enumerate() {
+                let mut parts = line.split_whitespace();
+                let runtime = match parts.next() {
+                    Some("golang" | "go") => Runtime::Go,
+                    Some("java") => Runtime::Java,
+                    Some("nodejs" | "node") => Runtime::NodeJs,
+                    _ => continue,
+                };
+                if let Some(version) = parts.next().and_then(|v| normalize_version(runtime, v)) {
+                    evidence.version(runtime, &version, path, idx + 1, name.to_string());
+                }
+            }
+        }
+        "package.json" => scan_package_json(path, content, &mut evidence),
+        "Cargo.lock" => {
+            if let Some(captures) = OPENSSL_SRC_VERSION.captures(content) {
+                let line = line_of(content, captures.get(0).expect("lock match").start());
+                evidence.version(
+                    Runtime::OpenSsl,
+                    &captures[1],
+                    path,
+                    line,
+                    "openssl-src crate".to_string(),
+                );
+            }
+        }
+        "conanfile.txt" | "vcpkg.json" => {
+            let pattern: &Regex = if name == "vcpkg.json" {
+                &VCPKG_OPENSSL
+            } else {
+                &CONAN_OPENSSL
+            };
+            if let Some(captures) = pattern.captures(content) {
+                let line = line_of(content, captures.get(0).expect("openssl match").start());
+                evidence.version(Runtime::OpenSsl, &captures[1], path, line, name.to_string());
+            }
+        }
+        _ if lower.starts_with("dockerfile")
+            || lower.ends_with(".dockerfile")
+            || lower == "containerfile" =>
+        {
+            scan_dockerfile(path, content, &mut evidence)
+        }
+        _ => {}
+    }
+
+    scan_settings(path, content, lower.ends_with(".cnf"), &mut evidence);
+    evidence
+}
+
+fn scan_go_mod(path: &str, content: &str, evidence: &mut RuntimeEvidence) {
+    let mut toolchain = None;
+    for (idx, line) in content.lines().enumerate() {
+        let code = line.split("//").next().unwrap_or_default().trim();
+        if let Some(captures) = GO_DIRECTIVE.captures(code) {
+            evidence.version(
+                Runtime::Go,
+                &captures[1],
+                path,
+                idx + 1,
+                "go directive".to_string(),
+            );
+        } else if let Some(captures) = TOOLCHAIN_DIRECTIVE.captures(code) {
+            toolchain = Some(captures[1].to_string());
+        } else if let Some(captures) = GODEBUG_PQ_OFF.captures(code) {
+            evidence.setting(
+                Runtime::Go,
+                format!(
+                    "godebug {}",
+                    captures.get(0).expect("godebug match").as_str()
+                ),
+                path,
+                idx + 1,
+            );
+        }
+    }
+    if let Some(go) = evidence
+        .versions
+        .iter_mut()
+        .find(|v| v.runtime == Runtime::Go && v.path == path)
+    {
+        go.toolchain = toolchain;
+    }
+}
+
+fn scan_package_json(path: &str, content: &str, evidence: &mut RuntimeEvidence) {
+    let Ok(json) = serde_json::from_str::<serde_json::Value>(content) else {
+        return;
+    };
+    let Some(range) = json
+        .get("engines")
+        .and_then(|engines| engines.get("node"))
+        .and_then(|node| node.as_str())
+    else {
+        return;
+    };
+    if let Some(version) = normalize_version(Runtime::NodeJs, range) {
+        let line = content
+            .find("\"engines\"")
+            .map(|engines| engines + content[engines..].find("\"node\"").unwrap_or(0))
+            .map_or(1, |offset| line_of(content, offset));
+        evidence.version(
+            Runtime::NodeJs,
+            &version,
+            path,
+            line,
+            format!("engines.node {}", range),
+        );
+    }
+}
+
+fn scan_dockerfile(path: &str, content: &str, evidence: &mut RuntimeEvidence) {
+    // Only the ARGs declared before the first FROM can be used in FROM lines
+    let mut args = HashMap::new();
+    let mut in_stage = false;
+    for (idx, line) in content.lines().enumerate() {
+        if !in_stage && let Some(captures) = DOCKER_ARG.captures(line.trim()) {
+            args.insert(captures[1].to_string(), captures[2].to_string());
+        }
+        if let Some(captures) = DOCKER_FROM.captures(line.trim()) {
+            in_stage = true;
+            let Some(image) = substitute_args(&captures[1], &args) else {
+                continue;
+            };
+            let image = image.as_str();
+            let (name, tag) = image.rsplit_once(':').unwrap_or((image, ""));
+            let name = name.rsplit('/').next().unwrap_or(name);
+            let found = match name {
+                "golang" => normalize_version(Runtime::Go, tag).map(|v| (Runtime::Go, v)),
+                "node" => normalize_version(Runtime::NodeJs, tag).map(|v| (Runtime::NodeJs, v)),
+                _ if JDK_IMAGES.contains(&name) => {
+                    let version = match JDK_TAG.captures(tag) {
+                        Some(captures) => Some(captures[1].to_string()),
+                        None if name != "maven" && name != "gradle" => {
+                            normalize_version(Runtime::Java, tag)
+                        }
+                        None => None,
+                    };
+                    version.map(|v| (Runtime::Java, v))
+                }
+                _ => None,
+            };
+            if let Some((runtime, version)) = found {
+                evidence.version(runtime, &version, path, idx + 1, format!("FROM {}", image));
+            }
+        }
+        if let Some(captures) = OPENSSL_PACKAGE.captures(line) {
+            let version = captures.get(1).or_else(|| captures.get(2));
+            if let Some(version) = version {
+                evidence.version(
+                    Runtime::OpenSsl,
+                    version.as_str(),
+                    path,
+                    idx + 1,
+                    "Dockerfile package".to_string(),
+                );
+            }
+        }
+    }
+}
+
+/// `image` with `$NAME`, `${NAME}` and `${NAME:-default}` replaced by their
+/// `ARG` values, or `None` when a reference has no value
+fn substitute_args(image: &str, args: &HashMap<String, String>) -> Option<String> {
+    let mut unresolved = false;
+    let image = ARG_REFERENCE.replace_all(image, |captures: &Captures| {
+        let name = captures
+            .get(1)
+            .or_else(|| captures.get(3))
+            .expect("arg name");
+        let value = args
+            .get(name.as_str())
+            .filter(|value| !value.is_empty())
+            .map(String::as_str)
+            .or_else(|| captures.get(2).map(|default| default.as_str()));
+        value.map(str::to_string).unwrap_or_else(|| {
+            unresolved = true;
+            String::new()
+        })
+    });
+    (!unresolved).then(|| image.into_owned())
+}
+
+/// `//go:debug` directives and `CurvePreferences` without a PQ group
+fn scan_go_source(path: &str, content: &str, evidence: &mut RuntimeEvidence) {
+    for (idx, line) in content.lines().enumerate() {
+        if let Some(directive) = line.trim().strip_prefix("//go:debug ")
+            && let Some(captures) = GODEBUG_PQ_OFF.captures(directive)
+        {
+            evidence.setting(
+                Runtime::Go,
+                format!(
+                    "//go:debug {}",
+                    captures.get(0).expect("debug match").as_str()
+                ),
+                path,
+                idx + 1,
+            );
+        }
+    }
+
+    let mut offset = 0;
+    while let Some(index) = content[offset..].find("CurvePreferences") {
+        let start = offset + index;
+        offset = start + "CurvePreferences".len();
+        let Some(open) = content[offset..]
+            .find(['{', '\n'])
+            .map(|i| offset + i)
+            .filter(|&i| content.as_bytes()[i] == b'{')
+        else {
+            continue;
+        };
+        let Some(close) = closing(content, open) else {
+            continue;
+        };
+        let curves = &content[open + 1..close];
+        if !curves.contains("MLKEM") && !curves.contains("Kyber") {
+            let curves: Vec<&str> = curves
+                .split(',')
+                .map(str::trim)
+                .filter(|c| !c.is_empty())
+                .collect();
+            evidence.setting(
+                Runtime::Go,
+                format!(
+                    "CurvePreferences without X25519MLKEM768 ({})",
+                    curves.join(", ")
+                ),
+                path,
+                line_of(content, start),
+            );
+        }
+    }
+}
+
+/// Environment and configuration settings in any runtime file
+fn scan_settings(path: &str, content: &str, openssl_config: bool, evidence: &mut RuntimeEvidence) {
+    let lines: Vec<&str> = content.lines().collect();
+    for (idx, line) in lines.iter().enumerate() {
+        let trimmed = line.trim();
+        if trimmed.starts_with('#') || trimmed.starts_with("//") {
+            continue;
+        }
+
+        // `GODEBUG=tlsmlkem=0`, `GODEBUG: "tlsmlkem=0"` or a Kubernetes
+        // `name: GODEBUG` entry with the value on the next line
+        if let Some(position) = line.find("GODEBUG") {
+            let value = if line.contains("name:") {
+                lines.get(idx + 1).copied().unwrap_or_default()
+            } else {
+                &line[position..]
+            };
+            if let Some(captures) = GODEBUG_PQ_OFF.captures(value) {
+                evidence.setting(
+                    Runtime::Go,
+                    format!(
+                        "GODEBUG={}",
+                        captures.get(0).expect("godebug match").as_str()
+                    ),
+                    path,
+                    idx + 1,
+                );
+            }
+        }
+
+        if let Some(captures) = JAVA_NAMED_GROUPS.captures(line)
+            && !captures[1].to_ascii_lowercase().contains("mlkem")
+        {
+            evidence.setting(
+                Runtime::Java,
+                format!("jdk.tls.namedGroups={}", captures[1].trim()),
+                path,
+                idx + 1,
+            );
+        }
+
+        if line.contains("--tls-max-v1.2") {
+            evidence.setting(Runtime::NodeJs, "--tls-max-v1.2".to_string(), path, idx + 1);
+        }
+
+        if openssl_config
+            && let Some(captures) = OPENSSL_GROUPS.captures(line)
+            && !captures[1].to_ascii_lowercase().contains("mlkem")
+        {
+            evidence.setting(
+                Runtime::OpenSsl,
+                format!("Groups = {}", &captures[1]),
+                path,
+                idx + 1,
+            );
+        }
+    }
+}
+
+/// Dotted version of a tag or range (`1.22-alpine`, `>=20.10`, `v18`, `lts/iron`);
+/// the lowest alternative of a `^18 || >=20` range
+fn normalize_version(runtime: Runtime, text: &str) -> Option<String> {
+    let text = text.trim();
+    if text.contains("||") {
+        return text
+            .split("||")
+            .filter_map(|alternative| normalize_version(runtime, alternative))
+            .filter_map(|version| Some((major_minor(runtime, &version)?, version)))
+            .min()
+            .map(|(_, version)| version);
+    }
+    if runtime == Runtime::NodeJs
+        && let Some(name) = text.strip_prefix("lts/")
+    {
+        return NODE_LTS_NAMES
+            .iter()
+            .find(|(lts, _)| *lts == name)
+            .map(|(_, major)| major.to_string());
+    }
+    let start = text.find(|c: char| c.is_ascii_digit())?;
+    let version: String = text[start..]
+        .chars()
+        .take_while(|c| c.is_ascii_digit() || *c == '.')
+        .collect();
+    let version = version.trim_end_matches('.');
+    (!version.is_empty()).then(|| version.to_string())
+}
+
+/// `(major, minor)` of a version; Java `1.8` is 8
+fn major_minor(runtime: Runtime, version: &str) -> Option<(u32, u32)> {
+    let mut parts = version.split('.').map(|p| p.parse::<u32>().ok());
+    let major = parts.next()??;
+    let minor = parts.next().flatten().unwrap_or(0);
+    if runtime == Runtime::Java && major == 1 {
+        return Some((minor, 0));
+    }
+    Some((major, minor))
+}
+
+fn line_of(content: &str, offset: usize) -> usize {
+    content[..offset].matches('\n').count() + 1
+}
+
+/// Assess every version found, including settings in the project's Go sources
+pub fn assess_runtimes(evidence: &RuntimeEvidence, files: &[ProjectFile]) -> RuntimeReport {
+    let mut settings = evidence.settings.clone();
+    for file in files.iter().filter(|f| f.audit.language == Language::Go) {
+        let mut found = RuntimeEvidence::default();
+        scan_go_source(&file.path, &file.source, &mut found);
+        settings.extend(found.settings);
+    }
+
+    let assessments = evidence
+        .versions
+        .iter()
+        .filter_map(|version| assess(version, &settings))
+        .collect();
+    RuntimeReport {
+        assessments,
+        settings,
+    }
+}
+
+fn assess(version: &RuntimeVersion, settings: &[PqSetting]) -> Option<RuntimeAssessment> {
+    let (major, minor) = major_minor(version.runtime, &version.version)?;
+    let v = &version.version;
+
+    let (status, detail, recommendation) = match version.runtime {
+        Runtime::Go => {
+            let toolchain = version
+                .toolchain
+                .as_deref()
+                .and_then(|t| major_minor(Runtime::Go, t));
+            if (major, minor) >= (1, 24) {
+                (
+                    PqStatus::EnabledByDefault,
+                    format!("Go {} offers X25519MLKEM768 by default in crypto/tls", v),
+                    None,
+                )
+            } else if toolchain.is_some_and(|t| t >= (1, 24)) {
+                (
+                    PqStatus::Disabled,
+                    format!(
+                        "toolchain go{} supports X25519MLKEM768, but the go {} line keeps the \
+                         older GODEBUG default tlsmlkem=0",
+                        version.toolchain.as_deref().unwrap_or_default(),
+                        v
+                    ),
+                    Some(
+                        "Raise the go directive to 1.24 or later; the go line selects the \
+                         GODEBUG defaults, so a newer toolchain alone leaves PQ key exchange off"
+                            .to_string(),
+                    ),
+                )
+            } else if (major, minor) == (1, 23) {
+                (
+                    PqStatus::EnabledByDefault,
+                    format!(
+                        "Go {} offers the pre-standard X25519Kyber768Draft00 by default",
+                        v
+                    ),
+                    Some(
+                        "Raise the go directive to 1.24 or later to move from the Kyber draft \
+                         to the standardized X25519MLKEM768"
+                            .to_string(),
+                    ),
+                )
+            } else {
+                (
+                    PqStatus::Unavailable,
+                    format!("Go {} has no post-quantum key exchange in crypto/tls", v),
+                    Some(
+                        "Upgrade to Go 1.24 or later and raise the go directive to 1.24 to get \
+                         X25519MLKEM768 by default"
+                            .to_string(),
+                    ),
+                )
+            }
+        }
+        Runtime::Java if major >= 24 => (
+            PqStatus::Available,
+            format!(
+                "JDK {} provides ML-KEM and ML-DSA (JEP 496, JEP 497); JSSE does not \
+                 negotiate hybrid PQ key exchange by default",
+                v
+            ),
+            Some(
+                "Use ML-KEM through javax.crypto.KEM, and Bouncy Castle JSSE (bctls) or a \
+                 PQ-capable TLS terminator for hybrid TLS"
+                    .to_string(),
+            ),
+        ),
+        Runtime::Java => (
+            PqStatus::Unavailable,
+            format!("JDK {} has no ML-KEM or ML-DSA", v),
+            Some(
+                "Upgrade to JDK 24 or later for ML-KEM and ML-DSA, or add Bouncy Castle \
+                 (bcprov, bctls) for post-quantum algorithms and hybrid TLS"
+                    .to_string(),
+            ),
+        ),
+        Runtime::OpenSsl if (major, minor) >= (3, 5) => (
+            PqStatus::EnabledByDefault,
+            format!(
+                "OpenSSL {} includes X25519MLKEM768 in its default TLS groups",
+                v
+            ),
+            None,
+        ),
+        Runtime::OpenSsl if major == 3 => (
+            PqStatus::Unavailable,
+            format!("OpenSSL {} has no native ML-KEM", v),
+            Some(
+                "Upgrade to OpenSSL 3.5 or later, or load the oqs-provider for ML-KEM".to_string(),
+            ),
+        ),
+        Runtime::OpenSsl => (
+            PqStatus::Unavailable,
+            format!("OpenSSL {} is end of life and has no ML-KEM", v),
+            Some("Upgrade to OpenSSL 3.5 or later".to_string()),
+        ),
+        Runtime::NodeJs if major >= 24 => (
+            PqStatus::Available,
+            format!(
+                "Node.js {} negotiates X25519MLKEM768 when linked against OpenSSL 3.5 or later",
+                v
+            ),
+            Some(
+                "Confirm process.versions.openssl reports 3.5 or later, especially with a \
+                 shared system OpenSSL"
+                    .to_string(),
+            ),
+        ),
+        Runtime::NodeJs => (
+            PqStatus::Unavailable,
+            format!("Node.js {} bundles an OpenSSL without ML-KEM", v),
+            Some("Upgrade to Node.js 24 or later, which bundles OpenSSL 3.5".to_string()),
+        ),
+    };
+
+    let disabled_by: Vec<PqSetting> = if status == PqStatus::Unavailable {
+        Vec::new()
+    } else {
+        settings
+            .iter()
+            .filter(|s| s.runtime == version.runtime)
+            .cloned()
+            .collect()
+    };
+    // Removing the setting comes first; an upgrade the version needs still applies
+    let (status, recommendation) = match disabled_by.first() {
+        Some(setting) => {
+            let remove = format!(
+                "Remove or update {} ({}:{}), which turns post-quantum key exchange off",
+                setting.setting, setting.path, setting.line
+            );
+            let recommendation = match recommendation {
+                Some(upgrade) => format!("{}. {}", remove, upgrade),
+                None => remove,
+            };
+            (PqStatus::Disabled, Some(recommendation))
+        }
+        None => (status, recommendation),
+    };
+
+    Some(RuntimeAssessment {
+        version: version.clone(),
+        status,
+        detail,
+        disabled_by,
+        recommendation,
+    })
+}
+
+/// Export a runtime report as JSON
+pub fn export_runtimes_json(report: &RuntimeReport) -> Result<String, serde_json::Error> {
+    serde_json::to_string_pretty(report)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_runtime_versions() {
+        let go_mod = "module example.com/svc\n\ngo 1.22.4\n\ntoolchain go1.24.1\n";
+        let evidence = scan_runtime_file("svc/go.mod", go_mod);
+        assert_eq!(evidence.versions.len(), 1);
+        assert_eq!(evidence.versions[0].version, "1.22.4");
+        assert_eq!(evidence.versions[0].line, 3);
+        assert_eq!(evidence.versions[0].toolchain.as_deref(), Some("1.24.1"));
+
+        let dockerfile = "FROM golang:1.24-alpine AS build\nENV GODEBUG=tlsmlkem=0\n\
+                          FROM eclipse-temurin:21-jre\nRUN apk add --no-cache openssl=3.5.0-r0\n\
+                          FROM node:20-slim\n";
+        let evidence = scan_runtime_file("Dockerfile", dockerfile);
+        let versions: Vec<(Runtime, &str)> = evidence
+            .versions
+            .iter()
+            .map(|v| (v.runtime, v.version.as_str()))
+            .collect();
+        assert_eq!(
+            versions,
+            vec![
+                (Runtime::Go, "1.24"),
+                (Runtime::Java, "21"),
+                (Runtime::OpenSsl, "3.5.0"),
+                (Runtime::NodeJs, "20"),
+            ]
+        );
+        assert_eq!(evidence.settings[0].setting, "GODEBUG=tlsmlkem=0");
+        assert_eq!(evidence.settings[0].line, 2);
+
+        let pom = "<project>\n  <properties>\n    <java.version>1.8</java.version>\n";
+        assert_eq!(scan_runtime_file("pom.xml", pom).versions[0].version, "1.8");
+        assert_eq!(
+            scan_runtime_file(".nvmrc", "lts/krypton\n").versions[0].version,
+            "24"
+        );
+        let package = r#"{"name": "web", "engines": {"node": ">=22.11.0"}}"#;
+        assert_eq!(
+            scan_runtime_file("package.json", package).versions[0].version,
+            "22.11.0"
+        );
+
+        let k8s = "env:\n  - name: GODEBUG\n    value: \"tlskyber=0,http2client=0\"\n";
+        assert_eq!(
+            scan_runtime_file("deploy.yaml", k8s).settings[0].setting,
+            "GODEBUG=tlskyber=0"
+        );
+        assert!(is_runtime_file("services/api/Dockerfile.prod"));
+        assert!(!is_runtime_file("main.go"));
+    }
+
+    #[test]
+    fn test_runtime_assessment() {
+        let mut evidence = scan_runtime_file("go.mod", "module m\n\ngo 1.24\n");
+        evidence.extend(scan_runtime_file("legacy/go.mod", "module l\n\ngo 1.21\n"));
+        evidence.extend(scan_runtime_file(
+            "old/go.mod",
+            "module o\n\ngo 1.22\ntoolchain go1.24.0\n",
+        ));
+        evidence.extend(scan_runtime_file(
+            "Dockerfile",
+            "FROM openjdk:1.8\nRUN apt-get install -y openssl=3.0.2-0ubuntu1\n",
+        ));
+
+        let report = assess_runtimes(&evidence, &[]);
+        let status: Vec<PqStatus> = report.assessments.iter().map(|a| a.status).collect();
+        assert_eq!(
+            status,
+            vec![
+                PqStatus::EnabledByDefault,
+                PqStatus::Unavailable,
+                PqStatus::Disabled,
+                PqStatus::Unavailable,
+                PqStatus::Unavailable,
+            ]
+        );
+        assert!(report.assessments[0].recommendation.is_none());
+        assert!(
+            report.assessments[4]
+                .recommendation
+                .as_deref()
+                .unwrap()
+                .contains("OpenSSL 3.5")
+        );
+
+        // A TLS config without ML-KEM turns the default off
+        let source = "package main\n\nvar cfg = &tls.Config{\n\tCurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},\n}\n";
+        let files = vec![crate::project_file("main.go", source, "go")];
+        let report = assess_runtimes(&scan_runtime_file("go.mod", "go 1.24\n"), &files);
+        let go = &report.assessments[0];
+        assert_eq!(go.status, PqStatus::Disabled);
+        assert_eq!(go.disabled_by[0].line, 4);
+        assert!(
+            go.disabled_by[0]
+                .setting
+                .contains("tls.X25519, tls.CurveP256")
+        );
+    }
+
+    #[test]
+    fn test_malformed_versions() {
+        for (path, content) in [
+            ("go.mod", "module m\n\ngo 1.x\ntoolchain default\n"),
+            (".nvmrc", "lts/*\n"),
+            (".nvmrc", "lts/unknown\n"),
+            (".node-version", "system\n"),
+            (".tool-versions", "golang latest\nnodejs\n"),
+            ("package.json", r#"{"engines": {"node": "*"}}"#),
+            ("package.json", r#"{"engines": {"node": ">=20""#),
+            ("pom.xml", "<java.version>${jdk}</java.version>\n"),
+            (
+                "Dockerfile",
+                "FROM golang:latest\nFROM node:lts-alpine\nFROM openjdk\n",
+            ),
+        ] {
+            let evidence = scan_runtime_file(path, content);
+            assert!(evidence.versions.is_empty(), "{path}: {content}");
+        }
+
+        // A version too large to compare is not assessed
+        let mut evidence = RuntimeEvidence::default();
+        evidence.version(
+            Runtime::NodeJs,
+            "99999999999",
+            "package.json",
+            1,
+            "engines.node".to_string(),
+        );
+        assert!(assess_runtimes(&evidence, &[]).assessments.is_empty());
+    }
+
+    #[test]
+    fn test_version_ranges() {
+        let package = r#"{"engines": {"node": "^18 || >=20"}}"#;
+        let evidence = scan_runtime_file("package.json", package);
+        assert_eq!(evidence.versions[0].version, "18");
+        assert_eq!(evidence.versions[0].source, "engines.node ^18 || >=20");
+
+        // The lowest alternative is assessed, whatever its position
+        for (range, lowest) in [
+            (">=20 || ^18.17.0", "18.17.0"),
+            ("18.x || 20.x", "18"),
+            ("16 - 22", "16"),
+            ("^24.1", "24.1"),
+        ] {
+            let package = format!(r#"{{"engines": {{"node": "{}"}}}}"#, range);
+            let evidence = scan_runtime_file("package.json", &package);
+            assert_eq!(evidence.versions[0].version, lowest, "{range}");
+        }
+
+        // Node.js 18 is still supported, so the range cannot rely on ML-KEM
+        let report = assess_runtimes(&evidence, &[]);
+        assert_eq!(report.assessments[0].status, PqStatus::Unavailable);
+        let package = r#"{"engines": {"node": ">=24 || ^26"}}"#;
+        let report = assess_runtimes(&scan_runtime_file("package.json", package), &[]);
+        assert_eq!(report.assessments[0].status, PqStatus::Available);
+    }
+
+    #[test]
+    fn test_multi_stage_dockerfile_args() {
+        let dockerfile = "ARG GO_VERSION=1.23\n\
+                          ARG NODE_VERSION=\"22\"\n\
+                          ARG JDK\n\
+                          FROM --platform=$BUILDPLATFORM golang:${GO_VERSION}-alpine AS build\n\
+                          ARG GO_VERSION=1.24\n\
+                          FROM node:$NODE_VERSION-slim AS assets\n\
+                          FROM eclipse-temurin:${JDK}-jre AS jre\n\
+                          FROM eclipse-temurin:${JDK:-17}-jdk AS jdk\n\
+                          FROM build AS test\n\
+                          FROM gcr.io/distroless/static:nonroot\n";
+        let evidence = scan_runtime_file("Dockerfile", dockerfile);
+        let versions: Vec<(Runtime, &str, usize)> = evidence
+            .versions
+            .iter()
+            .map(|v| (v.runtime, v.version.as_str(), v.line))
+            .collect();
+        assert_eq!(
+            versions,
+            vec![
+                (Runtime::Go, "1.23", 4),
+                (Runtime::NodeJs, "22", 6),
+                (Runtime::Java, "17", 8),
+            ]
+        );
+        assert_eq!(evidence.versions[0].source, "FROM golang:1.23-alpine");
+
+        let report = assess_runtimes(&evidence, &[]);
+        let status: Vec<PqStatus> = report.assessments.iter().map(|a| a.status).collect();
+        assert_eq!(
+            status,
+            vec![
+                PqStatus::EnabledByDefault,
+                PqStatus::Unavailable,
+                PqStatus::Unavailable,
+            ]
+        );
+    }
+
+    #[test]
+    fn test_no_runtime_evidence() {
+        for (path, content) in [
+            ("Dockerfile", "FROM scratch\nCOPY app /app\n"),
+            ("package.json", r#"{"name": "web", "dependencies": {}}"#),
+            ("go.mod", "module example.com/m\n"),
+            ("ci.yml", "name: ci\non: push\n"),
+            ("Dockerfile", ""),
+        ] {
+            assert_eq!(
+                scan_runtime_file(path, content),
+                RuntimeEvidence::default(),
+                "{path}"
+            );
+        }
+
+        let report = assess_runtimes(&RuntimeEvidence::default(), &[]);
+        assert!(report.assessments.is_empty());
+        assert!(report.settings.is_empty());
+        assert_eq!(report.count(PqStatus::Disabled), 0);
+    }
+}
@@ -0,0 +1,355 @@
+//! SARIF 2.1.0 output
+//!
+//! Produces a SARIF log that code scanning tools (GitHub code scanning,
+//! IDE viewers) can ingest. Each crypto type is a rule; a rule's `precision`
+//! is the highest confidence among its results, and every result carries its
+//! own confidence and match kind in `properties`.
+
+use crate::catalog::category;
+use crate::types::*;
+use serde::Serialize;
+use std::collections::BTreeMap;
+
+const SARIF_SCHEMA: &str = "https://json.schemastore.org/sarif-2.1.0.json";
+const SARIF_VERSION: &str = "2.1.0";
+const TOOL_NAME: &str = "pqc-scanner";
+/// Key of the finding fingerprint in `partialFingerprints`
+const FINGERPRINT_KEY: &str = "pqcFindingHash/v1";
+const TOOL_URI: &str = "https://github.com/arcqubit/quantum-pqc";
+
+#[derive(Debug, Clone, Serialize)]
+pub struct SarifLog {
+    #[serde(rename = "$schema")]
+    pub schema: String,
+    pub version: String,
+    pub runs: Vec<SarifRun>,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct SarifRun {
+    pub tool: SarifTool,
+    pub results: Vec<SarifResult>,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct SarifTool {
+    pub driver: SarifDriver,
+}
+
+#[derive(Debug, Clone, Serialize)]
+#[serde(rename_all = "camelCase")]
+pub struct SarifDriver {
+    pub name: String,
+    pub version: String,
+    pub information_uri: String,
+    pub rules: Vec<SarifRule>,
+}
+
+#[derive(Debug, Clone, Serialize)]
+#[serde(rename_all = "camelCase")]
+pub struct SarifRule {
+    pub id: String,
+    pub name: String,
+    pub short_description: SarifMessage,
+    pub help: SarifMessage,
+    pub default_configuration: SarifConfiguration,
+    pub properties: SarifRuleProperties,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct SarifConfiguration {
+    pub level: String,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct SarifRuleProperties {
+    /// `low`, `medium` or `high`: highest confidence among the rule's results
+    pub precision: String,
+    #[serde(rename = "security-severity")]
+    pub security_severity: String,
+    pub tags: Vec<String>,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct SarifMessage {
+    pub text: String,
+}
+
+#[derive(Debug, Clone, Serialize)]
+#[serde(rename_all = "camelCase")]
+pub struct SarifResult {
+    pub rule_id: String,
+    pub rule_index: usize,
+    pub level: String,
+    pub message: SarifMessage,
+    pub locations: Vec<SarifLocation>,
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub related_locations: Vec<SarifLocation>,
+    /// `pqcFindingHash/v1`: the finding's fingerprint, when assigned
+    #[serde(skip_serializing_if = "BTreeMap::is_empty")]
+    pub partial_fingerprints: BTreeMap<String, String>,
+    pub properties: SarifResultProperties,
+}
+
+#[derive(Debug, Clone, Serialize)]
+#[serde(rename_all = "camelCase")]
+pub struct SarifResultProperties {
+    pub confidence: Confidence,
+    pub match_kind: MatchKind,
+    pub risk_score: u32,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub key_size: Option<u32>,
+}
+
+#[derive(Debug, Clone, Serialize)]
+#[serde(rename_all = "camelCase")]
+pub struct SarifLocation {
+    pub physical_location: SarifPhysicalLocation,
+}
+
+#[derive(Debug, Clone, Serialize)]
+#[serde(rename_all = "camelCase")]
+pub struct SarifPhysicalLocation {
+    pub artifact_location: SarifArtifactLocation,
+    pub region: SarifRegion,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct SarifArtifactLocation {
+    pub uri: String,
+}
+
+/// Region with 1-based line and column numbers
+#[derive(Debug, Clone, Serialize)]
+#[serde(rename_all = "camelCase")]
+pub struct SarifRegion {
+    pub start_line: usize,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub start_column: Option<usize>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub end_column: Option<usize>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub snippet: Option<SarifMessage>,
+}
+
+/// Build a SARIF log from per-file findings (`(path, findings)` pairs)
+pub fn generate_sarif_report<'a>(
+    results: impl IntoIterator<Item = (&'a str, &'a [Vulnerability])>,
+) -> SarifLog {
+    let mut rules: Vec<SarifRule> = Vec::new();
+    let mut precision: Vec<Confidence> = Vec::new();
+    let mut sarif_results = Vec::new();
+
+    for (path, findings) in results {
+        for vuln in findings {
+            let rule_index = match rules.iter().position(|r| r.id == rule_id(vuln)) {
+                Some(index) => {
+                    precision[index] = precision[index].max(vuln.confidence);
+                    index
+                }
+                None => {
+                    rules.push(rule_for(vuln));
+                    precision.push(vuln.confidence);
+                    rules.len() - 1
+                }
+            };
+            sarif_results.push(result_for(path, vuln, rule_index));
+        }
+    }
+
+    for (rule, confidence) in rules.iter_mut().zip(precision) {
+        rule.properties.precision = confidence.to_string();
+    }
+
+    SarifLog {
+        schema: SARIF_SCHEMA.to_string(),
+        version: SARIF_VERSION.to_string(),
+        runs: vec![SarifRun {
+            tool: SarifTool {
+                driver: SarifDriver {
+                    name: TOOL_NAME.to_string(),
+                    version: env!("CARGO_PKG_VERSION").to_string(),
+                    information_uri: TOOL_URI.to_string(),
+                    rules,
+                },
+            },
+            results: sarif_results,
+        }],
+    }
+}
+
+/// Export SARIF log as JSON string
+pub fn export_sarif_json(log: &SarifLog) -> Result<String, serde_json::Error> {
+    serde_json::to_string_pretty(log)
+}
+
+fn rule_for(vuln: &Vulnerability) -> SarifRule {
+    let category = category(&vuln.crypto_type);
+    let mut tags = vec!["security".to_string(), "cryptography".to_string()];
+    tags.push(category.to_string());
+    if vuln.rule_id.is_some() {
+        tags.push("custom-rule".to_string());
+    }
+
+    SarifRule {
+        id: rule_id(vuln),
+        name: vuln.crypto_type.to_string(),
+        short_description: SarifMessage {
+            text: match category {
+                "quantum-vulnerable" => {
+                    format!("Quantum-vulnerable algorithm: {}", vuln.crypto_type)
+                }
+                "deprecated-api" => "Deprecated cryptographic API".to_string(),
+                "transport-security" => "TLS verification disabled".to_string(),
+                _ => format!("Deprecated algorithm: {}", vuln.crypto_type),
+            },
+        },
+        help: SarifMessage {
+            text: vuln.recommendation.clone(),
+        },
+        default_configuration: SarifConfiguration {
+            level: level(vuln.severity).to_string(),
+        },
+        properties: SarifRuleProperties {
+            precision: vuln.confidence.to_string(),
+            security_severity: format!("{:.1}", vuln.risk_score as f64 / 10.0),
+            tags,
+        },
+    }
+}
+
+fn result_for(path: &str, vuln: &Vulnerability, rule_index: usize) -> SarifResult {
+    let generated = SarifLocation {
+        physical_location: SarifPhysicalLocation {
+            artifact_location: SarifArtifactLocation {
+                uri: path.to_string(),
+            },
+            region: SarifRegion {
+                start_line: vuln.line,
+                start_column: Some(vuln.column + 1),
+                end_column: (vuln.end_column > vuln.column).then_some(vuln.end_column + 1),
+                snippet: Some(SarifMessage {
+                    text: vuln.context.clone(),
+                }),
+            },
+        },
+    };
+
+    // Findings remapped through a source map are reported in the original source
+    let (locations, mut related_locations) = match &vuln.original_location {
+        Some(original) => (vec![source_location(original)], vec![generated]),
+        None => (vec![generated], Vec::new()),
+    };
+    related_locations.extend(vuln.secondary_locations.iter().map(source_location));
+
+    SarifResult {
+        rule_id: rule_id(vuln),
+        rule_index,
+        level: level(vuln.severity).to_string(),
+        message: SarifMessage {
+            text: vuln.message.clone(),
+        },
+        locations,
+        related_locations,
+        partial_fingerprints: vuln
+            .fingerprint
+            .iter()
+            .map(|fp| (FINGERPRINT_KEY.to_string(), fp.clone()))
+            .collect(),
+        properties: SarifResultProperties {
+            confidence: vuln.confidence,
+            match_kind: vuln.match_kind,
+            risk_score: vuln.risk_score,
+            key_size: vuln.key_size,
+        },
+    }
+}
+
+fn source_location(location: &SourceLocation) -> SarifLocation {
+    SarifLocation {
+        physical_location: SarifPhysicalLocation {
+            artifact_location: SarifArtifactLocation {
+                uri: location.file_path.clone(),
+            },
+            region: SarifRegion {
+                start_line: location.line,
+                start_column: Some(location.column + 1),
+                end_column: None,
+                snippet: (!location.snippet.is_empty()).then(|| SarifMessage {
+                    text: location.snippet.clone(),
+                }),
+            },
+        },
+    }
+}
+
+/// Custom rule id, or the built-in rule of the finding's algorithm
+fn rule_id(vuln: &Vulnerability) -> String {
+    vuln.rule_id
+        .clone()
+        .unwrap_or_else(|| vuln.crypto_type.rule_id().to_string())
+}
+
+fn level(severity: Severity) -> &'static str {
+    match severity {
+        Severity::Critical | Severity::High => "error",
+        Severity::Medium => "warning",
+        Severity::Low => "note",
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::analyze;
+
+    #[test]
+    fn test_sarif_precision_and_regions() {
+        let source =
+            "let rsaKey = load();\nkey, _ := rsa.GenerateKey(rand.Reader, 2048)\nx = 'md5'\n";
+        let audit = analyze(source, "go").unwrap();
+        let log = generate_sarif_report([("main.go", audit.vulnerabilities.as_slice())]);
+        let run = &log.runs[0];
+
+        let rsa = run
+            .tool
+            .driver
+            .rules
+            .iter()
+            .find(|r| r.id == "pqc-rsa")
+            .unwrap();
+        assert_eq!(rsa.properties.precision, "high");
+        let md5 = run
+            .tool
+            .driver
+            .rules
+            .iter()
+            .find(|r| r.id == "pqc-md5")
+            .unwrap();
+        assert_eq!(md5.properties.precision, "medium");
+
+        let result = run
+            .results
+            .iter()
+            .find(|r| r.properties.match_kind == MatchKind::ApiCall)
+            .unwrap();
+        let region = &result.locations[0].physical_location.region;
+        assert_eq!(region.start_line, 2);
+        assert_eq!(region.start_column, Some(11));
+        assert_eq!(region.end_column, Some(14));
+        let rsa_finding = audit
+            .vulnerabilities
+            .iter()
+            .find(|v| v.crypto_type == CryptoType::Rsa && v.line == 2)
+            .unwrap();
+        assert_eq!(
+            result.partial_fingerprints.get("pqcFindingHash/v1"),
+            rsa_finding.fingerprint.as_ref()
+        );
+
+        let json = export_sarif_json(&log).unwrap();
+        assert!(json.contains("\"$schema\""));
+        assert!(json.contains("\"ruleIndex\""));
+        assert!(json.contains("\"partialFingerprints\""));
+    }
+}
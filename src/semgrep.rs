@@ -0,0 +1,716 @@
+//! Semgrep rule import
+//!
+//! Crypto rules kept in Semgrep's YAML format are imported as custom rules
+//! that run alongside the built-in detectors. The engine is line based, so
+//! only the part of Semgrep that can be expressed as a per-line match is
+//! supported:
+//!
+//! - `pattern-regex`, compiled as a Rust regex
+//! - `pattern` with metavariables (`$X`, `$...ARGS`), `...` and string
+//!   literals (`"..."` matches any string); whitespace is insignificant
+//! - `pattern-either` over the two above
+//!
+//! Rules using anything else (`patterns`, `pattern-not`, `pattern-inside`,
+//! `metavariable-*`, taint mode, multi-line patterns, ...) are skipped and
+//! reported as [`ImportIssue`]s. The algorithm comes from
+//! `metadata.crypto-type` (falling back to a word of the rule id, such as
+//! `weak-rsa-keygen`), the severity from `metadata.severity` or the rule's
+//! Semgrep severity.
+
+use crate::audit::{recommendation_for, score_vulnerability, severity_for_score, truncate_context};
+use crate::confidence::classify_match;
+use crate::types::*;
+use crate::yaml::{self, Yaml};
+use lazy_static::lazy_static;
+use regex::Regex;
+use serde::Serialize;
+use std::fmt;
+use thiserror::Error;
+
+lazy_static! {
+    static ref METAVARIABLE_RE: Regex = Regex::new(r"\$(?:\.\.\.)?[A-Z_][A-Z0-9_]*")
+        .expect("METAVARIABLE_RE: Invalid regex pattern - this is a compile-time bug");
+}
+
+/// What a `$X` metavariable matches: one expression without top-level whitespace
+const METAVARIABLE_EXPR: &str = r#"(?:"[^"]*"|'[^']*'|[\w.$\[\]:-]+(?:\([^()]*\))?)"#;
+
+/// Keys that change what a rule matches and cannot be honoured line by line
+const UNSUPPORTED_KEYS: &[&str] = &[
+    "patterns",
+    "pattern-not",
+    "pattern-inside",
+    "pattern-not-inside",
+    "pattern-not-regex",
+    "pattern-sources",
+    "pattern-sinks",
+    "pattern-sanitizers",
+    "pattern-propagators",
+    "metavariable-regex",
+    "metavariable-pattern",
+    "metavariable-comparison",
+    "focus-metavariable",
+    "join",
+    "match",
+];
+
+/// Keys that are accepted but have no effect here
+const IGNORED_KEYS: &[&str] = &[
+    "fix",
+    "fix-regex",
+    "paths",
+    "options",
+    "min-version",
+    "max-version",
+];
+
+#[derive(Error, Debug)]
+pub enum SemgrepError {
+    #[error("Invalid YAML: {0}")]
+    InvalidYaml(#[from] yaml::YamlError),
+
+    #[error("Expected a top-level `rules` list")]
+    MissingRules,
+}
+
+/// A rule imported from a Semgrep rule file
+#[derive(Debug, Clone)]
+pub struct CustomRule {
+    pub id: String,
+    /// Languages the rule applies to; empty for `generic`/`regex` rules
+    pub languages: Vec<Language>,
+    pub crypto_type: CryptoType,
+    /// Fixed severity; derived from the risk score when `None`
+    pub severity: Option<Severity>,
+    /// Message template, may reference metavariables
+    pub message: String,
+    pub recommendation: String,
+    /// Fixed confidence (`metadata.confidence`); derived from the match kind when `None`
+    pub confidence: Option<Confidence>,
+    pub references: Vec<String>,
+    /// Snippets the rule should (`metadata.examples.positive`) and should not match
+    pub positive_examples: Vec<String>,
+    pub negative_examples: Vec<String>,
+    /// Alternatives, any of which produces a finding
+    pub patterns: Vec<RulePattern>,
+}
+
+/// One `pattern` or `pattern-regex` compiled to a line regex
+#[derive(Debug, Clone)]
+pub struct RulePattern {
+    pub kind: PatternKind,
+    /// Pattern as written in the rule file
+    pub source: String,
+    regex: Regex,
+    /// Metavariable bound by each capture group (`m0`, `m1`, ...)
+    metavariables: Vec<String>,
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum PatternKind {
+    Pattern,
+    PatternRegex,
+}
+
+/// A construct the importer could not honour
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct ImportIssue {
+    pub rule_id: String,
+    /// Rule key or pattern element involved (`pattern-inside`, `<... $X ...>`)
+    pub construct: String,
+    pub reason: String,
+    /// Whether the rule was dropped (otherwise only the construct is ignored)
+    pub rule_skipped: bool,
+}
+
+impl fmt::Display for ImportIssue {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        write!(
+            f,
+            "rule '{}': `{}` {} ({})",
+            self.rule_id,
+            self.construct,
+            self.reason,
+            if self.rule_skipped {
+                "rule skipped"
+            } else {
+                "ignored"
+            }
+        )
+    }
+}
+
+/// Rules imported from one file, and what could not be imported
+#[derive(Debug, Clone, Default)]
+pub struct RuleImport {
+    pub rules: Vec<CustomRule>,
+    pub issues: Vec<ImportIssue>,
+}
+
+impl RuleImport {
+    /// Issues that dropped a rule
+    pub fn skipped(&self) -> impl Iterator<Item = &ImportIssue> {
+        self.issues.iter().filter(|issue| issue.rule_skipped)
+    }
+}
+
+/// Import the supported subset of a Semgrep rule file
+pub fn import_semgrep_rules(source: &str) -> Result<RuleImport, SemgrepError> {
+    let document = yaml::parse(source)?;
+    let rules = document
+        .get("rules")
+        .and_then(Yaml::as_seq)
+        .ok_or(SemgrepError::MissingRules)?;
+
+    let mut import = RuleImport::default();
+    for (index, rule) in rules.iter().enumerate() {
+        let id = rule
+            .get("id")
+            .and_then(Yaml::as_str)
+            .map(str::to_string)
+            .unwrap_or_else(|| format!("rules[{}]", index));
+        match import_rule(&id, rule, &mut import.issues) {
+            Ok(imported) => import.rules.push(imported),
+            Err((construct, reason)) => import.issues.push(ImportIssue {
+                rule_id: id,
+                construct,
+                reason,
+                rule_skipped: true,
+            }),
+        }
+    }
+
+    Ok(import)
+}
+
+/// Convert one rule; `Err((construct, reason))` when it cannot be run
+fn import_rule(
+    id: &str,
+    rule: &Yaml,
+    issues: &mut Vec<ImportIssue>,
+) -> Result<CustomRule, (String, String)> {
+    let entries = rule
+        .as_map()
+        .ok_or_else(|| ("rule".to_string(), "is not a mapping".to_string()))?;
+
+    for (key, _) in entries {
+        if UNSUPPORTED_KEYS.contains(&key.as_str()) {
+            return Err((key.clone(), "is not supported".to_string()));
+        }
+        if IGNORED_KEYS.contains(&key.as_str()) {
+            issues.push(ImportIssue {
+                rule_id: id.to_string(),
+                construct: key.clone(),
+                reason: "has no effect".to_string(),
+                rule_skipped: false,
+            });
+        }
+    }
+    if let Some(mode) = rule.get("mode").and_then(Yaml::as_str)
+        && mode != "search"
+    {
+        return Err((format!("mode: {}", mode), "is not supported".to_string()));
+    }
+
+    let languages = import_languages(rule)?;
+
+    let mut patterns = Vec::new();
+    if let Some(pattern) = rule.get("pattern") {
+        patterns.push(import_pattern(PatternKind::Pattern, pattern)?);
+    }
+    if let Some(pattern) = rule.get("pattern-regex") {
+        patterns.push(import_pattern(PatternKind::PatternRegex, pattern)?);
+    }
+    if let Some(either) = rule.get("pattern-either") {
+        let alternatives = either
+            .as_seq()
+            .ok_or_else(|| ("pattern-either".to_string(), "must be a list".to_string()))?;
+        for alternative in alternatives {
+            match alternative.as_map() {
+                Some([(key, pattern)]) if key == "pattern" => {
+                    patterns.push(import_pattern(PatternKind::Pattern, pattern)?)
+                }
+                Some([(key, pattern)]) if key == "pattern-regex" => {
+                    patterns.push(import_pattern(PatternKind::PatternRegex, pattern)?)
+                }
+                Some([(key, _)]) => {
+                    return Err((
+                        format!("pattern-either: {}", key),
+                        "is not supported".to_string(),
+                    ));
+                }
+                _ => {
+                    return Err((
+                        "pattern-either".to_string(),
+                        "alternatives must each be a single pattern".to_string(),
+                    ));
+                }
+            }
+        }
+    }
+    if patterns.is_empty() {
+        return Err((
+            "pattern".to_string(),
+            "is missing (expected `pattern`, `pattern-regex` or `pattern-either`)".to_string(),
+        ));
+    }
+
+    let metadata = rule.get("metadata");
+    let meta = |key: &str| metadata.and_then(|m| m.get(key)).and_then(Yaml::as_str);
+
+    let crypto_type = ["crypto-type", "crypto_type", "algorithm"]
+        .iter()
+        .find_map(|key| meta(key))
+        .map(|value| {
+            CryptoType::from_string(value).ok_or_else(|| {
+                (
+                    "metadata.crypto-type".to_string(),
+                    format!("names an unknown algorithm '{}'", value),
+                )
+            })
+        })
+        .transpose()?
+        .or_else(|| id.split(['-', '_', '.']).find_map(CryptoType::from_string))
+        .ok_or_else(|| {
+            (
+                "metadata.crypto-type".to_string(),
+                "is missing and the rule id names no algorithm".to_string(),
+            )
+        })?;
+
+    let severity = match meta("severity").or_else(|| rule.get("severity").and_then(Yaml::as_str)) {
+        Some(value) => Some(parse_severity(value).ok_or_else(|| {
+            (
+                "severity".to_string(),
+                format!("has unknown value '{}'", value),
+            )
+        })?),
+        None => None,
+    };
+    let confidence = meta("confidence").and_then(Confidence::from_string);
+
+    let strings = |node: Option<&Yaml>| -> Vec<String> {
+        node.map(|n| n.scalars().into_iter().map(str::to_string).collect())
+            .unwrap_or_default()
+    };
+    let references = strings(metadata.and_then(|m| m.get("references")));
+    let examples = metadata.and_then(|m| m.get("examples"));
+    let positive_examples = strings(examples.and_then(|e| e.get("positive")));
+    let negative_examples = strings(examples.and_then(|e| e.get("negative")));
+
+    Ok(CustomRule {
+        id: id.to_string(),
+        languages,
+        message: rule
+            .get("message")
+            .and_then(Yaml::as_str)
+            .map(|m| m.trim().to_string())
+            .unwrap_or_else(|| format!("{} detected by rule {}", crypto_type, id)),
+        recommendation: meta("recommendation")
+            .map(str::to_string)
+            .unwrap_or_else(|| recommendation_for(&crypto_type).to_string()),
+        crypto_type,
+        severity,
+        confidence,
+        references,
+        positive_examples,
+        negative_examples,
+        patterns,
+    })
+}
+
+fn import_languages(rule: &Yaml) -> Result<Vec<Language>, (String, String)> {
+    let names = rule.get("languages").map(Yaml::scalars).unwrap_or_default();
+    if names.is_empty() {
+        return Err(("languages".to_string(), "is missing".to_string()));
+    }
+
+    let mut languages = Vec::new();
+    for name in &names {
+        match name.to_lowercase().as_str() {
+            "generic" | "regex" | "none" => return Ok(Vec::new()),
+            "c" => languages.push(Language::Cpp),
+            other => languages.extend(Language::from_string(other)),
+        }
+    }
+    if languages.is_empty() {
+        return Err((
+            format!("languages: {}", names.join(", ")),
+            "names no supported language".to_string(),
+        ));
+    }
+    languages.dedup();
+    Ok(languages)
+}
+
+/// Semgrep (`ERROR`, `WARNING`, `INFO`) or scanner (`critical` ... `low`) severity
+fn parse_severity(value: &str) -> Option<Severity> {
+    match value.to_lowercase().as_str() {
+        "critical" => Some(Severity::Critical),
+        "error" | "high" => Some(Severity::High),
+        "warning" | "medium" => Some(Severity::Medium),
+        "info" | "low" | "inventory" | "experiment" => Some(Severity::Low),
+        _ => None,
+    }
+}
+
+fn import_pattern(kind: PatternKind, pattern: &Yaml) -> Result<RulePattern, (String, String)> {
+    let key = match kind {
+        PatternKind::Pattern => "pattern",
+        PatternKind::PatternRegex => "pattern-regex",
+    };
+    let source = pattern
+        .as_str()
+        .ok_or_else(|| (key.to_string(), "must be a string".to_string()))?
+        .trim()
+        .to_string();
+    if source.is_empty() {
+        return Err((key.to_string(), "is empty".to_string()));
+    }
+    if source.contains('\n') {
+        return Err((
+            key.to_string(),
+            "spans several lines; rules are matched line by line".to_string(),
+        ));
+    }
+
+    let (regex, metavariables) = match kind {
+        PatternKind::PatternRegex => {
+            let regex = Regex::new(&source)
+                .map_err(|e| (key.to_string(), format!("is not a supported regex: {}", e)))?;
+            (regex, Vec::new())
+        }
+        PatternKind::Pattern => {
+            let (translated, metavariables) = translate_pattern(&source)?;
+            let regex = Regex::new(&translated)
+                .map_err(|e| (key.to_string(), format!("could not be translated: {}", e)))?;
+            (regex, metavariables)
+        }
+    };
+
+    Ok(RulePattern {
+        kind,
+        source,
+        regex,
+        metavariables,
+    })
+}
+
+/// Translate a single-line Semgrep pattern into a regex and its metavariable groups
+fn translate_pattern(pattern: &str) -> Result<(String, Vec<String>), (String, String)> {
+    if pattern.contains("<...") {
+        return Err((
+            "<... ...>".to_string(),
+            "deep expression operators are not supported".to_string(),
+        ));
+    }
+
+    let chars: Vec<char> = pattern.chars().collect();
+    let is_word = |c: char| c.is_alphanumeric() || c == '_';
+    let mut regex = String::new();
+    let mut metavariables: Vec<String> = Vec::new();
+    // Whether the previous token ended in a word character, for `\s+` vs `\s*`
+    let mut prev_word = false;
+    let mut pending_space = false;
+    let mut i = 0;
+
+    while i < chars.len() {
+        let c = chars[i];
+        if c.is_whitespace() {
+            pending_space = true;
+            i += 1;
+            continue;
+        }
+
+        let rest: String = chars[i..].iter().collect();
+        let (token, starts_word, ends_word, len) = if rest.starts_with("...") {
+            (".*?".to_string(), false, false, 3)
+        } else if let Some(m) = METAVARIABLE_RE.find(&rest).filter(|m| m.start() == 0) {
+            let name = m.as_str().trim_start_matches('$').to_string();
+            let group = format!("m{}", metavariables.len());
+            let token = match name.strip_prefix("...") {
+                Some(_) => format!("(?P<{}>.*?)", group),
+                None => format!("(?P<{}>{})", group, METAVARIABLE_EXPR),
+            };
+            metavariables.push(name.trim_start_matches("...").to_string());
+            (token, false, false, m.end())
+        } else if c == '"' || c == '\'' {
+            let close = chars[i + 1..]
+                .iter()
+                .position(|&q| q == c)
+                .map(|p| i + 1 + p)
+                .ok_or_else(|| {
+                    (
+                        "pattern".to_string(),
+                        "has an unterminated string".to_string(),
+                    )
+                })?;
+            let content: String = chars[i + 1..close].iter().collect();
+            let token = if content == "..." {
+                r#"(?:"[^"]*"|'[^']*')"#.to_string()
+            } else {
+                // Semgrep matches a string literal regardless of its quote style
+                format!(r#"["'`]{}["'`]"#, regex::escape(&content))
+            };
+            (token, false, false, close - i + 1)
+        } else if is_word(c) {
+            let len = chars[i..].iter().take_while(|&&c| is_word(c)).count();
+            let word: String = chars[i..i + len].iter().collect();
+            (regex::escape(&word), true, true, len)
+        } else {
+            (regex::escape(&c.to_string()), false, false, 1)
+        };
+
+        if regex.is_empty() && starts_word {
+            regex.push_str(r"\b");
+        } else if pending_space && prev_word && starts_word {
+            regex.push_str(r"\s+");
+        } else if !regex.is_empty() {
+            regex.push_str(r"\s*");
+        }
+        regex.push_str(&token);
+        prev_word = ends_word;
+        pending_space = false;
+        i += len;
+    }
+
+    if prev_word {
+        regex.push_str(r"\b");
+    }
+    Ok((regex, metavariables))
+}
+
+impl CustomRule {
+    /// Whether the rule runs on files of `language`
+    pub fn applies_to(&self, language: Language) -> bool {
+        self.languages.is_empty() || self.languages.contains(&language)
+    }
+
+    /// Findings of this rule on one line (at most one, at the first match)
+    pub(crate) fn detect_line(&self, line: &str, line_num: usize) -> Option<Vulnerability> {
+        self.patterns
+            .iter()
+            .find_map(|pattern| pattern.find(line))
+            .map(|found| self.build_finding(line, line_num, &found))
+    }
+
+    /// Metavariable a finding's key size is read from, and its value
+    pub(crate) fn key_size_binding(&self, line: &str) -> Option<(String, u32)> {
+        let found = self
+            .patterns
+            .iter()
+            .find_map(|pattern| pattern.find(line))?;
+        found
+            .bindings
+            .into_iter()
+            .find_map(|(name, value)| Some((name, value.trim().parse().ok()?)))
+    }
+
+    fn build_finding(&self, line: &str, line_num: usize, found: &PatternMatch) -> Vulnerability {
+        let PatternMatch {
+            start,
+            end,
+            ref bindings,
+        } = *found;
+        // A bound number (`rsa.GenerateKey($R, $BITS)`) is the key size
+        let key_size = bindings
+            .iter()
+            .find_map(|(_, value)| value.trim().parse::<u32>().ok());
+        let risk_score = score_vulnerability(&self.crypto_type, key_size);
+        let severity = self
+            .severity
+            .unwrap_or_else(|| severity_for_score(risk_score));
+        let match_kind = classify_match(line, start, end);
+
+        let mut message = self.message.clone();
+        for (name, value) in bindings {
+            message = message.replace(&format!("${}", name), value.trim());
+        }
+
+        Vulnerability {
+            end_column: end,
+            match_kind,
+            confidence: self.confidence.unwrap_or(match_kind.confidence()),
+            context: truncate_context(line, start),
+            message,
+            recommendation: self.recommendation.clone(),
+            key_size,
+            rule_id: Some(self.id.clone()),
+            ..Vulnerability::new(
+                self.crypto_type.clone(),
+                severity,
+                risk_score,
+                line_num,
+                start,
+            )
+        }
+    }
+}
+
+/// Span of a pattern match and the text bound to each metavariable
+struct PatternMatch {
+    start: usize,
+    end: usize,
+    bindings: Vec<(String, String)>,
+}
+
+impl RulePattern {
+    /// First match in `line` and its metavariable bindings
+    fn find(&self, line: &str) -> Option<PatternMatch> {
+        self.regex.captures_iter(line).find_map(|caps| {
+            let whole = caps.get(0)?;
+            if self.kind == PatternKind::Pattern
+                && classify_match(line, whole.start(), whole.end()) == MatchKind::Comment
+            {
+                // Code patterns do not match inside comments
+                return None;
+            }
+
+            let mut bindings: Vec<(String, String)> = Vec::new();
+            for (index, name) in self.metavariables.iter().enumerate() {
+                let value = caps
+                    .name(&format!("m{}", index))
+                    .map_or("", |m| m.as_str())
+                    .to_string();
+                // A metavariable used twice must bind the same text both times
+                match bindings.iter().find(|(n, _)| n == name) {
+                    Some((_, bound)) if bound.trim() != value.trim() => return None,
+                    Some(_) => {}
+                    None => bindings.push((name.clone(), value)),
+                }
+            }
+            if self.kind == PatternKind::PatternRegex {
+                for (index, group) in caps.iter().enumerate().skip(1) {
+                    if let Some(group) = group {
+                        bindings.push((index.to_string(), group.as_str().to_string()));
+                    }
+                }
+            }
+
+            Some(PatternMatch {
+                start: whole.start(),
+                end: whole.end(),
+                bindings,
+            })
+        })
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    const RULES: &str = r#"
+rules:
+  - id: go-weak-rsa-keygen
+    languages: [go]
+    severity: ERROR
+    message: RSA key generated with $BITS bits
+    pattern: rsa.GenerateKey($RAND, $BITS)
+    metadata:
+      references: [https://pkg.go.dev/crypto/rsa]
+  - id: java-cipher
+    languages: [java]
+    severity: WARNING
+    message: Legacy cipher
+    pattern-either:
+      - pattern: $C.getInstance("DESede")
+      - pattern-regex: 'DESede/CBC'
+    metadata:
+      crypto-type: 3DES
+      confidence: HIGH
+  - id: inside
+    languages: [python]
+    patterns:
+      - pattern-inside: def $F(...)
+      - pattern: hashlib.md5(...)
+  - id: ruby-md5
+    languages: [ruby]
+    pattern: Digest::MD5.hexdigest(...)
+  - id: no-algorithm
+    languages: [go]
+    pattern: foo($X)
+    fix: bar($X)
+"#;
+
+    #[test]
+    fn test_import_semgrep_rules() {
+        let import = import_semgrep_rules(RULES).unwrap();
+        let ids: Vec<&str> = import.rules.iter().map(|r| r.id.as_str()).collect();
+        assert_eq!(ids, vec!["go-weak-rsa-keygen", "java-cipher"]);
+
+        let skipped: Vec<(&str, &str)> = import
+            .skipped()
+            .map(|i| (i.rule_id.as_str(), i.construct.as_str()))
+            .collect();
+        assert_eq!(
+            skipped,
+            vec![
+                ("inside", "patterns"),
+                ("ruby-md5", "languages: ruby"),
+                ("no-algorithm", "metadata.crypto-type"),
+            ]
+        );
+        assert!(
+            import
+                .issues
+                .iter()
+                .any(|i| i.rule_id == "no-algorithm" && i.construct == "fix" && !i.rule_skipped)
+        );
+
+        let rsa = &import.rules[0];
+        assert_eq!(rsa.crypto_type, CryptoType::Rsa);
+        assert_eq!(rsa.severity, Some(Severity::High));
+        assert_eq!(rsa.references, vec!["https://pkg.go.dev/crypto/rsa"]);
+        let cipher = &import.rules[1];
+        assert_eq!(cipher.crypto_type, CryptoType::TripleDes);
+        assert_eq!(cipher.confidence, Some(Confidence::High));
+    }
+
+    #[test]
+    fn test_pattern_metavariables() {
+        let import = import_semgrep_rules(RULES).unwrap();
+        let rsa = &import.rules[0];
+
+        let line = "\tkey, err := rsa.GenerateKey( rand.Reader, 1024 )";
+        let vuln = rsa.detect_line(line, 7).unwrap();
+        assert_eq!(vuln.key_size, Some(1024));
+        assert_eq!(vuln.message, "RSA key generated with 1024 bits");
+        assert_eq!(
+            &line[vuln.column..vuln.end_column],
+            "rsa.GenerateKey( rand.Reader, 1024 )"
+        );
+        assert_eq!(vuln.rule_id.as_deref(), Some("go-weak-rsa-keygen"));
+        assert_eq!(vuln.risk_score, 100);
+
+        assert!(rsa.detect_line("// rsa.GenerateKey(r, 1024)", 1).is_none());
+        assert!(rsa.detect_line("myrsa.GenerateKey(r, 1024)", 1).is_none());
+
+        let cipher = &import.rules[1];
+        let vuln = cipher
+            .detect_line("Cipher c = Cipher.getInstance('DESede');", 3)
+            .unwrap();
+        assert_eq!(vuln.column, 11);
+        assert_eq!(vuln.severity, Severity::Medium);
+        assert!(
+            cipher
+                .detect_line("String mode = \"DESede/CBC\";", 1)
+                .is_some()
+        );
+    }
+
+    #[test]
+    fn test_repeated_metavariable() {
+        let (regex, metavariables) = translate_pattern("$A == $A").unwrap();
+        assert_eq!(metavariables, vec!["A", "A"]);
+        let pattern = RulePattern {
+            kind: PatternKind::Pattern,
+            source: "$A == $A".to_string(),
+            regex: Regex::new(&regex).unwrap(),
+            metavariables,
+        };
+        assert!(pattern.find("if x == x {").is_some());
+        assert!(pattern.find("if x == y {").is_none());
+    }
+}
@@ -0,0 +1,499 @@
+//! Sharded scanning
+//!
+//! A large repository can be scanned by parallel CI jobs: each job scans
+//! the files of one shard (`--shard 3/8`) and writes a partial result, and
+//! `merge` combines the partial results into one project result before
+//! reports are generated. Files are assigned to shards by a stable hash of
+//! their path relative to the scan root, so every job computes the same
+//! partition without coordinating.
+
+use crate::diagnostics::ScanDiagnostic;
+use crate::projects::SubProject;
+use crate::types::*;
+use serde::{Deserialize, Serialize};
+use std::collections::{HashMap, HashSet};
+use std::fmt;
+use thiserror::Error;
+
+#[derive(Error, Debug)]
+pub enum ShardError {
+    #[error("Invalid shard: {0} (expected <index>/<count>, e.g. 3/8)")]
+    InvalidSpec(String),
+
+    #[error("Invalid shard result: {0}")]
+    InvalidResult(String),
+
+    #[error("Shards of different splits cannot be merged: {0} and {1}")]
+    MismatchedSplits(ShardSpec, ShardSpec),
+
+    #[error("No shard results to merge")]
+    NoShards,
+}
+
+/// One shard of a split: `index` (1-based) of `count`
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+pub struct ShardSpec {
+    pub index: usize,
+    pub count: usize,
+}
+
+impl ShardSpec {
+    /// Parse `3/8`
+    pub fn parse(spec: &str) -> Result<Self, ShardError> {
+        let invalid = || ShardError::InvalidSpec(spec.to_string());
+        let (index, count) = spec.split_once('/').ok_or_else(invalid)?;
+        let index: usize = index.trim().parse().map_err(|_| invalid())?;
+        let count: usize = count.trim().parse().map_err(|_| invalid())?;
+        if count == 0 || index == 0 || index > count {
+            return Err(invalid());
+        }
+        Ok(Self { index, count })
+    }
+
+    /// Whether the file at `path` (relative to the scan root) belongs to this shard
+    pub fn contains(&self, path: &str) -> bool {
+        (path_hash(path) % self.count as u64) as usize == self.index - 1
+    }
+}
+
+impl fmt::Display for ShardSpec {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        write!(f, "{}/{}", self.index, self.count)
+    }
+}
+
+/// FNV-1a of the path with `/` separators, stable across platforms and releases
+fn path_hash(path: &str) -> u64 {
+    let mut hash: u64 = 0xcbf2_9ce4_8422_2325;
+    for byte in path.bytes().map(|b| if b == b'\\' { b'/' } else { b }) {
+        hash ^= byte as u64;
+        hash = hash.wrapping_mul(0x0000_0100_0000_01b3);
+    }
+    hash
+}
+
+/// A file of another shard, reduced to the constants symbol resolution reads
+///
+/// Every shard resolves constants against the whole tree (see
+/// `resolve_symbols_in`), so a key size defined in another shard's file is
+/// resolved as in a full scan. Only the definition lines of the source are
+/// kept, for the snippets of secondary locations.
+pub fn definitions_only(path: &str, source: &str, parsed: ParsedSource) -> ProjectFile {
+    let lines: HashSet<usize> = parsed.constants.iter().map(|c| c.line).collect();
+    let source = source
+        .lines()
+        .enumerate()
+        .map(|(index, line)| {
+            if lines.contains(&(index + 1)) {
+                line
+            } else {
+                ""
+            }
+        })
+        .collect::<Vec<_>>()
+        .join("\n");
+
+    ProjectFile {
+        path: path.to_string(),
+        source,
+        audit: AuditResult::new(parsed.language, 0),
+        parsed: ParsedSource {
+            constants: parsed.constants,
+            ..ParsedSource::new(parsed.language)
+        },
+    }
+}
+
+/// Audit result of one scanned source file
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct FileResult {
+    pub path: String,
+    pub audit: AuditResult,
+}
+
+/// Plugin findings in a file without language support
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct PluginFileResult {
+    pub path: String,
+    pub vulnerabilities: Vec<Vulnerability>,
+}
+
+/// Partial result written by one shard
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ShardResult {
+    /// Scanner version that produced the shard
+    pub version: String,
+    pub shard: ShardSpec,
+    /// Scanned directory or repository
+    pub target: String,
+    pub files: Vec<FileResult>,
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub plugin_files: Vec<PluginFileResult>,
+    /// Files of the shard that were skipped or not analyzed
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub diagnostics: Vec<ScanDiagnostic>,
+    /// Project roots of the whole tree (`--projects`); every shard records all of them
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub projects: Vec<SubProject>,
+}
+
+impl ShardResult {
+    pub fn new(shard: ShardSpec, target: &str) -> Self {
+        Self {
+            version: env!("CARGO_PKG_VERSION").to_string(),
+            shard,
+            target: target.to_string(),
+            files: Vec::new(),
+            plugin_files: Vec::new(),
+            diagnostics: Vec::new(),
+            projects: Vec::new(),
+        }
+    }
+}
+
+/// Shard results combined into one project result
+#[derive(Debug, Clone, Serialize)]
+pub struct MergedScan {
+    pub target: String,
+    /// Files in path order, with statistics and risk recomputed
+    pub files: Vec<FileResult>,
+    pub plugin_files: Vec<PluginFileResult>,
+    pub diagnostics: Vec<ScanDiagnostic>,
+    pub projects: Vec<SubProject>,
+    /// Shards that were merged, in index order
+    pub shards: Vec<ShardSpec>,
+    /// Shard indexes of the split with no result
+    pub missing_shards: Vec<usize>,
+    /// Findings reported by more than one shard
+    pub duplicates_removed: usize,
+    pub warnings: Vec<String>,
+}
+
+impl MergedScan {
+    /// Project-level aggregate of every file
+    pub fn project_result(&self) -> Option<AuditResult> {
+        AuditResult::aggregate(self.files.iter().map(|file| &file.audit))
+    }
+}
+
+/// Export a shard result as JSON
+pub fn export_shard_json(result: &ShardResult) -> Result<String, serde_json::Error> {
+    serde_json::to_string_pretty(result)
+}
+
+/// Parse a shard result written by `export_shard_json`
+pub fn parse_shard_json(json: &str) -> Result<ShardResult, ShardError> {
+    serde_json::from_str(json).map_err(|e| ShardError::InvalidResult(e.to_string()))
+}
+
+/// Merge shard results of one split
+///
+/// A file reported by several shards (a re-run job, or shards of an earlier
+/// run) is merged with its duplicate findings removed; statistics, risk
+/// scores and recommendations are recomputed from the merged findings.
+pub fn merge_shards(shards: Vec<ShardResult>) -> Result<MergedScan, ShardError> {
+    let first = shards.first().ok_or(ShardError::NoShards)?;
+    let split = first.shard;
+    let target = first.target.clone();
+    let version = first.version.clone();
+    let mut warnings = Vec::new();
+
+    let mut merged_shards: Vec<ShardSpec> = Vec::new();
+    let mut files: Vec<FileResult> = Vec::new();
+    let mut plugin_files: Vec<PluginFileResult> = Vec::new();
+    let mut file_index: HashMap<String, usize> = HashMap::new();
+    let mut plugin_index: HashMap<String, usize> = HashMap::new();
+    let mut diagnostics: Vec<ScanDiagnostic> = Vec::new();
+    let mut projects: Vec<SubProject> = Vec::new();
+    let mut duplicates_removed = 0;
+
+    for shard in shards {
+        if shard.shard.count != split.count {
+            return Err(ShardError::MismatchedSplits(split, shard.shard));
+        }
+        if shard.version != version {
+            warnings.push(format!(
+                "Shard {} was produced by version {} (shard {}: {})",
+                shard.shard, shard.version, split, version
+            ));
+        }
+        if shard.target != target {
+            warnings.push(format!(
+                "Shard {} scanned {} (shard {}: {})",
+                shard.shard, shard.target, split, target
+            ));
+        }
+        if merged_shards.contains(&shard.shard) {
+            warnings.push(format!("Shard {} appears more than once", shard.shard));
+        } else {
+            merged_shards.push(shard.shard);
+        }
+
+        for file in shard.files {
+            match file_index.get(&file.path) {
+                Some(&index) => {
+                    let existing = &mut files[index];
+                    let stats = &mut existing.audit.stats;
+                    stats.lines_scanned = stats.lines_scanned.max(file.audit.stats.lines_scanned);
+                    duplicates_removed += merge_findings(
+                        &mut existing.audit.vulnerabilities,
+                        file.audit.vulnerabilities,
+                    );
+                }
+                None => {
+                    file_index.insert(file.path.clone(), files.len());
+                    files.push(file);
+                }
+            }
+        }
+        for file in shard.plugin_files {
+            match plugin_index.get(&file.path) {
+                Some(&index) => {
+                    duplicates_removed += merge_findings(
+                        &mut plugin_files[index].vulnerabilities,
+                        file.vulnerabilities,
+                    );
+                }
+                None => {
+                    plugin_index.insert(file.path.clone(), plugin_files.len());
+                    plugin_files.push(file);
+                }
+            }
+        }
+        for diagnostic in shard.diagnostics {
+            if !diagnostics
+                .iter()
+                .any(|d| d.path == diagnostic.path && d.kind == diagnostic.kind)
+            {
+                diagnostics.push(diagnostic);
+            }
+        }
+        for project in shard.projects {
+            if !projects.contains(&project) {
+                projects.push(project);
+            }
+        }
+    }
+
+    for file in &mut files {
+        duplicates_removed += dedupe(&mut file.audit.vulnerabilities);
+        file.audit.refresh();
+    }
+    for file in &mut plugin_files {
+        duplicates_removed += dedupe(&mut file.vulnerabilities);
+    }
+    files.sort_by(|a, b| a.path.cmp(&b.path));
+    plugin_files.sort_by(|a, b| a.path.cmp(&b.path));
+    diagnostics.sort_by(|a, b| a.path.cmp(&b.path));
+    merged_shards.sort_by_key(|s| s.index);
+
+    let present: HashSet<usize> = merged_shards.iter().map(|s| s.index).collect();
+    let missing_shards = (1..=split.count)
+        .filter(|index| !present.contains(index))
+        .collect();
+
+    Ok(MergedScan {
+        target,
+        files,
+        plugin_files,
+        diagnostics,
+        projects,
+        shards: merged_shards,
+        missing_shards,
+        duplicates_removed,
+        warnings,
+    })
+}
+
+/// Identity of a finding across shards
+fn finding_key(vuln: &Vulnerability) -> (usize, usize, usize, String, Option<String>) {
+    (
+        vuln.line,
+        vuln.column,
+        vuln.end_column,
+        vuln.crypto_type.to_string(),
+        vuln.rule_id.clone(),
+    )
+}
+
+/// Append the findings of `other` not already in `findings`; returns the number dropped
+fn merge_findings(findings: &mut Vec<Vulnerability>, other: Vec<Vulnerability>) -> usize {
+    let before = findings.len() + other.len();
+    findings.extend(other);
+    dedupe(findings);
+    findings.sort_by_key(|v| (v.line, v.column));
+    before - findings.len()
+}
+
+/// Remove repeated findings, keeping the first; returns the number removed
+fn dedupe(findings: &mut Vec<Vulnerability>) -> usize {
+    let before = findings.len();
+    let mut seen = HashSet::new();
+    findings.retain(|vuln| seen.insert(finding_key(vuln)));
+    before - findings.len()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::{analyze, project_file, resolve_project_symbols, resolve_symbols_in};
+
+    fn file(path: &str, source: &str) -> FileResult {
+        FileResult {
+            path: path.to_string(),
+            audit: analyze(source, "go").unwrap(),
+        }
+    }
+
+    #[test]
+    fn test_shard_partition() {
+        assert!(ShardSpec::parse("0/8").is_err());
+        assert!(ShardSpec::parse("9/8").is_err());
+        assert!(ShardSpec::parse("three").is_err());
+
+        let paths: Vec<String> = (0..200)
+            .map(|i| format!("pkg{}/file{}.go", i % 7, i))
+            .collect();
+        let shards: Vec<ShardSpec> = (1..=8)
+            .map(|i| ShardSpec::parse(&format!("{}/8", i)).unwrap())
+            .collect();
+        for path in &paths {
+            assert_eq!(shards.iter().filter(|s| s.contains(path)).count(), 1);
+        }
+        assert!(shards.iter().all(|s| paths.iter().any(|p| s.contains(p))));
+        assert_eq!(
+            shards[2].contains("src\\main.go"),
+            shards[2].contains("src/main.go")
+        );
+    }
+
+    #[test]
+    fn test_merge_shards() {
+        let spec = |index| ShardSpec { index, count: 3 };
+        let mut one = ShardResult::new(spec(1), "repo");
+        one.files.push(file("a.go", "h := md5.New()\n"));
+        let mut two = ShardResult::new(spec(2), "repo");
+        two.files
+            .push(file("b.go", "k, _ := rsa.GenerateKey(r, 1024)\n"));
+        // A re-run of shard 1 that also picked up a new finding
+        let mut rerun = ShardResult::new(spec(1), "repo");
+        rerun
+            .files
+            .push(file("a.go", "h := md5.New()\ns := sha1.New()\n"));
+
+        let json = export_shard_json(&two).unwrap();
+        let two = parse_shard_json(&json).unwrap();
+
+        let merged = merge_shards(vec![two, one, rerun]).unwrap();
+        assert_eq!(merged.files[0].path, "a.go");
+        assert_eq!(merged.files[0].audit.stats.total_vulnerabilities, 2);
+        assert_eq!(merged.duplicates_removed, 1);
+        assert_eq!(merged.missing_shards, vec![3]);
+        assert_eq!(merged.warnings.len(), 1);
+
+        let project = merged.project_result().unwrap();
+        assert_eq!(project.stats.total_vulnerabilities, 3);
+        assert_eq!(project.stats.critical_count, 3);
+        assert_eq!(project.stats.lines_scanned, 3);
+
+        let other = ShardResult::new(ShardSpec { index: 1, count: 4 }, "repo");
+        assert!(matches!(
+            merge_shards(vec![ShardResult::new(spec(1), "repo"), other]),
+            Err(ShardError::MismatchedSplits(..))
+        ));
+    }
+
+    #[test]
+    fn test_sharded_symbol_resolution_matches_full_scan() {
+        let fixture = [
+            (
+                "internal/config/config.go",
+                "package config\n\nconst RSAKeyBits = 1024\nconst Bits = RSAKeyBits\n",
+                "go",
+            ),
+            (
+                "internal/crypto/keys.go",
+                "package keys\n\nfunc New() {\n\trsa.GenerateKey(rand.Reader, config.Bits)\n}\n",
+                "go",
+            ),
+            (
+                "app/constants.py",
+                "import os\n\nSIGNING_ALG = os.getenv(\"SIGNING_ALG\", \"RSA\")\n",
+                "python",
+            ),
+            (
+                "app/tokens.py",
+                "def sign(key, payload):\n    return jwt.sign(payload, key, algorithm=constants.SIGNING_ALG)\n",
+                "python",
+            ),
+        ];
+        let files = || -> Vec<ProjectFile> {
+            fixture
+                .iter()
+                .map(|(path, source, language)| project_file(path, source, language))
+                .collect()
+        };
+        let findings = |files: &[FileResult]| -> Vec<(String, String)> {
+            let mut findings: Vec<(String, String)> = files
+                .iter()
+                .map(|file| {
+                    let json = serde_json::to_string(&file.audit.vulnerabilities).unwrap();
+                    (file.path.clone(), json)
+                })
+                .collect();
+            findings.sort();
+            findings
+        };
+
+        let mut full = files();
+        let summary = resolve_project_symbols(&mut full);
+        assert_eq!(summary.findings_added + summary.findings_updated, 2);
+        let full: Vec<FileResult> = full
+            .into_iter()
+            .map(|file| FileResult {
+                path: file.path,
+                audit: file.audit,
+            })
+            .collect();
+
+        let count = 3;
+        let specs: Vec<ShardSpec> = (1..=count)
+            .map(|index| ShardSpec { index, count })
+            .collect();
+        // Definitions and uses land in different shards
+        for (definition, usage) in [(0, 1), (2, 3)] {
+            let shard_of = |i: usize| specs.iter().position(|s| s.contains(fixture[i].0));
+            assert_ne!(shard_of(definition), shard_of(usage));
+        }
+
+        let shards = specs
+            .iter()
+            .map(|spec| {
+                let (mut own, others): (Vec<ProjectFile>, Vec<ProjectFile>) = files()
+                    .into_iter()
+                    .partition(|file| spec.contains(&file.path));
+                let reported = own.len();
+                own.extend(
+                    others
+                        .into_iter()
+                        .map(|file| definitions_only(&file.path, &file.source, file.parsed)),
+                );
+                resolve_symbols_in(&mut own, reported);
+                own.truncate(reported);
+
+                let mut result = ShardResult::new(*spec, "repo");
+                result.files = own
+                    .into_iter()
+                    .map(|file| FileResult {
+                        path: file.path,
+                        audit: file.audit,
+                    })
+                    .collect();
+                result
+            })
+            .collect();
+
+        let merged = merge_shards(shards).unwrap();
+        assert_eq!(findings(&merged.files), findings(&full));
+    }
+}
@@ -0,0 +1,426 @@
+//! Source map support for minified and bundled JavaScript
+//!
+//! Bundlers put a whole application on one line, which makes the generated
+//! line/column of a finding meaningless. When a bundle carries a
+//! `//# sourceMappingURL` comment (or ships with an adjacent `.map` file) the
+//! findings are remapped to the original source file and position.
+//!
+//! File access stays in the caller so the library remains WASM-compatible:
+//! the CLI reads the map, this module only parses and applies it.
+
+use crate::audit::truncate_context;
+use crate::types::*;
+use serde::Deserialize;
+use thiserror::Error;
+
+/// Lines longer than this are treated as minified when most of the file is on them
+const MINIFIED_LINE_LEN: usize = 500;
+
+#[derive(Error, Debug)]
+pub enum SourceMapError {
+    #[error("Invalid source map JSON: {0}")]
+    InvalidJson(String),
+
+    #[error("Unsupported source map version: {0}")]
+    UnsupportedVersion(u32),
+
+    #[error("Invalid mappings: {0}")]
+    InvalidMappings(String),
+
+    #[error("Invalid inline source map: {0}")]
+    InvalidInline(String),
+}
+
+/// Where a `sourceMappingURL` comment points
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum SourceMapReference {
+    /// Map embedded as a `data:` URL, already decoded to JSON
+    Inline(String),
+    /// Path or URL relative to the bundle
+    External(String),
+}
+
+/// Position in an original source file (1-based line, 0-based column)
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct OriginalPosition {
+    pub source: String,
+    pub line: usize,
+    pub column: usize,
+}
+
+#[derive(Debug, Clone, Copy)]
+struct Segment {
+    generated_column: usize,
+    source: usize,
+    line: usize,
+    column: usize,
+}
+
+#[derive(Deserialize)]
+#[serde(rename_all = "camelCase")]
+struct RawSourceMap {
+    version: u32,
+    #[serde(default)]
+    source_root: Option<String>,
+    sources: Vec<Option<String>>,
+    #[serde(default)]
+    sources_content: Vec<Option<String>>,
+    mappings: String,
+}
+
+/// Decoded revision 3 source map
+#[derive(Debug, Clone)]
+pub struct SourceMap {
+    pub sources: Vec<String>,
+    pub sources_content: Vec<Option<String>>,
+    /// Segments per generated line, sorted by generated column
+    lines: Vec<Vec<Segment>>,
+}
+
+impl SourceMap {
+    pub fn parse(json: &str) -> Result<Self, SourceMapError> {
+        let raw: RawSourceMap =
+            serde_json::from_str(json).map_err(|e| SourceMapError::InvalidJson(e.to_string()))?;
+        if raw.version != 3 {
+            return Err(SourceMapError::UnsupportedVersion(raw.version));
+        }
+
+        let root = raw
+            .source_root
+            .filter(|r| !r.is_empty())
+            .map(|r| format!("{}/", r.trim_end_matches('/')));
+        let sources = raw
+            .sources
+            .into_iter()
+            .map(|s| {
+                let s = normalize_source(&s.unwrap_or_default());
+                match &root {
+                    Some(root) => format!("{}{}", root, s),
+                    None => s,
+                }
+            })
+            .collect();
+
+        Ok(Self {
+            sources,
+            sources_content: raw.sources_content,
+            lines: decode_mappings(&raw.mappings)?,
+        })
+    }
+
+    /// Map a generated position (1-based line, 0-based column) to the original source
+    pub fn lookup(&self, line: usize, column: usize) -> Option<OriginalPosition> {
+        let segments = self.lines.get(line.checked_sub(1)?)?;
+        let idx = segments.partition_point(|s| s.generated_column <= column);
+        let segment = segments.get(idx.checked_sub(1)?)?;
+
+        Some(OriginalPosition {
+            source: self.sources.get(segment.source)?.clone(),
+            line: segment.line + 1,
+            column: segment.column,
+        })
+    }
+
+    /// Original source line, when the map embeds `sourcesContent`
+    pub fn original_line(&self, position: &OriginalPosition) -> Option<&str> {
+        let idx = self.sources.iter().position(|s| *s == position.source)?;
+        self.sources_content
+            .get(idx)?
+            .as_deref()?
+            .lines()
+            .nth(position.line - 1)
+    }
+}
+
+/// Strip bundler URL schemes (`webpack:///./src/a.js` -> `src/a.js`)
+fn normalize_source(source: &str) -> String {
+    let path = source.split_once("://").map_or(source, |(_, rest)| rest);
+    let mut path = path.trim_start_matches('/');
+    while let Some(rest) = path.strip_prefix("./") {
+        path = rest;
+    }
+    path.to_string()
+}
+
+/// Find the `//# sourceMappingURL=` comment of a bundle
+pub fn find_source_mapping_url(source: &str) -> Option<SourceMapReference> {
+    let url = source.lines().rev().take(5).find_map(|line| {
+        let line = line.trim();
+        let rest = line
+            .strip_prefix("//# sourceMappingURL=")
+            .or_else(|| line.strip_prefix("//@ sourceMappingURL="))
+            .or_else(|| {
+                line.strip_prefix("/*# sourceMappingURL=")
+                    .map(|r| r.trim_end_matches("*/"))
+            })?;
+        Some(rest.trim().to_string())
+    })?;
+
+    if let Some(data) = url.strip_prefix("data:") {
+        let (header, payload) = data.split_once(',')?;
+        let json = if header.ends_with(";base64") {
+            String::from_utf8(decode_base64(payload).ok()?).ok()?
+        } else {
+            payload.to_string()
+        };
+        return Some(SourceMapReference::Inline(json));
+    }
+
+    Some(SourceMapReference::External(url))
+}
+
+/// Heuristic for bundled/minified code: very long lines carrying most of the file
+pub fn is_minified(source: &str) -> bool {
+    let long: usize = source
+        .lines()
+        .filter(|l| l.len() > MINIFIED_LINE_LEN)
+        .map(str::len)
+        .sum();
+    long > 0 && long * 2 > source.len()
+}
+
+/// Remap every finding of a bundle to its original source position
+///
+/// Findings inside the `sourceMappingURL` comment itself (inline base64 maps
+/// easily contain `DES` or `md5`) are dropped. Returns the number of findings
+/// that could be mapped.
+pub fn remap_findings(audit: &mut AuditResult, map: &SourceMap, generated: &str) -> usize {
+    let comment_lines: Vec<usize> = generated
+        .lines()
+        .enumerate()
+        .filter(|(_, l)| l.contains("sourceMappingURL="))
+        .map(|(i, _)| i + 1)
+        .collect();
+    if !comment_lines.is_empty() {
+        audit
+            .vulnerabilities
+            .retain(|v| !comment_lines.contains(&v.line));
+        audit.refresh();
+    }
+
+    let mut mapped = 0;
+
+    for vuln in &mut audit.vulnerabilities {
+        let Some(position) = map.lookup(vuln.line, vuln.column) else {
+            continue;
+        };
+
+        let snippet = map
+            .original_line(&position)
+            .map(|line| truncate_context(line, position.column))
+            .unwrap_or_default();
+
+        vuln.original_location = Some(SourceLocation {
+            file_path: position.source,
+            line: position.line,
+            column: position.column,
+            snippet,
+        });
+        mapped += 1;
+    }
+
+    mapped
+}
+
+fn base64_value(c: u8) -> Option<u32> {
+    match c {
+        b'A'..=b'Z' => Some((c - b'A') as u32),
+        b'a'..=b'z' => Some((c - b'a') as u32 + 26),
+        b'0'..=b'9' => Some((c - b'0') as u32 + 52),
+        b'+' | b'-' => Some(62),
+        b'/' | b'_' => Some(63),
+        _ => None,
+    }
+}
+
+fn decode_base64(input: &str) -> Result<Vec<u8>, SourceMapError> {
+    let mut out = Vec::with_capacity(input.len() * 3 / 4);
+    let mut buffer = 0u32;
+    let mut bits = 0;
+
+    for c in input
+        .bytes()
+        .filter(|c| !c.is_ascii_whitespace() && *c != b'=')
+    {
+        let value = base64_value(c).ok_or_else(|| {
+            SourceMapError::InvalidInline(format!("bad character {:?}", c as char))
+        })?;
+        buffer = (buffer << 6) | value;
+        bits += 6;
+        if bits >= 8 {
+            bits -= 8;
+            out.push((buffer >> bits) as u8);
+            buffer &= (1 << bits) - 1;
+        }
+    }
+
+    Ok(out)
+}
+
+/// Decode one Base64 VLQ field, advancing `pos`
+fn decode_vlq(bytes: &[u8], pos: &mut usize) -> Result<i64, SourceMapError> {
+    let mut result = 0i64;
+    let mut shift = 0;
+
+    loop {
+        let c = *bytes
+            .get(*pos)
+            .ok_or_else(|| SourceMapError::InvalidMappings("truncated VLQ".to_string()))?;
+        *pos += 1;
+        let digit = base64_value(c).ok_or_else(|| {
+            SourceMapError::InvalidMappings(format!("bad character {:?}", c as char))
+        })? as i64;
+        if shift > 60 {
+            return Err(SourceMapError::InvalidMappings("VLQ overflow".to_string()));
+        }
+        result |= (digit & 0x1f) << shift;
+        if digit & 0x20 == 0 {
+            break;
+        }
+        shift += 5;
+    }
+
+    Ok(if result & 1 == 1 {
+        -(result >> 1)
+    } else {
+        result >> 1
+    })
+}
+
+fn decode_mappings(mappings: &str) -> Result<Vec<Vec<Segment>>, SourceMapError> {
+    let mut lines = Vec::new();
+    // Source index, original line and column are relative across the whole map
+    let (mut source, mut line, mut column) = (0i64, 0i64, 0i64);
+
+    for generated in mappings.split(';') {
+        let mut segments = Vec::new();
+        let mut generated_column = 0i64;
+
+        for segment in generated.split(',').filter(|s| !s.is_empty()) {
+            let bytes = segment.as_bytes();
+            let mut pos = 0;
+            let mut fields = Vec::with_capacity(5);
+            while pos < bytes.len() {
+                fields.push(decode_vlq(bytes, &mut pos)?);
+            }
+
+            generated_column += fields[0];
+            // Single-field segments map to nothing in the original
+            if fields.len() < 4 {
+                continue;
+            }
+            source += fields[1];
+            line += fields[2];
+            column += fields[3];
+
+            if generated_column < 0 || source < 0 || line < 0 || column < 0 {
+                return Err(SourceMapError::InvalidMappings(
+                    "negative position".to_string(),
+                ));
+            }
+            segments.push(Segment {
+                generated_column: generated_column as usize,
+                source: source as usize,
+                line: line as usize,
+                column: column as usize,
+            });
+        }
+
+        segments.sort_by_key(|s| s.generated_column);
+        lines.push(segments);
+    }
+
+    Ok(lines)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::analyze;
+
+    // Generated by hand: line 1 maps column 0 -> src/a.js:1:0 and column 20 -> src/b.js:3:2
+    const MAP: &str = r#"{
+        "version": 3,
+        "sources": ["src/a.js", "src/b.js"],
+        "sourcesContent": ["const x = 1;", "// b\n\nconst h = crypto.createHash('md5');"],
+        "names": [],
+        "mappings": "AAAA,oBCEE"
+    }"#;
+
+    #[test]
+    fn test_vlq_decoding() {
+        let mut pos = 0;
+        assert_eq!(decode_vlq(b"A", &mut pos).unwrap(), 0);
+        pos = 0;
+        assert_eq!(decode_vlq(b"D", &mut pos).unwrap(), -1);
+        pos = 0;
+        assert_eq!(decode_vlq(b"gB", &mut pos).unwrap(), 16);
+    }
+
+    #[test]
+    fn test_lookup() {
+        let map = SourceMap::parse(MAP).unwrap();
+        assert_eq!(
+            map.lookup(1, 5),
+            Some(OriginalPosition {
+                source: "src/a.js".to_string(),
+                line: 1,
+                column: 0
+            })
+        );
+        let position = map.lookup(1, 40).unwrap();
+        assert_eq!(
+            (position.source.as_str(), position.line, position.column),
+            ("src/b.js", 3, 2)
+        );
+        assert_eq!(
+            map.original_line(&position),
+            Some("const h = crypto.createHash('md5');")
+        );
+        assert_eq!(map.lookup(2, 0), None);
+    }
+
+    #[test]
+    fn test_remap_findings() {
+        let bundle = format!(
+            "var x=1;var y=2;var h=require('crypto').createHash('md5');\n//# sourceMappingURL=data:application/json;base64,{}\n",
+            encode_base64(MAP)
+        );
+        let map = match find_source_mapping_url(&bundle) {
+            Some(SourceMapReference::Inline(json)) => SourceMap::parse(&json).unwrap(),
+            other => panic!("expected inline map, got {:?}", other),
+        };
+
+        let mut audit = analyze(&bundle, "javascript").unwrap();
+        assert_eq!(remap_findings(&mut audit, &map, &bundle), 1);
+        assert_eq!(audit.vulnerabilities.len(), 1);
+        let original = audit.vulnerabilities[0].original_location.as_ref().unwrap();
+        assert_eq!(original.file_path, "src/b.js");
+        assert_eq!(original.line, 3);
+        assert!(original.snippet.contains("createHash('md5')"));
+    }
+
+    #[test]
+    fn test_external_reference_and_minified() {
+        assert_eq!(
+            find_source_mapping_url("a();\n//# sourceMappingURL=app.min.js.map\n"),
+            Some(SourceMapReference::External("app.min.js.map".to_string()))
+        );
+        assert_eq!(normalize_source("webpack:///./src/a.js"), "src/a.js");
+        assert!(is_minified(&"a();".repeat(500)));
+        assert!(!is_minified("function a() {\n  return 1;\n}\n"));
+    }
+
+    fn encode_base64(input: &str) -> String {
+        const TABLE: &[u8] = b"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/";
+        let mut out = String::new();
+        for chunk in input.as_bytes().chunks(3) {
+            let n =
+                chunk.iter().fold(0u32, |acc, b| (acc << 8) | *b as u32) << (8 * (3 - chunk.len()));
+            for i in 0..=chunk.len() {
+                out.push(TABLE[((n >> (18 - 6 * i)) & 0x3f) as usize] as char);
+            }
+        }
+        out
+    }
+}
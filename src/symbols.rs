@@ -0,0 +1,459 @@
+//! Project-level symbol resolution
+//!
+//! Key sizes and algorithm names are often defined once in a shared
+//! `config.go` / `constants.py` (or read from the environment with a literal
+//! default) and used elsewhere. The symbol table collects those definitions
+//! across every file of a project and re-runs detection on lines that use
+//! them, with the resolved literal substituted in. Findings gained or refined
+//! this way record the definition as a secondary location.
+
+use crate::audit::detect_line;
+use crate::types::*;
+use lazy_static::lazy_static;
+use regex::Regex;
+use serde::{Deserialize, Serialize};
+use std::collections::HashMap;
+use std::path::Path;
+
+lazy_static! {
+    static ref IDENTIFIER_USE_RE: Regex = Regex::new(r"[A-Za-z_]\w*(?:(?:\.|::)[A-Za-z_]\w*)*")
+        .expect("IDENTIFIER_USE_RE: Invalid regex pattern - this is a compile-time bug");
+}
+
+/// Key sizes worth substituting into a line (mirrors the RSA key size detector)
+const KEY_SIZES: &[u64] = &[512, 1024, 2048, 3072, 4096, 8192];
+
+/// Maximum length of a `A = B`, `B = C` reference chain
+const MAX_REFERENCE_DEPTH: usize = 8;
+
+/// Location of a constant definition: file index and index into `parsed.constants`
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub struct SymbolRef {
+    pub file: usize,
+    pub constant: usize,
+}
+
+/// A constant resolved to a literal, with the definitions it was resolved through
+#[derive(Debug, Clone)]
+pub struct ResolvedSymbol {
+    pub value: ConstantValue,
+    pub chain: Vec<SymbolRef>,
+}
+
+/// Constant definitions of a project, indexed by name
+#[derive(Debug, Default)]
+pub struct SymbolTable {
+    definitions: HashMap<String, Vec<SymbolRef>>,
+}
+
+/// Summary of a resolution pass
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct SymbolResolutionSummary {
+    /// Constant definitions collected across the project
+    pub symbols: usize,
+    /// Findings that only exist once a constant is resolved
+    pub findings_added: usize,
+    /// Existing findings refined with a resolved key size
+    pub findings_updated: usize,
+}
+
+impl SymbolTable {
+    pub fn build(files: &[ProjectFile]) -> Self {
+        let mut definitions: HashMap<String, Vec<SymbolRef>> = HashMap::new();
+        for (file_idx, file) in files.iter().enumerate() {
+            for (constant_idx, constant) in file.parsed.constants.iter().enumerate() {
+                definitions
+                    .entry(constant.name.clone())
+                    .or_default()
+                    .push(SymbolRef {
+                        file: file_idx,
+                        constant: constant_idx,
+                    });
+            }
+        }
+        Self { definitions }
+    }
+
+    pub fn len(&self) -> usize {
+        self.definitions.values().map(Vec::len).sum()
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.definitions.is_empty()
+    }
+
+    /// Resolve a (possibly qualified) name used in `from_file` to a literal value
+    pub fn resolve(
+        &self,
+        files: &[ProjectFile],
+        name: &str,
+        from_file: usize,
+    ) -> Option<ResolvedSymbol> {
+        let mut chain = Vec::new();
+        let mut name = name.to_string();
+        let mut from_file = from_file;
+
+        for _ in 0..MAX_REFERENCE_DEPTH {
+            let symbol = self.lookup(files, &name, from_file)?;
+            if chain.contains(&symbol) {
+                return None;
+            }
+            chain.push(symbol);
+
+            let constant = &files[symbol.file].parsed.constants[symbol.constant];
+            match &constant.value {
+                ConstantValue::Reference(target) => {
+                    name = target.clone();
+                    from_file = symbol.file;
+                }
+                value => {
+                    return Some(ResolvedSymbol {
+                        value: value.clone(),
+                        chain,
+                    });
+                }
+            }
+        }
+
+        None
+    }
+
+    /// Pick the definition a use refers to: same file, then same package, then a unique one
+    fn lookup(&self, files: &[ProjectFile], name: &str, from_file: usize) -> Option<SymbolRef> {
+        let short = name.rsplit(['.', ':']).next().unwrap_or(name);
+        let qualified = short.len() < name.len();
+
+        // Config fields are only ever read through their struct or object (`cfg.KeySize`)
+        let candidates: Vec<&SymbolRef> = self
+            .definitions
+            .get(short)?
+            .iter()
+            .filter(|s| qualified || !files[s.file].parsed.constants[s.constant].field)
+            .collect();
+
+        if let Some(symbol) = candidates.iter().find(|s| s.file == from_file) {
+            return Some(**symbol);
+        }
+
+        let dir = |file: usize| Path::new(&files[file].path).parent().map(Path::to_path_buf);
+        let same_dir: Vec<&&SymbolRef> = candidates
+            .iter()
+            .filter(|s| dir(s.file) == dir(from_file))
+            .collect();
+        if same_dir.len() == 1 {
+            return Some(**same_dir[0]);
+        }
+
+        // Fall back to any definition when they all agree on the value
+        let first = *candidates.first()?;
+        let value = &files[first.file].parsed.constants[first.constant].value;
+        candidates
+            .iter()
+            .all(|s| &files[s.file].parsed.constants[s.constant].value == value)
+            .then_some(*first)
+    }
+}
+
+/// Whether substituting a resolved value into a line can change what is detected
+fn is_crypto_relevant(value: &ConstantValue) -> bool {
+    match value {
+        ConstantValue::Integer(size) => KEY_SIZES.contains(size),
+        // Algorithm and curve names are single tokens; prose (messages, docs) is not
+        ConstantValue::String(text) => {
+            text.len() <= 64
+                && !text.contains(char::is_whitespace)
+                && !detect_line(&format!("\"{}\"", text), 1).is_empty()
+        }
+        ConstantValue::Reference(_) => false,
+    }
+}
+
+/// Source text a resolved value is substituted with
+fn literal_text(value: &ConstantValue) -> String {
+    match value {
+        ConstantValue::Integer(size) => size.to_string(),
+        ConstantValue::String(text) => format!("\"{}\"", text),
+        ConstantValue::Reference(name) => name.clone(),
+    }
+}
+
+/// A change to one finding, computed before any file is mutated
+enum Resolution {
+    Add(Vulnerability),
+    Update {
+        index: usize,
+        refined: Vulnerability,
+        secondary: Vec<SourceLocation>,
+    },
+}
+
+/// Resolve constants across the project and add or refine the findings that depend on them
+pub fn resolve_project_symbols(files: &mut [ProjectFile]) -> SymbolResolutionSummary {
+    let reported = files.len();
+    resolve_symbols_in(files, reported)
+}
+
+/// Resolve constants in the first `reported` files, with definitions from all of `files`
+///
+/// A shard passes the files of other shards after its own, so that their
+/// constants resolve as in a full scan while only its own files get findings.
+pub fn resolve_symbols_in(files: &mut [ProjectFile], reported: usize) -> SymbolResolutionSummary {
+    let table = SymbolTable::build(files);
+    let mut summary = SymbolResolutionSummary {
+        symbols: table.len(),
+        ..Default::default()
+    };
+    if table.is_empty() {
+        return summary;
+    }
+
+    let mut resolutions: Vec<(usize, Resolution)> = Vec::new();
+
+    for (file_idx, file) in files.iter().enumerate().take(reported) {
+        let literal_lines: Vec<usize> = file
+            .parsed
+            .constants
+            .iter()
+            .filter(|c| !matches!(c.value, ConstantValue::Reference(_)))
+            .map(|c| c.line)
+            .collect();
+
+        for (line_idx, line) in file.source.lines().enumerate() {
+            let line_num = line_idx + 1;
+            let trimmed = line.trim_start();
+            if literal_lines.contains(&line_num)
+                || trimmed.starts_with("//")
+                || trimmed.starts_with('#')
+                || trimmed.starts_with('*')
+                || trimmed.starts_with("/*")
+            {
+                continue;
+            }
+
+            let mut substituted = String::with_capacity(line.len());
+            let mut last = 0;
+            let mut secondary = Vec::new();
+            // (original span, substituted span) of every replaced identifier
+            let mut spans = Vec::new();
+            let mut names = Vec::new();
+
+            for ident in IDENTIFIER_USE_RE.find_iter(line) {
+                // Identifiers inside string literals are not references
+                let before = &line[..ident.start()];
+                if ['"', '\'', '`']
+                    .iter()
+                    .any(|q| before.matches(*q).count() % 2 == 1)
+                {
+                    continue;
+                }
+                let Some(resolved) = table.resolve(files, ident.as_str(), file_idx) else {
+                    continue;
+                };
+                if !is_crypto_relevant(&resolved.value) {
+                    continue;
+                }
+
+                substituted.push_str(&line[last..ident.start()]);
+                let start = substituted.len();
+                substituted.push_str(&literal_text(&resolved.value));
+                spans.push((ident.range(), start..substituted.len()));
+                last = ident.end();
+                names.push(ident.as_str().to_string());
+                secondary.extend(
+                    resolved
+                        .chain
+                        .iter()
+                        .map(|s| definition_location(files, *s)),
+                );
+            }
+
+            if names.is_empty() {
+                continue;
+            }
+            substituted.push_str(&line[last..]);
+
+            let existing: Vec<(usize, &Vulnerability)> = file
+                .audit
+                .vulnerabilities
+                .iter()
+                .enumerate()
+                .filter(|(_, v)| v.line == line_num)
+                .collect();
+
+            for mut vuln in detect_line(&substituted, line_num) {
+                match existing
+                    .iter()
+                    .find(|(_, v)| v.crypto_type == vuln.crypto_type)
+                {
+                    Some((index, current)) => {
+                        if current.key_size.is_none() && vuln.key_size.is_some() {
+                            resolutions.push((
+                                file_idx,
+                                Resolution::Update {
+                                    index: *index,
+                                    refined: vuln,
+                                    secondary: secondary.clone(),
+                                },
+                            ));
+                        }
+                    }
+                    None => {
+                        // Point at the identifier whose value produced the match
+                        let (span, _) = spans
+                            .iter()
+                            .find(|(_, sub)| sub.start < vuln.end_column && vuln.column < sub.end)
+                            .unwrap_or(&spans[0]);
+                        vuln.column = span.start;
+                        vuln.end_column = span.end;
+                        vuln.context = line.trim().to_string();
+                        vuln.message =
+                            format!("{} (resolved from {})", vuln.message, names.join(", "));
+                        vuln.secondary_locations = secondary.clone();
+                        resolutions.push((file_idx, Resolution::Add(vuln)));
+                    }
+                }
+            }
+        }
+    }
+
+    let mut changed = vec![false; files.len()];
+    for (file_idx, resolution) in resolutions {
+        let audit = &mut files[file_idx].audit;
+        match resolution {
+            Resolution::Add(vuln) => {
+                audit.vulnerabilities.push(vuln);
+                summary.findings_added += 1;
+            }
+            Resolution::Update {
+                index,
+                refined,
+                secondary,
+            } => {
+                let vuln = &mut audit.vulnerabilities[index];
+                vuln.key_size = refined.key_size;
+                vuln.severity = refined.severity;
+                vuln.risk_score = refined.risk_score;
+                vuln.message = refined.message;
+                vuln.secondary_locations.extend(secondary);
+                summary.findings_updated += 1;
+            }
+        }
+        changed[file_idx] = true;
+    }
+
+    for (file, changed) in files.iter_mut().zip(changed) {
+        if changed {
+            file.audit
+                .vulnerabilities
+                .sort_by(|a, b| a.line.cmp(&b.line).then(a.column.cmp(&b.column)));
+            file.audit.refresh();
+        }
+    }
+
+    summary
+}
+
+fn definition_location(files: &[ProjectFile], symbol: SymbolRef) -> SourceLocation {
+    let file = &files[symbol.file];
+    let constant = &file.parsed.constants[symbol.constant];
+    let snippet = file
+        .source
+        .lines()
+        .nth(constant.line - 1)
+        .unwrap_or("")
+        .trim()
+        .to_string();
+
+    SourceLocation {
+        file_path: file.path.clone(),
+        line: constant.line,
+        column: constant.column,
+        snippet: match &constant.env_var {
+            Some(var) => format!("{} (default for ${})", snippet, var),
+            None => snippet,
+        },
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::project_file;
+
+    #[test]
+    fn test_key_size_resolved_across_files() {
+        let mut files = vec![
+            project_file(
+                "internal/config/config.go",
+                "package config\n\nconst RSAKeyBits = 1024\n",
+                "go",
+            ),
+            project_file(
+                "internal/keys/keys.go",
+                "package keys\n\nfunc New() {\n\trsa.GenerateKey(rand.Reader, config.RSAKeyBits)\n}\n",
+                "go",
+            ),
+        ];
+        assert_eq!(files[1].audit.vulnerabilities[0].key_size, None);
+
+        let summary = resolve_project_symbols(&mut files);
+        assert_eq!(summary.findings_updated, 1);
+
+        let vuln = &files[1].audit.vulnerabilities[0];
+        assert_eq!(vuln.key_size, Some(1024));
+        assert_eq!(vuln.severity, Severity::Critical);
+        assert_eq!(
+            vuln.secondary_locations[0].file_path,
+            "internal/config/config.go"
+        );
+        assert_eq!(vuln.secondary_locations[0].line, 3);
+        assert_eq!(files[1].audit.stats.critical_count, 1);
+    }
+
+    #[test]
+    fn test_algorithm_name_resolved_from_constants_module() {
+        let mut files = vec![
+            project_file(
+                "app/constants.py",
+                "import os\n\nSIGNING_ALG = os.getenv(\"SIGNING_ALG\", \"RSA\")\n",
+                "python",
+            ),
+            project_file(
+                "app/tokens.py",
+                "from app import constants\n\ndef sign(key, payload):\n    return jwt.sign(payload, key, algorithm=constants.SIGNING_ALG)\n",
+                "python",
+            ),
+        ];
+        assert!(files[1].audit.vulnerabilities.is_empty());
+
+        let summary = resolve_project_symbols(&mut files);
+        assert_eq!(summary.findings_added, 1);
+
+        let vuln = &files[1].audit.vulnerabilities[0];
+        assert_eq!(vuln.crypto_type, CryptoType::Rsa);
+        assert_eq!(vuln.line, 4);
+        assert!(vuln.secondary_locations[0].snippet.contains("$SIGNING_ALG"));
+    }
+
+    #[test]
+    fn test_reference_chain_and_ambiguity() {
+        let files = vec![
+            project_file(
+                "a/sizes.go",
+                "package a\n\nconst Base = 2048\nconst KeyBits = Base\n",
+                "go",
+            ),
+            project_file("b/one.go", "package b\n\nconst Size = 1024\n", "go"),
+            project_file("c/two.go", "package c\n\nconst Size = 4096\n", "go"),
+            project_file("d/use.go", "package d\n\nvar x = Size\n", "go"),
+        ];
+        let table = SymbolTable::build(&files);
+
+        let resolved = table.resolve(&files, "a.KeyBits", 3).unwrap();
+        assert_eq!(resolved.value, ConstantValue::Integer(2048));
+        assert_eq!(resolved.chain.len(), 2);
+
+        // Two packages define `Size` differently - nothing to pick from
+        assert!(table.resolve(&files, "Size", 3).is_none());
+    }
+}
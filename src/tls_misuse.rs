@@ -0,0 +1,381 @@
+//! TLS certificate and hostname verification bypasses
+//!
+//! Post-quantum key exchange protects nothing when the peer is not
+//! authenticated: a man in the middle simply negotiates ML-KEM with both
+//! sides. This module flags code that turns TLS verification off — Go
+//! `InsecureSkipVerify: true`, Python `verify=False` and
+//! `ssl._create_unverified_context`, Node `rejectUnauthorized: false` and
+//! `NODE_TLS_REJECT_UNAUTHORIZED=0`, Java trust-all `TrustManager`s and
+//! permissive `HostnameVerifier`s. Findings are transport security issues,
+//! assessed under SC-8 and SC-23 rather than SC-13.
+
+use crate::audit::score_vulnerability;
+use crate::confidence::classify_match;
+use crate::parser::closing;
+use crate::types::*;
+use lazy_static::lazy_static;
+use regex::Regex;
+
+/// Risk score of a bypass that leaves certificates checked but not host names
+pub(crate) const HOSTNAME_RISK_SCORE: u32 = 80;
+
+/// What a bypass stops verifying
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+enum Bypass {
+    /// Any certificate chain is accepted
+    Certificate,
+    /// Valid certificates for any host name are accepted
+    Hostname,
+}
+
+/// A TLS misuse pattern of one or more languages
+struct TlsRule {
+    languages: &'static [Language],
+    pattern: &'static str,
+    bypass: Bypass,
+    /// The match declares a method that only bypasses when its body is empty
+    /// or returns `true`
+    trivial_body: bool,
+    message: &'static str,
+    recommendation: &'static str,
+}
+
+const JS: &[Language] = &[Language::JavaScript, Language::TypeScript];
+
+const TLS_RULES: &[TlsRule] = &[
+    TlsRule {
+        languages: &[Language::Go],
+        pattern: r"\bInsecureSkipVerify\s*[:=]\s*true\b",
+        bypass: Bypass::Certificate,
+        trivial_body: false,
+        message: "InsecureSkipVerify disables TLS certificate and host name verification",
+        recommendation: "Remove InsecureSkipVerify; add private CAs to tls.Config.RootCAs, or \
+            use VerifyPeerCertificate or VerifyConnection for custom checks",
+    },
+    TlsRule {
+        languages: &[Language::Python],
+        pattern: r"\bverify\s*=\s*False\b",
+        bypass: Bypass::Certificate,
+        trivial_body: false,
+        message: "verify=False disables TLS certificate verification",
+        recommendation: "Keep verify enabled; pass verify=\"/path/to/ca-bundle.pem\" for private CAs",
+    },
+    TlsRule {
+        languages: &[Language::Python],
+        pattern: r"\bssl\._create_unverified_context\b",
+        bypass: Bypass::Certificate,
+        trivial_body: false,
+        message: "ssl._create_unverified_context disables TLS certificate and host name verification",
+        recommendation: "Use ssl.create_default_context(), with cafile= for private CAs",
+    },
+    TlsRule {
+        languages: &[Language::Python],
+        pattern: r"\bCERT_NONE\b",
+        bypass: Bypass::Certificate,
+        trivial_body: false,
+        message: "ssl.CERT_NONE accepts any TLS certificate",
+        recommendation: "Use ssl.CERT_REQUIRED (the default of ssl.create_default_context())",
+    },
+    TlsRule {
+        languages: &[Language::Python],
+        pattern: r"\bcheck_hostname\s*=\s*False\b",
+        bypass: Bypass::Hostname,
+        trivial_body: false,
+        message: "check_hostname=False disables TLS host name verification",
+        recommendation: "Keep check_hostname enabled so certificates are bound to the server name",
+    },
+    TlsRule {
+        languages: JS,
+        pattern: r"\brejectUnauthorized\s*:\s*false\b",
+        bypass: Bypass::Certificate,
+        trivial_body: false,
+        message: "rejectUnauthorized: false disables TLS certificate verification",
+        recommendation: "Remove rejectUnauthorized: false; pass private CAs with the ca option",
+    },
+    TlsRule {
+        languages: JS,
+        pattern: r#"\bNODE_TLS_REJECT_UNAUTHORIZED['"\]]*\s*[=:]\s*['"]?0\b"#,
+        bypass: Bypass::Certificate,
+        trivial_body: false,
+        message: "NODE_TLS_REJECT_UNAUTHORIZED=0 disables TLS certificate verification for the whole process",
+        recommendation: "Remove NODE_TLS_REJECT_UNAUTHORIZED; use NODE_EXTRA_CA_CERTS for private CAs",
+    },
+    TlsRule {
+        languages: JS,
+        pattern: r"\bcheckServerIdentity\s*:\s*\(\s*\)\s*=>\s*(?:undefined|null|\{\s*\})",
+        bypass: Bypass::Hostname,
+        trivial_body: false,
+        message: "checkServerIdentity accepting every host disables TLS host name verification",
+        recommendation: "Remove the override, or call tls.checkServerIdentity from it before \
+            adding checks",
+    },
+    TlsRule {
+        languages: &[Language::Java],
+        pattern: r"\bvoid\s+checkServerTrusted\s*\(",
+        bypass: Bypass::Certificate,
+        trivial_body: true,
+        message: "Trust-all X509TrustManager: checkServerTrusted accepts every certificate chain",
+        recommendation: "Use the default TrustManagerFactory, initialized with a KeyStore of \
+            private CAs if needed",
+    },
+    TlsRule {
+        languages: &[Language::Java],
+        pattern: r"\bInsecureTrustManagerFactory\.INSTANCE\b|\bTrustAllStrategy\b|\bTrustSelfSignedStrategy\b",
+        bypass: Bypass::Certificate,
+        trivial_body: false,
+        message: "Trust-all TrustManager accepts every certificate chain",
+        recommendation: "Use the default TrustManagerFactory, initialized with a KeyStore of \
+            private CAs if needed",
+    },
+    TlsRule {
+        languages: &[Language::Java],
+        pattern: r"\bboolean\s+verify\s*\(\s*(?:final\s+)?String\s+\w+\s*,\s*(?:final\s+)?SSLSession\s+\w+\s*\)",
+        bypass: Bypass::Hostname,
+        trivial_body: true,
+        message: "HostnameVerifier accepting every host disables TLS host name verification",
+        recommendation: "Remove the custom HostnameVerifier; the default verifies the host name \
+            against the certificate",
+    },
+    TlsRule {
+        languages: &[Language::Java],
+        pattern: r"\bNoopHostnameVerifier\b|\bALLOW_ALL_HOSTNAME_VERIFIER\b|\bAllowAllHostnameVerifier\b|\bset(?:Default)?HostnameVerifier\s*\(\s*\(\s*\w*\s*,\s*\w*\s*\)\s*->\s*true\b",
+        bypass: Bypass::Hostname,
+        trivial_body: false,
+        message: "HostnameVerifier accepting every host disables TLS host name verification",
+        recommendation: "Remove the custom HostnameVerifier; the default verifies the host name \
+            against the certificate",
+    },
+];
+
+lazy_static! {
+    static ref TLS_PATTERNS: Vec<Regex> = TLS_RULES
+        .iter()
+        .map(|rule| Regex::new(rule.pattern)
+            .expect("TLS_PATTERNS: Invalid regex pattern - this is a compile-time bug"))
+        .collect();
+}
+
+/// Patterns of the TLS misuse rules, for the rule catalog
+pub(crate) fn tls_misuse_patterns(crypto_type: &CryptoType) -> Vec<String> {
+    if *crypto_type != CryptoType::InsecureTls {
+        return Vec::new();
+    }
+    TLS_RULES
+        .iter()
+        .map(|rule| rule.pattern.to_string())
+        .collect()
+}
+
+/// Findings for TLS verification bypasses, at most one per line
+pub(crate) fn detect_tls_misuse(source: &str, language: Language) -> Vec<Vulnerability> {
+    let mut found: Vec<Vulnerability> = Vec::new();
+
+    for (rule, pattern) in TLS_RULES.iter().zip(TLS_PATTERNS.iter()) {
+        if !rule.languages.contains(&language) {
+            continue;
+        }
+        for m in pattern.find_iter(source) {
+            if rule.trivial_body && !has_trivial_body(source, m.end()) {
+                continue;
+            }
+            let line_start = source[..m.start()].rfind('\n').map_or(0, |i| i + 1);
+            let line_num = source[..m.start()].matches('\n').count() + 1;
+            if found.iter().any(|v| v.line == line_num) {
+                continue;
+            }
+            let line = source[line_start..].lines().next().unwrap_or_default();
+            // A pattern can span lines; the finding ends with the first one
+            let start = m.start() - line_start;
+            let end = (m.end() - line_start).min(line.len());
+            found.push(finding(rule, line, line_num, (start, end)));
+        }
+    }
+
+    found.sort_by_key(|v| v.line);
+    found
+}
+
+/// Whether the method whose parameter list opens before `offset` has an empty body or
+/// only returns `true`
+fn has_trivial_body(source: &str, offset: usize) -> bool {
+    let Some(params) = source[..offset]
+        .rfind('(')
+        .and_then(|open| closing(source, open))
+    else {
+        return false;
+    };
+    // Skip a `throws` clause up to the body
+    let Some(open) = source[params..]
+        .find(['{', ';'])
+        .map(|i| params + i)
+        .filter(|&i| source.as_bytes()[i] == b'{')
+    else {
+        return false;
+    };
+    let Some(close) = closing(source, open) else {
+        return false;
+    };
+
+    let body: String = source[open + 1..close]
+        .lines()
+        .map(|line| line.split("//").next().unwrap_or_default())
+        .collect::<Vec<_>>()
+        .join(" ");
+    let body = strip_block_comments(&body);
+    let body: String = body.split_whitespace().collect::<Vec<_>>().join(" ");
+    matches!(body.as_str(), "" | "return;" | "return true;")
+}
+
+fn strip_block_comments(text: &str) -> String {
+    let mut out = String::new();
+    let mut rest = text;
+    while let Some(start) = rest.find("/*") {
+        out.push_str(&rest[..start]);
+        match rest[start..].find("*/") {
+            Some(end) => rest = &rest[start + end + 2..],
+            None => return out,
+        }
+    }
+    out.push_str(rest);
+    out
+}
+
+fn finding(
+    rule: &TlsRule,
+    line: &str,
+    line_num: usize,
+    (start, end): (usize, usize),
+) -> Vulnerability {
+    let (severity, risk_score) = match rule.bypass {
+        Bypass::Certificate => {
+            let risk_score = score_vulnerability(&CryptoType::InsecureTls, None);
+            (Severity::Critical, risk_score)
+        }
+        Bypass::Hostname => (Severity::High, HOSTNAME_RISK_SCORE),
+    };
+    // A rule match is the bypass itself, whatever construct it sits in; only a
+    // commented-out one is doubtful
+    let match_kind = classify_match(line, start, end);
+    let confidence = match match_kind {
+        MatchKind::Comment => match_kind.confidence(),
+        _ => Confidence::High,
+    };
+
+    Vulnerability {
+        end_column: end,
+        match_kind,
+        confidence,
+        context: line.trim().to_string(),
+        message: rule.message.to_string(),
+        recommendation: rule.recommendation.to_string(),
+        ..Vulnerability::new(
+            CryptoType::InsecureTls,
+            severity,
+            risk_score,
+            line_num,
+            start,
+        )
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::analyze;
+
+    fn misuse(source: &str, language: &str) -> Vec<(usize, Severity)> {
+        analyze(source, language)
+            .unwrap()
+            .vulnerabilities
+            .iter()
+            .filter(|v| v.crypto_type == CryptoType::InsecureTls)
+            .map(|v| (v.line, v.severity))
+            .collect()
+    }
+
+    fn confidences(source: &str, language: &str) -> Vec<Confidence> {
+        analyze(source, language)
+            .unwrap()
+            .vulnerabilities
+            .iter()
+            .filter(|v| v.crypto_type == CryptoType::InsecureTls)
+            .map(|v| v.confidence)
+            .collect()
+    }
+
+    #[test]
+    fn test_tls_bypass_per_language() {
+        let go = "package main\n\nvar c = &tls.Config{\n\tInsecureSkipVerify: true,\n}\n\
+                  var d = &tls.Config{InsecureSkipVerify: false}\n";
+        assert_eq!(misuse(go, "go"), vec![(4, Severity::Critical)]);
+
+        let python = "r = requests.get(url, verify=False)\n\
+                      ctx = ssl._create_unverified_context()\n\
+                      ctx.check_hostname = False\n\
+                      s = requests.get(url, verify=True)\n";
+        assert_eq!(
+            misuse(python, "python"),
+            vec![
+                (1, Severity::Critical),
+                (2, Severity::Critical),
+                (3, Severity::High)
+            ]
+        );
+
+        let node = "const agent = new https.Agent({ rejectUnauthorized: false });\n\
+                    process.env['NODE_TLS_REJECT_UNAUTHORIZED'] = '0';\n\
+                    const ok = { rejectUnauthorized: true };\n";
+        assert_eq!(
+            misuse(node, "javascript"),
+            vec![(1, Severity::Critical), (2, Severity::Critical)]
+        );
+
+        // Python's keyword does not apply to Go
+        assert!(misuse("package main\n\nvar verify = False\n", "go").is_empty());
+
+        // Settings and keyword arguments are the bypass itself, so they survive
+        // `--min-confidence medium`; a commented-out one does not
+        assert_eq!(confidences(go, "go"), vec![Confidence::High]);
+        assert_eq!(confidences(python, "python"), vec![Confidence::High; 3]);
+        assert_eq!(confidences(node, "javascript"), vec![Confidence::High; 2]);
+        let commented = "package main\n\n// cfg := &tls.Config{InsecureSkipVerify: true}\n";
+        assert_eq!(confidences(commented, "go"), vec![Confidence::Low]);
+    }
+
+    #[test]
+    fn test_java_trust_all() {
+        let java = r#"class Insecure {
+    TrustManager[] trustAll = new TrustManager[] {
+        new X509TrustManager() {
+            public void checkClientTrusted(X509Certificate[] chain, String authType) {}
+            public void checkServerTrusted(X509Certificate[] chain, String authType)
+                    throws CertificateException {
+                // trust everyone
+            }
+            public X509Certificate[] getAcceptedIssuers() { return null; }
+        }
+    };
+    HostnameVerifier any = new HostnameVerifier() {
+        public boolean verify(String host, SSLSession session) { return true; }
+    };
+    void client() {
+        conn.setHostnameVerifier((h, s) -> true);
+    }
+}
+
+class Pinned implements X509TrustManager {
+    public void checkServerTrusted(X509Certificate[] chain, String authType) throws CertificateException {
+        pinning.check(chain);
+    }
+}
+"#;
+        assert_eq!(
+            misuse(java, "java"),
+            vec![
+                (5, Severity::Critical),
+                (13, Severity::High),
+                (16, Severity::High)
+            ]
+        );
+    }
+}
@@ -70,6 +70,67 @@ pub enum CryptoType {
     Des,
     TripleDes,
     Rc4,
+    Md4,
+    Ripemd160,
+    Blowfish,
+    Cast5,
+    /// Deprecated crypto API or package whose algorithm is covered elsewhere
+    DeprecatedApi,
+    /// Disabled TLS certificate or host name verification (a misuse, not an algorithm)
+    InsecureTls,
+}
+
+impl CryptoType {
+    /// Parse a display name or rule id (`RSA`, `SHA-1`, `sha1`, `pqc-3des`, ...)
+    pub fn from_string(s: &str) -> Option<Self> {
+        let lower = s.trim().to_lowercase();
+        let normalized: String = lower
+            .trim_start_matches("pqc-")
+            .chars()
+            .filter(|c| c.is_ascii_alphanumeric())
+            .collect();
+        match normalized.as_str() {
+            "rsa" => Some(CryptoType::Rsa),
+            "ecdsa" => Some(CryptoType::Ecdsa),
+            "ecdh" => Some(CryptoType::Ecdh),
+            "dsa" => Some(CryptoType::Dsa),
+            "dh" | "diffiehellman" => Some(CryptoType::DiffieHellman),
+            "sha1" => Some(CryptoType::Sha1),
+            "md5" => Some(CryptoType::Md5),
+            "des" => Some(CryptoType::Des),
+            "3des" | "tripledes" => Some(CryptoType::TripleDes),
+            "rc4" => Some(CryptoType::Rc4),
+            "md4" => Some(CryptoType::Md4),
+            "ripemd160" => Some(CryptoType::Ripemd160),
+            "blowfish" => Some(CryptoType::Blowfish),
+            "cast5" | "cast128" => Some(CryptoType::Cast5),
+            "deprecatedapi" => Some(CryptoType::DeprecatedApi),
+            "insecuretls" => Some(CryptoType::InsecureTls),
+            _ => None,
+        }
+    }
+
+    /// Stable rule identifier used in SARIF output
+    pub fn rule_id(&self) -> &'static str {
+        match self {
+            CryptoType::Rsa => "pqc-rsa",
+            CryptoType::Ecdsa => "pqc-ecdsa",
+            CryptoType::Ecdh => "pqc-ecdh",
+            CryptoType::Dsa => "pqc-dsa",
+            CryptoType::DiffieHellman => "pqc-diffie-hellman",
+            CryptoType::Sha1 => "pqc-sha1",
+            CryptoType::Md5 => "pqc-md5",
+            CryptoType::Des => "pqc-des",
+            CryptoType::TripleDes => "pqc-3des",
+            CryptoType::Rc4 => "pqc-rc4",
+            CryptoType::Md4 => "pqc-md4",
+            CryptoType::Ripemd160 => "pqc-ripemd160",
+            CryptoType::Blowfish => "pqc-blowfish",
+            CryptoType::Cast5 => "pqc-cast5",
+            CryptoType::DeprecatedApi => "pqc-deprecated-api",
+            CryptoType::InsecureTls => "pqc-insecure-tls",
+        }
+    }
 }
 
 impl fmt::Display for CryptoType {
@@ -85,6 +146,95 @@ impl fmt::Display for CryptoType {
             CryptoType::Des => write!(f, "DES"),
             CryptoType::TripleDes => write!(f, "3DES"),
             CryptoType::Rc4 => write!(f, "RC4"),
+            CryptoType::Md4 => write!(f, "MD4"),
+            CryptoType::Ripemd160 => write!(f, "RIPEMD-160"),
+            CryptoType::Blowfish => write!(f, "Blowfish"),
+            CryptoType::Cast5 => write!(f, "CAST5"),
+            CryptoType::DeprecatedApi => write!(f, "Deprecated API"),
+            CryptoType::InsecureTls => write!(f, "Insecure TLS"),
+        }
+    }
+}
+
+/// How certain a finding is that the algorithm is actually in use
+#[derive(
+    Debug, Clone, Copy, PartialEq, Eq, Hash, Default, Serialize, Deserialize, PartialOrd, Ord,
+)]
+#[serde(rename_all = "lowercase")]
+pub enum Confidence {
+    Low,
+    #[default]
+    Medium,
+    High,
+}
+
+impl Confidence {
+    pub fn from_string(s: &str) -> Option<Self> {
+        match s.to_lowercase().as_str() {
+            "low" => Some(Confidence::Low),
+            "medium" => Some(Confidence::Medium),
+            "high" => Some(Confidence::High),
+            _ => None,
+        }
+    }
+}
+
+impl fmt::Display for Confidence {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Confidence::Low => write!(f, "low"),
+            Confidence::Medium => write!(f, "medium"),
+            Confidence::High => write!(f, "high"),
+        }
+    }
+}
+
+/// Number of findings at each confidence level
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+pub struct ConfidenceBreakdown {
+    pub high: usize,
+    pub medium: usize,
+    pub low: usize,
+}
+
+impl ConfidenceBreakdown {
+    pub fn from_vulnerabilities<'a>(vulns: impl IntoIterator<Item = &'a Vulnerability>) -> Self {
+        let mut breakdown = Self::default();
+        for vuln in vulns {
+            match vuln.confidence {
+                Confidence::High => breakdown.high += 1,
+                Confidence::Medium => breakdown.medium += 1,
+                Confidence::Low => breakdown.low += 1,
+            }
+        }
+        breakdown
+    }
+}
+
+/// Where in the source the algorithm name matched
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash, Default, Serialize, Deserialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum MatchKind {
+    /// Callee or call argument: `rsa.GenerateKey(...)`, `createHash('md5')`
+    ApiCall,
+    /// Import, include or require of a crypto module
+    Import,
+    /// String literal that is not passed to a call
+    StringLiteral,
+    /// Part of an identifier, such as `rsaKey` or `TLS_ECDHE_RSA_...`
+    #[default]
+    Identifier,
+    /// Comment text
+    Comment,
+}
+
+impl MatchKind {
+    /// Confidence implied by the match kind
+    pub fn confidence(&self) -> Confidence {
+        match self {
+            MatchKind::ApiCall | MatchKind::Import => Confidence::High,
+            MatchKind::StringLiteral => Confidence::Medium,
+            MatchKind::Identifier | MatchKind::Comment => Confidence::Low,
         }
     }
 }
@@ -107,6 +257,18 @@ pub struct Vulnerability {
     /// Column number in source code
     pub column: usize,
 
+    /// Column one past the end of the matched token
+    #[serde(default)]
+    pub end_column: usize,
+
+    /// Kind of source construct the match was found in
+    #[serde(default)]
+    pub match_kind: MatchKind,
+
+    /// Confidence derived from the match kind
+    #[serde(default)]
+    pub confidence: Confidence,
+
     /// Context snippet from source
     pub context: String,
 
@@ -118,6 +280,208 @@ pub struct Vulnerability {
 
     /// Key size detected (if applicable)
     pub key_size: Option<u32>,
+
+    /// Custom rule that produced the finding (`None` for built-in detectors)
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub rule_id: Option<String>,
+
+    /// Whether the finding is reachable from a project entry point (project scans only)
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub reachability: Option<Reachability>,
+
+    /// Related locations, such as the definition of a constant resolved at this line
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub secondary_locations: Vec<SourceLocation>,
+
+    /// Position in the original source when the finding was remapped through a source map
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub original_location: Option<SourceLocation>,
+
+    /// Build constraint leaving the file out of the target build (Go project scans only)
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub build_exclusion: Option<BuildExclusion>,
+
+    /// Native code the algorithm runs in, outside the Go standard library (cgo)
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub native_code: Option<NativeCode>,
+
+    /// What a weak hash is used for, with the reasoning behind its severity
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub hash_context: Option<HashContext>,
+
+    /// Stable identifier of the finding within its file, independent of its line
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub fingerprint: Option<String>,
+}
+
+impl Vulnerability {
+    /// A finding at `line`/`column` without context, message or metadata
+    ///
+    /// Detectors fill in the rest with struct update syntax, so metadata
+    /// fields added later need no change at every construction site.
+    pub fn new(
+        crypto_type: CryptoType,
+        severity: Severity,
+        risk_score: u32,
+        line: usize,
+        column: usize,
+    ) -> Self {
+        Self {
+            crypto_type,
+            severity,
+            risk_score,
+            line,
+            column,
+            end_column: column,
+            match_kind: MatchKind::default(),
+            confidence: Confidence::default(),
+            context: String::new(),
+            message: String::new(),
+            recommendation: String::new(),
+            key_size: None,
+            rule_id: None,
+            reachability: None,
+            secondary_locations: Vec::new(),
+            original_location: None,
+            build_exclusion: None,
+            native_code: None,
+            hash_context: None,
+            fingerprint: None,
+        }
+    }
+
+    /// Whether the usage context takes the finding out of cryptographic compliance scope
+    pub fn compliance_exempt(&self) -> bool {
+        self.hash_context
+            .as_ref()
+            .is_some_and(|context| context.compliance_exempt)
+    }
+}
+
+/// How a project entry point is recognized
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash, Serialize, Deserialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum EntryPointKind {
+    /// `main`/`init` functions
+    Main,
+    /// Public API of a library (exported Go identifiers, `pub fn`, `export`, `public`)
+    ExportedApi,
+    /// Function registered as an HTTP route handler
+    HttpHandler,
+    /// Test, benchmark or fuzz function
+    Test,
+    /// Code that runs at module load (top-level statements, `if __name__ == "__main__"`)
+    ModuleScope,
+}
+
+impl fmt::Display for EntryPointKind {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            EntryPointKind::Main => write!(f, "main"),
+            EntryPointKind::ExportedApi => write!(f, "exported"),
+            EntryPointKind::HttpHandler => write!(f, "http"),
+            EntryPointKind::Test => write!(f, "test"),
+            EntryPointKind::ModuleScope => write!(f, "module"),
+        }
+    }
+}
+
+/// Reachability status of a finding
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum ReachabilityStatus {
+    /// Called (directly or transitively) from at least one entry point
+    Reachable,
+    /// No entry point reaches the enclosing function - apparently dead code
+    Unreachable,
+}
+
+/// Reachability label attached to a finding
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct Reachability {
+    pub status: ReachabilityStatus,
+
+    /// Number of entry points that reach the finding
+    pub entry_point_count: usize,
+
+    /// Sample of reaching entry points (`file:function (kind)`)
+    pub entry_points: Vec<String>,
+}
+
+/// Why a finding's file is left out of the target build
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct BuildExclusion {
+    /// Platform and tags the constraints were evaluated for, e.g. `linux/amd64`
+    pub target: String,
+
+    /// Constraint the target fails (`//go:build windows`, `_windows.go file name suffix`)
+    pub constraint: String,
+}
+
+/// How Go code reaches native crypto
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum NativeCodeKind {
+    /// C code embedded in the comment preceding `import "C"`
+    CgoPreamble,
+    /// Go code calling into C (`C.RSA_new()`)
+    CgoCall,
+    /// A Go package that wraps a native library through cgo
+    WrapperPackage,
+}
+
+/// Native code a finding runs in
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct NativeCode {
+    pub kind: NativeCodeKind,
+
+    /// Native crypto libraries linked by the file (`openssl`, `libsodium`)
+    pub libraries: Vec<String>,
+
+    /// Import path of the wrapper package, for `WrapperPackage`
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub package: Option<String>,
+}
+
+/// What a hash computes, which decides how much a broken hash matters
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum HashUsage {
+    /// Digest of a signature (`SHA1withRSA`), exposed to collision attacks
+    Signature,
+    /// Keyed MAC (`hmac.new(key, msg, hashlib.sha1)`), which does not rely on collision resistance
+    Hmac,
+    /// Password storage or key derivation
+    Password,
+    /// Integrity checksum against accidental corruption (`md5sum`, `Content-MD5`)
+    Checksum,
+    /// Identifier or cache key (`usedforsecurity=False`, git object IDs, ETags)
+    NonSecurity,
+}
+
+impl fmt::Display for HashUsage {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            HashUsage::Signature => write!(f, "signature"),
+            HashUsage::Hmac => write!(f, "HMAC"),
+            HashUsage::Password => write!(f, "password"),
+            HashUsage::Checksum => write!(f, "checksum"),
+            HashUsage::NonSecurity => write!(f, "non-security"),
+        }
+    }
+}
+
+/// Usage context of a weak hash finding
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct HashContext {
+    pub usage: HashUsage,
+
+    /// Evidence for the usage and how it changed severity and compliance
+    pub reason: String,
+
+    /// The hash provides no cryptographic protection, so SC-13 and ITSP.40.111
+    /// do not count it as a deprecated or prohibited algorithm
+    pub compliance_exempt: bool,
 }
 
 /// Complete audit result
@@ -137,6 +501,10 @@ pub struct AuditResult {
 
     /// Statistics
     pub stats: AuditStats,
+
+    /// Protocol configurations found in the source (ITSP.40.062)
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub protocol_compliance: Vec<ProtocolCompliance>,
 }
 
 /// Audit statistics
@@ -165,6 +533,7 @@ impl AuditResult {
                 low_count: 0,
                 lines_scanned,
             },
+            protocol_compliance: Vec::new(),
         }
     }
 
@@ -179,6 +548,65 @@ impl AuditResult {
         self.vulnerabilities.push(vuln);
     }
 
+    /// Combine per-file results into one project-level result
+    ///
+    /// Findings, statistics and scanned lines are summed and the risk score
+    /// recomputed; the language is the one with the most scanned lines.
+    /// Returns `None` when there are no results.
+    pub fn aggregate<'a>(results: impl IntoIterator<Item = &'a AuditResult>) -> Option<Self> {
+        let results: Vec<&AuditResult> = results.into_iter().collect();
+        let mut lines_by_language: Vec<(Language, usize)> = Vec::new();
+        for result in &results {
+            match lines_by_language
+                .iter_mut()
+                .find(|(language, _)| *language == result.language)
+            {
+                Some((_, lines)) => *lines += result.stats.lines_scanned,
+                None => lines_by_language.push((result.language, result.stats.lines_scanned)),
+            }
+        }
+        let language = lines_by_language.iter().max_by_key(|(_, lines)| *lines)?.0;
+
+        let lines_scanned = results.iter().map(|r| r.stats.lines_scanned).sum();
+        let mut project = AuditResult::new(language, lines_scanned);
+        for result in results {
+            for vuln in &result.vulnerabilities {
+                project.add_vulnerability(vuln.clone());
+            }
+            project
+                .protocol_compliance
+                .extend(result.protocol_compliance.iter().cloned());
+        }
+        project.calculate_risk_score();
+        project.generate_recommendations();
+        Some(project)
+    }
+
+    /// Recompute statistics, risk score and recommendations after findings were changed in place
+    pub fn refresh(&mut self) {
+        let vulnerabilities = std::mem::take(&mut self.vulnerabilities);
+        let protocol_compliance = std::mem::take(&mut self.protocol_compliance);
+        *self = AuditResult::new(self.language, self.stats.lines_scanned);
+        self.protocol_compliance = protocol_compliance;
+        for vuln in vulnerabilities {
+            self.add_vulnerability(vuln);
+        }
+        self.calculate_risk_score();
+        self.generate_recommendations();
+    }
+
+    /// A copy holding only the findings `keep` accepts, with statistics and risk score recomputed
+    pub fn filtered(&self, keep: impl Fn(&Vulnerability) -> bool) -> Self {
+        let mut result = AuditResult::new(self.language, self.stats.lines_scanned);
+        result.protocol_compliance = self.protocol_compliance.clone();
+        for vuln in self.vulnerabilities.iter().filter(|v| keep(v)) {
+            result.add_vulnerability(vuln.clone());
+        }
+        result.calculate_risk_score();
+        result.generate_recommendations();
+        result
+    }
+
     pub fn calculate_risk_score(&mut self) {
         if self.vulnerabilities.is_empty() {
             self.risk_score = 0;
@@ -267,12 +695,56 @@ pub struct FunctionCall {
     pub args: Vec<String>,
 }
 
+/// Function or method definition with the line range of its body
+#[derive(Debug, Clone)]
+pub struct FunctionDefinition {
+    pub name: String,
+    pub line: usize,
+    pub end_line: usize,
+    pub column: usize,
+    /// Trimmed declaration line (visibility, receiver, parameters)
+    pub signature: String,
+    /// Decorators or attributes directly above the definition
+    pub annotations: Vec<String>,
+}
+
+impl FunctionDefinition {
+    /// Check whether a line falls inside this function's body
+    pub fn contains_line(&self, line: usize) -> bool {
+        line >= self.line && line <= self.end_line
+    }
+}
+
+/// Literal value bound to a constant, variable or config field
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum ConstantValue {
+    Integer(u64),
+    String(String),
+    /// Another identifier, possibly defined in a different file
+    Reference(String),
+}
+
+/// Constant, top-level variable or config field initialized with a literal
+#[derive(Debug, Clone)]
+pub struct ConstantDefinition {
+    pub name: String,
+    pub value: ConstantValue,
+    pub line: usize,
+    pub column: usize,
+    /// Environment variable the value is read from, when the literal is its default
+    pub env_var: Option<String>,
+    /// Set by a struct or object literal field (`KeySize: 2048`) rather than a declaration
+    pub field: bool,
+}
+
 #[derive(Debug, Clone)]
 pub struct ParsedSource {
     pub language: Language,
     pub ast_nodes: Vec<AstNode>,
     pub imports: Vec<String>,
     pub function_calls: Vec<FunctionCall>,
+    pub functions: Vec<FunctionDefinition>,
+    pub constants: Vec<ConstantDefinition>,
 }
 
 impl ParsedSource {
@@ -282,8 +754,27 @@ impl ParsedSource {
             ast_nodes: Vec::new(),
             imports: Vec::new(),
             function_calls: Vec::new(),
+            functions: Vec::new(),
+            constants: Vec::new(),
         }
     }
+
+    /// Find the innermost function whose body contains the given line
+    pub fn enclosing_function(&self, line: usize) -> Option<&FunctionDefinition> {
+        self.functions
+            .iter()
+            .filter(|f| f.contains_line(line))
+            .min_by_key(|f| f.end_line - f.line)
+    }
+}
+
+/// A single source file of a project, parsed and audited
+#[derive(Debug, Clone)]
+pub struct ProjectFile {
+    pub path: String,
+    pub source: String,
+    pub parsed: ParsedSource,
+    pub audit: AuditResult,
 }
 
 // NIST 800-53 SC-13 Control Assessment Types
@@ -388,6 +879,10 @@ pub struct SC13AssessmentReport {
     /// Control assessment
     pub control_assessment: ControlAssessment,
 
+    /// SC-8 and SC-23 assessments, present when TLS verification bypasses were found
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub transport_assessments: Vec<ControlAssessment>,
+
     /// Summary statistics
     pub summary: AssessmentSummary,
 
@@ -468,6 +963,10 @@ pub struct AssessmentSummary {
 
     /// Risk score (0-100)
     pub risk_score: u32,
+
+    /// Findings per confidence level
+    #[serde(default)]
+    pub confidence: ConfidenceBreakdown,
 }
 
 // OSCAL Assessment Results Schema Types
@@ -841,6 +1340,8 @@ pub struct CanadianAssessmentSummary {
     pub weak_key_sizes: Vec<String>,
     pub compliance_score: u32,
     pub risk_score: u32,
+    #[serde(default)]
+    pub confidence: ConfidenceBreakdown,
 
     // Canadian-specific fields
     pub cccs_approved_algorithms: Vec<String>,
@@ -879,6 +1380,9 @@ pub struct CanadianFinding {
 pub struct ITSG33Report {
     pub metadata: ReportMetadata,
     pub control_assessment: ITSG33ControlAssessment,
+    /// ITSG-33 SC-8 and SC-23 assessments, present when TLS verification bypasses were found
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub transport_assessments: Vec<ITSG33ControlAssessment>,
     pub summary: CanadianAssessmentSummary,
     pub findings: Vec<CanadianFinding>,
     pub protocol_compliance: Vec<ProtocolCompliance>,
@@ -893,11 +1397,15 @@ pub struct UnifiedComplianceReport {
 
     // NIST components
     pub nist_sc13_assessment: ControlAssessment,
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub nist_transport_assessments: Vec<ControlAssessment>,
     pub nist_summary: AssessmentSummary,
     pub nist_findings: Vec<ControlFinding>,
 
     // Canadian components
     pub itsg33_sc13_assessment: ITSG33ControlAssessment,
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub itsg33_transport_assessments: Vec<ITSG33ControlAssessment>,
     pub canadian_summary: CanadianAssessmentSummary,
     pub canadian_findings: Vec<CanadianFinding>,
 
@@ -0,0 +1,681 @@
+//! Minimal YAML reader for rule files
+//!
+//! Covers the subset rule files are written in: block mappings and sequences,
+//! flow sequences and mappings (`[go, java]`, `{a: b}`), plain, single- and
+//! double-quoted scalars, literal (`|`) and folded (`>`) block scalars, and
+//! comments. Anchors, aliases, tags and multi-document streams are rejected
+//! rather than misread. Every scalar is kept as a string; callers interpret it.
+
+use thiserror::Error;
+
+#[derive(Error, Debug, Clone, PartialEq, Eq)]
+#[error("line {line}: {message}")]
+pub struct YamlError {
+    pub line: usize,
+    pub message: String,
+}
+
+/// A parsed YAML node
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum Yaml {
+    Null,
+    Scalar(String),
+    Seq(Vec<Yaml>),
+    /// Keys in document order
+    Map(Vec<(String, Yaml)>),
+}
+
+impl Yaml {
+    /// Value of a mapping key
+    pub fn get(&self, key: &str) -> Option<&Yaml> {
+        match self {
+            Yaml::Map(entries) => entries.iter().find(|(k, _)| k == key).map(|(_, v)| v),
+            _ => None,
+        }
+    }
+
+    pub fn as_str(&self) -> Option<&str> {
+        match self {
+            Yaml::Scalar(s) => Some(s),
+            _ => None,
+        }
+    }
+
+    pub fn as_seq(&self) -> Option<&[Yaml]> {
+        match self {
+            Yaml::Seq(items) => Some(items),
+            _ => None,
+        }
+    }
+
+    pub fn as_map(&self) -> Option<&[(String, Yaml)]> {
+        match self {
+            Yaml::Map(entries) => Some(entries),
+            _ => None,
+        }
+    }
+
+    /// A scalar or every scalar of a sequence (`languages: go` and `languages: [go]`)
+    pub fn scalars(&self) -> Vec<&str> {
+        match self {
+            Yaml::Scalar(s) => vec![s.as_str()],
+            Yaml::Seq(items) => items.iter().filter_map(Yaml::as_str).collect(),
+            _ => Vec::new(),
+        }
+    }
+}
+
+/// Parse a single YAML document
+pub fn parse(source: &str) -> Result<Yaml, YamlError> {
+    let mut parser = Parser {
+        lines: source
+            .lines()
+            .enumerate()
+            .map(|(i, raw)| Line {
+                number: i + 1,
+                indent: raw.len() - raw.trim_start_matches(' ').len(),
+                text: raw.trim_start_matches(' ').trim_end().to_string(),
+            })
+            .collect(),
+        pos: 0,
+    };
+
+    parser.skip_blank();
+    if parser.current().is_some_and(|l| l.text == "---") {
+        parser.pos += 1;
+        parser.skip_blank();
+    }
+    let Some(first) = parser.current() else {
+        return Ok(Yaml::Null);
+    };
+    let indent = first.indent;
+    let document = parser.parse_node(indent)?;
+
+    parser.skip_blank();
+    match parser.current() {
+        None => Ok(document),
+        Some(line) if line.text == "..." => Ok(document),
+        Some(line) if line.text == "---" => Err(line.error("multiple documents are not supported")),
+        Some(line) => Err(line.error("unexpected indentation")),
+    }
+}
+
+struct Line {
+    number: usize,
+    indent: usize,
+    /// Content without indentation or trailing whitespace (comments included)
+    text: String,
+}
+
+impl Line {
+    fn error(&self, message: impl Into<String>) -> YamlError {
+        YamlError {
+            line: self.number,
+            message: message.into(),
+        }
+    }
+
+    /// Content with any trailing comment removed
+    fn content(&self) -> &str {
+        strip_comment(&self.text)
+    }
+
+    fn is_seq_item(&self) -> bool {
+        let content = self.content();
+        content == "-" || content.starts_with("- ")
+    }
+}
+
+struct Parser {
+    lines: Vec<Line>,
+    pos: usize,
+}
+
+impl Parser {
+    fn current(&self) -> Option<&Line> {
+        self.lines.get(self.pos)
+    }
+
+    /// Advance past blank and comment-only lines
+    fn skip_blank(&mut self) {
+        while self.current().is_some_and(|l| l.content().is_empty()) {
+            self.pos += 1;
+        }
+    }
+
+    /// Parse the block node starting at the current line, indented by `indent`
+    fn parse_node(&mut self, indent: usize) -> Result<Yaml, YamlError> {
+        let line = &self.lines[self.pos];
+        if line.text.starts_with('\t') {
+            return Err(line.error("tabs are not allowed for indentation"));
+        }
+        if line.is_seq_item() {
+            self.parse_seq(indent)
+        } else if split_key(line.content()).is_some() {
+            self.parse_map(indent)
+        } else {
+            let number = line.number;
+            let text = line.content().to_string();
+            self.pos += 1;
+            self.parse_inline(&text, number, indent)
+        }
+    }
+
+    fn parse_seq(&mut self, indent: usize) -> Result<Yaml, YamlError> {
+        let mut items = Vec::new();
+
+        loop {
+            self.skip_blank();
+            let Some(line) = self.current() else { break };
+            if line.indent != indent || !line.is_seq_item() {
+                if line.indent > indent {
+                    return Err(line.error("unexpected indentation in sequence"));
+                }
+                break;
+            }
+
+            let rest = line.content()[1..].trim_start();
+            if rest.is_empty() {
+                self.pos += 1;
+                items.push(self.parse_child(indent)?);
+            } else if split_key(rest).is_some() || rest.starts_with("- ") || rest == "-" {
+                // `- id: x` opens a mapping (or nested sequence) at the item's column
+                let offset = line.text.len() - line.text[1..].trim_start().len();
+                let item_indent = indent + offset;
+                let text = line.text[offset..].to_string();
+                self.lines[self.pos].indent = item_indent;
+                self.lines[self.pos].text = text;
+                items.push(self.parse_node(item_indent)?);
+            } else {
+                let number = line.number;
+                let rest = rest.to_string();
+                self.pos += 1;
+                items.push(self.parse_inline(&rest, number, indent)?);
+            }
+        }
+
+        Ok(Yaml::Seq(items))
+    }
+
+    fn parse_map(&mut self, indent: usize) -> Result<Yaml, YamlError> {
+        let mut entries: Vec<(String, Yaml)> = Vec::new();
+
+        loop {
+            self.skip_blank();
+            let Some(line) = self.current() else { break };
+            if line.indent != indent || line.is_seq_item() {
+                if line.indent > indent {
+                    return Err(line.error("unexpected indentation in mapping"));
+                }
+                break;
+            }
+            let Some((key, value)) = split_key(line.content()) else {
+                return Err(line.error("expected `key: value`"));
+            };
+            let key = unquote(key, line.number)?;
+            if entries.iter().any(|(k, _)| *k == key) {
+                return Err(line.error(format!("duplicate key `{}`", key)));
+            }
+            let number = line.number;
+            let value = value.to_string();
+            self.pos += 1;
+
+            let node = if value.is_empty() {
+                self.skip_blank();
+                match self.current() {
+                    // A sequence may sit at the same indentation as its key
+                    Some(next) if next.indent == indent && next.is_seq_item() => {
+                        self.parse_seq(indent)?
+                    }
+                    _ => self.parse_child(indent)?,
+                }
+            } else {
+                self.parse_inline(&value, number, indent)?
+            };
+            entries.push((key, node));
+        }
+
+        Ok(Yaml::Map(entries))
+    }
+
+    /// Node nested under a key or `-` at `parent` indentation, or null if none
+    fn parse_child(&mut self, parent: usize) -> Result<Yaml, YamlError> {
+        self.skip_blank();
+        match self.current() {
+            Some(line) if line.indent > parent => {
+                let indent = line.indent;
+                self.parse_node(indent)
+            }
+            _ => Ok(Yaml::Null),
+        }
+    }
+
+    /// Value written after `key:` or `- `, possibly continued on following lines
+    fn parse_inline(
+        &mut self,
+        text: &str,
+        number: usize,
+        parent: usize,
+    ) -> Result<Yaml, YamlError> {
+        let error = |message: &str| YamlError {
+            line: number,
+            message: message.to_string(),
+        };
+
+        if text.starts_with('&') || text.starts_with('*') {
+            return Err(error("anchors and aliases are not supported"));
+        }
+        if text.starts_with('!') {
+            return Err(error("tags are not supported"));
+        }
+        if let Some(header) = text.strip_prefix('|') {
+            return Ok(Yaml::Scalar(self.block_scalar(parent, header, false)));
+        }
+        if let Some(header) = text.strip_prefix('>') {
+            return Ok(Yaml::Scalar(self.block_scalar(parent, header, true)));
+        }
+
+        if text.starts_with('[') || text.starts_with('{') {
+            // Flow collections may span lines until their brackets balance
+            let mut flow = text.to_string();
+            while !brackets_balanced(&flow) {
+                let Some(line) = self.current() else {
+                    return Err(error("unterminated flow collection"));
+                };
+                flow.push(' ');
+                flow.push_str(line.content());
+                self.pos += 1;
+            }
+            let mut chars = FlowParser {
+                chars: flow.chars().collect(),
+                pos: 0,
+                line: number,
+            };
+            let node = chars.parse_value()?;
+            chars.skip_space();
+            if chars.pos != chars.chars.len() {
+                return Err(error("unexpected text after flow collection"));
+            }
+            return Ok(node);
+        }
+
+        if text.starts_with('"') || text.starts_with('\'') {
+            return unquote(text, number).map(Yaml::Scalar);
+        }
+
+        // Plain scalars fold more-indented continuation lines into one line
+        let mut value = text.to_string();
+        while let Some(line) = self.current() {
+            if line.indent <= parent
+                || line.content().is_empty()
+                || line.is_seq_item()
+                || split_key(line.content()).is_some()
+            {
+                break;
+            }
+            value.push(' ');
+            value.push_str(line.content());
+            self.pos += 1;
+        }
+        Ok(plain_scalar(&value))
+    }
+
+    /// Literal or folded block scalar body below the current key
+    fn block_scalar(&mut self, parent: usize, header: &str, folded: bool) -> String {
+        let header = strip_comment(header);
+        let strip = header.contains('-');
+        let keep = header.contains('+');
+
+        let start = self.pos;
+        let mut block_indent = None;
+        while let Some(line) = self.lines.get(self.pos) {
+            if !line.text.is_empty()
+                && (line.indent <= parent || line.indent < *block_indent.get_or_insert(line.indent))
+            {
+                break;
+            }
+            self.pos += 1;
+        }
+
+        // Keep indentation beyond the block's own
+        let base = block_indent.unwrap_or(0);
+        let texts: Vec<String> = self.lines[start..self.pos]
+            .iter()
+            .map(|l| {
+                if l.text.is_empty() {
+                    String::new()
+                } else {
+                    format!("{}{}", " ".repeat(l.indent - base), l.text)
+                }
+            })
+            .collect();
+
+        let mut value = if folded {
+            let mut out = String::new();
+            for (i, text) in texts.iter().enumerate() {
+                if i > 0 {
+                    let prev = &texts[i - 1];
+                    if text.is_empty() || prev.is_empty() || text.starts_with(' ') {
+                        out.push('\n');
+                    } else {
+                        out.push(' ');
+                    }
+                }
+                out.push_str(text);
+            }
+            out
+        } else {
+            texts.join("\n")
+        };
+
+        let content_len = value.trim_end_matches('\n').len();
+        if keep {
+            value.push('\n');
+        } else {
+            value.truncate(content_len);
+            if !strip && content_len > 0 {
+                value.push('\n');
+            }
+        }
+        value
+    }
+}
+
+/// Flow-style collections (`[a, "b"]`, `{k: v}`)
+struct FlowParser {
+    chars: Vec<char>,
+    pos: usize,
+    line: usize,
+}
+
+impl FlowParser {
+    fn error(&self, message: &str) -> YamlError {
+        YamlError {
+            line: self.line,
+            message: message.to_string(),
+        }
+    }
+
+    fn skip_space(&mut self) {
+        while self.chars.get(self.pos).is_some_and(|c| c.is_whitespace()) {
+            self.pos += 1;
+        }
+    }
+
+    fn parse_value(&mut self) -> Result<Yaml, YamlError> {
+        self.skip_space();
+        match self.chars.get(self.pos) {
+            Some('[') => {
+                self.pos += 1;
+                let mut items = Vec::new();
+                loop {
+                    self.skip_space();
+                    if self.chars.get(self.pos) == Some(&']') {
+                        self.pos += 1;
+                        return Ok(Yaml::Seq(items));
+                    }
+                    items.push(self.parse_value()?);
+                    self.separator(']')?;
+                }
+            }
+            Some('{') => {
+                self.pos += 1;
+                let mut entries = Vec::new();
+                loop {
+                    self.skip_space();
+                    if self.chars.get(self.pos) == Some(&'}') {
+                        self.pos += 1;
+                        return Ok(Yaml::Map(entries));
+                    }
+                    let key = match self.parse_value()? {
+                        Yaml::Scalar(key) => key,
+                        _ => return Err(self.error("flow mapping keys must be scalars")),
+                    };
+                    self.skip_space();
+                    if self.chars.get(self.pos) != Some(&':') {
+                        return Err(self.error("expected `:` in flow mapping"));
+                    }
+                    self.pos += 1;
+                    let value = self.parse_value()?;
+                    entries.push((key, value));
+                    self.separator('}')?;
+                }
+            }
+            Some(quote @ ('"' | '\'')) => {
+                let quote = *quote;
+                let start = self.pos;
+                self.pos += 1;
+                while let Some(&c) = self.chars.get(self.pos) {
+                    self.pos += 1;
+                    if c == '\\' && quote == '"' {
+                        self.pos += 1;
+                    } else if c == quote {
+                        if quote == '\'' && self.chars.get(self.pos) == Some(&'\'') {
+                            self.pos += 1;
+                            continue;
+                        }
+                        let text: String = self.chars[start..self.pos].iter().collect();
+                        return unquote(&text, self.line).map(Yaml::Scalar);
+                    }
+                }
+                Err(self.error("unterminated quoted string"))
+            }
+            Some(_) => {
+                let start = self.pos;
+                while let Some(&c) = self.chars.get(self.pos) {
+                    let ends_key = c == ':'
+                        && self
+                            .chars
+                            .get(self.pos + 1)
+                            .is_none_or(|n| n.is_whitespace());
+                    if matches!(c, ',' | ']' | '}') || ends_key {
+                        break;
+                    }
+                    self.pos += 1;
+                }
+                let text: String = self.chars[start..self.pos].iter().collect();
+                Ok(plain_scalar(text.trim()))
+            }
+            None => Err(self.error("unexpected end of flow collection")),
+        }
+    }
+
+    fn separator(&mut self, close: char) -> Result<(), YamlError> {
+        self.skip_space();
+        match self.chars.get(self.pos) {
+            Some(',') => {
+                self.pos += 1;
+                Ok(())
+            }
+            Some(&c) if c == close => Ok(()),
+            _ => Err(self.error(&format!("expected `,` or `{}`", close))),
+        }
+    }
+}
+
+fn plain_scalar(text: &str) -> Yaml {
+    match text {
+        "" | "~" | "null" | "Null" | "NULL" => Yaml::Null,
+        _ => Yaml::Scalar(text.to_string()),
+    }
+}
+
+/// Split `key: value` at the first `:` outside quotes that ends the key
+fn split_key(text: &str) -> Option<(&str, &str)> {
+    let bytes = text.as_bytes();
+    let mut i = match bytes.first()? {
+        quote @ (b'"' | b'\'') => {
+            let close = text[1..].find(*quote as char)? + 1;
+            close + 1
+        }
+        b'[' | b'{' | b'-' if bytes.get(1).is_none_or(|b| *b == b' ') => return None,
+        b'[' | b'{' | b'|' | b'>' => return None,
+        _ => 0,
+    };
+
+    while i < bytes.len() {
+        if bytes[i] == b':' && bytes.get(i + 1).is_none_or(|b| *b == b' ') {
+            return Some((text[..i].trim_end(), text[i + 1..].trim()));
+        }
+        if bytes[i] == b'#' && i > 0 && bytes[i - 1] == b' ' {
+            return None;
+        }
+        i += 1;
+    }
+    None
+}
+
+/// Text before a ` #` comment that is not inside quotes
+fn strip_comment(text: &str) -> &str {
+    let mut quote = None;
+    let mut prev = ' ';
+    for (i, c) in text.char_indices() {
+        match quote {
+            Some(q) if c == q => quote = None,
+            Some(_) => {}
+            None if c == '"' || c == '\'' => {
+                // Quotes only open a string at the start of a token
+                if prev == ' ' || prev == '[' || prev == '{' || prev == ',' || prev == ':' {
+                    quote = Some(c);
+                }
+            }
+            None if c == '#' && prev.is_whitespace() => return text[..i].trim_end(),
+            None => {}
+        }
+        prev = c;
+    }
+    text
+}
+
+fn brackets_balanced(text: &str) -> bool {
+    let mut depth = 0i32;
+    let mut quote = None;
+    for c in text.chars() {
+        match (quote, c) {
+            (Some(q), c) if c == q => quote = None,
+            (Some(_), _) => {}
+            (None, '"' | '\'') => quote = Some(c),
+            (None, '[' | '{') => depth += 1,
+            (None, ']' | '}') => depth -= 1,
+            _ => {}
+        }
+    }
+    depth <= 0
+}
+
+/// Decode a possibly quoted scalar
+fn unquote(text: &str, line: usize) -> Result<String, YamlError> {
+    let error = |message: &str| YamlError {
+        line,
+        message: message.to_string(),
+    };
+
+    if let Some(inner) = text.strip_prefix('\'') {
+        let inner = inner
+            .strip_suffix('\'')
+            .ok_or_else(|| error("unterminated quoted string"))?;
+        return Ok(inner.replace("''", "'"));
+    }
+
+    let Some(inner) = text.strip_prefix('"') else {
+        return Ok(text.to_string());
+    };
+    let inner = inner
+        .strip_suffix('"')
+        .ok_or_else(|| error("unterminated quoted string"))?;
+
+    let mut out = String::with_capacity(inner.len());
+    let mut chars = inner.chars();
+    while let Some(c) = chars.next() {
+        if c != '\\' {
+            out.push(c);
+            continue;
+        }
+        match chars.next() {
+            Some('n') => out.push('\n'),
+            Some('t') => out.push('\t'),
+            Some('r') => out.push('\r'),
+            Some('0') => out.push('\0'),
+            Some('"') => out.push('"'),
+            Some('\\') => out.push('\\'),
+            Some('/') => out.push('/'),
+            Some(' ') => out.push(' '),
+            Some('u') => {
+                let hex: String = chars.by_ref().take(4).collect();
+                let decoded = u32::from_str_radix(&hex, 16)
+                    .ok()
+                    .and_then(char::from_u32)
+                    .ok_or_else(|| error("invalid \\u escape"))?;
+                out.push(decoded);
+            }
+            _ => return Err(error("invalid escape sequence")),
+        }
+    }
+    Ok(out)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_parse_rule_document() {
+        let yaml = r#"
+# crypto rules
+rules:
+  - id: weak-rsa
+    languages: [go, "java"]
+    severity: ERROR  # blocking
+    message: >-
+      RSA key of $BITS bits
+      is too small
+    pattern-either:
+      - pattern: rsa.GenerateKey($R, $BITS)
+      - pattern-regex: 'RSA_generate_key\(.*''x'''
+    metadata:
+      references:
+      - https://example.com/a
+      note: |
+        line one
+          indented
+"#;
+        let doc = parse(yaml).unwrap();
+        let rule = &doc.get("rules").unwrap().as_seq().unwrap()[0];
+        assert_eq!(rule.get("id").unwrap().as_str(), Some("weak-rsa"));
+        assert_eq!(rule.get("languages").unwrap().scalars(), vec!["go", "java"]);
+        assert_eq!(rule.get("severity").unwrap().as_str(), Some("ERROR"));
+        assert_eq!(
+            rule.get("message").unwrap().as_str(),
+            Some("RSA key of $BITS bits is too small")
+        );
+
+        let either = rule.get("pattern-either").unwrap().as_seq().unwrap();
+        assert_eq!(
+            either[0].get("pattern").unwrap().as_str(),
+            Some("rsa.GenerateKey($R, $BITS)")
+        );
+        assert_eq!(
+            either[1].get("pattern-regex").unwrap().as_str(),
+            Some(r"RSA_generate_key\(.*'x'")
+        );
+
+        let metadata = rule.get("metadata").unwrap();
+        assert_eq!(
+            metadata.get("references").unwrap().scalars(),
+            vec!["https://example.com/a"]
+        );
+        assert_eq!(
+            metadata.get("note").unwrap().as_str(),
+            Some("line one\n  indented\n")
+        );
+    }
+
+    #[test]
+    fn test_parse_errors() {
+        assert_eq!(parse("a: &x 1").unwrap_err().line, 1);
+        assert!(parse("a: 1\na: 2").is_err());
+        assert!(parse("a: [1, 2").is_err());
+        assert!(parse("a:\n  b: 1\n    c: 2").is_err());
+        assert_eq!(parse("").unwrap(), Yaml::Null);
+    }
+}
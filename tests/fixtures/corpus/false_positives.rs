@@ -0,0 +1,8 @@
+// This file contains comments and strings that mention crypto
+// but should not trigger actual vulnerabilities
+
+fn main() {
+    // We used to use RSA but migrated to quantum-safe algorithms // expect: none
+    let comment = "This mentions ECDSA in a string"; // expect: none
+    println!("MD5 is mentioned here but not used"); // expect: none
+}
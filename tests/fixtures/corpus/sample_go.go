@@ -0,0 +1,13 @@
+package main
+
+import (
+    "crypto/rsa" // expect: RSA
+    "crypto/ecdsa" // expect: ECDSA
+    "crypto/dh" // expect: DH
+)
+
+func main() {
+    privateKey, _ := rsa.GenerateKey(rand.Reader, 2048) // expect: RSA key_size=2048
+    ecdsaKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader) // expect: ECDSA
+    params := dh.GenerateParameters(2048) // expect: DH
+}
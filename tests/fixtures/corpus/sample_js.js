@@ -0,0 +1,10 @@
+const crypto = require('crypto');
+
+const { publicKey, privateKey } = crypto.generateKeyPairSync('rsa', { // expect: RSA
+    modulusLength: 2048,
+});
+
+const ecdh = crypto.createECDH('secp256k1'); // expect: ECDH
+const keys = ecdh.generateKeys(); // expect: ECDH
+
+const hash = crypto.createHash('md5'); // expect: MD5
@@ -0,0 +1,8 @@
+import hashlib
+from Crypto.PublicKey import RSA, DSA # expect: RSA, DSA
+
+md5_hash = hashlib.md5(data).hexdigest() # expect: MD5
+sha1_hash = hashlib.sha1(data).hexdigest() # expect: SHA-1
+
+rsa_key = RSA.generate(2048) # expect: RSA key_size=2048
+dsa_key = DSA.generate(2048) # expect: DSA
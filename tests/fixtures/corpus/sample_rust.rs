@@ -0,0 +1,8 @@
+use rsa::{RsaPrivateKey, RsaPublicKey}; // expect: RSA
+use ecdsa::SigningKey; // expect: ECDSA
+
+fn main() {
+    let bits = 2048;
+    let private_key = RsaPrivateKey::new(&mut rng, bits).unwrap(); // expect: RSA key_size=2048
+    let signing_key = SigningKey::random(&mut rng); // expect: ECDSA
+}
@@ -137,3 +137,37 @@ fn test_language_support() {
         assert!(result.is_ok(), "Failed for language: {}", lang);
     }
 }
+
+#[test]
+fn test_fixture_corpus_evaluation() {
+    use pqc_scanner::{Evaluation, Language, parse_labeled_source};
+
+    let corpus = std::path::Path::new(env!("CARGO_MANIFEST_DIR")).join("tests/fixtures/corpus");
+    let mut evaluation = Evaluation::new();
+
+    for (name, language) in [
+        ("sample_go.go", Language::Go),
+        ("sample_java.java", Language::Java),
+        ("sample_js.js", Language::JavaScript),
+        ("sample_py.py", Language::Python),
+        ("sample_rust.rs", Language::Rust),
+        ("false_positives.rs", Language::Rust),
+    ] {
+        let source = std::fs::read_to_string(corpus.join(name)).unwrap();
+        let labeled = parse_labeled_source(&source).unwrap();
+        let result = analyze(&labeled.source, &language.to_string()).unwrap();
+        evaluation.record(
+            name,
+            language,
+            &labeled.expectations,
+            &result.vulnerabilities,
+        );
+    }
+    evaluation.finish();
+
+    // Floors, not targets: raise them as detection improves
+    assert!(evaluation.overall.true_positives >= 18);
+    assert!(evaluation.overall.precision >= 0.8);
+    assert!(evaluation.overall.recall >= 0.8);
+    assert_eq!(evaluation.by_crypto_type["RSA"].false_negatives, 0);
+}